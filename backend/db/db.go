@@ -9,10 +9,21 @@ import (
 	"time"
 
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Supported values for Config.Driver (DB_DRIVER)
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
 )
 
 // Config holds database connection parameters
 type Config struct {
+	// Driver selects Postgres or a SQLite file
+	Driver string
+	// Path is the SQLite database file, created if it doesn't exist
+	Path     string
 	Host     string
 	Port     string
 	User     string
@@ -31,6 +42,8 @@ type Config struct {
 // DefaultConfig returns a configuration with reasonable defaults
 func DefaultConfig() Config {
 	return Config{
+		Driver:          DriverPostgres,
+		Path:            "mealplanner.db",
 		Host:            "localhost",
 		Port:            "5432",
 		MaxOpenConns:    25,
@@ -44,6 +57,16 @@ func DefaultConfig() Config {
 
 // Validate checks if the required config fields are provided
 func (c *Config) Validate() error {
+	switch c.Driver {
+	case DriverSQLite:
+		if c.Path == "" {
+			return errors.New("database path is required for sqlite")
+		}
+		return nil
+	case DriverPostgres, "":
+	default:
+		return fmt.Errorf("unknown database driver %q: use %s or %s", c.Driver, DriverPostgres, DriverSQLite)
+	}
 	if c.Host == "" {
 		return errors.New("database host is required")
 	}
@@ -75,6 +98,9 @@ func ConnectDB(cfg Config) (*sql.DB, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid database configuration: %w", err)
 	}
+	if cfg.Driver == DriverSQLite {
+		return connectSQLite(cfg)
+	}
 
 	// Create connection string
 	connStr := fmt.Sprintf(
@@ -125,6 +151,28 @@ func ConnectDB(cfg Config) (*sql.DB, error) {
 	return db, nil
 }
 
+// connectSQLite opens the SQLite database file at cfg.Path, creating it if needed.
+// Foreign keys are switched on so deletes cascade as they do in Postgres, and WAL mode
+// with a busy timeout lets requests read while another one writes.
+func connectSQLite(cfg Config) (*sql.DB, error) {
+	dsn := "file:" + cfg.Path + "?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, &ConnectionError{
+			Original: err,
+			Message:  "Failed to open the SQLite database at " + cfg.Path,
+		}
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, &ConnectionError{
+			Original: err,
+			Message:  "Failed to open the SQLite database at " + cfg.Path + ". Make sure its directory exists and is writable",
+		}
+	}
+	return db, nil
+}
+
 // IsConnectionError checks if an error is related to database connectivity
 func IsConnectionError(err error) bool {
 	if err == nil {
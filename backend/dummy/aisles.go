@@ -0,0 +1,51 @@
+package dummy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"mealplanner/models"
+)
+
+// GetAisleSettings returns the in-memory store layout and aisle overrides
+func (s *Store) GetAisleSettings() (models.AisleSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	overrides := make(map[string]string, len(s.aisleOverrides))
+	for name, aisle := range s.aisleOverrides {
+		overrides[name] = aisle
+	}
+	return models.AisleSettings{Order: s.aisleOrder, Overrides: overrides}, nil
+}
+
+// SaveAisleOrder validates and replaces the in-memory store layout
+func (s *Store) SaveAisleOrder(order []string) ([]string, error) {
+	order, err := models.NormalizeAisleOrder(order)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.aisleOrder = order
+	return order, nil
+}
+
+// SetAisleOverride sets or, with an empty aisle, clears the aisle for an ingredient
+func (s *Store) SetAisleOverride(name, aisle string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return errors.New("ingredient name is required")
+	}
+	if aisle != "" && !models.IsKnownAisle(aisle) {
+		return fmt.Errorf("unknown aisle %q", aisle)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if aisle == "" {
+		delete(s.aisleOverrides, name)
+		return nil
+	}
+	s.aisleOverrides[name] = aisle
+	return nil
+}
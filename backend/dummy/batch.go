@@ -0,0 +1,51 @@
+package dummy
+
+import (
+	"mealplanner/models"
+	"mealplanner/store"
+)
+
+// Atomically runs fn against the store and, when fn fails, puts the meals, tags, plans
+// and cooking log back as they were before it ran. Writes other callers make while fn
+// runs are undone along with it.
+func (s *Store) Atomically(fn func(w store.Writer) error) error {
+	s.mu.RLock()
+	meals := make([]*models.Meal, len(s.meals))
+	for i, m := range s.meals {
+		meals[i] = cloneMeal(m)
+	}
+	plans := make([]*models.MealPlan, len(s.plans))
+	for i, p := range s.plans {
+		plans[i] = clonePlan(p)
+	}
+	tags := append([]models.Tag{}, s.tags...)
+	history := append([]models.CookingLogEntry{}, s.history...)
+	ids := nextIDs{
+		Meal:       s.nextMealID,
+		Ingredient: s.nextIngredientID,
+		Step:       s.nextStepID,
+		Tag:        s.nextTagID,
+		Plan:       s.nextPlanID,
+		Entry:      s.nextEntry,
+	}
+	s.mu.RUnlock()
+
+	err := fn(s)
+	if err == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meals = meals
+	s.plans = plans
+	s.tags = tags
+	s.history = history
+	s.nextMealID = ids.Meal
+	s.nextIngredientID = ids.Ingredient
+	s.nextStepID = ids.Step
+	s.nextTagID = ids.Tag
+	s.nextPlanID = ids.Plan
+	s.nextEntry = ids.Entry
+	return err
+}
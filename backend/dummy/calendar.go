@@ -0,0 +1,25 @@
+package dummy
+
+import "mealplanner/models"
+
+// GetCalendarToken returns the in-memory calendar feed token
+func (s *Store) GetCalendarToken() (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.calendarToken == "" {
+		return "", models.ErrNoCalendarToken
+	}
+	return s.calendarToken, nil
+}
+
+// RotateCalendarToken replaces the in-memory calendar feed token with a new one
+func (s *Store) RotateCalendarToken() (string, error) {
+	token, err := models.NewCalendarToken()
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calendarToken = token
+	return token, nil
+}
@@ -1,26 +1,81 @@
+// Package dummy is an in-memory Store used when the server runs without a database.
 package dummy
 
 import (
 	"encoding/csv"
-	"math/rand"
+	"errors"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"mealplanner/models"
 )
 
-var meals []*models.Meal
+// Store keeps meals, steps, tags, plans, rules, the cooking log, the pantry and aisle
+// settings in memory.
+// It is safe for concurrent use.
+type Store struct {
+	mu sync.RWMutex
 
-// Load reads meals from a CSV file (same format used for seeding)
-func Load(csvPath string) error {
+	meals            []*models.Meal
+	nextMealID       int
+	nextIngredientID int
+	nextStepID       int
+
+	tags      []models.Tag
+	nextTagID int
+
+	rules      models.PlanRules
+	plans      []*models.MealPlan
+	nextPlanID int
+	nextEntry  int
+
+	history   []models.CookingLogEntry
+	nextLogID int
+
+	pantry       []models.PantryItem
+	nextPantryID int
+
+	aisleOrder     []string
+	aisleOverrides map[string]string
+
+	calendarToken string
+}
+
+// NewStore returns an empty in-memory store with the default planning rules and store layout.
+func NewStore() *Store {
+	return &Store{
+		nextMealID:       1,
+		nextIngredientID: 1,
+		nextStepID:       1,
+		nextTagID:        1,
+		rules:            models.DefaultPlanRules(),
+		nextPlanID:       1,
+		nextEntry:        1,
+		nextLogID:        1,
+		nextPantryID:     1,
+		aisleOrder:       models.DefaultAisleOrder,
+		aisleOverrides:   map[string]string{},
+	}
+}
+
+// Ping always succeeds; there's nothing to connect to.
+func (s *Store) Ping() error {
+	return nil
+}
+
+// Load reads meals from a CSV file (same format used for seeding), keeping each meal's
+// last_planned date so the planner's repeat cooldown behaves as it does with a database
+func (s *Store) Load(csvPath string) error {
 	file, err := os.Open(csvPath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
 	records, err := r.ReadAll()
 	if err != nil {
 		return err
@@ -29,133 +84,459 @@ func Load(csvPath string) error {
 		return nil
 	}
 	records = records[1:]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	mealMap := map[string]*models.Meal{}
 	for _, rec := range records {
-		if len(rec) < 3 {
+		row, err := models.ParseSeedRecord(rec)
+		if err != nil {
 			continue
 		}
-		name := rec[0]
-		ingredientField := rec[1]
-		effort, _ := strconv.Atoi(rec[2])
-		m, ok := mealMap[name]
+		key := strings.ToLower(row.MealName)
+		m, ok := mealMap[key]
 		if !ok {
 			m = &models.Meal{
-				ID:             len(mealMap) + 1,
-				MealName:       name,
-				RelativeEffort: effort,
-				RedMeat:        isRedMeat(name),
+				ID:             s.nextMealID,
+				MealName:       row.MealName,
+				RelativeEffort: row.RelativeEffort,
+				LastPlanned:    row.LastPlanned,
+				RedMeat:        models.IsRedMeat(row.MealName),
+				Tags:           []string{},
 				Ingredients:    []models.Ingredient{},
 				Steps:          []models.Step{},
 			}
-			mealMap[name] = m
-			meals = append(meals, m)
-		}
-		qty, unit, ingName := parseIngredient(ingredientField)
-		qtyF, _ := strconv.ParseFloat(qty, 64)
-		m.Ingredients = append(m.Ingredients, models.Ingredient{
-			ID:       len(m.Ingredients) + 1,
-			MealID:   m.ID,
-			Quantity: qtyF,
-			Unit:     unit,
-			Name:     ingName,
-		})
+			s.nextMealID++
+			mealMap[key] = m
+			s.meals = append(s.meals, m)
+		}
+		ing := row.Ingredient
+		ing.ID = s.nextIngredientID
+		ing.MealID = m.ID
+		m.Ingredients = append(m.Ingredients, ing)
+		s.nextIngredientID++
+	}
+	return nil
+}
+
+// cloneMeal copies a meal so callers can't change the store's copy.
+func cloneMeal(m *models.Meal) *models.Meal {
+	c := *m
+	c.Tags = append([]string{}, m.Tags...)
+	c.Slots = append([]string{}, m.Slots...)
+	c.Ingredients = append([]models.Ingredient{}, m.Ingredients...)
+	c.Steps = append([]models.Step{}, m.Steps...)
+	return &c
+}
+
+// findMeal returns the stored meal with the given ID, or nil. Callers hold the lock.
+func (s *Store) findMeal(id int) *models.Meal {
+	for _, m := range s.meals {
+		if m.ID == id {
+			return m
+		}
 	}
 	return nil
 }
 
-// GetAllMeals returns all loaded meals
-func GetAllMeals() ([]*models.Meal, error) {
-	return meals, nil
+// GetAllMeals returns all meals
+func (s *Store) GetAllMeals() ([]*models.Meal, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*models.Meal, 0, len(s.meals))
+	for _, m := range s.meals {
+		out = append(out, cloneMeal(m))
+	}
+	return out, nil
 }
 
 // GetMealsByIDs returns meals matching the given IDs
-func GetMealsByIDs(ids []int) ([]*models.Meal, error) {
+func (s *Store) GetMealsByIDs(ids []int) ([]*models.Meal, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	var out []*models.Meal
 	for _, id := range ids {
-		for _, m := range meals {
-			if m.ID == id {
-				out = append(out, m)
-				break
-			}
+		if m := s.findMeal(id); m != nil {
+			out = append(out, cloneMeal(m))
 		}
 	}
 	return out, nil
 }
 
-// SwapMeal returns a random meal excluding the given ID
-func SwapMeal(currentID int) (*models.Meal, error) {
-	if len(meals) == 0 {
-		return nil, nil
+// CreateMeal adds a meal with its ingredients, steps and tags, assigning new IDs
+func (s *Store) CreateMeal(meal models.Meal) (*models.Meal, error) {
+	tags, err := models.NormalizeTagNames(meal.Tags)
+	if err != nil {
+		return nil, err
 	}
-	rand.Seed(time.Now().UnixNano())
-	for i := 0; i < 10; i++ {
-		m := meals[rand.Intn(len(meals))]
-		if m.ID != currentID {
-			return m, nil
-		}
+	meal.Tags = tags
+	if meal.Slots, err = models.NormalizeSlots(meal.Slots); err != nil {
+		return nil, err
 	}
-	// fallback to first different meal
-	for _, m := range meals {
-		if m.ID != currentID {
-			return m, nil
-		}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureTags(tags)
+	m := cloneMeal(&meal)
+	m.Nights = m.NightsCovered()
+	m.ID = s.nextMealID
+	s.nextMealID++
+	s.numberParts(m)
+	s.meals = append(s.meals, m)
+	return cloneMeal(m), nil
+}
+
+// numberParts gives a meal's ingredients and steps new IDs. Callers hold the lock.
+func (s *Store) numberParts(m *models.Meal) {
+	for i := range m.Ingredients {
+		m.Ingredients[i].ID = s.nextIngredientID
+		m.Ingredients[i].MealID = m.ID
+		s.nextIngredientID++
+	}
+	for i := range m.Steps {
+		m.Steps[i].ID = s.nextStepID
+		m.Steps[i].MealID = m.ID
+		m.Steps[i].StepNumber = i + 1
+		s.nextStepID++
 	}
-	return nil, nil
 }
 
-// GenerateWeeklyMealPlan creates a simple meal plan using effort ranges
-func GenerateWeeklyMealPlan() (map[string]*models.Meal, error) {
-	plan := make(map[string]*models.Meal)
-	rand.Seed(time.Now().UnixNano())
-	redUsed := false
+// ReplaceMeal overwrites a meal in place, keeping its ID, last planned time, plans and
+// cooking log
+func (s *Store) ReplaceMeal(mealID int, meal models.Meal) (*models.Meal, error) {
+	tags, err := models.NormalizeTagNames(meal.Tags)
+	if err != nil {
+		return nil, err
+	}
+	meal.Tags = tags
+	if meal.Slots, err = models.NormalizeSlots(meal.Slots); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.findMeal(mealID)
+	if old == nil {
+		return nil, models.ErrMealNotFound
+	}
+	s.ensureTags(tags)
+	m := cloneMeal(&meal)
+	m.ID = mealID
+	m.LastPlanned = old.LastPlanned
+	m.Nights = m.NightsCovered()
+	s.numberParts(m)
+	*old = *m
+
+	// Saved plans show the meal's new columns, as a join on the meals table would.
+	for _, plan := range s.plans {
+		for j, e := range plan.Entries {
+			if e.MealID != mealID || e.Meal == nil {
+				continue
+			}
+			if e.Status == models.EntryStatusLeftovers {
+				plan.Entries[j].Meal = models.NewLeftovers(m)
+				continue
+			}
+			plan.Entries[j].Meal = &models.Meal{
+				ID:             m.ID,
+				MealName:       m.MealName,
+				RelativeEffort: m.RelativeEffort,
+				LastPlanned:    e.Meal.LastPlanned,
+				RedMeat:        m.RedMeat,
+				URL:            m.URL,
+				Servings:       m.Servings,
+				Nights:         m.Nights,
+				Slots:          append([]string{}, m.Slots...),
+			}
+		}
+	}
+	return cloneMeal(m), nil
+}
 
-	pick := func(min, max int) *models.Meal {
-		filtered := make([]*models.Meal, 0)
-		for _, m := range meals {
-			if m.RelativeEffort >= min && m.RelativeEffort <= max {
-				if redUsed && m.RedMeat {
-					continue
+// DeleteMeal removes a meal. Saved plans keep the day but lose the meal, as they do
+// when a meal is deleted from the database.
+func (s *Store) DeleteMeal(mealID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.meals {
+		if m.ID != mealID {
+			continue
+		}
+		s.meals = append(s.meals[:i], s.meals[i+1:]...)
+		kept := s.history[:0]
+		for _, e := range s.history {
+			if e.MealID != mealID {
+				kept = append(kept, e)
+			}
+		}
+		s.history = kept
+		for _, plan := range s.plans {
+			for j := range plan.Entries {
+				if plan.Entries[j].MealID == mealID {
+					plan.Entries[j].MealID = 0
+					plan.Entries[j].Meal = nil
 				}
-				filtered = append(filtered, m)
 			}
 		}
-		if len(filtered) == 0 {
+		return nil
+	}
+	return models.ErrMealNotFound
+}
+
+// UpdateMealIngredient replaces the name, quantity and unit of one of a meal's ingredients
+func (s *Store) UpdateMealIngredient(mealID int, ing models.Ingredient) error {
+	if ing.ID == 0 {
+		return errors.New("ingredient ID not provided")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.findMeal(mealID)
+	if m == nil {
+		return models.ErrIngredientNotFound
+	}
+	for i := range m.Ingredients {
+		if m.Ingredients[i].ID == ing.ID {
+			m.Ingredients[i].Name = ing.Name
+			m.Ingredients[i].Quantity = ing.Quantity
+			m.Ingredients[i].Unit = ing.Unit
+			return nil
+		}
+	}
+	return models.ErrIngredientNotFound
+}
+
+// DeleteMealIngredient removes one of a meal's ingredients
+func (s *Store) DeleteMealIngredient(mealID, ingredientID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.findMeal(mealID)
+	if m == nil {
+		return models.ErrIngredientNotFound
+	}
+	for i := range m.Ingredients {
+		if m.Ingredients[i].ID == ingredientID {
+			m.Ingredients = append(m.Ingredients[:i], m.Ingredients[i+1:]...)
 			return nil
 		}
-		m := filtered[rand.Intn(len(filtered))]
-		if m.RedMeat {
-			redUsed = true
+	}
+	return models.ErrIngredientNotFound
+}
+
+// SetLastPlanned records when a meal was last planned
+func (s *Store) SetLastPlanned(mealID int, lastPlanned time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.findMeal(mealID)
+	if m == nil {
+		return models.ErrMealNotFound
+	}
+	m.LastPlanned = lastPlanned
+	return nil
+}
+
+// SetMealSlots replaces the slots a meal can be planned in
+func (s *Store) SetMealSlots(mealID int, slots []string) ([]string, error) {
+	normalized, err := models.NormalizeSlots(slots)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.findMeal(mealID)
+	if m == nil {
+		return nil, models.ErrMealNotFound
+	}
+	m.Slots = normalized
+	return append([]string{}, normalized...), nil
+}
+
+// pool returns copies of every meal for the planner, with their cooking stats. Callers
+// hold the lock.
+func (s *Store) pool() []*models.Meal {
+	pool := make([]*models.Meal, 0, len(s.meals))
+	for _, m := range s.meals {
+		pool = append(pool, cloneMeal(m))
+	}
+	models.ApplyCookingStats(pool, s.history)
+	return pool
+}
+
+// GenerateMealPlan creates a meal plan with the same planner the SQL generator uses
+func (s *Store) GenerateMealPlan(rules models.PlanRules, opts models.SolveOptions) (*models.PlanResult, error) {
+	s.mu.RLock()
+	pool := s.pool()
+	s.mu.RUnlock()
+	return models.SolvePlan(pool, rules, opts)
+}
+
+// SwapMealInPlan ranks replacements for one day of the plan with the same rules as the generator
+func (s *Store) SwapMealInPlan(rules models.PlanRules, plan map[string]int, day string, limit int) ([]models.SwapCandidate, error) {
+	s.mu.RLock()
+	pool := s.pool()
+	s.mu.RUnlock()
+	return models.RankSwapCandidates(pool, rules, plan, day, models.SolveOptions{}, limit)
+}
+
+// GetPlanRules returns the in-memory planning rules
+func (s *Store) GetPlanRules() (models.PlanRules, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rules, nil
+}
+
+// SavePlanRules validates and replaces the in-memory planning rules
+func (s *Store) SavePlanRules(r models.PlanRules) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = r
+	return nil
+}
+
+// SaveMealPlan stores the plan over the dates PlanSpan gives it under the in-memory rules,
+// replacing any plan already saved with the same start, and updates last_planned for its
+// meals
+func (s *Store) SaveMealPlan(weekStart time.Time, plan map[string]*models.Meal, servings map[string]int) (*models.MealPlan, error) {
+	var keys []string
+	for _, key := range models.PlanKeys(plan) {
+		if plan[key] != nil && models.IsPlanKey(key) {
+			keys = append(keys, key)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	weekStart, days, dates := s.rules.PlanSpan(weekStart, keys)
+
+	// What the saved plans held on these days, the latest-starting plan winning.
+	ordered := append([]*models.MealPlan{}, s.plans...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if !ordered[i].WeekStart.Equal(ordered[j].WeekStart) {
+			return ordered[i].WeekStart.Before(ordered[j].WeekStart)
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+	previous := make(map[string]models.MealPlanEntry)
+	for _, p := range ordered {
+		for _, e := range p.Entries {
+			if !e.Date.Before(weekStart) && e.Date.Before(weekStart.AddDate(0, 0, days)) {
+				previous[e.DateKey()] = e
+			}
+		}
+	}
+
+	kept := s.plans[:0]
+	for _, p := range s.plans {
+		if !p.WeekStart.Equal(weekStart) {
+			kept = append(kept, p)
+			continue
+		}
+		// The cooking log outlives the replaced plan's days.
+		for _, e := range p.Entries {
+			for i := range s.history {
+				if s.history[i].PlanEntryID == e.ID {
+					s.history[i].PlanEntryID = 0
+				}
+			}
 		}
-		return m
 	}
+	s.plans = kept
+
+	saved := &models.MealPlan{ID: s.nextPlanID, WeekStart: weekStart, Days: days, CreatedAt: time.Now().UTC()}
+	s.nextPlanID++
+	stored := &models.MealPlan{ID: saved.ID, WeekStart: saved.WeekStart, Days: days, CreatedAt: saved.CreatedAt}
+	for _, key := range keys {
+		meal := plan[key]
+		_, slot := models.SplitPlanKey(key)
+		date := dates[key]
+		entry := models.MealPlanEntry{ID: s.nextEntry, PlanID: saved.ID, Date: date, Day: date.Weekday().String(), Slot: slot, MealID: meal.ID, Status: models.EntryStatusPlanned, Servings: servings[key], Meal: meal}
+		switch {
+		case meal.IsLeftovers():
+			entry.Status = models.EntryStatusLeftovers
+			entry.MealID = meal.LeftoversOf
+		case meal.ID == 0:
+			entry.Status = models.EntryStatusEatingOut
+		}
+		if prev, ok := previous[entry.DateKey()]; ok {
+			entry.Sequence = entry.SequenceAfter(prev)
+		}
+		s.nextEntry++
+		saved.Entries = append(saved.Entries, entry)
 
-	plan["Monday"] = pick(0, 2)
-	plan["Tuesday"] = pick(3, 5)
-	plan["Wednesday"] = pick(3, 5)
-	plan["Thursday"] = pick(3, 5)
-	plan["Friday"] = &models.Meal{MealName: "Eating out"}
-	plan["Saturday"] = pick(3, 5)
-	plan["Sunday"] = pick(6, 100)
+		// The stored entry holds what the database would give back: the meal's own
+		// columns, or the eating out placeholder.
+		entry.Meal = nil
+		if meal.IsLeftovers() {
+			if m := s.findMeal(meal.LeftoversOf); m != nil {
+				entry.Meal = models.NewLeftovers(m)
+			}
+		} else if meal.ID == 0 {
+			entry.Meal = &models.Meal{MealName: models.EatingOutMealName}
+		} else if m := s.findMeal(meal.ID); m != nil {
+			m.LastPlanned = saved.CreatedAt
+			entry.Meal = &models.Meal{
+				ID:             m.ID,
+				MealName:       m.MealName,
+				RelativeEffort: m.RelativeEffort,
+				LastPlanned:    m.LastPlanned,
+				RedMeat:        m.RedMeat,
+				URL:            m.URL,
+				Servings:       m.Servings,
+				Nights:         m.Nights,
+				Slots:          append([]string{}, m.Slots...),
+			}
+		}
+		stored.Entries = append(stored.Entries, entry)
+	}
+	sort.SliceStable(stored.Entries, func(i, j int) bool { return stored.Entries[i].Date.Before(stored.Entries[j].Date) })
+	s.plans = append(s.plans, stored)
+	return saved, nil
+}
 
-	return plan, nil
+// clonePlan copies a stored plan and its entries.
+func clonePlan(p *models.MealPlan) *models.MealPlan {
+	c := *p
+	c.Entries = make([]models.MealPlanEntry, len(p.Entries))
+	for i, e := range p.Entries {
+		if e.Meal != nil {
+			m := *e.Meal
+			e.Meal = &m
+		}
+		c.Entries[i] = e
+	}
+	return &c
 }
 
-// Helper functions copied from seed.go
-func parseIngredient(s string) (string, string, string) {
-	tokens := strings.Fields(s)
-	if len(tokens) < 3 {
-		return "", "", s
+// GetLatestMealPlan returns the saved plan with the most recent start
+func (s *Store) GetLatestMealPlan() (*models.MealPlan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var latest *models.MealPlan
+	for _, p := range s.plans {
+		if latest == nil || p.WeekStart.After(latest.WeekStart) ||
+			(p.WeekStart.Equal(latest.WeekStart) && p.ID > latest.ID) {
+			latest = p
+		}
+	}
+	if latest == nil {
+		return nil, models.ErrNoMealPlan
 	}
-	return tokens[0], tokens[1], strings.Join(tokens[2:], " ")
+	return clonePlan(latest), nil
 }
 
-func isRedMeat(mealName string) bool {
-	lower := strings.ToLower(mealName)
-	keywords := []string{"beef", "steak", "burger", "pork", "ham"}
-	for _, kw := range keywords {
-		if strings.Contains(lower, kw) {
-			return true
+// ListMealPlans returns the saved plans covering any date within [from, to], oldest first
+func (s *Store) ListMealPlans(from, to time.Time) ([]*models.MealPlan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	plans := []*models.MealPlan{}
+	for _, p := range s.plans {
+		if !from.IsZero() && p.EndsBefore(from) {
+			continue
+		}
+		if !to.IsZero() && p.WeekStart.After(to) {
+			continue
 		}
+		plans = append(plans, clonePlan(p))
 	}
-	return false
+	sort.Slice(plans, func(i, j int) bool { return plans[i].WeekStart.Before(plans[j].WeekStart) })
+	return plans, nil
 }
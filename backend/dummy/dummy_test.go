@@ -0,0 +1,157 @@
+package dummy
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"mealplanner/models"
+	"mealplanner/store"
+	"mealplanner/store/storetest"
+)
+
+func TestStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store { return NewStore() })
+}
+
+func TestLoad(t *testing.T) {
+	s := NewStore()
+	if err := s.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	meals, _ := s.GetAllMeals()
+	if len(meals) == 0 {
+		t.Fatalf("expected meals from the CSV")
+	}
+	created, err := s.CreateMeal(models.Meal{MealName: "New meal", RelativeEffort: 2})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	for _, m := range meals {
+		if m.ID == created.ID {
+			t.Errorf("created meal reused loaded ID %d", m.ID)
+		}
+	}
+}
+
+func TestLoad_KeepsLastPlanned(t *testing.T) {
+	s := NewStore()
+	if err := s.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	meals, _ := s.GetAllMeals()
+	want := time.Date(2024, 11, 3, 0, 0, 0, 0, time.UTC)
+	for _, m := range meals {
+		if m.MealName == "Ham and cheese buns (NYT)" {
+			if !m.LastPlanned.Equal(want) {
+				t.Errorf("expected last planned %v, got %v", want, m.LastPlanned)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected the CSV's first meal to be loaded")
+}
+
+func TestStore_ConcurrentWrites(t *testing.T) {
+	s := NewStore()
+	var wg sync.WaitGroup
+	ids := make(chan int, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m, err := s.CreateMeal(models.Meal{MealName: fmt.Sprintf("Meal %d", i), RelativeEffort: 2})
+			if err != nil {
+				t.Errorf("CreateMeal: %v", err)
+				return
+			}
+			if _, err := s.AddStepToMeal(models.Step{MealID: m.ID, Instruction: "Cook"}); err != nil {
+				t.Errorf("AddStepToMeal: %v", err)
+			}
+			s.GetAllMeals()
+			ids <- m.ID
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[int]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("meal ID %d handed out twice", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != 50 {
+		t.Errorf("expected 50 meals, got %d", len(seen))
+	}
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	s := NewStore()
+	if err := s.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	created, err := s.CreateMeal(models.Meal{MealName: "Pancakes", RelativeEffort: 1, Steps: []models.Step{{Instruction: "Flip"}}})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	if _, err := s.CreatePantryItem(models.PantryItem{Name: "flour", Quantity: 1, Unit: "kg"}); err != nil {
+		t.Fatalf("CreatePantryItem: %v", err)
+	}
+	if _, err := s.SaveMealPlan(time.Now(), map[string]*models.Meal{"Monday": created}, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	if err := s.SetAisleOverride("flour", models.AisleDairy); err != nil {
+		t.Fatalf("SetAisleOverride: %v", err)
+	}
+	if _, err := s.MarkPlanDayDone(time.Now(), "Monday", models.CookingLogEntry{Status: models.CookingStatusCooked, Rating: 5}); err != nil {
+		t.Fatalf("MarkPlanDayDone: %v", err)
+	}
+	// Deleting the newest meal must not let its ID be reused after a restart.
+	doomed, _ := s.CreateMeal(models.Meal{MealName: "Doomed", RelativeEffort: 1})
+	if err := s.DeleteMeal(doomed.ID); err != nil {
+		t.Fatalf("DeleteMeal: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := s.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+	restored := NewStore()
+	if err := restored.LoadSnapshot(path); err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+
+	before, _ := s.GetAllMeals()
+	after, _ := restored.GetAllMeals()
+	if len(after) != len(before) {
+		t.Fatalf("expected %d meals, got %d", len(before), len(after))
+	}
+	got, _ := restored.GetMealsByIDs([]int{created.ID})
+	if len(got) != 1 || len(got[0].Steps) != 1 || got[0].Steps[0].Instruction != "Flip" {
+		t.Errorf("expected the created meal with its step, got %+v", got)
+	}
+	if items, _ := restored.ListPantryItems(); len(items) != 1 || items[0].Name != "flour" {
+		t.Errorf("expected the pantry to be restored, got %+v", items)
+	}
+	if plan, err := restored.GetLatestMealPlan(); err != nil || len(plan.Entries) != 1 {
+		t.Errorf("expected the saved plan to be restored, got %+v, %v", plan, err)
+	}
+	if history, _ := restored.GetMealHistory(created.ID); len(history) != 1 || history[0].Rating != 5 {
+		t.Errorf("expected the cooking log to be restored, got %+v", history)
+	}
+	if settings, _ := restored.GetAisleSettings(); settings.Overrides["flour"] != models.AisleDairy {
+		t.Errorf("expected the aisle override to be restored, got %v", settings.Overrides)
+	}
+
+	next, err := restored.CreateMeal(models.Meal{MealName: "Waffles", RelativeEffort: 1})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	if next.ID <= doomed.ID {
+		t.Errorf("expected a new ID after %d, got %d", doomed.ID, next.ID)
+	}
+}
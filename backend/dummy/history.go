@@ -0,0 +1,84 @@
+package dummy
+
+import (
+	"sort"
+	"time"
+
+	"mealplanner/models"
+)
+
+// MarkPlanDayDone records whether the meal planned on a day of the saved plans was cooked
+// or skipped, replacing any earlier record for that day. A weekday is in the household's
+// week containing weekStart; when saved plans overlap, the one that starts latest is marked
+func (s *Store) MarkPlanDayDone(weekStart time.Time, day string, entry models.CookingLogEntry) (*models.CookingLogEntry, error) {
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
+	_, slot := models.SplitPlanKey(day)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	date := s.rules.DayDate(weekStart, day)
+
+	covered := false
+	var planned *models.MealPlanEntry
+	var from *models.MealPlan
+	for _, p := range s.plans {
+		if date.Before(p.WeekStart) || !date.Before(p.WeekStart.AddDate(0, 0, p.Days)) {
+			continue
+		}
+		covered = true
+		for i := range p.Entries {
+			e := &p.Entries[i]
+			if !e.Date.Equal(date) || e.Slot != slot {
+				continue
+			}
+			if from == nil || p.WeekStart.After(from.WeekStart) || (p.WeekStart.Equal(from.WeekStart) && p.ID > from.ID) {
+				planned, from = e, p
+			}
+		}
+	}
+	if !covered {
+		return nil, models.ErrNoMealPlan
+	}
+	if planned == nil || planned.MealID == 0 || planned.Status == models.EntryStatusEatingOut || planned.Status == models.EntryStatusLeftovers {
+		return nil, models.ErrNoMealOnDay
+	}
+
+	entry.ID = s.nextLogID
+	s.nextLogID++
+	entry.MealID = planned.MealID
+	entry.PlanEntryID = planned.ID
+	entry.CookedOn = date
+	entry.CreatedAt = time.Now().UTC()
+	kept := s.history[:0]
+	for _, e := range s.history {
+		if e.PlanEntryID != planned.ID {
+			kept = append(kept, e)
+		}
+	}
+	s.history = append(kept, entry)
+	planned.Status = entry.Status
+	return &entry, nil
+}
+
+// GetMealHistory returns a meal's cooking log, newest first
+func (s *Store) GetMealHistory(mealID int) ([]models.CookingLogEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.findMeal(mealID) == nil {
+		return nil, models.ErrMealNotFound
+	}
+	history := []models.CookingLogEntry{}
+	for _, e := range s.history {
+		if e.MealID == mealID {
+			history = append(history, e)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		if !history[i].CookedOn.Equal(history[j].CookedOn) {
+			return history[i].CookedOn.After(history[j].CookedOn)
+		}
+		return history[i].ID > history[j].ID
+	})
+	return history, nil
+}
@@ -0,0 +1,69 @@
+package dummy
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"mealplanner/models"
+)
+
+// ListPantryItems returns the in-memory pantry ordered by name
+func (s *Store) ListPantryItems() ([]models.PantryItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	items := append([]models.PantryItem{}, s.pantry...)
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+// CreatePantryItem validates and adds an item to the in-memory pantry
+func (s *Store) CreatePantryItem(item models.PantryItem) (*models.PantryItem, error) {
+	item.Normalize()
+	if err := item.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.pantry {
+		if p.Name == item.Name {
+			return nil, fmt.Errorf("pantry item %q already exists", item.Name)
+		}
+	}
+	item.ID = s.nextPantryID
+	s.nextPantryID++
+	item.UpdatedAt = time.Now().UTC()
+	s.pantry = append(s.pantry, item)
+	return &item, nil
+}
+
+// UpdatePantryItem validates and replaces an item in the in-memory pantry
+func (s *Store) UpdatePantryItem(item models.PantryItem) (*models.PantryItem, error) {
+	item.Normalize()
+	if err := item.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, p := range s.pantry {
+		if p.ID == item.ID {
+			item.UpdatedAt = time.Now().UTC()
+			s.pantry[i] = item
+			return &item, nil
+		}
+	}
+	return nil, models.ErrPantryItemNotFound
+}
+
+// DeletePantryItem removes an item from the in-memory pantry
+func (s *Store) DeletePantryItem(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, p := range s.pantry {
+		if p.ID == id {
+			s.pantry = append(s.pantry[:i], s.pantry[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrPantryItemNotFound
+}
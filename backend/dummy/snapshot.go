@@ -0,0 +1,203 @@
+package dummy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"mealplanner/models"
+)
+
+// snapshot is everything in a Store, as written to a JSON file.
+type snapshot struct {
+	Meals          []*models.Meal           `json:"meals"`
+	Tags           []models.Tag             `json:"tags"`
+	Rules          models.PlanRules         `json:"rules"`
+	Plans          []*models.MealPlan       `json:"plans"`
+	History        []models.CookingLogEntry `json:"history"`
+	Pantry         []models.PantryItem      `json:"pantry"`
+	AisleOrder     []string                 `json:"aisleOrder"`
+	AisleOverrides map[string]string        `json:"aisleOverrides"`
+	CalendarToken  string                   `json:"calendarToken,omitempty"`
+	NextIDs        nextIDs                  `json:"nextIds"`
+}
+
+// nextIDs are the IDs the store hands out next, kept so IDs of deleted rows aren't reused
+// after a restart.
+type nextIDs struct {
+	Meal       int `json:"meal"`
+	Ingredient int `json:"ingredient"`
+	Step       int `json:"step"`
+	Tag        int `json:"tag"`
+	Plan       int `json:"plan"`
+	Entry      int `json:"entry"`
+	Log        int `json:"log"`
+	Pantry     int `json:"pantry"`
+}
+
+// SaveSnapshot writes the whole store to a JSON file. The file is replaced in one step,
+// so a crash while saving leaves the previous snapshot intact.
+func (s *Store) SaveSnapshot(path string) error {
+	s.mu.RLock()
+	snap := snapshot{
+		Meals:          s.meals,
+		Tags:           s.tags,
+		Rules:          s.rules,
+		Plans:          s.plans,
+		History:        s.history,
+		Pantry:         s.pantry,
+		AisleOrder:     s.aisleOrder,
+		AisleOverrides: s.aisleOverrides,
+		CalendarToken:  s.calendarToken,
+		NextIDs: nextIDs{
+			Meal:       s.nextMealID,
+			Ingredient: s.nextIngredientID,
+			Step:       s.nextStepID,
+			Tag:        s.nextTagID,
+			Plan:       s.nextPlanID,
+			Entry:      s.nextEntry,
+			Log:        s.nextLogID,
+			Pantry:     s.nextPantryID,
+		},
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot replaces the store's contents with a file written by SaveSnapshot.
+func (s *Store) LoadSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+
+	fresh := NewStore()
+	if snap.Meals != nil {
+		fresh.meals = snap.Meals
+	}
+	if snap.Tags != nil {
+		fresh.tags = snap.Tags
+	}
+	for _, t := range fresh.tags {
+		fresh.nextTagID = after(fresh.nextTagID, t.ID)
+	}
+	if len(snap.Rules.Days) > 0 {
+		fresh.rules = snap.Rules
+	}
+	if snap.Plans != nil {
+		fresh.plans = snap.Plans
+	}
+	if snap.History != nil {
+		fresh.history = snap.History
+	}
+	if snap.Pantry != nil {
+		fresh.pantry = snap.Pantry
+	}
+	if len(snap.AisleOrder) > 0 {
+		fresh.aisleOrder = snap.AisleOrder
+	}
+	if snap.AisleOverrides != nil {
+		fresh.aisleOverrides = snap.AisleOverrides
+	}
+	for _, m := range fresh.meals {
+		if m.Ingredients == nil {
+			m.Ingredients = []models.Ingredient{}
+		}
+		if m.Steps == nil {
+			m.Steps = []models.Step{}
+		}
+		if m.Tags == nil {
+			m.Tags = []string{}
+		}
+		// Register any tag a meal carries that the snapshot's tag list is missing.
+		tags, err := models.NormalizeTagNames(m.Tags)
+		if err != nil {
+			return err
+		}
+		m.Tags = tags
+		fresh.ensureTags(tags)
+	}
+
+	// Never hand out an ID that's already in the snapshot, even if the saved counters
+	// are missing or behind.
+	ids := snap.NextIDs
+	for _, next := range []*int{&ids.Meal, &ids.Ingredient, &ids.Step, &ids.Tag, &ids.Plan, &ids.Entry, &ids.Log, &ids.Pantry} {
+		if *next < 1 {
+			*next = 1
+		}
+	}
+	for _, m := range fresh.meals {
+		ids.Meal = after(ids.Meal, m.ID)
+		for _, ing := range m.Ingredients {
+			ids.Ingredient = after(ids.Ingredient, ing.ID)
+		}
+		for _, step := range m.Steps {
+			ids.Step = after(ids.Step, step.ID)
+		}
+	}
+	for _, t := range fresh.tags {
+		ids.Tag = after(ids.Tag, t.ID)
+	}
+	for _, p := range fresh.plans {
+		ids.Plan = after(ids.Plan, p.ID)
+		for _, e := range p.Entries {
+			ids.Entry = after(ids.Entry, e.ID)
+		}
+	}
+	for _, e := range fresh.history {
+		ids.Log = after(ids.Log, e.ID)
+	}
+	for _, item := range fresh.pantry {
+		ids.Pantry = after(ids.Pantry, item.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meals = fresh.meals
+	s.tags = fresh.tags
+	s.rules = fresh.rules
+	s.plans = fresh.plans
+	s.history = fresh.history
+	s.pantry = fresh.pantry
+	s.aisleOrder = fresh.aisleOrder
+	s.aisleOverrides = fresh.aisleOverrides
+	s.calendarToken = snap.CalendarToken
+	s.nextMealID = ids.Meal
+	s.nextIngredientID = ids.Ingredient
+	s.nextStepID = ids.Step
+	s.nextTagID = ids.Tag
+	s.nextPlanID = ids.Plan
+	s.nextEntry = ids.Entry
+	s.nextLogID = ids.Log
+	s.nextPantryID = ids.Pantry
+	return nil
+}
+
+// after returns next, or the ID following id if that's larger.
+func after(next, id int) int {
+	if id >= next {
+		return id + 1
+	}
+	return next
+}
@@ -0,0 +1,149 @@
+package dummy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"mealplanner/models"
+)
+
+// sortSteps keeps a meal's steps in step number order.
+func sortSteps(m *models.Meal) {
+	sort.SliceStable(m.Steps, func(i, j int) bool { return m.Steps[i].StepNumber < m.Steps[j].StepNumber })
+}
+
+// nextStepNumber returns the number after the meal's last step.
+func nextStepNumber(m *models.Meal) int {
+	next := 1
+	for _, step := range m.Steps {
+		if step.StepNumber >= next {
+			next = step.StepNumber + 1
+		}
+	}
+	return next
+}
+
+// GetStepsForMeal returns a meal's steps ordered by step number
+func (s *Store) GetStepsForMeal(mealID int) ([]models.Step, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m := s.findMeal(mealID)
+	if m == nil {
+		return []models.Step{}, nil
+	}
+	return append([]models.Step{}, m.Steps...), nil
+}
+
+// AddStepToMeal adds a step, numbering it after the last one when no number is given
+func (s *Store) AddStepToMeal(step models.Step) (*models.Step, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.findMeal(step.MealID)
+	if m == nil {
+		return nil, models.ErrMealNotFound
+	}
+	if step.StepNumber <= 0 {
+		step.StepNumber = nextStepNumber(m)
+	}
+	for _, existing := range m.Steps {
+		if existing.StepNumber == step.StepNumber {
+			return nil, fmt.Errorf("meal %d already has a step %d", m.ID, step.StepNumber)
+		}
+	}
+	step.ID = s.nextStepID
+	s.nextStepID++
+	m.Steps = append(m.Steps, step)
+	sortSteps(m)
+	return &step, nil
+}
+
+// AddMultipleStepsToMeal appends a step for each instruction
+func (s *Store) AddMultipleStepsToMeal(mealID int, instructions []string) ([]models.Step, error) {
+	if len(instructions) == 0 {
+		return []models.Step{}, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.findMeal(mealID)
+	if m == nil {
+		return nil, models.ErrMealNotFound
+	}
+	next := nextStepNumber(m)
+	steps := make([]models.Step, len(instructions))
+	for i, instruction := range instructions {
+		steps[i] = models.Step{ID: s.nextStepID, MealID: mealID, StepNumber: next + i, Instruction: instruction}
+		s.nextStepID++
+	}
+	m.Steps = append(m.Steps, steps...)
+	return steps, nil
+}
+
+// UpdateStep replaces the number and instruction of one of a meal's steps
+func (s *Store) UpdateStep(step models.Step) error {
+	if step.ID == 0 {
+		return errors.New("step ID not provided")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.findMeal(step.MealID)
+	if m == nil {
+		return models.ErrStepNotFound
+	}
+	for i := range m.Steps {
+		if m.Steps[i].ID == step.ID {
+			m.Steps[i].StepNumber = step.StepNumber
+			m.Steps[i].Instruction = step.Instruction
+			sortSteps(m)
+			return nil
+		}
+	}
+	return models.ErrStepNotFound
+}
+
+// DeleteStep removes one of a meal's steps
+func (s *Store) DeleteStep(stepID, mealID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.findMeal(mealID)
+	if m == nil {
+		return models.ErrStepNotFound
+	}
+	for i := range m.Steps {
+		if m.Steps[i].ID == stepID {
+			m.Steps = append(m.Steps[:i], m.Steps[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrStepNotFound
+}
+
+// ReorderSteps numbers the given steps 1, 2, 3... in the order listed. IDs that aren't
+// steps of the meal are ignored.
+func (s *Store) ReorderSteps(mealID int, stepIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.findMeal(mealID)
+	if m == nil {
+		return nil
+	}
+	for i, id := range stepIDs {
+		for j := range m.Steps {
+			if m.Steps[j].ID == id {
+				m.Steps[j].StepNumber = i + 1
+			}
+		}
+	}
+	sortSteps(m)
+	return nil
+}
+
+// DeleteAllStepsForMeal removes every step of a meal
+func (s *Store) DeleteAllStepsForMeal(mealID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m := s.findMeal(mealID); m != nil {
+		m.Steps = []models.Step{}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package dummy
+
+import (
+	"errors"
+	"sort"
+
+	"mealplanner/models"
+)
+
+// findTag returns the index of the tag with the given ID or name, or -1. Callers hold the lock.
+func (s *Store) findTag(id int, name string) int {
+	for i, t := range s.tags {
+		if (id != 0 && t.ID == id) || (name != "" && t.Name == name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ensureTags adds any of the normalized names that aren't tags yet. Callers hold the lock.
+func (s *Store) ensureTags(names []string) {
+	for _, name := range names {
+		if s.findTag(0, name) < 0 {
+			s.tags = append(s.tags, models.Tag{ID: s.nextTagID, Name: name})
+			s.nextTagID++
+		}
+	}
+}
+
+// ListTags returns every tag ordered by name, counting the meals that carry it
+func (s *Store) ListTags() ([]models.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tags := make([]models.Tag, 0, len(s.tags))
+	for _, t := range s.tags {
+		t.MealCount = 0
+		for _, m := range s.meals {
+			if m.HasTag(t.Name) {
+				t.MealCount++
+			}
+		}
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// CreateTag adds a tag with a normalized name
+func (s *Store) CreateTag(name string) (*models.Tag, error) {
+	name = models.NormalizeTagName(name)
+	if name == "" {
+		return nil, errors.New("tag name is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.findTag(0, name) >= 0 {
+		return nil, models.ErrTagExists
+	}
+	s.ensureTags([]string{name})
+	tag := s.tags[len(s.tags)-1]
+	return &tag, nil
+}
+
+// RenameTag renames a tag and every meal's use of it
+func (s *Store) RenameTag(id int, name string) (*models.Tag, error) {
+	name = models.NormalizeTagName(name)
+	if name == "" {
+		return nil, errors.New("tag name is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.findTag(id, "")
+	if i < 0 {
+		return nil, models.ErrTagNotFound
+	}
+	if other := s.findTag(0, name); other >= 0 && other != i {
+		return nil, models.ErrTagExists
+	}
+	old := s.tags[i].Name
+	s.tags[i].Name = name
+	tag := s.tags[i]
+	for _, m := range s.meals {
+		for j, t := range m.Tags {
+			if t == old {
+				m.Tags[j] = name
+				sort.Strings(m.Tags)
+				tag.MealCount++
+				break
+			}
+		}
+	}
+	return &tag, nil
+}
+
+// DeleteTag removes a tag from every meal and deletes it
+func (s *Store) DeleteTag(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.findTag(id, "")
+	if i < 0 {
+		return models.ErrTagNotFound
+	}
+	name := s.tags[i].Name
+	s.tags = append(s.tags[:i], s.tags[i+1:]...)
+	for _, m := range s.meals {
+		kept := m.Tags[:0]
+		for _, t := range m.Tags {
+			if t != name {
+				kept = append(kept, t)
+			}
+		}
+		m.Tags = kept
+	}
+	return nil
+}
+
+// SetMealTags replaces a meal's tags, adding tags that don't exist yet
+func (s *Store) SetMealTags(mealID int, names []string) ([]string, error) {
+	tags, err := models.NormalizeTagNames(names)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.findMeal(mealID)
+	if m == nil {
+		return nil, models.ErrMealNotFound
+	}
+	s.ensureTags(tags)
+	m.Tags = tags
+	return append([]string{}, tags...), nil
+}
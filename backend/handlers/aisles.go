@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"mealplanner/models"
+)
+
+// currentAisleSettings returns the stored store layout and aisle overrides.
+func (h *Handler) currentAisleSettings() (models.AisleSettings, error) {
+	return h.Store().GetAisleSettings()
+}
+
+// GetAislesHandler handles GET /api/aisles and returns the store layout and aisle overrides.
+func (h *Handler) GetAislesHandler(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.currentAisleSettings()
+	if err != nil {
+		http.Error(w, "Error retrieving aisles: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateAisleOrderHandler handles PUT /api/aisles/order and replaces the store layout.
+// Aisles left out of the order are appended in their default order.
+func (h *Handler) UpdateAisleOrderHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Order []string `json:"order"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := models.NormalizeAisleOrder(payload.Order); err != nil {
+		http.Error(w, "Invalid aisle order: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.Store().SaveAisleOrder(payload.Order)
+	if err != nil {
+		http.Error(w, "Error saving aisle order: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"order": order})
+}
+
+// SetAisleOverrideHandler handles PUT /api/aisles/overrides and sets the aisle for one
+// ingredient name. An empty aisle removes the override.
+func (h *Handler) SetAisleOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name  string `json:"name"`
+		Aisle string `json:"aisle"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(payload.Name) == "" {
+		http.Error(w, "Missing ingredient name", http.StatusBadRequest)
+		return
+	}
+	if payload.Aisle != "" && !models.IsKnownAisle(payload.Aisle) {
+		http.Error(w, "Unknown aisle: "+payload.Aisle, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store().SetAisleOverride(payload.Name, payload.Aisle); err != nil {
+		http.Error(w, "Error saving aisle override: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
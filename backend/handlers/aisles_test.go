@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mealplanner/dummy"
+	"mealplanner/models"
+)
+
+func TestGetShoppingList_GroupByAisle(t *testing.T) {
+	mem := dummy.NewStore()
+	if err := mem.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("failed loading dummy data: %v", err)
+	}
+	api := New(mem)
+	if _, err := mem.SaveAisleOrder([]string{models.AisleDairy}); err != nil {
+		t.Fatalf("failed saving aisle order: %v", err)
+	}
+
+	req, _ := http.NewRequest("POST", "/api/shoppinglist?group_by=aisle", bytes.NewBufferString(`{"plan":[1,2,3]}`))
+	rr := httptest.NewRecorder()
+	api.GetShoppingList(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var resp struct {
+		Sections []models.AisleSection `json:"sections"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Sections) == 0 {
+		t.Fatalf("expected at least one aisle section")
+	}
+	if resp.Sections[0].Aisle != models.AisleDairy {
+		t.Errorf("expected dairy first per the store layout, got %s", resp.Sections[0].Aisle)
+	}
+	for _, section := range resp.Sections {
+		for _, item := range section.Items {
+			if item.Aisle != section.Aisle {
+				t.Errorf("item %q in section %s has aisle %s", item.Name, section.Aisle, item.Aisle)
+			}
+		}
+	}
+
+	req, _ = http.NewRequest("POST", "/api/shoppinglist?group_by=meal", bytes.NewBufferString(`{"plan":[1]}`))
+	rr = httptest.NewRecorder()
+	api.GetShoppingList(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown group_by, got %d", rr.Code)
+	}
+}
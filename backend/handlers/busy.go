@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"mealplanner/models"
+)
+
+// maxCalendarSize limits the size of an uploaded calendar.
+const maxCalendarSize = 10 << 20
+
+// errNoCalendarDir is returned for a calendar path when CalendarDir isn't set.
+var errNoCalendarDir = errors.New("reading calendars by path is disabled, set CALENDAR_DIR")
+
+// busyCalendar is a calendar whose evening events change the dinners of a generated plan.
+type busyCalendar struct {
+	ics     io.Reader
+	evening models.Evening
+	action  string
+}
+
+// openCalendarFile opens a calendar file by its path within CalendarDir. The path can't
+// reach outside the directory.
+func (h *Handler) openCalendarFile(path string) (*os.File, error) {
+	if h.CalendarDir == "" {
+		return nil, errNoCalendarDir
+	}
+	return os.Open(filepath.Join(h.CalendarDir, filepath.Clean("/"+path)))
+}
+
+// GenerateFromCalendarHandler handles POST /api/mealplan/generate/calendar. It generates
+// a plan like POST /api/mealplan/generate, planning around the nights a calendar shows as
+// busy: nights with a timed event between evening_start and evening_end (17:00 and 21:00
+// by default, in the household's time zone). With busy set to eat_out those nights eat
+// out; otherwise, or with low_effort, their dinners take the rules' lowest effort range.
+//
+// The calendar is either uploaded as multipart/form-data in the calendar field, with the
+// other fields as form values (skip_days comma separated), or named by path, relative to
+// CALENDAR_DIR, in a form value or a JSON payload.
+func (h *Handler) GenerateFromCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		generateRequest
+		Path         string `json:"path"`          // a calendar file within CALENDAR_DIR, instead of an upload
+		Busy         string `json:"busy"`          // low_effort (default) or eat_out
+		EveningStart string `json:"evening_start"` // HH:MM
+		EveningEnd   string `json:"evening_end"`   // HH:MM
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxCalendarSize)
+	var upload io.Reader
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxCalendarSize); err != nil {
+			http.Error(w, "Invalid upload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		input.Path = r.FormValue("path")
+		input.Busy = r.FormValue("busy")
+		input.EveningStart = r.FormValue("evening_start")
+		input.EveningEnd = r.FormValue("evening_end")
+		input.StartDate = r.FormValue("start_date")
+		input.ReduceWaste = r.FormValue("reduce_waste") == "true"
+		if v := r.FormValue("days"); v != "" {
+			days, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "Invalid days: "+v, http.StatusBadRequest)
+				return
+			}
+			input.Days = days
+		}
+		for _, day := range strings.Split(r.FormValue("skip_days"), ",") {
+			if day = strings.TrimSpace(day); day != "" {
+				input.SkipDays = append(input.SkipDays, day)
+			}
+		}
+		if file, _, err := r.FormFile("calendar"); err == nil {
+			defer file.Close()
+			upload = file
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	calendar := busyCalendar{ics: upload, evening: models.DefaultEvening, action: input.Busy}
+	if input.EveningStart != "" {
+		at, ok := parseClock(input.EveningStart)
+		if !ok {
+			http.Error(w, "Invalid evening_start, expected HH:MM", http.StatusBadRequest)
+			return
+		}
+		calendar.evening.Start = at
+	}
+	if input.EveningEnd != "" {
+		at, ok := parseClock(input.EveningEnd)
+		if !ok {
+			http.Error(w, "Invalid evening_end, expected HH:MM", http.StatusBadRequest)
+			return
+		}
+		calendar.evening.End = at
+	}
+	if calendar.evening.End <= calendar.evening.Start {
+		http.Error(w, "Invalid evening: evening_end must be after evening_start", http.StatusBadRequest)
+		return
+	}
+
+	if calendar.ics == nil {
+		if input.Path == "" {
+			http.Error(w, "Missing calendar: upload one in the calendar field or give its path", http.StatusBadRequest)
+			return
+		}
+		file, err := h.openCalendarFile(input.Path)
+		switch {
+		case errors.Is(err, errNoCalendarDir):
+			http.Error(w, "Invalid path: "+err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, fs.ErrNotExist):
+			http.Error(w, "Calendar file not found: "+input.Path, http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, "Error opening calendar: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+		calendar.ics = file
+	}
+
+	h.generateMealPlan(w, input.generateRequest, &calendar)
+}
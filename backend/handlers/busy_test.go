@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mealplanner/dummy"
+	"mealplanner/models"
+)
+
+const busyCalendarICS = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Book club\r\n" +
+	"DTSTART:20240305T183000Z\r\n" +
+	"DTEND:20240305T203000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Swim practice\r\n" +
+	"DTSTART:20240306T170000Z\r\n" +
+	"DURATION:PT1H\r\n" +
+	"RRULE:FREQ=WEEKLY\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+type busyResponse map[string]struct {
+	MealName       string   `json:"mealName"`
+	RelativeEffort int      `json:"relativeEffort"`
+	Busy           []string `json:"busy"`
+}
+
+func TestGenerateFromCalendarHandler_Upload(t *testing.T) {
+	mem := dummy.NewStore()
+	if err := mem.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("failed loading dummy data: %v", err)
+	}
+	api := New(mem)
+
+	upload := func(fields map[string]string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, _ := mw.CreateFormFile("calendar", "family.ics")
+		part.Write([]byte(busyCalendarICS))
+		for k, v := range fields {
+			mw.WriteField(k, v)
+		}
+		mw.Close()
+		req, _ := http.NewRequest("POST", "/api/mealplan/generate/calendar", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rr := httptest.NewRecorder()
+		api.GenerateFromCalendarHandler(rr, req)
+		return rr
+	}
+
+	rr := upload(map[string]string{"start_date": "2024-03-04", "days": "7", "busy": models.BusyEatOut})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp busyResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for date, event := range map[string]string{"2024-03-05": "Book club", "2024-03-06": "Swim practice"} {
+		if meal := resp[date]; meal.MealName != models.EatingOutMealName || len(meal.Busy) != 1 || meal.Busy[0] != event {
+			t.Errorf("expected eating out on %s for %s, got %+v", date, event, meal)
+		}
+	}
+	if meal := resp["2024-03-07"]; meal.MealName == models.EatingOutMealName || meal.Busy != nil {
+		t.Errorf("expected a free Thursday to be cooked, got %+v", meal)
+	}
+
+	rr = upload(map[string]string{"start_date": "2024-03-04", "days": "7"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	resp = nil
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for _, date := range []string{"2024-03-05", "2024-03-06"} {
+		if meal := resp[date]; meal.MealName == models.EatingOutMealName || meal.RelativeEffort > 2 || len(meal.Busy) != 1 {
+			t.Errorf("expected a low effort dinner on %s, got %+v", date, meal)
+		}
+	}
+
+	for _, fields := range []map[string]string{
+		{"busy": "cancel"},
+		{"evening_start": "5pm"},
+		{"evening_start": "21:00", "evening_end": "17:00"},
+		{"days": "many"},
+	} {
+		if rr := upload(fields); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %v, got %d", fields, rr.Code)
+		}
+	}
+}
+
+func TestGenerateFromCalendarHandler_Path(t *testing.T) {
+	mem := dummy.NewStore()
+	if err := mem.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("failed loading dummy data: %v", err)
+	}
+	api := New(mem)
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/mealplan/generate/calendar", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		api.GenerateFromCalendarHandler(rr, req)
+		return rr
+	}
+
+	body := `{"path":"family.ics","start_date":"2024-03-04","busy":"eat_out"}`
+	if rr := post(body); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without CALENDAR_DIR, got %d", rr.Code)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "family.ics"), []byte(busyCalendarICS), 0o644); err != nil {
+		t.Fatalf("failed writing calendar: %v", err)
+	}
+	api.CalendarDir = dir
+	rr := post(body)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp busyResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if meal := resp["2024-03-05"]; meal.MealName != models.EatingOutMealName {
+		t.Errorf("expected eating out on the busy Tuesday, got %+v", meal)
+	}
+
+	if rr := post(`{"path":"../` + filepath.Base(dir) + `/family.ics"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a path outside CALENDAR_DIR, got %d", rr.Code)
+	}
+	if rr := post(`{}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without a calendar, got %d", rr.Code)
+	}
+}
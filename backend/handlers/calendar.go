@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"mealplanner/models"
+)
+
+// calendarFeedPath returns the path a calendar subscribes to for a token.
+func calendarFeedPath(token string) string {
+	return "/api/calendar/" + token + ".ics"
+}
+
+// parseClock reads a time of day given as HH:MM, returning it as the time since midnight.
+func parseClock(v string) (time.Duration, bool) {
+	at, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute, true
+}
+
+// GetCalendarTokenHandler handles GET /api/calendar/token and returns the calendar feed's
+// token and path, or 404 when the feed hasn't been set up.
+func (h *Handler) GetCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := h.Store().GetCalendarToken()
+	if errors.Is(err, models.ErrNoCalendarToken) {
+		http.Error(w, "Calendar feed not set up", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error retrieving calendar token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"token": token, "url": calendarFeedPath(token)})
+}
+
+// RotateCalendarTokenHandler handles POST /api/calendar/token. It sets up the calendar feed
+// with a new token, or replaces the existing one so the old URL stops working.
+func (h *Handler) RotateCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := h.Store().RotateCalendarToken()
+	if err != nil {
+		http.Error(w, "Error creating calendar token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"token": token, "url": calendarFeedPath(token)})
+}
+
+// CalendarFeedHandler handles GET /api/calendar/{token}.ics and serves every saved plan,
+// past and future, as a calendar to subscribe to. With timed=true each meal runs from when
+// prep starts until it's eaten; breakfast, lunch and dinner set when each slot is eaten as
+// HH:MM in the household's time zone.
+func (h *Handler) CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := h.Store().GetCalendarToken()
+	if err != nil && !errors.Is(err, models.ErrNoCalendarToken) {
+		http.Error(w, "Error retrieving calendar token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	given := chi.URLParam(r, "token")
+	if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+
+	rules, err := h.Store().GetPlanRules()
+	if err != nil {
+		http.Error(w, "Error retrieving planning rules: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	opts := models.CalendarOptions{
+		Timed:     r.URL.Query().Get("timed") == "true",
+		MealTimes: make(map[string]time.Duration),
+		Location:  rules.Location(),
+	}
+	for _, slot := range models.Slots {
+		v := r.URL.Query().Get(slot)
+		if v == "" {
+			continue
+		}
+		at, ok := parseClock(v)
+		if !ok {
+			http.Error(w, "Invalid "+slot+" time, expected HH:MM", http.StatusBadRequest)
+			return
+		}
+		opts.MealTimes[slot] = at
+	}
+
+	plans, err := h.Store().ListMealPlans(time.Time{}, time.Time{})
+	if err != nil {
+		http.Error(w, "Error retrieving meal plans: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	seen := make(map[int]bool)
+	var ids []int
+	for _, p := range plans {
+		for _, e := range p.Entries {
+			if e.MealID != 0 && !seen[e.MealID] {
+				seen[e.MealID] = true
+				ids = append(ids, e.MealID)
+			}
+		}
+	}
+	recipes := make(map[int]*models.Meal, len(ids))
+	if len(ids) > 0 {
+		meals, err := h.Store().GetMealsByIDs(ids)
+		if err != nil {
+			http.Error(w, "Error retrieving meals: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, m := range meals {
+			recipes[m.ID] = m
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Write([]byte(models.CalendarFeed(plans, recipes, opts)))
+}
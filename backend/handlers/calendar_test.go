@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"mealplanner/dummy"
+	"mealplanner/models"
+)
+
+func TestCalendarFeedHandler_Dummy(t *testing.T) {
+	store := dummy.NewStore()
+	api := New(store)
+	r := chi.NewRouter()
+	r.Get("/api/calendar/token", api.GetCalendarTokenHandler)
+	r.Post("/api/calendar/token", api.RotateCalendarTokenHandler)
+	r.Get("/api/calendar/{token}.ics", api.CalendarFeedHandler)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := serve("GET", "/api/calendar/token"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 before the feed is set up, got %d", rr.Code)
+	}
+	if rr := serve("GET", "/api/calendar/.ics"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an empty token, got %d", rr.Code)
+	}
+
+	rr := serve("POST", "/api/calendar/token")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.URL != "/api/calendar/"+resp.Token+".ics" {
+		t.Errorf("unexpected feed URL %q for token %q", resp.URL, resp.Token)
+	}
+
+	soup, _ := store.CreateMeal(models.Meal{
+		MealName:       "Soup",
+		RelativeEffort: 2,
+		Ingredients:    []models.Ingredient{{Name: "leek", Quantity: 2, Unit: "whole"}},
+	})
+	week := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	if _, err := store.SaveMealPlan(week, map[string]*models.Meal{"Monday": soup, "Friday": {MealName: models.EatingOutMealName}}, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+
+	rr = serve("GET", resp.URL+"?timed=true&dinner=19:00")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("expected a calendar, got %q", ct)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"UID:20240304-dinner@mealplanner",
+		"DTSTART:20240304T182500Z",
+		"DESCRIPTION:Ingredients:\\n- 2 whole leek",
+		"UID:20240308-dinner@mealplanner",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("feed missing %q, got:\n%s", want, body)
+		}
+	}
+
+	if rr := serve("GET", resp.URL+"?dinner=7pm"); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a bad dinner time, got %d", rr.Code)
+	}
+	if rr := serve("GET", "/api/calendar/wrong.ics"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a wrong token, got %d", rr.Code)
+	}
+	serve("POST", "/api/calendar/token")
+	if rr := serve("GET", resp.URL); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a rotated token, got %d", rr.Code)
+	}
+}
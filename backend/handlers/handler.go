@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"sync"
+
+	"mealplanner/store"
+)
+
+// Handler serves the API from a Store. The store can be swapped while the server is
+// running, e.g. when the database is reconnected after starting in dummy mode.
+type Handler struct {
+	mu    sync.RWMutex
+	store store.Store
+
+	// CalendarDir is the directory calendar files can be read from by path when
+	// generating around busy nights. When empty, calendars can only be uploaded.
+	CalendarDir string
+}
+
+// New returns a Handler backed by s.
+func New(s store.Store) *Handler {
+	return &Handler{store: s}
+}
+
+// Store returns the store requests are currently served from.
+func (h *Handler) Store() store.Store {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.store
+}
+
+// SetStore replaces the store for subsequent requests.
+func (h *Handler) SetStore(s store.Store) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.store = s
+}
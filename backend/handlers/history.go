@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"mealplanner/models"
+)
+
+// MarkPlanDayDoneHandler handles POST /api/mealplan/done and records in the cooking log
+// whether a day's meal of a saved plan was cooked or skipped, with an optional rating and
+// notes. The day is a date, or a weekday of the household's week containing week_start.
+// The day's dinner is marked unless another slot is given. Marking the same day again
+// replaces its record.
+func (h *Handler) MarkPlanDayDoneHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		WeekStart string `json:"week_start"` // optional, YYYY-MM-DD; defaults to this week
+		Day       string `json:"day"`        // a weekday, or a date as YYYY-MM-DD
+		Slot      string `json:"slot"`       // optional; defaults to dinner
+		Status    string `json:"status"`     // cooked or skipped
+		Rating    int    `json:"rating"`     // optional, 1-5
+		Notes     string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var weekStart time.Time
+	if payload.WeekStart != "" {
+		parsed, err := time.Parse("2006-01-02", payload.WeekStart)
+		if err != nil {
+			http.Error(w, "Invalid week_start, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		weekStart = parsed
+	}
+	if !models.IsPlanDay(payload.Day) {
+		http.Error(w, "Invalid day: "+payload.Day, http.StatusBadRequest)
+		return
+	}
+	slot := models.NormalizeSlot(payload.Slot)
+	if !models.IsSlot(slot) {
+		http.Error(w, "Invalid slot: "+payload.Slot, http.StatusBadRequest)
+		return
+	}
+	entry := models.CookingLogEntry{Status: payload.Status, Rating: payload.Rating, Notes: payload.Notes}
+	if err := entry.Validate(); err != nil {
+		http.Error(w, "Invalid cooking log entry: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rules, err := h.currentPlanRules()
+	if err != nil {
+		http.Error(w, "Error retrieving planning rules: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if weekStart.IsZero() {
+		weekStart = rules.WeekStart(time.Now())
+	} else {
+		weekStart = rules.StartOfWeek(weekStart)
+	}
+	day, _ := models.DatedKey(models.PlanKey(payload.Day, slot), weekStart, len(models.Weekdays))
+
+	recorded, err := h.Store().MarkPlanDayDone(weekStart, day, entry)
+	if errors.Is(err, models.ErrNoMealPlan) || errors.Is(err, models.ErrNoMealOnDay) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error recording the cooking log: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recorded)
+}
+
+// GetMealHistoryHandler handles GET /api/meals/{mealId}/history and returns the meal's
+// cooking log, newest first.
+func (h *Handler) GetMealHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	mealID, err := strconv.Atoi(chi.URLParam(r, "mealId"))
+	if err != nil {
+		http.Error(w, "Invalid meal ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.Store().GetMealHistory(mealID)
+	if errors.Is(err, models.ErrMealNotFound) {
+		http.Error(w, "Meal not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error retrieving meal history: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
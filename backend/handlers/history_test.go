@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mealplanner/dummy"
+	"mealplanner/models"
+)
+
+func TestHistoryHandlers_Dummy(t *testing.T) {
+	store := dummy.NewStore()
+	api := New(store)
+	soup, _ := store.CreateMeal(models.Meal{MealName: "Soup", RelativeEffort: 2, Ingredients: []models.Ingredient{}})
+	week := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	if _, err := store.SaveMealPlan(week, map[string]*models.Meal{"Monday": soup, "Friday": {MealName: models.EatingOutMealName}}, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"cooked", `{"week_start":"2024-03-06","day":"Monday","status":"cooked","rating":4,"notes":"more garlic"}`, http.StatusOK},
+		{"bad day", `{"week_start":"2024-03-04","day":"Someday","status":"cooked"}`, http.StatusBadRequest},
+		{"bad rating", `{"week_start":"2024-03-04","day":"Monday","status":"cooked","rating":6}`, http.StatusBadRequest},
+		{"bad status", `{"week_start":"2024-03-04","day":"Monday","status":"eaten"}`, http.StatusBadRequest},
+		{"eating out", `{"week_start":"2024-03-04","day":"Friday","status":"cooked"}`, http.StatusNotFound},
+		{"no plan", `{"week_start":"2024-04-01","day":"Monday","status":"skipped"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/api/mealplan/done", bytes.NewBufferString(tt.body))
+		rr := httptest.NewRecorder()
+		api.MarkPlanDayDoneHandler(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%s: expected status %d got %d: %s", tt.name, tt.want, rr.Code, rr.Body.String())
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/api/meals/1/history", nil)
+	rr := httptest.NewRecorder()
+	api.GetMealHistoryHandler(rr, withURLParam(req, "mealId", "1"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var history []models.CookingLogEntry
+	if err := json.NewDecoder(rr.Body).Decode(&history); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(history) != 1 || history[0].Rating != 4 || history[0].Notes != "more garlic" || !history[0].CookedOn.Equal(week) {
+		t.Errorf("unexpected history %+v", history)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/meals/9999/history", nil)
+	rr = httptest.NewRecorder()
+	api.GetMealHistoryHandler(rr, withURLParam(req, "mealId", "9999"))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown meal, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"mealplanner/ingredient"
+)
+
+// ParseIngredientsHandler handles POST /api/ingredients/parse and previews how ingredient
+// lines will be parsed. The payload holds either a list of lines or a block of text with
+// one ingredient per line. Nothing is stored, so it doesn't need a store.
+func ParseIngredientsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Lines []string `json:"lines"`
+		Text  string   `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	lines := append(payload.Lines, strings.Split(payload.Text, "\n")...)
+	parsed := []ingredient.Parsed{}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parsed = append(parsed, ingredient.Parse(line))
+	}
+	if len(parsed) == 0 {
+		http.Error(w, "No ingredient lines provided", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(parsed)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mealplanner/ingredient"
+)
+
+func TestParseIngredientsHandler(t *testing.T) {
+	body := `{"lines":["1 1/2 cups flour"],"text":"2 (14-ounce) cans black beans, drained\n\nSalt"}`
+	req, _ := http.NewRequest("POST", "/api/ingredients/parse", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	ParseIngredientsHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var parsed []ingredient.Parsed
+	if err := json.NewDecoder(rr.Body).Decode(&parsed); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(parsed) != 3 {
+		t.Fatalf("expected 3 parsed lines, got %d", len(parsed))
+	}
+	if parsed[0].Quantity != 1.5 || parsed[0].Unit != "cup" || parsed[0].Name != "flour" {
+		t.Errorf("unexpected first line: %+v", parsed[0])
+	}
+	if parsed[1].Unit != "can" || parsed[1].Name != "black beans" || parsed[1].Notes != "drained" {
+		t.Errorf("unexpected second line: %+v", parsed[1])
+	}
+
+	req, _ = http.NewRequest("POST", "/api/ingredients/parse", bytes.NewBufferString(`{"lines":[]}`))
+	rr = httptest.NewRecorder()
+	ParseIngredientsHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for no lines, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"mealplanner/library"
+)
+
+// maxImportSize caps the size of an uploaded library.
+const maxImportSize = 20 << 20
+
+// ExportHandler handles GET /api/export?format=json|csv and downloads every meal, with
+// its ingredients and steps, and the saved plan history. JSON is the default.
+func (h *Handler) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "Invalid format, expected json or csv", http.StatusBadRequest)
+		return
+	}
+
+	lib, err := library.Export(h.Store())
+	if err != nil {
+		http.Error(w, "Error exporting library: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("mealplanner-%s.%s", lib.ExportedAt.Format("2006-01-02"), format)
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	if format == "csv" {
+		// Buffer the CSV so a failure can still be reported with an error status.
+		var buf bytes.Buffer
+		if err := library.WriteCSV(&buf, lib); err != nil {
+			http.Error(w, "Error exporting library: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write(buf.Bytes())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(lib)
+}
+
+// ImportHandler handles POST /api/import?format=json|csv&strategy=merge|replace&dry_run=true
+// and imports a file written by ExportHandler. The format defaults to the request's
+// Content-Type, and to JSON when that says neither. Meals with a name that is already
+// stored, and weeks that already have a plan, are conflicts: merge keeps the stored ones
+// and replace overwrites them. With dry_run nothing is stored and the report shows what
+// would happen.
+func (h *Handler) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+			format = "csv"
+		}
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "Invalid format, expected json or csv", http.StatusBadRequest)
+		return
+	}
+	opts := library.Options{Strategy: query.Get("strategy")}
+	if v := query.Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid dry_run, expected true or false", http.StatusBadRequest)
+			return
+		}
+		opts.DryRun = dryRun
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	var lib *library.Library
+	var err error
+	if format == "csv" {
+		lib, err = library.ReadCSV(body)
+	} else {
+		lib = &library.Library{}
+		err = json.NewDecoder(body).Decode(lib)
+	}
+	if err != nil {
+		http.Error(w, "Invalid import file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := library.Import(h.Store(), lib, opts)
+	var invalid *library.ValidationError
+	if errors.As(err, &invalid) {
+		http.Error(w, "Invalid import file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error importing library: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
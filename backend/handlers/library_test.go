@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mealplanner/dummy"
+	"mealplanner/library"
+)
+
+func TestExportImportHandlers_Dummy(t *testing.T) {
+	source := dummy.NewStore()
+	if err := source.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("failed to load dummy data: %v", err)
+	}
+	api := New(source)
+
+	req, _ := http.NewRequest("GET", "/api/export?format=csv", nil)
+	rr := httptest.NewRecorder()
+	api.ExportHandler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("expected text/csv, got %q", ct)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment; filename=mealplanner-") {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	exported := rr.Body.Bytes()
+
+	target := New(dummy.NewStore())
+	req, _ = http.NewRequest("POST", "/api/import?dry_run=true", bytes.NewReader(exported))
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	rr = httptest.NewRecorder()
+	target.ImportHandler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var report library.Report
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !report.DryRun || report.MealsCreated == 0 {
+		t.Errorf("unexpected dry run report: %+v", report)
+	}
+	if meals, _ := target.Store().GetAllMeals(); len(meals) != 0 {
+		t.Errorf("a dry run must not store anything, got %d meals", len(meals))
+	}
+
+	req, _ = http.NewRequest("POST", "/api/import?format=csv", bytes.NewReader(exported))
+	rr = httptest.NewRecorder()
+	target.ImportHandler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	meals, _ := target.Store().GetAllMeals()
+	if len(meals) != report.MealsCreated {
+		t.Errorf("expected %d meals imported, got %d", report.MealsCreated, len(meals))
+	}
+
+	// Importing the same file again only finds conflicts.
+	req, _ = http.NewRequest("POST", "/api/import?format=csv&dry_run=1", bytes.NewReader(exported))
+	rr = httptest.NewRecorder()
+	target.ImportHandler(rr, req)
+	report = library.Report{}
+	json.NewDecoder(rr.Body).Decode(&report)
+	if report.MealsCreated != 0 || len(report.Conflicts) != len(meals) {
+		t.Errorf("expected every meal to conflict, got %+v", report)
+	}
+}
+
+func TestImportHandler_BadRequests(t *testing.T) {
+	api := New(dummy.NewStore())
+	tests := []struct {
+		name  string
+		query string
+		body  string
+	}{
+		{"unknown format", "?format=xml", `{}`},
+		{"bad dry_run", "?dry_run=maybe", `{}`},
+		{"bad JSON", "", `{"meals":`},
+		{"unknown strategy", "?strategy=overwrite", `{"meals":[]}`},
+		{"duplicate meals", "", `{"meals":[{"name":"Soup"},{"name":"SOUP"}]}`},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/api/import"+tt.query, strings.NewReader(tt.body))
+		rr := httptest.NewRecorder()
+		api.ImportHandler(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400 got %d: %s", tt.name, rr.Code, rr.Body.String())
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/api/export?format=xml", nil)
+	rr := httptest.NewRecorder()
+	api.ExportHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown export format, got %d", rr.Code)
+	}
+}
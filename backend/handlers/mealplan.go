@@ -1,120 +1,318 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
 
-	"mealplanner/dummy"
 	"mealplanner/models"
 )
 
-// DB is a global database connection (set in main.go)
-var DB *sql.DB
-
-// GetMealPlan retrieves a meal plan - either the last saved one or generates a new one if none exists.
-func GetMealPlan(w http.ResponseWriter, r *http.Request) {
-	// First try to get the last planned meals
-	var plan map[string]*models.Meal
-	var err error
-	if UseDummy {
-		plan, err = dummy.GenerateWeeklyMealPlan()
-	} else {
-		plan, err = models.GetLastPlannedMeals(DB)
-		if err != nil {
-			log.Printf("No recent meal plan found, generating new one: %v", err)
-			plan, err = models.GenerateWeeklyMealPlan(DB)
-			if err != nil {
-				http.Error(w, "Error generating meal plan: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
-		}
+// generatedWeek generates a plan for the household's current week from the stored rules
+// and returns just its meals, keyed by dated plan key.
+func (h *Handler) generatedWeek() (map[string]*models.Meal, error) {
+	rules, err := h.currentPlanRules()
+	if err != nil {
+		return nil, err
 	}
+	rules, err = rules.ForDates(rules.WeekStart(time.Now()), len(models.Weekdays))
+	if err != nil {
+		return nil, err
+	}
+	result, err := h.Store().GenerateMealPlan(rules, models.SolveOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Plan, nil
+}
 
-	// Create an output map from day to a simplified meal object including effort.
+// GetMealPlan retrieves a meal plan - either the last saved one or generates a new one if none exists.
+func (h *Handler) GetMealPlan(w http.ResponseWriter, r *http.Request) {
+	// Create an output map from plan key to a simplified meal object including effort.
+	// Dinners are keyed by the day alone; other slots by "Monday/breakfast". Plans longer
+	// than a week are keyed by date instead, and every meal carries its date.
 	type OutputMeal struct {
 		ID             int    `json:"id"`
 		MealName       string `json:"mealName"`
 		RelativeEffort int    `json:"relativeEffort"`
 		URL            string `json:"url,omitempty"`
+		LeftoversOf    int    `json:"leftoversOf,omitempty"`
+		Day            string `json:"day"`
+		Slot           string `json:"slot"`
+		Date           string `json:"date"`
 	}
-	output := make(map[string]OutputMeal)
-	for day, meal := range plan {
-		output[day] = OutputMeal{
+	newOutputMeal := func(meal *models.Meal, key string, date time.Time) OutputMeal {
+		day, slot := models.SplitPlanKey(key)
+		return OutputMeal{
 			ID:             meal.ID,
 			MealName:       meal.MealName,
 			RelativeEffort: meal.RelativeEffort,
 			URL:            meal.URL,
+			LeftoversOf:    meal.LeftoversOf,
+			Day:            day,
+			Slot:           slot,
+			Date:           date.Format(models.DateLayout),
+		}
+	}
+	output := make(map[string]OutputMeal)
+
+	// First try to get the last planned meals
+	saved, err := h.Store().GetLatestMealPlan()
+	if err == nil {
+		for _, entry := range saved.Entries {
+			if entry.Meal != nil {
+				key := saved.EntryKey(entry)
+				output[key] = newOutputMeal(entry.Meal, key, entry.Date)
+			}
+		}
+	} else {
+		log.Printf("No saved meal plan found, generating new one: %v", err)
+		plan, err := h.generatedWeek()
+		if err != nil {
+			http.Error(w, "Error generating meal plan: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for key, meal := range plan {
+			day, _ := models.SplitPlanKey(key)
+			date, _ := models.ParsePlanDate(day)
+			output[models.WeekdayKey(key)] = newOutputMeal(meal, models.WeekdayKey(key), date)
 		}
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(output)
 }
 
-// GenerateMealPlan generates a new weekly meal plan regardless of whether a recent one exists.
-func GenerateMealPlan(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		SkipDays []string `json:"skip_days"`
-	}
+// GenerateMealPlan generates a new meal plan regardless of whether a recent one exists.
+// Without a start_date or days it plans the household's current week and keys the plan by
+// weekday, as it always has. With either, it plans days days (a week by default, at most
+// models.MaxPlanDays) from start_date (the current week's start by default) and keys the
+// plan by date. Each date follows the planning rules of its weekday.
+func (h *Handler) GenerateMealPlan(w http.ResponseWriter, r *http.Request) {
+	var input generateRequest
 	_ = json.NewDecoder(r.Body).Decode(&input)
+	h.generateMealPlan(w, input, nil)
+}
 
-	var plan map[string]*models.Meal
-	var err error
-	if UseDummy {
-		plan, err = dummy.GenerateWeeklyMealPlan()
-	} else {
-		plan, err = models.GenerateWeeklyMealPlan(DB)
+// generateRequest is the payload of POST /api/mealplan/generate.
+type generateRequest struct {
+	SkipDays    []string       `json:"skip_days"`    // whole days, or single slots as "Monday/lunch"; weekdays skip every such day
+	ReduceWaste bool           `json:"reduce_waste"` // prefer meals that share perishable ingredients
+	Locked      map[string]int `json:"locked"`       // plan key -> meal ID to keep while regenerating the rest
+	StartDate   string         `json:"start_date"`   // optional, YYYY-MM-DD
+	Days        int            `json:"days"`         // optional, how many days to plan
+}
+
+// generateMealPlan plans the request's days and writes the plan. Given a calendar, the
+// dinners of nights busy with its events are changed as busy says (see
+// models.PlanRules.WithBusyNights), and each such meal lists the events in busy.
+func (h *Handler) generateMealPlan(w http.ResponseWriter, input generateRequest, calendar *busyCalendar) {
+	for day, mealID := range input.Locked {
+		if !models.IsPlanKey(day) || mealID <= 0 {
+			http.Error(w, "Invalid locked day: "+day, http.StatusBadRequest)
+			return
+		}
 	}
+
+	rules, err := h.currentPlanRules()
 	if err != nil {
-		http.Error(w, "Error generating meal plan: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Error retrieving planning rules: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	byWeekday := input.StartDate == "" && input.Days == 0
+	start := rules.WeekStart(time.Now())
+	if input.StartDate != "" {
+		date, ok := models.ParsePlanDate(input.StartDate)
+		if !ok {
+			http.Error(w, "Invalid start_date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		start = date
+	}
+	numDays := input.Days
+	if numDays == 0 {
+		numDays = len(models.Weekdays)
+	}
+	rules, err = rules.ForDates(start, numDays)
+	if err != nil {
+		http.Error(w, "Invalid days: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	var busy map[string][]string
+	if calendar != nil {
+		events, err := models.ParseICS(calendar.ics, rules.Location())
+		if err != nil {
+			http.Error(w, "Invalid calendar: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		busy = models.BusyNights(events, start, numDays, rules.Location(), calendar.evening)
+		rules, err = rules.WithBusyNights(busy, calendar.action)
+		if err != nil {
+			http.Error(w, "Invalid busy: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Locked weekdays fall on the first such day of the plan.
+	locked := make(map[string]int, len(input.Locked))
+	for key, mealID := range input.Locked {
+		dated, ok := models.DatedKey(key, start, numDays)
+		if !ok {
+			http.Error(w, "Invalid locked day: "+key+" is not in the plan", http.StatusBadRequest)
+			return
+		}
+		locked[dated] = mealID
+	}
+
+	// Skipped days are left out of the search so they don't use up meals.
+	skipped := make(map[string]bool)
 	for _, day := range input.SkipDays {
-		delete(plan, day)
+		skipped[day] = true
 	}
+	days := make([]models.DayRule, 0, len(rules.Days))
+	for _, day := range rules.Days {
+		if !skipped[day.Day] && !skipped[day.Key()] && !skipped[models.WeekdayOf(day.Day)] && !skipped[models.WeekdayKey(day.Key())] {
+			days = append(days, day)
+		}
+	}
+	rules.Days = days
+
+	result, err := h.Store().GenerateMealPlan(rules, models.SolveOptions{ReduceWaste: input.ReduceWaste, Locked: locked})
+	if errors.Is(err, models.ErrMealNotFound) {
+		http.Error(w, "Invalid plan: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error generating meal plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	plan := result.Plan
 
-	// Create an output map from day to a simplified meal object including effort.
+	// Create an output map from plan key to a simplified meal object including effort.
+	// Days planned with relaxed rules carry an explanation of what was relaxed, and when
+	// reducing waste each meal lists the perishables it shares with the rest of the plan.
+	// Leftover days name the meal they were cooked from, and every meal carries its date.
+	// Dinners on busy nights list the events that made them busy.
 	type OutputMeal struct {
-		ID             int    `json:"id"`
-		MealName       string `json:"mealName"`
-		RelativeEffort int    `json:"relativeEffort"`
-		URL            string `json:"url,omitempty"`
+		ID                int                    `json:"id"`
+		MealName          string                 `json:"mealName"`
+		RelativeEffort    int                    `json:"relativeEffort"`
+		URL               string                 `json:"url,omitempty"`
+		LeftoversOf       int                    `json:"leftoversOf,omitempty"`
+		Explanation       *models.DayExplanation `json:"explanation,omitempty"`
+		SharedIngredients []string               `json:"sharedIngredients,omitempty"`
+		Day               string                 `json:"day"`
+		Slot              string                 `json:"slot"`
+		Date              string                 `json:"date"`
+		Busy              []string               `json:"busy,omitempty"`
 	}
 	output := make(map[string]OutputMeal)
-	for day, meal := range plan {
-		output[day] = OutputMeal{
+	for key, meal := range plan {
+		date, slot := models.SplitPlanKey(key)
+		outKey := key
+		if byWeekday {
+			outKey = models.WeekdayKey(key)
+		}
+		day, _ := models.SplitPlanKey(outKey)
+		out := OutputMeal{
 			ID:             meal.ID,
 			MealName:       meal.MealName,
 			RelativeEffort: meal.RelativeEffort,
 			URL:            meal.URL,
+			LeftoversOf:    meal.LeftoversOf,
+			Day:            day,
+			Slot:           slot,
+			Date:           date,
+		}
+		if exp, ok := result.Explanations[key]; ok {
+			exp.Day = day
+			out.Explanation = &exp
+		}
+		if result.Waste != nil {
+			out.SharedIngredients = result.Waste.SharedBy(meal)
+		}
+		if slot == models.SlotDinner {
+			out.Busy = busy[date]
 		}
+		output[outKey] = out
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(output)
 }
 
-// SwapMeal handles swapping a meal in the current meal plan.
-// It decodes the incoming payload, calls the real backend swap logic,
-// and returns the new meal as JSON.
-func SwapMeal(w http.ResponseWriter, r *http.Request) {
+// SwapMeal handles POST /api/mealplan/swap and replaces one day of the current plan.
+// The payload carries the day (a weekday or a date) and slot, the full current plan and
+// optionally a count.
+// Replacements follow the same rules as the generator: the slot's effort range, the
+// meals suitable for the slot, the repeat cooldown,
+// the category caps given the rest of the plan, and no meal already in the plan.
+// Without a count the single best replacement meal is returned; with a count, the top N
+// ranked alternatives are returned along with which rules each one relaxes. When no meal
+// can take the day, 404 is returned.
+func (h *Handler) SwapMeal(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
-		Day    string `json:"day"`     // day of the meal plan (if needed)
-		MealID int    `json:"meal_id"` // current meal ID
+		Day    string                  `json:"day"`     // day of the meal plan being swapped, or its plan key
+		Slot   string                  `json:"slot"`    // optional: the slot being swapped; defaults to dinner
+		MealID int                     `json:"meal_id"` // current meal ID (defaults to the plan's meal for the day)
+		Plan   map[string]*models.Meal `json:"plan"`    // the full current plan, plan key -> meal
+		Count  int                     `json:"count"`   // optional: return this many ranked alternatives
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	if payload.Slot != "" && models.IsPlanDay(payload.Day) {
+		payload.Day = models.PlanKey(payload.Day, payload.Slot)
+	}
+	if !models.IsPlanKey(payload.Day) {
+		http.Error(w, "Invalid day: "+payload.Day, http.StatusBadRequest)
+		return
+	}
 
-	var newMeal *models.Meal
-	var err error
-	if UseDummy {
-		newMeal, err = dummy.SwapMeal(payload.MealID)
-	} else {
-		newMeal, err = models.SwapMeal(payload.MealID, DB)
+	plan := make(map[string]int)
+	for day, meal := range payload.Plan {
+		if meal != nil && meal.ID != 0 {
+			plan[day] = meal.ID
+		}
+	}
+	if payload.MealID != 0 {
+		plan[payload.Day] = payload.MealID
+	}
+
+	rules, err := h.currentPlanRules()
+	if err != nil {
+		http.Error(w, "Error retrieving planning rules: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// A dated plan is swapped under the rules for the dates it covers.
+	if day, _ := models.SplitPlanKey(payload.Day); !models.IsWeekday(day) {
+		var keys []string
+		for key := range plan {
+			if d, _ := models.SplitPlanKey(key); !models.IsWeekday(d) {
+				keys = append(keys, key)
+			}
+		}
+		start, _ := models.ParsePlanDate(day)
+		start, numDays, _ := rules.PlanSpan(start, append(keys, payload.Day))
+		rules, err = rules.ForDates(start, numDays)
+		if err != nil {
+			http.Error(w, "Invalid plan: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := payload.Count
+	if limit <= 0 {
+		limit = 1
+	}
+	alternatives, err := h.Store().SwapMealInPlan(rules, plan, payload.Day, limit)
+	if errors.Is(err, models.ErrNoAlternative) {
+		http.Error(w, "No alternative meal for "+payload.Day+": every suitable meal is already planned or over a category cap", http.StatusNotFound)
+		return
 	}
 	if err != nil {
 		http.Error(w, "Error swapping meal: "+err.Error(), http.StatusInternalServerError)
@@ -122,29 +320,62 @@ func SwapMeal(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(newMeal)
+	if payload.Count > 0 {
+		json.NewEncoder(w).Encode(map[string]interface{}{"alternatives": alternatives})
+		return
+	}
+	json.NewEncoder(w).Encode(alternatives[0].Meal)
 }
 
-// GetShoppingList returns all ingredients for the planned meals (no aggregation yet, per MVP).
-func GetShoppingList(w http.ResponseWriter, r *http.Request) {
+// GetShoppingList returns the aggregated ingredients for the planned meals, less what the pantry already covers.
+// Meals listed in the optional servings object, keyed by meal ID, are scaled to that many servings.
+// The plan can instead be given as entries with a day, slot, meal ID and optional servings; a
+// meal planned in several slots is then bought for each one, and every item lists its slots.
+// With ?group_by=aisle the items are returned in aisle sections following the store layout.
+func (h *Handler) GetShoppingList(w http.ResponseWriter, r *http.Request) {
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "" && groupBy != "aisle" {
+		http.Error(w, "Invalid group_by, expected aisle", http.StatusBadRequest)
+		return
+	}
+
 	// Decode the plan payload from the frontend.
+	type PlanEntry struct {
+		Day      string `json:"day"`
+		Slot     string `json:"slot"`
+		MealID   int    `json:"meal_id"`
+		Servings int    `json:"servings"` // optional; overrides the servings by meal ID
+	}
 	type PlanPayload struct {
-		Plan []int `json:"plan"` // array of meal IDs
+		Plan     []int       `json:"plan"`     // array of meal IDs
+		Entries  []PlanEntry `json:"entries"`  // optional: the planned slots, used instead of plan
+		Servings map[int]int `json:"servings"` // optional servings by meal ID
 	}
 	var payload PlanPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		http.Error(w, "Invalid payload", http.StatusBadRequest)
 		return
 	}
+	for id, servings := range payload.Servings {
+		if servings < 0 {
+			http.Error(w, fmt.Sprintf("Invalid servings for meal %d", id), http.StatusBadRequest)
+			return
+		}
+	}
+	ids := payload.Plan
+	if len(payload.Entries) > 0 {
+		ids = nil
+		for _, e := range payload.Entries {
+			if !models.IsSlot(models.NormalizeSlot(e.Slot)) || e.Servings < 0 {
+				http.Error(w, fmt.Sprintf("Invalid entry for meal %d on %s", e.MealID, models.PlanKey(e.Day, e.Slot)), http.StatusBadRequest)
+				return
+			}
+			ids = append(ids, e.MealID)
+		}
+	}
 
 	// Retrieve the meals for the provided IDs.
-	var meals []*models.Meal
-	var err error
-	if UseDummy {
-		meals, err = dummy.GetMealsByIDs(payload.Plan)
-	} else {
-		meals, err = models.GetMealsByIDs(DB, payload.Plan)
-	}
+	meals, err := h.Store().GetMealsByIDs(ids)
 	if err != nil {
 		http.Error(w, "Error retrieving meals: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -153,38 +384,107 @@ func GetShoppingList(w http.ResponseWriter, r *http.Request) {
 	// Log the retrieved meals.
 	log.Printf("Retrieved meals for shopping list: %+v", meals)
 
-	// Generate the shopping list from the retrieved meals.
-	shoppingList := models.GenerateShoppingListFromMeals(meals)
+	// Generate the shopping list from the retrieved meals, once per planned slot when the
+	// plan came as entries.
+	var shoppingList []models.Ingredient
+	var planned []models.PlannedMeal
+	if len(payload.Entries) > 0 {
+		byID := make(map[int]*models.Meal, len(meals))
+		for _, m := range meals {
+			byID[m.ID] = m
+		}
+		for _, e := range payload.Entries {
+			m, ok := byID[e.MealID]
+			if !ok {
+				continue
+			}
+			servings := e.Servings
+			if servings == 0 {
+				servings = payload.Servings[e.MealID]
+			}
+			planned = append(planned, models.PlannedMeal{Meal: m, Slot: models.NormalizeSlot(e.Slot), Servings: servings})
+		}
+		shoppingList = models.GenerateShoppingListFromPlan(planned)
+	} else {
+		shoppingList = models.GenerateShoppingListFromMeals(meals, payload.Servings)
+	}
+
+	// Subtract what's already in the pantry and mark staples.
+	pantry, err := h.currentPantry()
+	if err != nil {
+		http.Error(w, "Error retrieving pantry: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	items := models.ApplyPantry(shoppingList, pantry)
+	if planned != nil {
+		models.AssignSlots(items, planned)
+	}
+
+	settings, err := h.currentAisleSettings()
+	if err != nil {
+		http.Error(w, "Error retrieving aisles: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	settings.AssignAisles(items)
 
 	// Log the generated shopping list.
-	log.Printf("Generated shopping list: %+v", shoppingList)
+	log.Printf("Generated shopping list: %+v", items)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(shoppingList)
+	if groupBy == "aisle" {
+		json.NewEncoder(w).Encode(map[string]interface{}{"sections": settings.GroupByAisle(items)})
+		return
+	}
+	json.NewEncoder(w).Encode(items)
 }
 
-// MealPlanICSHandler returns the current meal plan as an iCalendar file.
-func MealPlanICSHandler(w http.ResponseWriter, r *http.Request) {
+// MealPlanICSHandler returns the current meal plan as an iCalendar file. Without a saved
+// plan it generates one for the household's current week.
+func (h *Handler) MealPlanICSHandler(w http.ResponseWriter, r *http.Request) {
 	var plan map[string]*models.Meal
-	var err error
-	if UseDummy {
-		plan, err = dummy.GenerateWeeklyMealPlan()
+	saved, err := h.Store().GetLatestMealPlan()
+	if err == nil {
+		plan = saved.DateMap()
 	} else {
-		plan, err = models.GetLastPlannedMeals(DB)
+		plan, err = h.generatedWeek()
 		if err != nil {
-			plan, err = models.GenerateWeeklyMealPlan(DB)
-			if err != nil {
-				http.Error(w, "Error generating meal plan: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
+			http.Error(w, "Error generating meal plan: "+err.Error(), http.StatusInternalServerError)
+			return
 		}
 	}
-	monday := time.Now()
-	for monday.Weekday() != time.Monday {
-		monday = monday.AddDate(0, 0, -1)
-	}
-	ics := models.MealPlanToICS(plan, monday)
+	// Every key is dated, so the week start is never used.
+	ics := models.MealPlanToICS(plan, time.Time{})
 	w.Header().Set("Content-Type", "text/calendar")
 	w.Header().Set("Content-Disposition", "attachment; filename=mealplan.ics")
 	w.Write([]byte(ics))
 }
+
+// ListMealPlansHandler handles GET /api/mealplans?from=&to= and returns the saved plans
+// covering any day in the given range. Both dates are optional and use YYYY-MM-DD.
+func (h *Handler) ListMealPlansHandler(w http.ResponseWriter, r *http.Request) {
+	var from, to time.Time
+	var err error
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err = time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		to, err = time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	plans, err := h.Store().ListMealPlans(from, to)
+	if err != nil {
+		http.Error(w, "Error retrieving meal plans: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plans)
+}
@@ -10,13 +10,11 @@ import (
 )
 
 func TestMealPlanICSHandler(t *testing.T) {
-	originalUseDummy := UseDummy
-	UseDummy = true
-	defer func() { UseDummy = originalUseDummy }()
-
-	if err := dummy.Load("../Meal_db.csv"); err != nil {
+	mem := dummy.NewStore()
+	if err := mem.Load("../Meal_db.csv"); err != nil {
 		t.Fatalf("failed loading dummy data: %v", err)
 	}
+	api := New(mem)
 
 	req, err := http.NewRequest("GET", "/api/mealplan/ics", nil)
 	if err != nil {
@@ -24,7 +22,7 @@ func TestMealPlanICSHandler(t *testing.T) {
 	}
 	rr := httptest.NewRecorder()
 
-	MealPlanICSHandler(rr, req)
+	api.MealPlanICSHandler(rr, req)
 
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected status 200 got %d", rr.Code)
@@ -3,22 +3,24 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"mealplanner/dummy"
+	"mealplanner/models"
 )
 
 func TestGenerateMealPlan_SkipDays(t *testing.T) {
-	// Enable dummy mode and load sample data
-	originalUseDummy := UseDummy
-	UseDummy = true
-	defer func() { UseDummy = originalUseDummy }()
-
-	if err := dummy.Load("../Meal_db.csv"); err != nil {
+	// Load sample data into an in-memory store
+	mem := dummy.NewStore()
+	if err := mem.Load("../Meal_db.csv"); err != nil {
 		t.Fatalf("failed loading dummy data: %v", err)
 	}
+	api := New(mem)
 
 	body := []byte(`{"skip_days":["Monday","Friday"]}`)
 	req, err := http.NewRequest("POST", "/api/mealplan/generate", bytes.NewBuffer(body))
@@ -28,7 +30,7 @@ func TestGenerateMealPlan_SkipDays(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
-	GenerateMealPlan(rr, req)
+	api.GenerateMealPlan(rr, req)
 
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected status 200 got %d", rr.Code)
@@ -56,3 +58,291 @@ func TestGenerateMealPlan_SkipDays(t *testing.T) {
 		t.Errorf("expected some meals returned")
 	}
 }
+
+func TestListMealPlansHandler_InvalidDate(t *testing.T) {
+	api := New(dummy.NewStore())
+	req, err := http.NewRequest("GET", "/api/mealplans?from=last-week", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+
+	api.ListMealPlansHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 got %d", rr.Code)
+	}
+}
+
+func TestGenerateMealPlan_ReduceWaste(t *testing.T) {
+	mem := dummy.NewStore()
+	if err := mem.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("failed loading dummy data: %v", err)
+	}
+	api := New(mem)
+
+	req, err := http.NewRequest("POST", "/api/mealplan/generate", bytes.NewBufferString(`{"reduce_waste":true,"skip_days":["Sunday"]}`))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+
+	api.GenerateMealPlan(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp map[string]struct {
+		ID                int      `json:"id"`
+		SharedIngredients []string `json:"sharedIngredients"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if _, ok := resp["Sunday"]; ok {
+		t.Errorf("expected Sunday to be skipped")
+	}
+	if len(resp) != 6 {
+		t.Errorf("expected 6 planned days, got %d", len(resp))
+	}
+}
+
+func TestGenerateMealPlan_LockedDays(t *testing.T) {
+	mem := dummy.NewStore()
+	if err := mem.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("failed loading dummy data: %v", err)
+	}
+	api := New(mem)
+
+	req, _ := http.NewRequest("POST", "/api/mealplan/generate", bytes.NewBufferString(`{"locked":{"Tuesday":3}}`))
+	rr := httptest.NewRecorder()
+	api.GenerateMealPlan(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp map[string]struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["Tuesday"].ID != 3 {
+		t.Errorf("expected Tuesday to keep meal 3, got %d", resp["Tuesday"].ID)
+	}
+	for day, meal := range resp {
+		if day != "Tuesday" && meal.ID == 3 {
+			t.Errorf("locked meal repeated on %s", day)
+		}
+	}
+
+	req, _ = http.NewRequest("POST", "/api/mealplan/generate", bytes.NewBufferString(`{"locked":{"Someday":3}}`))
+	rr = httptest.NewRecorder()
+	api.GenerateMealPlan(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown locked day, got %d", rr.Code)
+	}
+
+	req, _ = http.NewRequest("POST", "/api/mealplan/generate", bytes.NewBufferString(`{"locked":{"Tuesday":9999}}`))
+	rr = httptest.NewRecorder()
+	api.GenerateMealPlan(rr, req)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "meal 9999") {
+		t.Errorf("expected status 400 for an unknown locked meal, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rules := models.DefaultPlanRules()
+	rules.Days[0].FixedMealID = 9999
+	if err := mem.SavePlanRules(rules); err != nil {
+		t.Fatalf("SavePlanRules: %v", err)
+	}
+	req, _ = http.NewRequest("POST", "/api/mealplan/generate", bytes.NewBufferString(`{}`))
+	rr = httptest.NewRecorder()
+	api.GenerateMealPlan(rr, req)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "meal 9999") {
+		t.Errorf("expected status 400 for an unknown fixed meal, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGenerateMealPlan_DateRange(t *testing.T) {
+	mem := dummy.NewStore()
+	if err := mem.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("failed loading dummy data: %v", err)
+	}
+	api := New(mem)
+
+	body := `{"start_date":"2024-03-06","days":10,"locked":{"Monday":3},"skip_days":["2024-03-14"]}`
+	req, _ := http.NewRequest("POST", "/api/mealplan/generate", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	api.GenerateMealPlan(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp map[string]struct {
+		ID   int    `json:"id"`
+		Day  string `json:"day"`
+		Date string `json:"date"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["2024-03-11"].ID != 3 {
+		t.Errorf("expected the locked Monday on 2024-03-11, got %+v", resp["2024-03-11"])
+	}
+	if _, ok := resp["2024-03-14"]; ok {
+		t.Errorf("expected 2024-03-14 to be skipped")
+	}
+	for key, meal := range resp {
+		date, ok := models.ParsePlanDate(meal.Date)
+		if !ok || meal.Day != meal.Date || date.Before(time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)) || date.After(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected %s outside the range: %+v", key, meal)
+		}
+	}
+	if len(resp) < 8 {
+		t.Errorf("expected most of the 10 days planned, got %d", len(resp))
+	}
+
+	for _, body := range []string{`{"days":32}`, `{"days":-1}`, `{"start_date":"6 March"}`, `{"days":3,"start_date":"2024-03-06","locked":{"Monday":3}}`} {
+		req, _ := http.NewRequest("POST", "/api/mealplan/generate", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		api.GenerateMealPlan(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, rr.Code)
+		}
+	}
+}
+
+func TestSwapMeal_RespectsPlan(t *testing.T) {
+	mem := dummy.NewStore()
+	if err := mem.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("failed loading dummy data: %v", err)
+	}
+	api := New(mem)
+
+	body := `{"day":"Tuesday","plan":{"Monday":{"id":1},"Tuesday":{"id":3},"Wednesday":{"id":5}},"count":3}`
+	req, _ := http.NewRequest("POST", "/api/mealplan/swap", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	api.SwapMeal(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Alternatives []models.SwapCandidate `json:"alternatives"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Alternatives) == 0 || len(resp.Alternatives) > 3 {
+		t.Fatalf("expected 1-3 alternatives, got %d", len(resp.Alternatives))
+	}
+	for _, alt := range resp.Alternatives {
+		switch alt.Meal.ID {
+		case 1, 3, 5:
+			t.Errorf("alternative %d is already in the plan", alt.Meal.ID)
+		}
+	}
+
+	req, _ = http.NewRequest("POST", "/api/mealplan/swap", bytes.NewBufferString(`{"day":"Someday"}`))
+	rr = httptest.NewRecorder()
+	api.SwapMeal(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown day, got %d", rr.Code)
+	}
+}
+
+func TestSwapMeal_NoAlternative(t *testing.T) {
+	mem := dummy.NewStore()
+	api := New(mem)
+	soup, _ := mem.CreateMeal(models.Meal{MealName: "Soup", RelativeEffort: 2, Ingredients: []models.Ingredient{}})
+	stew, _ := mem.CreateMeal(models.Meal{MealName: "Stew", RelativeEffort: 4, Ingredients: []models.Ingredient{}})
+
+	// Both meals are already in the plan, so nothing can replace Tuesday's.
+	body := fmt.Sprintf(`{"day":"Tuesday","plan":{"Monday":{"id":%d},"Tuesday":{"id":%d}}}`, soup.ID, stew.ID)
+	req, _ := http.NewRequest("POST", "/api/mealplan/swap", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	api.SwapMeal(rr, req)
+	if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "No alternative meal for Tuesday") {
+		t.Errorf("expected status 404 explaining there's no alternative, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestMealPlanSlots_Dummy(t *testing.T) {
+	store := dummy.NewStore()
+	api := New(store)
+	oats, _ := store.CreateMeal(models.Meal{MealName: "Oats", RelativeEffort: 1, Servings: 2, Ingredients: []models.Ingredient{{Name: "oats", Quantity: 1, Unit: "cup"}}})
+	soup, _ := store.CreateMeal(models.Meal{MealName: "Soup", RelativeEffort: 2, Servings: 2, Ingredients: []models.Ingredient{{Name: "stock", Quantity: 1, Unit: "cup"}}})
+
+	req, _ := http.NewRequest("PUT", "/api/meals/1/slots", bytes.NewBufferString(`{"slots":["Brunch"]}`))
+	rr := httptest.NewRecorder()
+	api.SetMealSlotsHandler(rr, withURLParam(req, "mealId", "1"))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown slot, got %d", rr.Code)
+	}
+	req, _ = http.NewRequest("PUT", "/api/meals/1/slots", bytes.NewBufferString(`{"slots":["Breakfast"]}`))
+	rr = httptest.NewRecorder()
+	api.SetMealSlotsHandler(rr, withURLParam(req, "mealId", "1"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rules := models.PlanRules{Days: []models.DayRule{
+		{Day: "Monday", Slot: models.SlotBreakfast, MaxEffort: 5},
+		{Day: "Monday", MaxEffort: 5},
+		{Day: "Tuesday", Slot: models.SlotBreakfast, MaxEffort: 5},
+	}}
+	if err := store.SavePlanRules(rules); err != nil {
+		t.Fatalf("SavePlanRules: %v", err)
+	}
+	req, _ = http.NewRequest("POST", "/api/mealplan/generate", bytes.NewBufferString(`{"skip_days":["Tuesday/breakfast"]}`))
+	rr = httptest.NewRecorder()
+	api.GenerateMealPlan(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp map[string]struct {
+		ID   int    `json:"id"`
+		Day  string `json:"day"`
+		Slot string `json:"slot"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp) != 2 {
+		t.Fatalf("expected Monday's breakfast and dinner, got %+v", resp)
+	}
+	if m := resp["Monday/breakfast"]; m.ID != oats.ID || m.Day != "Monday" || m.Slot != models.SlotBreakfast {
+		t.Errorf("unexpected breakfast: %+v", m)
+	}
+	if m := resp["Monday"]; m.ID != soup.ID || m.Slot != models.SlotDinner {
+		t.Errorf("unexpected dinner: %+v", m)
+	}
+
+	// The same meal in two slots is bought for twice, and each item names its slots.
+	body := `{"entries":[{"day":"Monday","slot":"breakfast","meal_id":1},{"day":"Tuesday","slot":"breakfast","meal_id":1,"servings":4},{"day":"Monday","meal_id":2}]}`
+	req, _ = http.NewRequest("POST", "/api/shoppinglist", bytes.NewBufferString(body))
+	rr = httptest.NewRecorder()
+	api.GetShoppingList(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var items []models.ShoppingItem
+	if err := json.NewDecoder(rr.Body).Decode(&items); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %+v", items)
+	}
+	for _, item := range items {
+		switch item.Name {
+		case "oats":
+			if item.Quantity != 3 || len(item.Slots) != 1 || item.Slots[0] != models.SlotBreakfast {
+				t.Errorf("expected 3 cups of oats for breakfast, got %+v", item)
+			}
+		case "stock":
+			if item.Quantity != 1 || len(item.Slots) != 1 || item.Slots[0] != models.SlotDinner {
+				t.Errorf("expected 1 cup of stock for dinner, got %+v", item)
+			}
+		}
+	}
+}
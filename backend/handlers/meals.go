@@ -2,33 +2,44 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
-	"mealplanner/dummy"
 	"mealplanner/models"
 
 	"github.com/go-chi/chi/v5"
 )
 
-// UseDummy indicates whether the server is running with in-memory data
-var UseDummy bool
-
 // GetAllMealsHandler handles GET /api/meals and returns all meals with their ingredients.
-func GetAllMealsHandler(w http.ResponseWriter, r *http.Request) {
-	var meals []*models.Meal
-	var err error
-    if UseDummy {
-		meals, err = dummy.GetAllMeals()
-	} else {
-		meals, err = models.GetAllMeals(DB)
+// Each ?tag= narrows the list to meals carrying that tag.
+func (h *Handler) GetAllMealsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := models.NormalizeTagNames(r.URL.Query()["tag"])
+	if err != nil {
+		http.Error(w, "Invalid tag filter: "+err.Error(), http.StatusBadRequest)
+		return
 	}
+	meals, err := h.Store().GetAllMeals()
 	if err != nil {
 		http.Error(w, "Error retrieving meals: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if len(tags) > 0 {
+		tagged := []*models.Meal{}
+		for _, m := range meals {
+			keep := true
+			for _, tag := range tags {
+				keep = keep && m.HasTag(tag)
+			}
+			if keep {
+				tagged = append(tagged, m)
+			}
+		}
+		meals = tagged
+	}
 
 	// Sort meals alphabetically by name (A -> Z), case-insensitive
 	sort.Slice(meals, func(i, j int) bool {
@@ -39,37 +50,48 @@ func GetAllMealsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(meals)
 }
 
-// SwapMealHandler handles POST /api/meals/swap and returns a new meal to replace the current one.
-func SwapMealHandler(w http.ResponseWriter, r *http.Request) {
-	var payload struct {
-		MealID int `json:"meal_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+// GetMealHandler handles GET /api/meals/{mealId}?servings=N and returns a meal with its
+// ingredients. With servings the ingredient quantities are scaled from the meal's own
+// servings and rounded to amounts that can be measured.
+func (h *Handler) GetMealHandler(w http.ResponseWriter, r *http.Request) {
+	mealID, err := strconv.Atoi(chi.URLParam(r, "mealId"))
+	if err != nil {
+		http.Error(w, "Invalid meal ID", http.StatusBadRequest)
 		return
 	}
-
-	var newMeal *models.Meal
-	var err error
-    if UseDummy {
-		newMeal, err = dummy.SwapMeal(payload.MealID)
-	} else {
-		newMeal, err = models.SwapMeal(payload.MealID, DB)
+	servings := 0
+	if v := r.URL.Query().Get("servings"); v != "" {
+		servings, err = strconv.Atoi(v)
+		if err != nil || servings <= 0 {
+			http.Error(w, "Invalid servings, expected a positive whole number", http.StatusBadRequest)
+			return
+		}
 	}
+
+	meals, err := h.Store().GetMealsByIDs([]int{mealID})
 	if err != nil {
-		http.Error(w, "Error swapping meal: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Error retrieving meal: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(meals) == 0 {
+		http.Error(w, "Meal not found", http.StatusNotFound)
 		return
 	}
+	meal := meals[0]
+	if servings > 0 {
+		if meal.Servings <= 0 {
+			http.Error(w, "Meal has no servings set, so it can't be scaled", http.StatusUnprocessableEntity)
+			return
+		}
+		meal = models.ScaleMeal(meal, servings)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(newMeal)
+	json.NewEncoder(w).Encode(meal)
 }
 
 // UpdateMealIngredientHandler handles updating a single ingredient for a specific meal.
-func UpdateMealIngredientHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) UpdateMealIngredientHandler(w http.ResponseWriter, r *http.Request) {
 	mealIdStr := chi.URLParam(r, "mealId")
 	if mealIdStr == "" {
 		http.Error(w, "Missing meal ID", http.StatusBadRequest)
@@ -100,13 +122,13 @@ func UpdateMealIngredientHandler(w http.ResponseWriter, r *http.Request) {
 
 	updatedIngredient.ID = ingredientID
 
-	err = models.UpdateMealIngredient(DB, mealID, updatedIngredient)
+	err = h.Store().UpdateMealIngredient(mealID, updatedIngredient)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	meals, err := models.GetMealsByIDs(DB, []int{mealID})
+	meals, err := h.Store().GetMealsByIDs([]int{mealID})
 	if err != nil || len(meals) == 0 {
 		http.Error(w, "Meal not found", http.StatusInternalServerError)
 		return
@@ -116,11 +138,7 @@ func UpdateMealIngredientHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // DeleteMealIngredientHandler handles DELETE /api/meals/{mealId}/ingredients/{ingredientId} and deletes a specific ingredient.
-func DeleteMealIngredientHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) DeleteMealIngredientHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse ingredientId from URL.
 	ingredientIdStr := chi.URLParam(r, "ingredientId")
 	if ingredientIdStr == "" {
@@ -133,21 +151,22 @@ func DeleteMealIngredientHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete the ingredient by its ID.
-	err = models.DeleteMealIngredient(DB, ingredientID)
+	mealIdStr := chi.URLParam(r, "mealId")
+	mealID, err := strconv.Atoi(mealIdStr)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Invalid meal ID", http.StatusBadRequest)
 		return
 	}
 
-	// Retrieve the meal to return the updated record.
-	mealIdStr := chi.URLParam(r, "mealId")
-	mealID, err := strconv.Atoi(mealIdStr)
+	// Delete the ingredient by its ID.
+	err = h.Store().DeleteMealIngredient(mealID, ingredientID)
 	if err != nil {
-		http.Error(w, "Invalid meal ID", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	updatedMeals, err := models.GetMealsByIDs(DB, []int{mealID})
+
+	// Retrieve the meal to return the updated record.
+	updatedMeals, err := h.Store().GetMealsByIDs([]int{mealID})
 	if err != nil || len(updatedMeals) == 0 {
 		http.Error(w, "Meal not found after deletion", http.StatusInternalServerError)
 		return
@@ -157,11 +176,7 @@ func DeleteMealIngredientHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // DeleteMealHandler handles DELETE /api/meals/{mealId} and deletes a meal and its ingredients.
-func DeleteMealHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) DeleteMealHandler(w http.ResponseWriter, r *http.Request) {
 	mealIdStr := chi.URLParam(r, "mealId")
 	if mealIdStr == "" {
 		http.Error(w, "Missing meal ID", http.StatusBadRequest)
@@ -173,7 +188,7 @@ func DeleteMealHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = models.DeleteMeal(DB, mealID)
+	err = h.Store().DeleteMeal(mealID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -183,11 +198,7 @@ func DeleteMealHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // ReplaceMealHandler handles POST /api/meals/replace and returns a new meal to replace the current one.
-func ReplaceMealHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) ReplaceMealHandler(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
 		Day       string `json:"day"`
 		NewMealID int    `json:"new_meal_id"`
@@ -197,7 +208,7 @@ func ReplaceMealHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	meals, err := models.GetMealsByIDs(DB, []int{payload.NewMealID})
+	meals, err := h.Store().GetMealsByIDs([]int{payload.NewMealID})
 	if err != nil || len(meals) == 0 {
 		http.Error(w, "Meal not found", http.StatusNotFound)
 		return
@@ -207,33 +218,48 @@ func ReplaceMealHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(meals[0])
 }
 
-// FinalizeMealPlanHandler handles POST /api/mealplan/finalize and updates the last planned date for all meals in the plan
-func FinalizeMealPlanHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		// In dummy mode, nothing to finalize
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Plan finalized"))
-		return
-	}
+// FinalizeMealPlanHandler handles POST /api/mealplan/finalize and saves the plan as the record for its dates.
+// Plans keyed by weekday are saved for the household's week containing week_start.
+func (h *Handler) FinalizeMealPlanHandler(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
-		Plan map[string]models.Meal `json:"plan"`
+		Plan      map[string]*models.Meal `json:"plan"`
+		WeekStart string                  `json:"week_start"` // optional, YYYY-MM-DD; defaults to this week
+		Servings  map[string]int          `json:"servings"`   // optional, per plan key; overrides the meal's servings
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
-	// Extract meal IDs from the plan
-	var mealIDs []int
-	for _, meal := range payload.Plan {
-		mealIDs = append(mealIDs, meal.ID)
+	var weekStart time.Time
+	if payload.WeekStart != "" {
+		parsed, err := time.Parse("2006-01-02", payload.WeekStart)
+		if err != nil {
+			http.Error(w, "Invalid week_start, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		weekStart = parsed
+	}
+	for day, servings := range payload.Servings {
+		if !models.IsPlanKey(day) || servings < 0 {
+			http.Error(w, "Invalid servings for "+day, http.StatusBadRequest)
+			return
+		}
 	}
 
-	// Sort meal IDs to ensure consistent order
-	sort.Ints(mealIDs)
+	// Weekdays fall in the household's week containing week_start, this week by default.
+	// Dated keys keep their dates, so a dated plan starts on its first date.
+	if weekStart.IsZero() {
+		rules, err := h.currentPlanRules()
+		if err != nil {
+			http.Error(w, "Error retrieving planning rules: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		weekStart = rules.WeekStart(time.Now())
+	}
 
-	// Update last planned date for all meals in the plan
-	err := models.UpdateLastPlannedDates(DB, mealIDs)
+	// Save the plan exactly as finalized; this also updates last_planned for its meals
+	_, err := h.Store().SaveMealPlan(weekStart, payload.Plan, payload.Servings)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -244,11 +270,7 @@ func FinalizeMealPlanHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // CreateMealHandler handles POST /api/meals and creates a new meal with ingredients.
-func CreateMealHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) CreateMealHandler(w http.ResponseWriter, r *http.Request) {
 	var meal models.Meal
 	if err := json.NewDecoder(r.Body).Decode(&meal); err != nil {
 		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
@@ -260,9 +282,25 @@ func CreateMealHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Meal name is required", http.StatusBadRequest)
 		return
 	}
+	if meal.Servings < 0 {
+		http.Error(w, "Servings can't be negative", http.StatusBadRequest)
+		return
+	}
+	if meal.Nights < 0 {
+		http.Error(w, "Nights can't be negative", http.StatusBadRequest)
+		return
+	}
+	if _, err := models.NormalizeTagNames(meal.Tags); err != nil {
+		http.Error(w, "Invalid tags: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := models.NormalizeSlots(meal.Slots); err != nil {
+		http.Error(w, "Invalid slots: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Create the meal in the database
-	createdMeal, err := models.CreateMeal(DB, meal)
+	createdMeal, err := h.Store().CreateMeal(meal)
 	if err != nil {
 		http.Error(w, "Error creating meal: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -273,3 +311,37 @@ func CreateMealHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(createdMeal)
 }
+
+// SetMealSlotsHandler handles PUT /api/meals/{mealId}/slots and replaces the meal slots
+// (breakfast, lunch, dinner) a meal can be planned in.
+func (h *Handler) SetMealSlotsHandler(w http.ResponseWriter, r *http.Request) {
+	mealID, err := strconv.Atoi(chi.URLParam(r, "mealId"))
+	if err != nil {
+		http.Error(w, "Invalid meal ID", http.StatusBadRequest)
+		return
+	}
+	var payload struct {
+		Slots []string `json:"slots"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := models.NormalizeSlots(payload.Slots); err != nil {
+		http.Error(w, "Invalid slots: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slots, err := h.Store().SetMealSlots(mealID, payload.Slots)
+	if errors.Is(err, models.ErrMealNotFound) {
+		http.Error(w, "Meal not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating meal slots: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"slots": slots})
+}
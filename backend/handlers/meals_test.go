@@ -15,15 +15,17 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-chi/chi/v5"
-	"github.com/lib/pq"
 
+	"mealplanner/dummy"
 	"mealplanner/models"
+	"mealplanner/store"
 )
 
 // testHelper contains utilities for testing handlers
 type testHelper struct {
 	db   *sql.DB
 	mock sqlmock.Sqlmock
+	api  *Handler
 }
 
 // setupTest creates a new test helper with mock DB
@@ -33,17 +35,12 @@ func setupTest(t *testing.T) *testHelper {
 		t.Fatalf("failed to create sqlmock: %v", err)
 	}
 
-	// Store the original DB
-	originalDB := DB
-	DB = db
-
-	// Restore the original DB after the test
+	// Close the mock DB after the test
 	t.Cleanup(func() {
 		db.Close()
-		DB = originalDB
 	})
 
-	return &testHelper{db, mock}
+	return &testHelper{db, mock, New(store.NewPostgres(db))}
 }
 
 // setupMealRows creates mock rows for meal queries
@@ -54,7 +51,7 @@ func setupMealRows(fields []string) *sqlmock.Rows {
 // expectMealQuery sets up expectations for a meal query
 func (h *testHelper) expectMealQuery(queryRegex string, args ...interface{}) *sqlmock.Rows {
 	rows := sqlmock.NewRows([]string{
-		"id", "meal_name", "relative_effort", "last_planned", "red_meat", "url",
+		"id", "meal_name", "relative_effort", "last_planned", "red_meat", "url", "servings", "nights", "slots",
 		"ingredient_id", "name", "quantity", "unit",
 	})
 
@@ -123,9 +120,9 @@ func TestGetAllMealsHandler(t *testing.T) {
 	rows := helper.expectMealQuery(models.GetAllMealsQuery)
 
 	// Add meal data to rows
-	rows.AddRow(1, "Meal A", 2, now, false, "https://example.com/meala", 1, "Eggs", 0, "dozen")
-	rows.AddRow(2, "Meal B", 3, now, true, "https://example.com/mealb", 2, "Milk", 2.5, "gallon")
-	rows.AddRow(2, "Meal B", 3, now, true, "https://example.com/mealb", 3, "Bread", 0, "loaf")
+	rows.AddRow(1, "Meal A", 2, now, false, "https://example.com/meala", 0, 1, "dinner", 1, "Eggs", 0, "dozen")
+	rows.AddRow(2, "Meal B", 3, now, true, "https://example.com/mealb", 0, 1, "dinner", 2, "Milk", 2.5, "gallon")
+	rows.AddRow(2, "Meal B", 3, now, true, "https://example.com/mealb", 0, 1, "dinner", 3, "Bread", 0, "loaf")
 
 	// Create request and response recorder
 	req, err := createRequest("GET", "/api/meals", nil)
@@ -134,7 +131,7 @@ func TestGetAllMealsHandler(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(GetAllMealsHandler)
+	handler := http.HandlerFunc(helper.api.GetAllMealsHandler)
 	handler.ServeHTTP(rr, req)
 
 	// Check response status
@@ -179,8 +176,8 @@ func TestUpdateMealIngredientHandler(t *testing.T) {
 
 	// Expect query to return updated meal
 	now := time.Now()
-	rows := helper.expectMealQuery(models.GetMealsByIDsQuery, pq.Array([]int{mealID}))
-	rows.AddRow(mealID, "Test Meal", 1, now, false, "https://example.com/test", 1, updatedIngredient.Name, updatedIngredient.Quantity, updatedIngredient.Unit)
+	rows := helper.expectMealQuery(models.GetMealsByIDsQuery(1), mealID)
+	rows.AddRow(mealID, "Test Meal", 1, now, false, "https://example.com/test", 0, 1, "dinner", 1, updatedIngredient.Name, updatedIngredient.Quantity, updatedIngredient.Unit)
 
 	// Create a PUT request to update the ingredient
 	req, err := createRequest("PUT", "/api/meals/1/ingredients/1", updatedIngredient)
@@ -196,7 +193,7 @@ func TestUpdateMealIngredientHandler(t *testing.T) {
 
 	// Execute the request
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(UpdateMealIngredientHandler)
+	handler := http.HandlerFunc(helper.api.UpdateMealIngredientHandler)
 	handler.ServeHTTP(rr, req)
 
 	// Check response status
@@ -233,14 +230,14 @@ func TestDeleteMealIngredientHandler(t *testing.T) {
 	ingredientID := 1
 
 	// Expect deletion query
-	helper.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM ingredients WHERE id = $1")).
-		WithArgs(ingredientID).
+	helper.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM ingredients WHERE id = $1 AND meal_id = $2")).
+		WithArgs(ingredientID, mealID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Expect query to return updated meal
 	now := time.Now()
-	rows := helper.expectMealQuery(models.GetMealsByIDsQuery, pq.Array([]int{mealID}))
-	rows.AddRow(mealID, "Test Meal", 1, now, false, "https://example.com/test", 2, "Pepper", 0.5, "tsp")
+	rows := helper.expectMealQuery(models.GetMealsByIDsQuery(1), mealID)
+	rows.AddRow(mealID, "Test Meal", 1, now, false, "https://example.com/test", 0, 1, "dinner", 2, "Pepper", 0.5, "tsp")
 
 	// Create request and add URL parameters
 	req, err := createRequest("DELETE", "/api/meals/1/ingredients/1", nil)
@@ -255,7 +252,7 @@ func TestDeleteMealIngredientHandler(t *testing.T) {
 
 	// Execute request
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(DeleteMealIngredientHandler)
+	handler := http.HandlerFunc(helper.api.DeleteMealIngredientHandler)
 	handler.ServeHTTP(rr, req)
 
 	// Check response status
@@ -291,8 +288,8 @@ func TestDeleteMealHandler(t *testing.T) {
 	}
 	defer db.Close()
 
-	// Set the DB for handlers
-	DB = db
+	// Serve the handlers from the mock DB
+	api := New(store.NewPostgres(db))
 
 	mealID := 1
 
@@ -330,7 +327,7 @@ func TestDeleteMealHandler(t *testing.T) {
 
 	// Execute request
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(DeleteMealHandler)
+	handler := http.HandlerFunc(api.DeleteMealHandler)
 	handler.ServeHTTP(rr, req)
 
 	// Check response status
@@ -408,8 +405,8 @@ func TestDeleteMealHandlerErrors(t *testing.T) {
 			}
 			defer db.Close()
 
-			// Set the DB for handlers
-			DB = db
+			// Serve the handlers from the mock DB
+			api := New(store.NewPostgres(db))
 
 			// Setup mock expectations
 			tt.setupMock(mock)
@@ -427,7 +424,7 @@ func TestDeleteMealHandlerErrors(t *testing.T) {
 
 			// Execute request
 			rr := httptest.NewRecorder()
-			handler := http.HandlerFunc(DeleteMealHandler)
+			handler := http.HandlerFunc(api.DeleteMealHandler)
 			handler.ServeHTTP(rr, req)
 
 			// Check response status
@@ -458,15 +455,39 @@ func TestFinalizeMealPlanHandler(t *testing.T) {
 	}{
 		{
 			name:    "successful finalization",
-			payload: `{"plan": {"Monday": {"id": 1}, "Tuesday": {"id": 2}}}`,
+			payload: `{"plan": {"Monday": {"id": 1}, "Tuesday": {"id": 2}, "Friday": {"mealName": "Eating out"}}, "week_start": "2024-04-03"}`,
 			setupMock: func(mock sqlmock.Sqlmock) {
+				weekStart := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT rules FROM planning_rules")).
+					WillReturnError(sql.ErrNoRows)
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(`
-					UPDATE meals 
-					SET last_planned = NOW() 
-					WHERE id = ANY($1)
-				`)).WithArgs(pq.Array([]int{1, 2})).
-					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT e.date, e.slot, e.meal_id, e.status, e.sequence")).
+					WithArgs(weekStart, weekStart.AddDate(0, 0, 7)).
+					WillReturnRows(sqlmock.NewRows([]string{"date", "slot", "meal_id", "status", "sequence"}).
+						AddRow(weekStart, models.SlotDinner, 1, models.CookingStatusCooked, 2).
+						AddRow(weekStart.AddDate(0, 0, 1), models.SlotDinner, 5, models.EntryStatusPlanned, 0))
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM meal_plan_entries")).
+					WithArgs(weekStart).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM meal_plans WHERE week_start = $1")).
+					WithArgs(weekStart).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO meal_plans (week_start, days, created_at) VALUES ($1, $2, $3) RETURNING id")).
+					WithArgs(weekStart, 7, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+				insertEntry := regexp.QuoteMeta("INSERT INTO meal_plan_entries (plan_id, date, day, slot, meal_id, status, servings, sequence) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id")
+				updateMeal := regexp.QuoteMeta("UPDATE meals SET last_planned = $1 WHERE id = $2")
+				mock.ExpectQuery(insertEntry).
+					WithArgs(7, weekStart.AddDate(0, 0, 0), "Monday", models.SlotDinner, 1, models.EntryStatusPlanned, 0, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec(updateMeal).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(insertEntry).
+					WithArgs(7, weekStart.AddDate(0, 0, 1), "Tuesday", models.SlotDinner, 2, models.EntryStatusPlanned, 0, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				mock.ExpectExec(updateMeal).WithArgs(sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(insertEntry).
+					WithArgs(7, weekStart.AddDate(0, 0, 4), "Friday", models.SlotDinner, nil, models.EntryStatusEatingOut, 0, 0).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
 				mock.ExpectCommit()
 			},
 			expectedCode: http.StatusOK,
@@ -479,16 +500,23 @@ func TestFinalizeMealPlanHandler(t *testing.T) {
 			expectedCode: http.StatusBadRequest,
 			expectedBody: "Invalid request payload\n",
 		},
+		{
+			name:         "invalid week start",
+			payload:      `{"plan": {"Monday": {"id": 1}}, "week_start": "next week"}`,
+			setupMock:    func(mock sqlmock.Sqlmock) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid week_start, expected YYYY-MM-DD\n",
+		},
 		{
 			name:    "database error",
 			payload: `{"plan": {"Monday": {"id": 1}}}`,
 			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT rules FROM planning_rules")).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT rules FROM planning_rules")).
+					WillReturnError(sql.ErrNoRows)
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(`
-					UPDATE meals 
-					SET last_planned = NOW() 
-					WHERE id = ANY($1)
-				`)).WithArgs(pq.Array([]int{1})).
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT e.date, e.slot, e.meal_id, e.status, e.sequence")).
 					WillReturnError(errors.New("database error"))
 				mock.ExpectRollback()
 			},
@@ -506,8 +534,8 @@ func TestFinalizeMealPlanHandler(t *testing.T) {
 			}
 			defer db.Close()
 
-			// Set the DB for handlers
-			DB = db
+			// Serve the handlers from the mock DB
+			api := New(store.NewPostgres(db))
 
 			// Setup mock expectations
 			tt.setupMock(mock)
@@ -522,7 +550,7 @@ func TestFinalizeMealPlanHandler(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 
 			rr := httptest.NewRecorder()
-			handler := http.HandlerFunc(FinalizeMealPlanHandler)
+			handler := http.HandlerFunc(api.FinalizeMealPlanHandler)
 			handler.ServeHTTP(rr, req)
 
 			if status := rr.Code; status != tt.expectedCode {
@@ -542,6 +570,43 @@ func TestFinalizeMealPlanHandler(t *testing.T) {
 	}
 }
 
+func TestFinalizeMealPlanHandler_LaterDatedPlan(t *testing.T) {
+	mem := dummy.NewStore()
+	api := New(mem)
+	soup, _ := mem.CreateMeal(models.Meal{MealName: "Soup", RelativeEffort: 2, Ingredients: []models.Ingredient{}})
+	stew, _ := mem.CreateMeal(models.Meal{MealName: "Stew", RelativeEffort: 4, Ingredients: []models.Ingredient{}})
+
+	finalize := func(body string) {
+		t.Helper()
+		req, _ := http.NewRequest("POST", "/api/mealplan/finalize", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		api.FinalizeMealPlanHandler(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+	thisWeek := models.DefaultPlanRules().WeekStart(time.Now())
+	nextWednesday := thisWeek.AddDate(0, 0, 9)
+	finalize(fmt.Sprintf(`{"plan":{"Monday":{"id":%d}}}`, soup.ID))
+	// Next week's dated plan, finalized without a week_start, must not replace this week's.
+	finalize(fmt.Sprintf(`{"plan":{"%s":{"id":%d},"%s":{"id":%d}}}`,
+		nextWednesday.Format(models.DateLayout), stew.ID, nextWednesday.AddDate(0, 0, 1).Format(models.DateLayout), stew.ID))
+
+	plans, err := mem.ListMealPlans(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("ListMealPlans: %v", err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("expected this week's and next week's plans, got %d", len(plans))
+	}
+	if p := plans[0]; !p.WeekStart.Equal(thisWeek) || p.Days != 7 || len(p.Entries) != 1 || p.Entries[0].MealID != soup.ID {
+		t.Errorf("expected this week's plan to be kept, got %+v", p)
+	}
+	if p := plans[1]; !p.WeekStart.Equal(nextWednesday) || p.Days != 2 || len(p.Entries) != 2 {
+		t.Errorf("expected next week's plan to start on its first date, got %+v", p)
+	}
+}
+
 func TestGetAllMealsHandler_AlphabeticalOrder(t *testing.T) {
 	// Create a new sqlmock database connection
 	db, mock, err := sqlmock.New()
@@ -550,20 +615,18 @@ func TestGetAllMealsHandler_AlphabeticalOrder(t *testing.T) {
 	}
 	defer db.Close()
 
-	// Store the original DB and restore it after the test
-	originalDB := DB
-	DB = db
-	defer func() { DB = originalDB }()
+	// Serve the handlers from the mock DB
+	api := New(store.NewPostgres(db))
 
 	// Setup rows with meals in non-alphabetical order
 	rows := sqlmock.NewRows([]string{
-		"id", "meal_name", "relative_effort", "last_planned", "red_meat", "url",
+		"id", "meal_name", "relative_effort", "last_planned", "red_meat", "url", "servings", "nights", "slots",
 		"ingredient_id", "name", "quantity", "unit",
 	}).
-		AddRow(1, "Zucchini Pasta", 2, nil, false, "https://example.com/zucchini", nil, nil, nil, nil).
-		AddRow(2, "apple pie", 3, nil, false, "https://example.com/apple", nil, nil, nil, nil).
-		AddRow(3, "Meatballs", 4, nil, true, "https://example.com/meatballs", nil, nil, nil, nil).
-		AddRow(4, "banana bread", 2, nil, false, "https://example.com/banana", nil, nil, nil, nil)
+		AddRow(1, "Zucchini Pasta", 2, nil, false, "https://example.com/zucchini", 0, 1, "dinner", nil, nil, nil, nil).
+		AddRow(2, "apple pie", 3, nil, false, "https://example.com/apple", 0, 1, "dinner", nil, nil, nil, nil).
+		AddRow(3, "Meatballs", 4, nil, true, "https://example.com/meatballs", 0, 1, "dinner", nil, nil, nil, nil).
+		AddRow(4, "banana bread", 2, nil, false, "https://example.com/banana", 0, 1, "dinner", nil, nil, nil, nil)
 
 	// Expect the query
 	mock.ExpectQuery(regexp.QuoteMeta(models.GetAllMealsQuery)).
@@ -577,7 +640,7 @@ func TestGetAllMealsHandler_AlphabeticalOrder(t *testing.T) {
 
 	// Create a ResponseRecorder to record the response
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(GetAllMealsHandler)
+	handler := http.HandlerFunc(api.GetAllMealsHandler)
 
 	// Call the handler
 	handler.ServeHTTP(rr, req)
@@ -620,10 +683,8 @@ func TestCreateMealHandler(t *testing.T) {
 	}
 	defer db.Close()
 
-	// Store the original DB and restore it after the test
-	originalDB := DB
-	DB = db
-	defer func() { DB = originalDB }()
+	// Serve the handlers from the mock DB
+	api := New(store.NewPostgres(db))
 
 	// Create a test meal with ingredients
 	newMeal := models.Meal{
@@ -648,8 +709,8 @@ func TestCreateMealHandler(t *testing.T) {
 	mock.ExpectBegin()
 
 	// 2. Insert meal
-	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO meals (meal_name, relative_effort, red_meat, url) VALUES ($1, $2, $3, $4) RETURNING id")).
-		WithArgs(newMeal.MealName, newMeal.RelativeEffort, newMeal.RedMeat, newMeal.URL).
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO meals (meal_name, relative_effort, red_meat, url, servings, nights, slots) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id")).
+		WithArgs(newMeal.MealName, newMeal.RelativeEffort, newMeal.RedMeat, newMeal.URL, newMeal.Servings, 1, "dinner").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(expectedMealID))
 
 	// 3. Insert first ingredient
@@ -679,7 +740,7 @@ func TestCreateMealHandler(t *testing.T) {
 
 	// Create response recorder and call handler
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateMealHandler)
+	handler := http.HandlerFunc(api.CreateMealHandler)
 	handler.ServeHTTP(rr, req)
 
 	// Check status code is 201 Created
@@ -748,10 +809,8 @@ func TestCreateMealHandler_ValidationError(t *testing.T) {
 	}
 	defer db.Close()
 
-	// Store the original DB and restore it after the test
-	originalDB := DB
-	DB = db
-	defer func() { DB = originalDB }()
+	// Serve the handlers from the mock DB
+	api := New(store.NewPostgres(db))
 
 	// Create an invalid meal with no name
 	invalidMeal := models.Meal{
@@ -776,7 +835,7 @@ func TestCreateMealHandler_ValidationError(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateMealHandler)
+	handler := http.HandlerFunc(api.CreateMealHandler)
 	handler.ServeHTTP(rr, req)
 
 	// Check status code is 400 Bad Request
@@ -804,10 +863,8 @@ func TestCreateMealHandler_DatabaseError(t *testing.T) {
 	}
 	defer db.Close()
 
-	// Store the original DB and restore it after the test
-	originalDB := DB
-	DB = db
-	defer func() { DB = originalDB }()
+	// Serve the handlers from the mock DB
+	api := New(store.NewPostgres(db))
 
 	newMeal := models.Meal{
 		MealName:       "Test Recipe",
@@ -821,8 +878,8 @@ func TestCreateMealHandler_DatabaseError(t *testing.T) {
 
 	// Set up mock to simulate a database error
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO meals (meal_name, relative_effort, red_meat, url) VALUES ($1, $2, $3, $4) RETURNING id")).
-		WithArgs(newMeal.MealName, newMeal.RelativeEffort, newMeal.RedMeat, newMeal.URL).
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO meals (meal_name, relative_effort, red_meat, url, servings, nights, slots) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id")).
+		WithArgs(newMeal.MealName, newMeal.RelativeEffort, newMeal.RedMeat, newMeal.URL, newMeal.Servings, 1, "dinner").
 		WillReturnError(errors.New("database error"))
 	mock.ExpectRollback()
 
@@ -839,7 +896,7 @@ func TestCreateMealHandler_DatabaseError(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateMealHandler)
+	handler := http.HandlerFunc(api.CreateMealHandler)
 	handler.ServeHTTP(rr, req)
 
 	// Check status code is 500 Internal Server Error
@@ -858,3 +915,115 @@ func TestCreateMealHandler_DatabaseError(t *testing.T) {
 		t.Errorf("unfulfilled expectations: %s", err)
 	}
 }
+
+// TestMealHandlers_Dummy checks that meal writes work end to end without a database.
+func TestMealHandlers_Dummy(t *testing.T) {
+	mem := dummy.NewStore()
+	if err := mem.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("failed loading dummy data: %v", err)
+	}
+	api := New(mem)
+	r := chi.NewRouter()
+	r.Post("/api/meals", api.CreateMealHandler)
+	r.Put("/api/meals/{mealId}/ingredients/{ingredientId}", api.UpdateMealIngredientHandler)
+	r.Delete("/api/meals/{mealId}", api.DeleteMealHandler)
+	r.Post("/api/mealplan/replace", api.ReplaceMealHandler)
+
+	body := `{"mealName":"Dummy Chili","relativeEffort":3,"ingredients":[{"Name":"beans","Quantity":2,"Unit":"can"}]}`
+	req, _ := http.NewRequest("POST", "/api/meals", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created models.Meal
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.ID == 0 || len(created.Ingredients) != 1 {
+		t.Fatalf("unexpected created meal: %+v", created)
+	}
+
+	ing := created.Ingredients[0]
+	path := fmt.Sprintf("/api/meals/%d/ingredients/%d", created.ID, ing.ID)
+	req, _ = http.NewRequest("PUT", path, bytes.NewBufferString(`{"Name":"black beans","Quantity":3,"Unit":"can"}`))
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 updating the ingredient, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/api/mealplan/replace", bytes.NewBufferString(fmt.Sprintf(`{"day":"Monday","new_meal_id":%d}`, created.ID)))
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	var replaced models.Meal
+	if err := json.NewDecoder(rr.Body).Decode(&replaced); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if replaced.Ingredients[0].Name != "black beans" {
+		t.Errorf("expected the updated ingredient, got %+v", replaced.Ingredients)
+	}
+
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/api/meals/%d", created.ID), nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK && rr.Code != http.StatusNoContent {
+		t.Fatalf("expected the delete to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got, _ := mem.GetMealsByIDs([]int{created.ID}); len(got) != 0 {
+		t.Errorf("expected the meal to be gone")
+	}
+}
+
+func TestGetMealHandler_Servings(t *testing.T) {
+	mem := dummy.NewStore()
+	chili, err := mem.CreateMeal(models.Meal{MealName: "Chili", RelativeEffort: 3, Servings: 4, Ingredients: []models.Ingredient{
+		{Name: "ground beef", Quantity: 1, Unit: "lb"},
+		{Name: "kidney beans", Quantity: 1, Unit: "can"},
+	}})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	toast, err := mem.CreateMeal(models.Meal{MealName: "Toast", RelativeEffort: 1, Ingredients: []models.Ingredient{}})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	r := chi.NewRouter()
+	r.Get("/api/meals/{mealId}", New(mem).GetMealHandler)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/meals/%d?servings=6", chili.ID), nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var scaled models.Meal
+	if err := json.NewDecoder(rr.Body).Decode(&scaled); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if scaled.Servings != 6 || scaled.Ingredients[0].Quantity != 1.5 || scaled.Ingredients[1].Quantity != 1.5 {
+		t.Errorf("unexpected scaled meal: %+v", scaled)
+	}
+	if stored, _ := mem.GetMealsByIDs([]int{chili.ID}); stored[0].Ingredients[0].Quantity != 1 {
+		t.Errorf("scaling must not change the stored meal, got %+v", stored[0].Ingredients)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{fmt.Sprintf("/api/meals/%d", toast.ID), http.StatusOK},
+		{fmt.Sprintf("/api/meals/%d?servings=2", toast.ID), http.StatusUnprocessableEntity},
+		{fmt.Sprintf("/api/meals/%d?servings=0", chili.ID), http.StatusBadRequest},
+		{"/api/meals/abc", http.StatusBadRequest},
+		{"/api/meals/9999", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("GET %s: expected status %d got %d: %s", tt.path, tt.want, rr.Code, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"mealplanner/models"
+)
+
+// currentPantry returns the stored pantry items.
+func (h *Handler) currentPantry() ([]models.PantryItem, error) {
+	return h.Store().ListPantryItems()
+}
+
+// ListPantryHandler handles GET /api/pantry and returns every pantry item.
+func (h *Handler) ListPantryHandler(w http.ResponseWriter, r *http.Request) {
+	items, err := h.currentPantry()
+	if err != nil {
+		http.Error(w, "Error retrieving pantry: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// CreatePantryItemHandler handles POST /api/pantry and adds an item to the pantry.
+func (h *Handler) CreatePantryItemHandler(w http.ResponseWriter, r *http.Request) {
+	var item models.PantryItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := item.Validate(); err != nil {
+		http.Error(w, "Invalid pantry item: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.Store().CreatePantryItem(item)
+	if err != nil {
+		http.Error(w, "Error creating pantry item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// UpdatePantryItemHandler handles PUT /api/pantry/{itemId} and replaces a pantry item.
+func (h *Handler) UpdatePantryItemHandler(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemId"))
+	if err != nil {
+		http.Error(w, "Invalid pantry item ID", http.StatusBadRequest)
+		return
+	}
+	var item models.PantryItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	item.ID = itemID
+	if err := item.Validate(); err != nil {
+		http.Error(w, "Invalid pantry item: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.Store().UpdatePantryItem(item)
+	if errors.Is(err, models.ErrPantryItemNotFound) {
+		http.Error(w, "Pantry item not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating pantry item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeletePantryItemHandler handles DELETE /api/pantry/{itemId} and removes a pantry item.
+func (h *Handler) DeletePantryItemHandler(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemId"))
+	if err != nil {
+		http.Error(w, "Invalid pantry item ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.Store().DeletePantryItem(itemID)
+	if errors.Is(err, models.ErrPantryItemNotFound) {
+		http.Error(w, "Pantry item not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting pantry item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"mealplanner/dummy"
+	"mealplanner/models"
+)
+
+func TestPantryHandlers_Dummy(t *testing.T) {
+	api := New(dummy.NewStore())
+
+	req, _ := http.NewRequest("POST", "/api/pantry", bytes.NewBufferString(`{"name":"Kosher Salt","staple":true}`))
+	rr := httptest.NewRecorder()
+	api.CreatePantryItemHandler(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created models.PantryItem
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.Name != "kosher salt" || !created.Staple {
+		t.Errorf("unexpected created item: %+v", created)
+	}
+
+	req, _ = http.NewRequest("POST", "/api/pantry", bytes.NewBufferString(`{"name":"","quantity":1}`))
+	rr = httptest.NewRecorder()
+	api.CreatePantryItemHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a missing name, got %d", rr.Code)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("itemId", "9999")
+	req, _ = http.NewRequest("PUT", "/api/pantry/9999", bytes.NewBufferString(`{"name":"salt"}`))
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr = httptest.NewRecorder()
+	api.UpdatePantryItemHandler(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown item, got %d", rr.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/pantry", nil)
+	rr = httptest.NewRecorder()
+	api.ListPantryHandler(rr, req)
+	var items []models.PantryItem
+	if err := json.NewDecoder(rr.Body).Decode(&items); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(items) != 1 {
+		t.Errorf("expected 1 pantry item, got %d", len(items))
+	}
+}
+
+func TestGetShoppingList_MarksPantryStaples(t *testing.T) {
+	mem := dummy.NewStore()
+	if err := mem.Load("../Meal_db.csv"); err != nil {
+		t.Fatalf("failed loading dummy data: %v", err)
+	}
+	api := New(mem)
+	_, err := mem.CreatePantryItem(models.PantryItem{Name: "salt", Staple: true})
+	if err != nil {
+		t.Fatalf("failed creating pantry item: %v", err)
+	}
+
+	all, _ := mem.GetAllMeals()
+	var ids []int
+	for _, m := range all {
+		ids = append(ids, m.ID)
+	}
+	body, _ := json.Marshal(map[string][]int{"plan": ids})
+	req, _ := http.NewRequest("POST", "/api/shoppinglist", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	api.GetShoppingList(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var items []models.ShoppingItem
+	if err := json.NewDecoder(rr.Body).Decode(&items); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	sawStaple := false
+	for _, item := range items {
+		if item.Staple {
+			sawStaple = true
+		} else if sawStaple {
+			t.Fatalf("expected staples after everything else, found %q after a staple", item.Name)
+		}
+	}
+	if !sawStaple {
+		t.Errorf("expected salt to be marked as a staple")
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"mealplanner/models"
+)
+
+// currentPlanRules returns the stored planning rules.
+func (h *Handler) currentPlanRules() (models.PlanRules, error) {
+	return h.Store().GetPlanRules()
+}
+
+// GetPlanRulesHandler handles GET /api/planning-rules and returns the current planning rules.
+func (h *Handler) GetPlanRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.currentPlanRules()
+	if err != nil {
+		http.Error(w, "Error retrieving planning rules: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// UpdatePlanRulesHandler handles PUT /api/planning-rules and replaces the planning rules.
+func (h *Handler) UpdatePlanRulesHandler(w http.ResponseWriter, r *http.Request) {
+	var rules models.PlanRules
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := rules.Validate(); err != nil {
+		http.Error(w, "Invalid planning rules: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store().SavePlanRules(rules); err != nil {
+		http.Error(w, "Error saving planning rules: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
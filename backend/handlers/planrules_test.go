@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mealplanner/dummy"
+	"mealplanner/models"
+)
+
+func TestPlanRulesHandlers(t *testing.T) {
+	api := New(dummy.NewStore())
+
+	rules := models.DefaultPlanRules()
+	rules.RepeatCooldownDays = 10
+	body, _ := json.Marshal(rules)
+	req, err := http.NewRequest("PUT", "/api/planning-rules", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	api.UpdatePlanRulesHandler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/api/planning-rules", nil)
+	rr = httptest.NewRecorder()
+	api.GetPlanRulesHandler(rr, req)
+	var got models.PlanRules
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.RepeatCooldownDays != 10 {
+		t.Errorf("expected cooldown 10, got %d", got.RepeatCooldownDays)
+	}
+
+	req, _ = http.NewRequest("PUT", "/api/planning-rules", bytes.NewBufferString(`{"days":[{"day":"Caturday"}]}`))
+	rr = httptest.NewRecorder()
+	api.UpdatePlanRulesHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid rules, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"mealplanner/ingredient"
+	"mealplanner/models"
+	"mealplanner/recipe"
+)
+
+// maxRecipePageSize caps how much of a recipe page is read when importing.
+const maxRecipePageSize = 5 << 20
+
+// recipeClient fetches recipe pages for import.
+var recipeClient = &http.Client{Timeout: 15 * time.Second}
+
+// RecipeImport is a meal drafted from a recipe page, returned for review before it is
+// saved with POST /api/meals.
+type RecipeImport struct {
+	Meal models.Meal `json:"meal"`
+	// Ingredients are the parsed ingredient lines, including the notes that don't make
+	// it into the meal's ingredients.
+	Ingredients  []ingredient.Parsed `json:"ingredients"`
+	TotalMinutes int                 `json:"totalMinutes,omitempty"`
+	Yield        string              `json:"yield,omitempty"`
+	// Warnings list what the page was missing and needs filling in by hand.
+	Warnings []string `json:"warnings"`
+}
+
+// ImportRecipeHandler handles POST /api/meals/import. It reads the schema.org Recipe
+// from a page's JSON-LD and drafts a meal from it. The payload holds the page's url,
+// and optionally its html; when html is given the page isn't fetched, which allows
+// importing pages saved offline or behind a login. Nothing is stored, so it doesn't
+// need a store.
+func ImportRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		URL  string `json:"url"`
+		HTML string `json:"html"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	page := []byte(payload.HTML)
+	if payload.HTML == "" {
+		if payload.URL == "" {
+			http.Error(w, "Provide the recipe's url or html", http.StatusBadRequest)
+			return
+		}
+		var err error
+		page, err = fetchRecipePage(r, payload.URL)
+		if err != nil {
+			var bad *badRecipeURLError
+			if errors.As(err, &bad) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Error fetching recipe: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	found, err := recipe.Extract(page)
+	if err != nil {
+		http.Error(w, "Error importing recipe: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draftMeal(found, payload.URL))
+}
+
+// badRecipeURLError is returned for URLs that can't be fetched at all.
+type badRecipeURLError struct {
+	url string
+}
+
+func (e *badRecipeURLError) Error() string {
+	return fmt.Sprintf("Invalid recipe url %q, expected an http or https address", e.url)
+}
+
+// fetchRecipePage downloads a recipe page, giving up when the request is cancelled.
+func fetchRecipePage(r *http.Request, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, &badRecipeURLError{url: rawURL}
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "mealplanner-recipe-import/1.0")
+	resp, err := recipeClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", u.Host, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxRecipePageSize))
+}
+
+// draftMeal turns an extracted recipe into a meal with parsed ingredients and numbered steps.
+func draftMeal(found *recipe.Recipe, pageURL string) RecipeImport {
+	out := RecipeImport{
+		Meal: models.Meal{
+			MealName:    found.Name,
+			URL:         pageURL,
+			Ingredients: []models.Ingredient{},
+			Steps:       []models.Step{},
+		},
+		Ingredients:  []ingredient.Parsed{},
+		TotalMinutes: int(found.TotalTime.Minutes()),
+		Yield:        found.Yield,
+		Warnings:     []string{},
+	}
+	if out.Meal.URL == "" {
+		out.Meal.URL = found.URL
+	}
+	if out.Meal.MealName == "" {
+		out.Warnings = append(out.Warnings, "The recipe has no name")
+	}
+
+	for _, line := range found.Ingredients {
+		parsed := ingredient.Parse(line)
+		out.Ingredients = append(out.Ingredients, parsed)
+		out.Meal.Ingredients = append(out.Meal.Ingredients, models.Ingredient{
+			Name:     parsed.Name,
+			Quantity: parsed.ShoppingQuantity(),
+			Unit:     parsed.Unit,
+		})
+	}
+	if len(out.Meal.Ingredients) == 0 {
+		out.Warnings = append(out.Warnings, "The recipe lists no ingredients")
+	}
+
+	for i, instruction := range found.Instructions {
+		out.Meal.Steps = append(out.Meal.Steps, models.Step{StepNumber: i + 1, Instruction: instruction})
+	}
+	if len(out.Meal.Steps) == 0 {
+		out.Warnings = append(out.Warnings, "The recipe has no instructions")
+	}
+
+	if found.TotalTime > 0 {
+		out.Meal.RelativeEffort = effortForTime(found.TotalTime)
+	} else {
+		out.Warnings = append(out.Warnings, "The recipe gives no cooking time, so set the effort by hand")
+	}
+	out.Meal.RedMeat = models.IsRedMeat(found.Name)
+	out.Meal.Servings = servingsFromYield(found.Yield)
+	return out
+}
+
+// yieldNumberRe finds the first whole number in a recipe yield.
+var yieldNumberRe = regexp.MustCompile(`\d+`)
+
+// servingsFromYield reads the servings from a recipe yield such as "4 servings" or
+// "Serves 4-6", taking the first number. It returns 0 when the yield has no number.
+func servingsFromYield(yield string) int {
+	n, err := strconv.Atoi(yieldNumberRe.FindString(yield))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// effortForTime suggests a relative effort from a recipe's total time, on the scale the
+// default planning rules use: up to 2 for a quick weeknight, 3-5 for a normal evening
+// and 6 or more for a big weekend cook.
+func effortForTime(d time.Duration) int {
+	minutes := d.Minutes()
+	switch {
+	case minutes <= 20:
+		return 1
+	case minutes <= 30:
+		return 2
+	case minutes <= 45:
+		return 3
+	case minutes <= 60:
+		return 4
+	case minutes <= 90:
+		return 5
+	case minutes <= 150:
+		return 6
+	default:
+		return 7
+	}
+}
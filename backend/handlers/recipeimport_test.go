@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const recipePage = `<html><head><script type="application/ld+json">
+{"@context":"https://schema.org","@type":"Recipe","name":"Weeknight Beef Chili",
+ "totalTime":"PT40M","recipeYield":"6 servings",
+ "recipeIngredient":["2 pounds ground beef","1 (14-ounce) can kidney beans, drained","2 tablespoons chili powder"],
+ "recipeInstructions":[{"@type":"HowToStep","text":"Brown the beef."},{"@type":"HowToStep","text":"Add everything else and simmer."}]}
+</script></head></html>`
+
+func decodeRecipeImport(t *testing.T, rr *httptest.ResponseRecorder) RecipeImport {
+	t.Helper()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var got RecipeImport
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return got
+}
+
+func TestImportRecipeHandler_HTML(t *testing.T) {
+	body, _ := json.Marshal(map[string]string{"url": "https://example.com/chili", "html": recipePage})
+	req, _ := http.NewRequest("POST", "/api/meals/import", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	ImportRecipeHandler(rr, req)
+
+	got := decodeRecipeImport(t, rr)
+	meal := got.Meal
+	if meal.MealName != "Weeknight Beef Chili" || meal.URL != "https://example.com/chili" {
+		t.Errorf("unexpected meal: %+v", meal)
+	}
+	if meal.RelativeEffort != 3 || !meal.RedMeat || meal.Servings != 6 {
+		t.Errorf("expected effort 3, red meat and 6 servings, got %d, %v and %d", meal.RelativeEffort, meal.RedMeat, meal.Servings)
+	}
+	if len(meal.Ingredients) != 3 || meal.Ingredients[0].Name != "ground beef" || meal.Ingredients[0].Unit != "lb" {
+		t.Errorf("unexpected ingredients: %+v", meal.Ingredients)
+	}
+	if got.Ingredients[1].Notes != "drained" {
+		t.Errorf("expected the parsed notes to be returned, got %+v", got.Ingredients[1])
+	}
+	if len(meal.Steps) != 2 || meal.Steps[1].StepNumber != 2 {
+		t.Errorf("unexpected steps: %+v", meal.Steps)
+	}
+	if got.TotalMinutes != 40 || got.Yield != "6 servings" || len(got.Warnings) != 0 {
+		t.Errorf("unexpected time %d, yield %q or warnings %v", got.TotalMinutes, got.Yield, got.Warnings)
+	}
+}
+
+func TestImportRecipeHandler_FetchesURL(t *testing.T) {
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chili" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(recipePage))
+	}))
+	defer page.Close()
+
+	body, _ := json.Marshal(map[string]string{"url": page.URL + "/chili"})
+	req, _ := http.NewRequest("POST", "/api/meals/import", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	ImportRecipeHandler(rr, req)
+	if got := decodeRecipeImport(t, rr); got.Meal.URL != page.URL+"/chili" {
+		t.Errorf("expected the page URL on the meal, got %q", got.Meal.URL)
+	}
+
+	body, _ = json.Marshal(map[string]string{"url": page.URL + "/missing"})
+	req, _ = http.NewRequest("POST", "/api/meals/import", bytes.NewBuffer(body))
+	rr = httptest.NewRecorder()
+	ImportRecipeHandler(rr, req)
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("expected status 502 for a page that can't be fetched, got %d", rr.Code)
+	}
+}
+
+func TestImportRecipeHandler_BadRequests(t *testing.T) {
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{}`, http.StatusBadRequest},
+		{`{"url":"ftp://example.com/chili"}`, http.StatusBadRequest},
+		{`{"html":"<html><body>No recipe here</body></html>"}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/api/meals/import", bytes.NewBufferString(tt.body))
+		rr := httptest.NewRecorder()
+		ImportRecipeHandler(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.body, tt.want, rr.Code)
+		}
+	}
+}
@@ -14,11 +14,7 @@ import (
 )
 
 // GetStepsHandler handles GET /api/meals/{mealId}/steps and returns all steps for a meal.
-func GetStepsHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) GetStepsHandler(w http.ResponseWriter, r *http.Request) {
 	mealIDStr := chi.URLParam(r, "mealId")
 	if mealIDStr == "" {
 		http.Error(w, "Missing meal ID", http.StatusBadRequest)
@@ -31,7 +27,7 @@ func GetStepsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	steps, err := models.GetStepsForMeal(DB, mealID)
+	steps, err := h.Store().GetStepsForMeal(mealID)
 	if err != nil {
 		http.Error(w, "Error retrieving steps: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -42,11 +38,7 @@ func GetStepsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // AddStepHandler handles POST /api/meals/{mealId}/steps and adds a new step to a meal.
-func AddStepHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) AddStepHandler(w http.ResponseWriter, r *http.Request) {
 	mealIDStr := chi.URLParam(r, "mealId")
 	if mealIDStr == "" {
 		http.Error(w, "Missing meal ID", http.StatusBadRequest)
@@ -68,7 +60,7 @@ func AddStepHandler(w http.ResponseWriter, r *http.Request) {
 	// Ensure the step is associated with the correct meal
 	step.MealID = mealID
 
-	createdStep, err := models.AddStepToMeal(DB, step)
+	createdStep, err := h.Store().AddStepToMeal(step)
 	if err != nil {
 		http.Error(w, "Error adding step: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -80,11 +72,7 @@ func AddStepHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // AddBulkStepsHandler handles POST /api/meals/{mealId}/steps/bulk and adds multiple steps to a meal from text.
-func AddBulkStepsHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) AddBulkStepsHandler(w http.ResponseWriter, r *http.Request) {
 	mealIDStr := chi.URLParam(r, "mealId")
 	if mealIDStr == "" {
 		http.Error(w, "Missing meal ID", http.StatusBadRequest)
@@ -157,7 +145,7 @@ func AddBulkStepsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	steps, err := models.AddMultipleStepsToMeal(DB, mealID, nonEmptyInstructions)
+	steps, err := h.Store().AddMultipleStepsToMeal(mealID, nonEmptyInstructions)
 	if err != nil {
 		http.Error(w, "Error adding steps: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -261,11 +249,7 @@ func parseStepsFromText(text string) []string {
 }
 
 // UpdateStepHandler handles PUT /api/meals/{mealId}/steps/{stepId} and updates a step.
-func UpdateStepHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) UpdateStepHandler(w http.ResponseWriter, r *http.Request) {
 	mealIDStr := chi.URLParam(r, "mealId")
 	stepIDStr := chi.URLParam(r, "stepId")
 
@@ -296,7 +280,7 @@ func UpdateStepHandler(w http.ResponseWriter, r *http.Request) {
 	step.ID = stepID
 	step.MealID = mealID
 
-	if err := models.UpdateStep(DB, step); err != nil {
+	if err := h.Store().UpdateStep(step); err != nil {
 		http.Error(w, "Error updating step: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -306,11 +290,7 @@ func UpdateStepHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // DeleteStepHandler handles DELETE /api/meals/{mealId}/steps/{stepId} and deletes a step.
-func DeleteStepHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) DeleteStepHandler(w http.ResponseWriter, r *http.Request) {
 	mealIDStr := chi.URLParam(r, "mealId")
 	stepIDStr := chi.URLParam(r, "stepId")
 
@@ -331,7 +311,7 @@ func DeleteStepHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := models.DeleteStep(DB, stepID, mealID); err != nil {
+	if err := h.Store().DeleteStep(stepID, mealID); err != nil {
 		http.Error(w, "Error deleting step: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -341,11 +321,7 @@ func DeleteStepHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // ReorderStepsHandler handles PUT /api/meals/{mealId}/steps/reorder and reorders steps.
-func ReorderStepsHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) ReorderStepsHandler(w http.ResponseWriter, r *http.Request) {
 	mealIDStr := chi.URLParam(r, "mealId")
 	if mealIDStr == "" {
 		http.Error(w, "Missing meal ID", http.StatusBadRequest)
@@ -372,7 +348,7 @@ func ReorderStepsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := models.ReorderSteps(DB, mealID, payload.StepIDs); err != nil {
+	if err := h.Store().ReorderSteps(mealID, payload.StepIDs); err != nil {
 		http.Error(w, "Error reordering steps: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -382,11 +358,7 @@ func ReorderStepsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // DeleteAllStepsHandler handles DELETE /api/meals/{mealId}/steps and deletes all steps for a meal.
-func DeleteAllStepsHandler(w http.ResponseWriter, r *http.Request) {
-    if UseDummy {
-		http.Error(w, "Not implemented in dummy mode", http.StatusNotImplemented)
-		return
-	}
+func (h *Handler) DeleteAllStepsHandler(w http.ResponseWriter, r *http.Request) {
 	mealIDStr := chi.URLParam(r, "mealId")
 	if mealIDStr == "" {
 		http.Error(w, "Missing meal ID", http.StatusBadRequest)
@@ -399,7 +371,7 @@ func DeleteAllStepsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := models.DeleteAllStepsForMeal(DB, mealID); err != nil {
+	if err := h.Store().DeleteAllStepsForMeal(mealID); err != nil {
 		http.Error(w, "Error deleting steps: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
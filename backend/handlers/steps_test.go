@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"mealplanner/models"
+	"mealplanner/store"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -15,7 +16,7 @@ import (
 	_ "github.com/mattn/go-sqlite3" // Import SQLite driver
 )
 
-func setupStepHandlerTest(t *testing.T) *sql.DB {
+func setupStepHandlerTest(t *testing.T) (*sql.DB, *Handler) {
 	// Create an in-memory SQLite database for testing
 	db, err := sql.Open("sqlite3", ":memory:")
 	if err != nil {
@@ -52,9 +53,6 @@ func setupStepHandlerTest(t *testing.T) *sql.DB {
 		t.Fatalf("Error creating recipe_steps table: %v", err)
 	}
 
-	// Set the package-level DB variable
-	DB = db
-
 	// Insert a test meal
 	_, err = db.Exec(`
 		INSERT INTO meals (id, meal_name, relative_effort, red_meat)
@@ -64,11 +62,11 @@ func setupStepHandlerTest(t *testing.T) *sql.DB {
 		t.Fatalf("Error inserting test meal: %v", err)
 	}
 
-	return db
+	return db, New(store.NewPostgres(db))
 }
 
 func TestGetStepsHandler(t *testing.T) {
-	db := setupStepHandlerTest(t)
+	db, api := setupStepHandlerTest(t)
 	defer db.Close()
 
 	// Add some steps
@@ -88,7 +86,7 @@ func TestGetStepsHandler(t *testing.T) {
 
 	// Create a router with the handler
 	r := chi.NewRouter()
-	r.Get("/api/meals/{mealId}/steps", GetStepsHandler)
+	r.Get("/api/meals/{mealId}/steps", api.GetStepsHandler)
 
 	// Create a response recorder
 	rr := httptest.NewRecorder()
@@ -122,7 +120,7 @@ func TestGetStepsHandler(t *testing.T) {
 }
 
 func TestAddStepHandler(t *testing.T) {
-	db := setupStepHandlerTest(t)
+	db, api := setupStepHandlerTest(t)
 	defer db.Close()
 
 	// Create a step to add
@@ -144,7 +142,7 @@ func TestAddStepHandler(t *testing.T) {
 
 	// Create a router with the handler
 	r := chi.NewRouter()
-	r.Post("/api/meals/{mealId}/steps", AddStepHandler)
+	r.Post("/api/meals/{mealId}/steps", api.AddStepHandler)
 
 	// Create a response recorder
 	rr := httptest.NewRecorder()
@@ -183,7 +181,7 @@ func TestAddStepHandler(t *testing.T) {
 }
 
 func TestAddBulkStepsHandler(t *testing.T) {
-	db := setupStepHandlerTest(t)
+	db, api := setupStepHandlerTest(t)
 	defer db.Close()
 
 	// Test cases for different formats
@@ -248,7 +246,7 @@ func TestAddBulkStepsHandler(t *testing.T) {
 
 			// Create a router with the handler
 			r := chi.NewRouter()
-			r.Post("/api/meals/{mealId}/steps/bulk", AddBulkStepsHandler)
+			r.Post("/api/meals/{mealId}/steps/bulk", api.AddBulkStepsHandler)
 
 			// Create a response recorder
 			rr := httptest.NewRecorder()
@@ -286,7 +284,7 @@ func TestAddBulkStepsHandler(t *testing.T) {
 	req.Header.Set("Content-Type", "text/plain")
 	rr := httptest.NewRecorder()
 	r := chi.NewRouter()
-	r.Post("/api/meals/{mealId}/steps/bulk", AddBulkStepsHandler)
+	r.Post("/api/meals/{mealId}/steps/bulk", api.AddBulkStepsHandler)
 	r.ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusBadRequest {
@@ -295,7 +293,7 @@ func TestAddBulkStepsHandler(t *testing.T) {
 }
 
 func TestUpdateStepHandler(t *testing.T) {
-	db := setupStepHandlerTest(t)
+	db, api := setupStepHandlerTest(t)
 	defer db.Close()
 
 	// Add a step
@@ -329,7 +327,7 @@ func TestUpdateStepHandler(t *testing.T) {
 
 	// Create a router with the handler
 	r := chi.NewRouter()
-	r.Put("/api/meals/{mealId}/steps/{stepId}", UpdateStepHandler)
+	r.Put("/api/meals/{mealId}/steps/{stepId}", api.UpdateStepHandler)
 
 	// Create a response recorder
 	rr := httptest.NewRecorder()
@@ -355,7 +353,7 @@ func TestUpdateStepHandler(t *testing.T) {
 }
 
 func TestDeleteStepHandler(t *testing.T) {
-	db := setupStepHandlerTest(t)
+	db, api := setupStepHandlerTest(t)
 	defer db.Close()
 
 	// Add a step
@@ -377,7 +375,7 @@ func TestDeleteStepHandler(t *testing.T) {
 
 	// Create a router with the handler
 	r := chi.NewRouter()
-	r.Delete("/api/meals/{mealId}/steps/{stepId}", DeleteStepHandler)
+	r.Delete("/api/meals/{mealId}/steps/{stepId}", api.DeleteStepHandler)
 
 	// Create a response recorder
 	rr := httptest.NewRecorder()
@@ -403,7 +401,7 @@ func TestDeleteStepHandler(t *testing.T) {
 }
 
 func TestReorderStepsHandler(t *testing.T) {
-	db := setupStepHandlerTest(t)
+	db, api := setupStepHandlerTest(t)
 	defer db.Close()
 
 	// Add multiple steps
@@ -439,7 +437,7 @@ func TestReorderStepsHandler(t *testing.T) {
 
 	// Create a router with the handler
 	r := chi.NewRouter()
-	r.Put("/api/meals/{mealId}/steps/reorder", ReorderStepsHandler)
+	r.Put("/api/meals/{mealId}/steps/reorder", api.ReorderStepsHandler)
 
 	// Create a response recorder
 	rr := httptest.NewRecorder()
@@ -484,7 +482,7 @@ func TestReorderStepsHandler(t *testing.T) {
 }
 
 func TestDeleteAllStepsHandler(t *testing.T) {
-	db := setupStepHandlerTest(t)
+	db, api := setupStepHandlerTest(t)
 	defer db.Close()
 
 	// Add multiple steps
@@ -506,7 +504,7 @@ func TestDeleteAllStepsHandler(t *testing.T) {
 
 	// Create a router with the handler
 	r := chi.NewRouter()
-	r.Delete("/api/meals/{mealId}/steps", DeleteAllStepsHandler)
+	r.Delete("/api/meals/{mealId}/steps", api.DeleteAllStepsHandler)
 
 	// Create a response recorder
 	rr := httptest.NewRecorder()
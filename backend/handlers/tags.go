@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"mealplanner/models"
+)
+
+// tagName returns the name of the stored tag with the given ID, or ErrTagNotFound.
+func (h *Handler) tagName(id int) (string, error) {
+	tags, err := h.Store().ListTags()
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tags {
+		if t.ID == id {
+			return t.Name, nil
+		}
+	}
+	return "", models.ErrTagNotFound
+}
+
+// retagPlanRules moves the saved tag caps and minimums for a renamed tag to its new name,
+// or drops them when to is empty because the tag was deleted.
+func (h *Handler) retagPlanRules(from, to string) error {
+	rules, err := h.currentPlanRules()
+	if err != nil {
+		return err
+	}
+	_, capped := rules.TagCaps[from]
+	_, required := rules.TagMinimums[from]
+	if !capped && !required {
+		return nil
+	}
+	rules.TagCaps = retag(rules.TagCaps, from, to)
+	rules.TagMinimums = retag(rules.TagMinimums, from, to)
+	return h.Store().SavePlanRules(rules)
+}
+
+// retag returns a copy of a per-tag rule map with from renamed to, or dropped when to is
+// empty. The stored map isn't modified.
+func retag(m map[string]int, from, to string) map[string]int {
+	if len(m) == 0 {
+		return m
+	}
+	out := make(map[string]int, len(m))
+	for tag, n := range m {
+		if tag == from {
+			if to == "" {
+				continue
+			}
+			tag = to
+		}
+		out[tag] = n
+	}
+	return out
+}
+
+// ListTagsHandler handles GET /api/tags and returns every tag with its meal count.
+func (h *Handler) ListTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.Store().ListTags()
+	if err != nil {
+		http.Error(w, "Error retrieving tags: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// CreateTagHandler handles POST /api/tags and adds a tag.
+func (h *Handler) CreateTagHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if models.NormalizeTagName(payload.Name) == "" {
+		http.Error(w, "Invalid tag: tag name is required", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.Store().CreateTag(payload.Name)
+	if errors.Is(err, models.ErrTagExists) {
+		http.Error(w, "Tag already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error creating tag: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// RenameTagHandler handles PUT /api/tags/{tagId} and renames a tag on every meal. Saved
+// tag caps and minimums follow the new name.
+func (h *Handler) RenameTagHandler(w http.ResponseWriter, r *http.Request) {
+	tagID, err := strconv.Atoi(chi.URLParam(r, "tagId"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if models.NormalizeTagName(payload.Name) == "" {
+		http.Error(w, "Invalid tag: tag name is required", http.StatusBadRequest)
+		return
+	}
+
+	old, err := h.tagName(tagID)
+	if err == nil {
+		var renamed *models.Tag
+		renamed, err = h.Store().RenameTag(tagID, payload.Name)
+		if err == nil {
+			if err := h.retagPlanRules(old, renamed.Name); err != nil {
+				http.Error(w, "Error updating planning rules: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(renamed)
+			return
+		}
+	}
+	switch {
+	case errors.Is(err, models.ErrTagNotFound):
+		http.Error(w, "Tag not found", http.StatusNotFound)
+	case errors.Is(err, models.ErrTagExists):
+		http.Error(w, "Tag already exists", http.StatusConflict)
+	default:
+		http.Error(w, "Error renaming tag: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// DeleteTagHandler handles DELETE /api/tags/{tagId}, removing the tag from every meal and
+// from the saved planning rules.
+func (h *Handler) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	tagID, err := strconv.Atoi(chi.URLParam(r, "tagId"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	name, err := h.tagName(tagID)
+	if err == nil {
+		err = h.Store().DeleteTag(tagID)
+	}
+	if errors.Is(err, models.ErrTagNotFound) {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting tag: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.retagPlanRules(name, ""); err != nil {
+		http.Error(w, "Error updating planning rules: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// SetMealTagsHandler handles PUT /api/meals/{mealId}/tags and replaces a meal's tags,
+// creating any that don't exist yet.
+func (h *Handler) SetMealTagsHandler(w http.ResponseWriter, r *http.Request) {
+	mealID, err := strconv.Atoi(chi.URLParam(r, "mealId"))
+	if err != nil {
+		http.Error(w, "Invalid meal ID", http.StatusBadRequest)
+		return
+	}
+	var payload struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := models.NormalizeTagNames(payload.Tags); err != nil {
+		http.Error(w, "Invalid tags: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tags, err := h.Store().SetMealTags(mealID, payload.Tags)
+	if errors.Is(err, models.ErrMealNotFound) {
+		http.Error(w, "Meal not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating meal tags: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"tags": tags})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"mealplanner/dummy"
+	"mealplanner/models"
+)
+
+// withURLParam attaches a chi URL parameter to a request.
+func withURLParam(req *http.Request, key, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestTagHandlers_Dummy(t *testing.T) {
+	store := dummy.NewStore()
+	api := New(store)
+	tacos, _ := store.CreateMeal(models.Meal{MealName: "Tacos", RelativeEffort: 2, Ingredients: []models.Ingredient{}})
+	store.CreateMeal(models.Meal{MealName: "Soup", RelativeEffort: 2, Tags: []string{"vegetarian"}, Ingredients: []models.Ingredient{}})
+
+	req, _ := http.NewRequest("POST", "/api/tags", bytes.NewBufferString(`{"name":"Kid Friendly"}`))
+	rr := httptest.NewRecorder()
+	api.CreateTagHandler(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created models.Tag
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.Name != "kid-friendly" {
+		t.Errorf("expected a normalized name, got %q", created.Name)
+	}
+
+	req, _ = http.NewRequest("POST", "/api/tags", bytes.NewBufferString(`{"name":"kid friendly"}`))
+	rr = httptest.NewRecorder()
+	api.CreateTagHandler(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for a duplicate tag, got %d", rr.Code)
+	}
+
+	req, _ = http.NewRequest("PUT", "/api/meals/9999/tags", bytes.NewBufferString(`{"tags":["mexican"]}`))
+	rr = httptest.NewRecorder()
+	api.SetMealTagsHandler(rr, withURLParam(req, "mealId", "9999"))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown meal, got %d", rr.Code)
+	}
+
+	req, _ = http.NewRequest("PUT", "/api/meals/1/tags", bytes.NewBufferString(`{"tags":["Mexican","kid-friendly"]}`))
+	rr = httptest.NewRecorder()
+	api.SetMealTagsHandler(rr, withURLParam(req, "mealId", "1"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/api/meals?tag=Mexican&tag=kid-friendly", nil)
+	rr = httptest.NewRecorder()
+	api.GetAllMealsHandler(rr, req)
+	var meals []models.Meal
+	if err := json.NewDecoder(rr.Body).Decode(&meals); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(meals) != 1 || meals[0].ID != tacos.ID {
+		t.Errorf("expected only tacos to carry both tags, got %+v", meals)
+	}
+
+	// Renaming and deleting a tag carries the saved rules along.
+	rules, _ := store.GetPlanRules()
+	rules.TagCaps = map[string]int{"mexican": 2}
+	rules.TagMinimums = map[string]int{"vegetarian": 1}
+	if err := store.SavePlanRules(rules); err != nil {
+		t.Fatalf("SavePlanRules: %v", err)
+	}
+	tags, _ := store.ListTags()
+	ids := map[string]string{}
+	for _, tag := range tags {
+		ids[tag.Name] = strconv.Itoa(tag.ID)
+	}
+
+	req, _ = http.NewRequest("PUT", "/api/tags/"+ids["mexican"], bytes.NewBufferString(`{"name":"Tex Mex"}`))
+	rr = httptest.NewRecorder()
+	api.RenameTagHandler(rr, withURLParam(req, "tagId", ids["mexican"]))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	req, _ = http.NewRequest("PUT", "/api/tags/"+ids["vegetarian"], bytes.NewBufferString(`{"name":"tex-mex"}`))
+	rr = httptest.NewRecorder()
+	api.RenameTagHandler(rr, withURLParam(req, "tagId", ids["vegetarian"]))
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status 409 renaming onto another tag, got %d", rr.Code)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/api/tags/"+ids["vegetarian"], nil)
+	rr = httptest.NewRecorder()
+	api.DeleteTagHandler(rr, withURLParam(req, "tagId", ids["vegetarian"]))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+	req, _ = http.NewRequest("DELETE", "/api/tags/"+ids["vegetarian"], nil)
+	rr = httptest.NewRecorder()
+	api.DeleteTagHandler(rr, withURLParam(req, "tagId", ids["vegetarian"]))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 deleting twice, got %d", rr.Code)
+	}
+
+	rules, _ = store.GetPlanRules()
+	if rules.TagCaps["tex-mex"] != 2 || len(rules.TagCaps) != 1 || len(rules.TagMinimums) != 0 {
+		t.Errorf("expected the rules to follow the rename and delete, got %+v", rules)
+	}
+}
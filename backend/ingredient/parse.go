@@ -0,0 +1,345 @@
+package ingredient
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"mealplanner/units"
+)
+
+// Parsed is a recipe ingredient line split into its parts. For
+// "2 (14-ounce) cans kidney beans, drained and rinsed" it holds quantity 2, unit "can",
+// size "14-ounce", name "kidney beans" and notes "drained and rinsed".
+type Parsed struct {
+	Raw      string  `json:"raw"`
+	Quantity float64 `json:"quantity"`
+	// QuantityMax is the upper bound of a range such as "2 to 3"; zero when there's no range.
+	QuantityMax float64 `json:"quantityMax,omitempty"`
+	Unit        string  `json:"unit"`
+	// Size is a parenthetical size ("14-ounce") or size word ("large") describing the unit.
+	Size     string `json:"size,omitempty"`
+	Name     string `json:"name"`
+	Notes    string `json:"notes,omitempty"`
+	Optional bool   `json:"optional"`
+}
+
+// ShoppingQuantity is the amount to buy: the top of a range, otherwise the quantity.
+func (p Parsed) ShoppingQuantity() float64 {
+	if p.QuantityMax > p.Quantity {
+		return p.QuantityMax
+	}
+	return p.Quantity
+}
+
+// unicodeFractions maps vulgar fraction characters to plain fractions.
+var unicodeFractions = map[rune]string{
+	'¼': "1/4", '½': "1/2", '¾': "3/4",
+	'⅓': "1/3", '⅔': "2/3",
+	'⅕': "1/5", '⅖': "2/5", '⅗': "3/5", '⅘': "4/5",
+	'⅙': "1/6", '⅚': "5/6",
+	'⅛': "1/8", '⅜': "3/8", '⅝': "5/8", '⅞': "7/8",
+}
+
+// sizeWords describe the size of a counted item rather than the ingredient itself.
+var sizeWords = map[string]bool{
+	"small": true, "medium": true, "large": true, "jumbo": true, "extra-large": true, "big": true,
+}
+
+// prepWords are leading words that describe preparation rather than the ingredient.
+var prepWords = map[string]bool{
+	"finely": true, "coarsely": true, "roughly": true, "thinly": true, "freshly": true, "very": true,
+	"chopped": true, "minced": true, "diced": true, "sliced": true, "grated": true, "shredded": true,
+	"crushed": true, "smashed": true, "peeled": true, "trimmed": true, "halved": true, "quartered": true,
+	"cubed": true, "torn": true, "packed": true, "sifted": true, "softened": true, "melted": true,
+}
+
+// describingWords are single words that recipes separate from the rest of the name with a comma.
+var describingWords = map[string]bool{
+	"boneless": true, "skinless": true, "bone-in": true, "skin-on": true, "cooked": true,
+	"uncooked": true, "peeled": true, "torn": true, "seeded": true, "pitted": true,
+}
+
+var (
+	numberRe   = regexp.MustCompile(`^(\d+(?:\.\d+)?|\.\d+|\d+/\d+)$`)
+	spaceRe    = regexp.MustCompile(`\s+`)
+	optionalRe = regexp.MustCompile(`(?i)\(\s*optional\s*\)|,?\s*\boptional\b`)
+	// emptyParenRe matches what's left of "(optional, for serving)" once "optional" is removed.
+	emptyParenRe = regexp.MustCompile(`\(\s*,\s*`)
+)
+
+// Parse splits an ingredient line into quantity, unit, name, notes and flags. Lines
+// without a quantity ("Kosher salt") come back with only a name.
+func Parse(line string) Parsed {
+	p := Parsed{Raw: line}
+	text := normalize(line)
+
+	if optionalRe.MatchString(text) {
+		p.Optional = true
+		text = optionalRe.ReplaceAllString(text, "")
+		text = strings.ReplaceAll(emptyParenRe.ReplaceAllString(text, "("), "()", "")
+	}
+
+	tokens := strings.Fields(text)
+	var n int
+	p.Quantity, p.QuantityMax, n = parseQuantity(tokens)
+	if n == 0 && len(tokens) > 1 && (strings.EqualFold(tokens[0], "a") || strings.EqualFold(tokens[0], "an")) && units.IsUnit(tokens[1]) {
+		// "A pinch of cinnamon"
+		p.Quantity, n = 1, 1
+	}
+	tokens = tokens[n:]
+
+	if n > 0 {
+		tokens = p.parseSize(tokens)
+		tokens = p.parseUnit(tokens)
+		tokens = p.parsePlus(tokens)
+		if len(tokens) > 0 && strings.EqualFold(tokens[0], "of") {
+			tokens = tokens[1:]
+		}
+	}
+
+	p.Name, p.Notes = splitNameAndNotes(strings.Join(tokens, " "))
+	return p
+}
+
+// normalize expands unicode fractions and separates quantities from what follows them.
+func normalize(line string) string {
+	var b strings.Builder
+	runes := []rune(strings.TrimSpace(line))
+	for i, r := range runes {
+		if frac, ok := unicodeFractions[r]; ok {
+			if i > 0 && runes[i-1] >= '0' && runes[i-1] <= '9' {
+				b.WriteByte(' ')
+			}
+			b.WriteString(frac)
+			if i+1 < len(runes) && runes[i+1] != ' ' && runes[i+1] != '-' {
+				b.WriteByte(' ')
+			}
+			continue
+		}
+		// "1(3-inch) piece" and "1 cup/8 ounces" separate the quantity from the rest.
+		if r == '(' && i > 0 && runes[i-1] >= '0' && runes[i-1] <= '9' {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return spaceRe.ReplaceAllString(b.String(), " ")
+}
+
+// parseQuantity reads a leading quantity: a number, a mixed number ("1 1/2") or a range
+// ("2-3", "2 to 3", "1 1/2 - 2"). It returns the quantity, the range maximum and the
+// number of tokens consumed.
+func parseQuantity(tokens []string) (float64, float64, int) {
+	qty, n := parseNumber(tokens)
+	if n == 0 {
+		// A range written without spaces, e.g. "2-3".
+		if len(tokens) > 0 {
+			if lo, hi, ok := strings.Cut(tokens[0], "-"); ok {
+				low, okLo := parseFraction(lo)
+				high, okHi := parseFraction(hi)
+				if okLo && okHi {
+					return low, high, 1
+				}
+			}
+		}
+		return 0, 0, 0
+	}
+
+	rest := tokens[n:]
+	if len(rest) > 1 && (rest[0] == "to" || rest[0] == "-" || rest[0] == "–") {
+		if high, m := parseNumber(rest[1:]); m > 0 {
+			return qty, high, n + 1 + m
+		}
+	}
+	return qty, 0, n
+}
+
+// parseNumber reads a number or mixed number and reports how many tokens it used.
+func parseNumber(tokens []string) (float64, int) {
+	if len(tokens) == 0 {
+		return 0, 0
+	}
+	whole, ok := parseFraction(tokens[0])
+	if !ok {
+		return 0, 0
+	}
+	if len(tokens) > 1 && strings.Contains(tokens[1], "/") && !strings.Contains(tokens[0], "/") {
+		if frac, ok := parseFraction(tokens[1]); ok && frac < 1 {
+			return whole + frac, 2
+		}
+	}
+	return whole, 1
+}
+
+// parseFraction parses "2", "1.5", ".5" or "3/4".
+func parseFraction(s string) (float64, bool) {
+	if !numberRe.MatchString(s) {
+		return 0, false
+	}
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, _ := strconv.ParseFloat(num, 64)
+		d, _ := strconv.ParseFloat(den, 64)
+		if d == 0 {
+			return 0, false
+		}
+		return n / d, true
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// parseSize takes a parenthetical size ("(14-ounce)") or a size word ("large") that
+// follows the quantity.
+func (p *Parsed) parseSize(tokens []string) []string {
+	if len(tokens) == 0 {
+		return tokens
+	}
+	if strings.HasPrefix(tokens[0], "(") {
+		for i, tok := range tokens {
+			if strings.HasSuffix(tok, ")") {
+				p.Size = strings.Trim(strings.Join(tokens[:i+1], " "), "()")
+				return tokens[i+1:]
+			}
+		}
+		return tokens
+	}
+	if sizeWords[strings.ToLower(tokens[0])] {
+		p.Size = strings.ToLower(tokens[0])
+		return tokens[1:]
+	}
+	return tokens
+}
+
+// parseUnit takes the unit following the quantity, if any. Alternate measures written as
+// "1 cup/8 ounces" keep the first unit and drop the second.
+func (p *Parsed) parseUnit(tokens []string) []string {
+	if len(tokens) == 0 {
+		return tokens
+	}
+	word := strings.TrimRight(tokens[0], ",")
+	alternate := ""
+	if before, after, ok := strings.Cut(word, "/"); ok {
+		word, alternate = before, after
+	}
+	if len(tokens) > 1 && units.IsUnit(word+" "+tokens[1]) {
+		// Two word units such as "fl oz".
+		p.Unit = units.Canonical(word + " " + tokens[1])
+		return tokens[2:]
+	}
+	if !units.IsUnit(word) {
+		return tokens
+	}
+	p.Unit = units.Canonical(word)
+	tokens = tokens[1:]
+	if alternate != "" && len(tokens) > 0 && units.IsUnit(tokens[0]) {
+		tokens = tokens[1:]
+	}
+	return tokens
+}
+
+// parsePlus folds "plus 2 tablespoons" into the quantity when the extra amount is in a
+// compatible unit, as in "1/4 cup plus 2 tablespoons mayonnaise".
+func (p *Parsed) parsePlus(tokens []string) []string {
+	if p.Unit == "" || len(tokens) < 3 || !strings.EqualFold(tokens[0], "plus") {
+		return tokens
+	}
+	extra, n := parseNumber(tokens[1:])
+	if n == 0 || len(tokens) < n+2 || !units.IsUnit(tokens[n+1]) {
+		return tokens
+	}
+	converted, ok := units.Convert(extra, tokens[n+1], p.Unit)
+	if !ok {
+		return tokens
+	}
+	p.Quantity = units.Round(p.Quantity + converted)
+	if p.QuantityMax > 0 {
+		p.QuantityMax = units.Round(p.QuantityMax + converted)
+	}
+	return tokens[n+2:]
+}
+
+// splitNameAndNotes separates the ingredient name from preparation notes. Notes are
+// anything after the first comma outside parentheses, parenthetical remarks, and
+// leading preparation words ("finely chopped parsley").
+func splitNameAndNotes(desc string) (string, string) {
+	var notes []string
+	name := desc
+	// A comma after a lone describing word ("boneless, skinless chicken") doesn't end the name.
+	offset := 0
+	for {
+		i := topLevelComma(desc[offset:])
+		if i < 0 {
+			break
+		}
+		i += offset
+		if words := strings.Fields(desc[offset:i]); len(words) == 1 && describingWords[strings.ToLower(words[0])] {
+			offset = i + 1
+			continue
+		}
+		name = desc[:i]
+		if rest := strings.TrimSpace(desc[i+1:]); rest != "" {
+			notes = append(notes, rest)
+		}
+		break
+	}
+	name = strings.ReplaceAll(name, ",", "")
+
+	// Move parenthetical remarks out of the name.
+	for {
+		start := strings.Index(name, "(")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(name[start:], ")")
+		if end < 0 {
+			name = name[:start]
+			break
+		}
+		remark := strings.TrimSpace(name[start+1 : start+end])
+		if remark != "" {
+			notes = append([]string{remark}, notes...)
+		}
+		name = name[:start] + name[start+end+1:]
+	}
+
+	words := strings.Fields(name)
+	var prep []string
+	for len(words) > 1 {
+		word := strings.ToLower(words[0])
+		if prepWords[word] {
+			prep = append(prep, words[0])
+			words = words[1:]
+			continue
+		}
+		// "sliced or cubed avocado"
+		if (word == "or" || word == "and") && len(prep) > 0 && len(words) > 2 && prepWords[strings.ToLower(words[1])] {
+			prep = append(prep, words[0], words[1])
+			words = words[2:]
+			continue
+		}
+		break
+	}
+	if len(prep) > 0 {
+		notes = append([]string{strings.Join(prep, " ")}, notes...)
+	}
+
+	return strings.ToLower(strings.Join(words, " ")), strings.Join(notes, "; ")
+}
+
+// topLevelComma returns the index of the first comma or semicolon outside parentheses, or -1.
+func topLevelComma(s string) int {
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',', ';':
+			if depth <= 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,78 @@
+package ingredient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want Parsed
+	}{
+		{
+			line: "1 jumbo sweet onion, such as Vidalia, finely chopped (3 cups)",
+			want: Parsed{Quantity: 1, Size: "jumbo", Name: "sweet onion", Notes: "such as Vidalia, finely chopped (3 cups)"},
+		},
+		{
+			line: "1½ teaspoons chili powder",
+			want: Parsed{Quantity: 1.5, Unit: "tsp", Name: "chili powder"},
+		},
+		{
+			line: "1 1/2 cups cooked rice",
+			want: Parsed{Quantity: 1.5, Unit: "cup", Name: "cooked rice"},
+		},
+		{
+			line: "2-3 lbs Boneless pork chops",
+			want: Parsed{Quantity: 2, QuantityMax: 3, Unit: "lb", Name: "boneless pork chops"},
+		},
+		{
+			line: "½ to ¾ cup heavy cream",
+			want: Parsed{Quantity: 0.5, QuantityMax: 0.75, Unit: "cup", Name: "heavy cream"},
+		},
+		{
+			line: "2(14-ounce) cans kidney beans, drained and rinsed",
+			want: Parsed{Quantity: 2, Unit: "can", Size: "14-ounce", Name: "kidney beans", Notes: "drained and rinsed"},
+		},
+		{
+			line: "1 cup unsalted butter (2 sticks), at room temperature",
+			want: Parsed{Quantity: 1, Unit: "cup", Name: "unsalted butter", Notes: "2 sticks; at room temperature"},
+		},
+		{
+			line: "2 pounds boneless, skinless chicken thighs",
+			want: Parsed{Quantity: 2, Unit: "lb", Name: "boneless skinless chicken thighs"},
+		},
+		{
+			line: "¼ cup plus 2 tablespoons mayonnaise",
+			want: Parsed{Quantity: 0.375, Unit: "cup", Name: "mayonnaise"},
+		},
+		{
+			line: "1 cup/8 ounces ricotta cheese",
+			want: Parsed{Quantity: 1, Unit: "cup", Name: "ricotta cheese"},
+		},
+		{
+			line: "Chopped fresh parsley, for serving (optional)",
+			want: Parsed{Name: "fresh parsley", Notes: "Chopped; for serving", Optional: true},
+		},
+		{
+			line: "Kosher salt",
+			want: Parsed{Name: "kosher salt"},
+		},
+	}
+	for _, tt := range tests {
+		got := Parse(tt.line)
+		tt.want.Raw = tt.line
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q)\n got  %+v\n want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestShoppingQuantity(t *testing.T) {
+	if q := Parse("2 to 3 carrots").ShoppingQuantity(); q != 3 {
+		t.Errorf("expected the top of the range, got %v", q)
+	}
+	if q := Parse("2 carrots").ShoppingQuantity(); q != 2 {
+		t.Errorf("expected the quantity, got %v", q)
+	}
+}
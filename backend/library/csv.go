@@ -0,0 +1,296 @@
+package library
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Row kinds in the CSV form. Each row is one meal, ingredient, step or plan day; the
+// columns a kind doesn't use are left empty.
+const (
+	kindMeal       = "meal"
+	kindIngredient = "ingredient"
+	kindStep       = "step"
+	kindPlan       = "plan"
+)
+
+// csvHeader is the header row of the CSV form.
+var csvHeader = []string{
+	"kind", "meal", "relative_effort", "red_meat", "url", "servings", "nights", "slots", "tags", "last_planned",
+	"ingredient", "quantity", "unit", "step", "week_start", "day", "date", "slot", "eating_out", "leftovers",
+}
+
+// WriteCSV writes a library as a single CSV table. Meals come first, each followed by its
+// ingredients and steps in order, then the plan days.
+func WriteCSV(w io.Writer, lib *Library) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	row := func(values map[string]string) error {
+		record := make([]string, len(csvHeader))
+		for i, col := range csvHeader {
+			record[i] = values[col]
+		}
+		return cw.Write(record)
+	}
+
+	for _, m := range lib.Meals {
+		lastPlanned := ""
+		if m.LastPlanned != nil {
+			lastPlanned = m.LastPlanned.UTC().Format(time.RFC3339)
+		}
+		if err := row(map[string]string{
+			"kind":            kindMeal,
+			"meal":            m.Name,
+			"relative_effort": strconv.Itoa(m.RelativeEffort),
+			"red_meat":        strconv.FormatBool(m.RedMeat),
+			"url":             m.URL,
+			"servings":        formatCount(m.Servings),
+			"nights":          formatCount(m.Nights),
+			"slots":           strings.Join(m.Slots, ";"),
+			"tags":            strings.Join(m.Tags, ";"),
+			"last_planned":    lastPlanned,
+		}); err != nil {
+			return err
+		}
+		for _, ing := range m.Ingredients {
+			if err := row(map[string]string{
+				"kind":       kindIngredient,
+				"meal":       m.Name,
+				"ingredient": ing.Name,
+				"quantity":   strconv.FormatFloat(ing.Quantity, 'f', -1, 64),
+				"unit":       ing.Unit,
+			}); err != nil {
+				return err
+			}
+		}
+		for _, step := range m.Steps {
+			if err := row(map[string]string{"kind": kindStep, "meal": m.Name, "step": step}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range lib.Plans {
+		for _, d := range p.Days {
+			values := map[string]string{
+				"kind":       kindPlan,
+				"week_start": p.WeekStart,
+				"day":        d.Day,
+				"date":       d.Date,
+				"slot":       d.Slot,
+				"meal":       d.Meal,
+				"servings":   formatCount(d.Servings),
+			}
+			if d.EatingOut {
+				values["eating_out"] = "true"
+			}
+			if d.Leftovers {
+				values["leftovers"] = "true"
+			}
+			if err := row(values); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads a library written by WriteCSV. Columns are found by their header, so
+// they may come in any order and unknown ones are ignored. Ingredient and step rows must
+// name a meal that has a meal row. Content problems are returned together as a
+// *ValidationError.
+func ReadCSV(r io.Reader) (*Library, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, &ValidationError{Problems: []string{"the file is empty"}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := cols["kind"]; !ok {
+		return nil, &ValidationError{Problems: []string{`the header has no "kind" column`}}
+	}
+
+	lib := &Library{Version: Version, Meals: []Meal{}, Plans: []Plan{}}
+	meals := map[string]int{} // meal name key to index in lib.Meals
+	weeks := map[string]int{} // week start to index in lib.Plans
+	// child is an ingredient or step row, attached to its meal once every row is read.
+	type child struct {
+		line int
+		kind string
+		meal string
+		add  func(*Meal)
+	}
+	var children []child
+	var problems []string
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(col string) string {
+			if i, ok := cols[col]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		fail := func(format string, args ...interface{}) {
+			problems = append(problems, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+		}
+
+		switch kind := strings.ToLower(get("kind")); kind {
+		case kindMeal:
+			m := Meal{Name: get("meal"), URL: get("url"), Ingredients: []Ingredient{}, Steps: []string{}}
+			if v := get("relative_effort"); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					fail("invalid relative_effort %q", v)
+				}
+				m.RelativeEffort = n
+			}
+			if v := get("red_meat"); v != "" {
+				b, err := strconv.ParseBool(v)
+				if err != nil {
+					fail("invalid red_meat %q", v)
+				}
+				m.RedMeat = b
+			}
+			m.Servings = parseCount("servings", get("servings"), fail)
+			m.Nights = parseCount("nights", get("nights"), fail)
+			for _, slot := range strings.Split(get("slots"), ";") {
+				if slot = strings.TrimSpace(slot); slot != "" {
+					m.Slots = append(m.Slots, slot)
+				}
+			}
+			for _, tag := range strings.Split(get("tags"), ";") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					m.Tags = append(m.Tags, tag)
+				}
+			}
+			if v := get("last_planned"); v != "" {
+				t, err := time.Parse(time.RFC3339, v)
+				if err != nil {
+					fail("invalid last_planned %q, expected an RFC 3339 time", v)
+				} else {
+					m.LastPlanned = &t
+				}
+			}
+			key := nameKey(m.Name)
+			if _, ok := meals[key]; !ok && key != "" {
+				meals[key] = len(lib.Meals)
+			}
+			// Duplicates are kept so Validate reports them.
+			lib.Meals = append(lib.Meals, m)
+
+		case kindIngredient, kindStep:
+			var add func(*Meal)
+			if kind == kindIngredient {
+				ing := Ingredient{Name: get("ingredient"), Unit: get("unit")}
+				if v := get("quantity"); v != "" {
+					q, err := strconv.ParseFloat(v, 64)
+					if err != nil {
+						fail("invalid quantity %q", v)
+					}
+					ing.Quantity = q
+				}
+				add = func(m *Meal) { m.Ingredients = append(m.Ingredients, ing) }
+			} else {
+				step := get("step")
+				if step == "" {
+					fail("step row has no step")
+					continue
+				}
+				add = func(m *Meal) { m.Steps = append(m.Steps, step) }
+			}
+			children = append(children, child{line: line, kind: kind, meal: get("meal"), add: add})
+
+		case kindPlan:
+			week := get("week_start")
+			i, ok := weeks[week]
+			if !ok {
+				i = len(lib.Plans)
+				weeks[week] = i
+				lib.Plans = append(lib.Plans, Plan{WeekStart: week, Days: []PlanDay{}})
+			}
+			day := PlanDay{Day: get("day"), Date: get("date"), Slot: get("slot"), Meal: get("meal"), Servings: parseCount("servings", get("servings"), fail)}
+			if v := get("eating_out"); v != "" {
+				b, err := strconv.ParseBool(v)
+				if err != nil {
+					fail("invalid eating_out %q", v)
+				}
+				day.EatingOut = b
+			}
+			if v := get("leftovers"); v != "" {
+				b, err := strconv.ParseBool(v)
+				if err != nil {
+					fail("invalid leftovers %q", v)
+				}
+				day.Leftovers = b
+			}
+			if day.EatingOut {
+				day.Meal = ""
+			}
+			lib.Plans[i].Days = append(lib.Plans[i].Days, day)
+
+		case "":
+			// Blank rows are skipped.
+		default:
+			fail("unknown kind %q, expected meal, ingredient, step or plan", kind)
+		}
+	}
+
+	// Ingredients and steps are attached once every meal row has been read, so they may
+	// come before their meal.
+	for _, c := range children {
+		i, ok := meals[nameKey(c.meal)]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("line %d: %s row for %q, which has no meal row", c.line, c.kind, c.meal))
+			continue
+		}
+		c.add(&lib.Meals[i])
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return lib, nil
+}
+
+// formatCount leaves a servings or nights column empty when the count isn't set.
+func formatCount(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// parseCount reads a servings or nights column, reporting a bad value through fail.
+func parseCount(col, v string, fail func(format string, args ...interface{})) int {
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		fail("invalid %s %q", col, v)
+	}
+	return n
+}
@@ -0,0 +1,229 @@
+package library
+
+import (
+	"fmt"
+	"time"
+
+	"mealplanner/models"
+	"mealplanner/store"
+)
+
+// Import strategies decide what happens to meals and plan weeks that already exist.
+const (
+	// StrategyMerge keeps what is already stored and skips the imported copy.
+	StrategyMerge = "merge"
+	// StrategyReplace replaces what is already stored with the imported copy.
+	StrategyReplace = "replace"
+)
+
+// Options control an import.
+type Options struct {
+	// Strategy is StrategyMerge or StrategyReplace; empty means merge.
+	Strategy string
+	// DryRun reports what the import would do without changing the store.
+	DryRun bool
+}
+
+// Report describes what an import did, or would do in a dry run.
+type Report struct {
+	DryRun        bool       `json:"dryRun"`
+	Strategy      string     `json:"strategy"`
+	MealsCreated  int        `json:"mealsCreated"`
+	MealsReplaced int        `json:"mealsReplaced"`
+	MealsSkipped  int        `json:"mealsSkipped"`
+	PlansSaved    int        `json:"plansSaved"`
+	PlansSkipped  int        `json:"plansSkipped"`
+	Conflicts     []Conflict `json:"conflicts"`
+	Warnings      []string   `json:"warnings"`
+}
+
+// Conflict is an imported meal or plan week that is already stored.
+type Conflict struct {
+	// Kind is "meal" or "plan".
+	Kind string `json:"kind"`
+	// Name is the meal name, or the plan's week start.
+	Name       string `json:"name"`
+	ExistingID int    `json:"existingId"`
+	// Resolution is "skipped" or "replaced", following the strategy.
+	Resolution string `json:"resolution"`
+}
+
+// mealAction is what the import does with one imported meal.
+type mealAction struct {
+	meal       Meal
+	existingID int // 0 when no stored meal has the name
+	skip       bool
+}
+
+// Import adds a library to a store. The library is validated first, and an invalid one
+// returns a *ValidationError without changing anything. Meals whose name is already
+// stored, and plans for weeks that already have one, are conflicts handled by the
+// strategy. Plan days naming a meal that is neither imported nor stored are dropped with
+// a warning. Replaced meals are updated in place, keeping their ID, plan days and
+// cooking log. Meals keep their exported last planned time, as saving their plans would
+// otherwise mark them planned today. Everything is written in one batch, so a failed
+// import leaves the store as it was.
+func Import(s store.Store, lib *Library, opts Options) (*Report, error) {
+	if opts.Strategy == "" {
+		opts.Strategy = StrategyMerge
+	}
+	if opts.Strategy != StrategyMerge && opts.Strategy != StrategyReplace {
+		return nil, &ValidationError{Problems: []string{fmt.Sprintf("unknown strategy %q, expected %s or %s", opts.Strategy, StrategyMerge, StrategyReplace)}}
+	}
+	if err := lib.Validate(); err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: opts.DryRun, Strategy: opts.Strategy, Conflicts: []Conflict{}, Warnings: []string{}}
+	resolution := "skipped"
+	if opts.Strategy == StrategyReplace {
+		resolution = "replaced"
+	}
+
+	stored, err := s.GetAllMeals()
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]*models.Meal{}
+	for _, m := range stored {
+		existing[nameKey(m.MealName)] = m
+	}
+
+	actions := make([]mealAction, 0, len(lib.Meals))
+	for _, m := range lib.Meals {
+		action := mealAction{meal: m}
+		if old, ok := existing[nameKey(m.Name)]; ok {
+			action.existingID = old.ID
+			action.skip = opts.Strategy == StrategyMerge
+			report.Conflicts = append(report.Conflicts, Conflict{Kind: "meal", Name: old.MealName, ExistingID: old.ID, Resolution: resolution})
+			if action.skip {
+				report.MealsSkipped++
+			} else {
+				report.MealsReplaced++
+			}
+		} else {
+			report.MealsCreated++
+		}
+		actions = append(actions, action)
+	}
+
+	storedPlans, err := s.ListMealPlans(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	weeks := map[string]int{}
+	for _, p := range storedPlans {
+		weeks[p.WeekStart.UTC().Format(DateLayout)] = p.ID
+	}
+
+	imported := map[string]bool{}
+	for _, m := range lib.Meals {
+		imported[nameKey(m.Name)] = true
+	}
+	var plans []Plan
+	writtenWeeks := map[string]bool{}
+	for _, p := range lib.Plans {
+		start, _ := time.Parse(DateLayout, p.WeekStart)
+		week := planStart(p, start)
+		if id, ok := weeks[week]; ok {
+			report.Conflicts = append(report.Conflicts, Conflict{Kind: "plan", Name: week, ExistingID: id, Resolution: resolution})
+			if opts.Strategy == StrategyMerge {
+				report.PlansSkipped++
+				continue
+			}
+		}
+		for _, d := range p.Days {
+			key := nameKey(d.Meal)
+			if !d.EatingOut && !imported[key] && existing[key] == nil {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("plan for %s: %s's meal %q is not in the library, so the day was left out", week, d.Day, d.Meal))
+			}
+		}
+		plans = append(plans, p)
+		writtenWeeks[week] = true
+		report.PlansSaved++
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+	err = s.Atomically(func(w store.Writer) error {
+		return apply(w, actions, plans, existing)
+	})
+	return report, err
+}
+
+// apply writes the planned meal actions and plans to the store.
+func apply(w store.Writer, actions []mealAction, plans []Plan, existing map[string]*models.Meal) error {
+	ids := map[string]int{}
+	imported := map[int]bool{}
+	lastPlanned := map[int]time.Time{}
+	for key, m := range existing {
+		ids[key] = m.ID
+		lastPlanned[m.ID] = m.LastPlanned
+	}
+
+	for _, action := range actions {
+		key := nameKey(action.meal.Name)
+		if action.skip {
+			continue
+		}
+		var meal *models.Meal
+		var err error
+		if action.existingID != 0 {
+			// Replaced in place, so the meal keeps its ID, plans and cooking log.
+			if meal, err = w.ReplaceMeal(action.existingID, action.meal.toModel()); err != nil {
+				return fmt.Errorf("replacing meal %q: %w", action.meal.Name, err)
+			}
+		} else {
+			if meal, err = w.CreateMeal(action.meal.toModel()); err != nil {
+				return fmt.Errorf("creating meal %q: %w", action.meal.Name, err)
+			}
+			lastPlanned[meal.ID] = time.Time{}
+		}
+		ids[key] = meal.ID
+		imported[meal.ID] = true
+		if action.meal.LastPlanned != nil {
+			lastPlanned[meal.ID] = action.meal.LastPlanned.UTC()
+		}
+	}
+
+	touched := map[int]bool{}
+	for _, p := range plans {
+		start, _ := time.Parse(DateLayout, p.WeekStart)
+		days := map[string]*models.Meal{}
+		servings := map[string]int{}
+		for _, d := range p.Days {
+			key := d.key(start)
+			if d.EatingOut {
+				days[key] = &models.Meal{MealName: models.EatingOutMealName}
+				continue
+			}
+			id, ok := ids[nameKey(d.Meal)]
+			if !ok {
+				continue
+			}
+			if d.Leftovers {
+				days[key] = models.NewLeftovers(&models.Meal{ID: id, MealName: d.Meal})
+				continue
+			}
+			days[key] = &models.Meal{ID: id, MealName: d.Meal}
+			servings[key] = d.Servings
+			touched[id] = true
+		}
+		if _, err := w.SaveMealPlan(start, days, servings); err != nil {
+			return fmt.Errorf("saving the plan for %s: %w", p.WeekStart, err)
+		}
+	}
+
+	// Imported meals get their exported last planned time; stored meals that appear in
+	// imported plans get back the time they had before.
+	for id, when := range lastPlanned {
+		if !touched[id] && !imported[id] {
+			continue
+		}
+		if err := w.SetLastPlanned(id, when); err != nil {
+			return fmt.Errorf("setting last planned for meal %d: %w", id, err)
+		}
+	}
+	return nil
+}
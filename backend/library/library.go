@@ -0,0 +1,343 @@
+// Package library exports the whole meal library, with the saved plan history, and
+// imports it again into any store. Meals are referenced by name rather than ID, so an
+// export from one database can be imported into another.
+package library
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mealplanner/models"
+	"mealplanner/store"
+)
+
+// Version is the format version written to exports.
+const Version = 1
+
+// DateLayout is the layout of plan week starts in exports.
+const DateLayout = "2006-01-02"
+
+// Library is an exported meal library.
+type Library struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Meals      []Meal    `json:"meals"`
+	Plans      []Plan    `json:"plans"`
+}
+
+// Meal is an exported meal with its ingredients and steps in order.
+type Meal struct {
+	Name           string   `json:"name"`
+	RelativeEffort int      `json:"relativeEffort"`
+	RedMeat        bool     `json:"redMeat"`
+	URL            string   `json:"url,omitempty"`
+	Servings       int      `json:"servings,omitempty"`
+	Nights         int      `json:"nights,omitempty"`
+	Slots          []string `json:"slots,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	// LastPlanned is nil for a meal that has never been planned.
+	LastPlanned *time.Time   `json:"lastPlanned,omitempty"`
+	Ingredients []Ingredient `json:"ingredients"`
+	Steps       []string     `json:"steps"`
+}
+
+// Ingredient is an exported ingredient of a meal.
+type Ingredient struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+}
+
+// Plan is an exported saved plan.
+type Plan struct {
+	// WeekStart is the first day of the plan, formatted with DateLayout. A plan whose days
+	// have no dates covers the Monday-based week containing it.
+	WeekStart string    `json:"weekStart"`
+	Days      []PlanDay `json:"days"`
+}
+
+// PlanDay is a meal slot of an exported plan: a meal by name, eating out, or the
+// leftovers of the named meal. An empty slot is dinner.
+type PlanDay struct {
+	Day string `json:"day"`
+	// Date is the day's date, formatted with DateLayout, and Day its weekday. Exports from
+	// before plans had dates only name the weekday.
+	Date      string `json:"date,omitempty"`
+	Slot      string `json:"slot,omitempty"`
+	Meal      string `json:"meal,omitempty"`
+	EatingOut bool   `json:"eatingOut,omitempty"`
+	Leftovers bool   `json:"leftovers,omitempty"`
+	Servings  int    `json:"servings,omitempty"`
+}
+
+// key returns the dated plan key of the day in a plan starting at start. Exports from
+// before plans had dates only name the weekday, of the Monday-based week containing start.
+func (d PlanDay) key(start time.Time) string {
+	if d.Date != "" {
+		return models.PlanKey(d.Date, d.Slot)
+	}
+	key := models.PlanKey(d.Day, d.Slot)
+	if dated, ok := models.DatedKey(key, models.WeekStartFor(start), len(models.Weekdays)); ok {
+		return dated
+	}
+	return key
+}
+
+// planStart returns the date a plan is saved under, formatted with DateLayout: the start
+// SaveMealPlan gives it.
+func planStart(p Plan, start time.Time) string {
+	keys := make([]string, 0, len(p.Days))
+	for _, d := range p.Days {
+		keys = append(keys, d.key(start))
+	}
+	start, _, _ = models.PlanRules{}.PlanSpan(start, keys)
+	return start.Format(DateLayout)
+}
+
+// Export reads every meal and saved plan from a store. Meals are sorted by name and plans
+// by week. Days whose meal has since been deleted are left out.
+func Export(s store.Store) (*Library, error) {
+	meals, err := s.GetAllMeals()
+	if err != nil {
+		return nil, err
+	}
+	plans, err := s.ListMealPlans(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	lib := &Library{
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		Meals:      make([]Meal, 0, len(meals)),
+		Plans:      make([]Plan, 0, len(plans)),
+	}
+	names := make(map[int]string, len(meals))
+	for _, m := range meals {
+		lib.Meals = append(lib.Meals, fromModel(m))
+		names[m.ID] = m.MealName
+	}
+	sort.SliceStable(lib.Meals, func(i, j int) bool {
+		return strings.ToLower(lib.Meals[i].Name) < strings.ToLower(lib.Meals[j].Name)
+	})
+
+	for _, p := range plans {
+		plan := Plan{WeekStart: p.WeekStart.UTC().Format(DateLayout), Days: []PlanDay{}}
+		entries := append([]models.MealPlanEntry{}, p.Entries...)
+		sort.SliceStable(entries, func(i, j int) bool {
+			if !entries[i].Date.Equal(entries[j].Date) {
+				return entries[i].Date.Before(entries[j].Date)
+			}
+			return slotOrder(entries[i].Slot) < slotOrder(entries[j].Slot)
+		})
+		for _, e := range entries {
+			exported := PlanDay{Day: e.Day, Date: e.Date.UTC().Format(DateLayout)}
+			if e.Slot != models.SlotDinner {
+				exported.Slot = e.Slot
+			}
+			switch {
+			case e.Status == models.EntryStatusEatingOut:
+				exported.EatingOut = true
+			case e.Status == models.EntryStatusLeftovers && e.Meal != nil:
+				exported.Meal, exported.Leftovers = names[e.MealID], true
+			case e.Meal != nil:
+				exported.Meal, exported.Servings = e.Meal.MealName, e.Servings
+			default:
+				continue
+			}
+			plan.Days = append(plan.Days, exported)
+		}
+		lib.Plans = append(lib.Plans, plan)
+	}
+	sort.SliceStable(lib.Plans, func(i, j int) bool { return lib.Plans[i].WeekStart < lib.Plans[j].WeekStart })
+	return lib, nil
+}
+
+// slotOrder returns the position of a slot in the day.
+func slotOrder(slot string) int {
+	for i, s := range models.Slots {
+		if s == slot {
+			return i
+		}
+	}
+	return len(models.Slots)
+}
+
+// fromModel converts a stored meal for export.
+func fromModel(m *models.Meal) Meal {
+	out := Meal{
+		Name:           m.MealName,
+		RelativeEffort: m.RelativeEffort,
+		RedMeat:        m.RedMeat,
+		URL:            m.URL,
+		Servings:       m.Servings,
+		Ingredients:    make([]Ingredient, 0, len(m.Ingredients)),
+		Steps:          make([]string, 0, len(m.Steps)),
+	}
+	if m.Nights > 1 {
+		out.Nights = m.Nights
+	}
+	if !m.SuitableFor(models.SlotDinner) || len(m.Slots) > 1 {
+		out.Slots = append([]string(nil), m.Slots...)
+	}
+	if !m.LastPlanned.IsZero() {
+		t := m.LastPlanned.UTC()
+		out.LastPlanned = &t
+	}
+	if len(m.Tags) > 0 {
+		out.Tags = append([]string(nil), m.Tags...)
+	}
+	for _, ing := range m.Ingredients {
+		out.Ingredients = append(out.Ingredients, Ingredient{Name: ing.Name, Quantity: ing.Quantity, Unit: ing.Unit})
+	}
+	steps := append([]models.Step(nil), m.Steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].StepNumber < steps[j].StepNumber })
+	for _, step := range steps {
+		out.Steps = append(out.Steps, step.Instruction)
+	}
+	return out
+}
+
+// toModel converts an imported meal for storing.
+func (m Meal) toModel() models.Meal {
+	out := models.Meal{
+		MealName:       strings.TrimSpace(m.Name),
+		RelativeEffort: m.RelativeEffort,
+		RedMeat:        m.RedMeat,
+		URL:            m.URL,
+		Servings:       m.Servings,
+		Nights:         m.Nights,
+		Slots:          m.Slots,
+		Tags:           m.Tags,
+		Ingredients:    make([]models.Ingredient, 0, len(m.Ingredients)),
+		Steps:          make([]models.Step, 0, len(m.Steps)),
+	}
+	for _, ing := range m.Ingredients {
+		out.Ingredients = append(out.Ingredients, models.Ingredient{
+			Name:     strings.TrimSpace(ing.Name),
+			Quantity: ing.Quantity,
+			Unit:     strings.TrimSpace(ing.Unit),
+		})
+	}
+	for i, step := range m.Steps {
+		out.Steps = append(out.Steps, models.Step{StepNumber: i + 1, Instruction: step})
+	}
+	return out
+}
+
+// ValidationError lists what is wrong with a library that can't be imported.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid library: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks a library before anything is imported from it, so a bad file never
+// leaves a half-finished import behind.
+func (lib *Library) Validate() error {
+	var problems []string
+	if lib.Version != 0 && lib.Version != Version {
+		problems = append(problems, fmt.Sprintf("unsupported version %d, expected %d", lib.Version, Version))
+	}
+
+	seen := map[string]int{}
+	for i, m := range lib.Meals {
+		name := strings.TrimSpace(m.Name)
+		if name == "" {
+			problems = append(problems, fmt.Sprintf("meal %d has no name", i+1))
+			continue
+		}
+		key := nameKey(name)
+		if first, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("meal %q appears twice (meals %d and %d)", name, first, i+1))
+		} else {
+			seen[key] = i + 1
+		}
+		if m.RelativeEffort < 0 {
+			problems = append(problems, fmt.Sprintf("meal %q has a negative effort", name))
+		}
+		if m.Servings < 0 {
+			problems = append(problems, fmt.Sprintf("meal %q has negative servings", name))
+		}
+		if m.Nights < 0 {
+			problems = append(problems, fmt.Sprintf("meal %q has negative nights", name))
+		}
+		if _, err := models.NormalizeTagNames(m.Tags); err != nil {
+			problems = append(problems, fmt.Sprintf("meal %q has an empty tag", name))
+		}
+		if _, err := models.NormalizeSlots(m.Slots); err != nil {
+			problems = append(problems, fmt.Sprintf("meal %q has an %v", name, err))
+		}
+	}
+
+	weeks := map[string]bool{}
+	for i, p := range lib.Plans {
+		start, err := time.Parse(DateLayout, p.WeekStart)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("plan %d has an invalid week start %q, expected YYYY-MM-DD", i+1, p.WeekStart))
+			continue
+		}
+		days := map[string]bool{}
+		valid := true
+		for _, d := range p.Days {
+			if d.Date != "" {
+				date, ok := models.ParsePlanDate(d.Date)
+				if !ok {
+					problems = append(problems, fmt.Sprintf("plan for %s has an invalid date %q, expected YYYY-MM-DD", p.WeekStart, d.Date))
+					valid = false
+					continue
+				}
+				if d.Day != "" && d.Day != date.Weekday().String() {
+					problems = append(problems, fmt.Sprintf("plan for %s has %s on %s, which is a %s", p.WeekStart, d.Day, d.Date, date.Weekday()))
+					valid = false
+					continue
+				}
+			} else if !models.IsWeekday(d.Day) {
+				problems = append(problems, fmt.Sprintf("plan for %s has an invalid day %q", p.WeekStart, d.Day))
+				valid = false
+				continue
+			}
+			if !models.IsSlot(models.NormalizeSlot(d.Slot)) {
+				problems = append(problems, fmt.Sprintf("plan for %s has an invalid slot %q on %s", p.WeekStart, d.Slot, d.Day))
+				valid = false
+				continue
+			}
+			key := d.key(start)
+			if days[key] {
+				problems = append(problems, fmt.Sprintf("plan for %s has %s twice", p.WeekStart, key))
+			}
+			days[key] = true
+			if !d.EatingOut && strings.TrimSpace(d.Meal) == "" {
+				problems = append(problems, fmt.Sprintf("plan for %s has no meal on %s", p.WeekStart, key))
+			}
+			if d.EatingOut && d.Leftovers {
+				problems = append(problems, fmt.Sprintf("plan for %s has both eating out and leftovers on %s", p.WeekStart, key))
+			}
+			if d.Servings < 0 {
+				problems = append(problems, fmt.Sprintf("plan for %s has negative servings on %s", p.WeekStart, key))
+			}
+		}
+		if !valid {
+			continue
+		}
+		if week := planStart(p, start); weeks[week] {
+			problems = append(problems, fmt.Sprintf("more than one plan starts on %s", week))
+		} else {
+			weeks[week] = true
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// nameKey is how meal names are compared: trimmed and ignoring case.
+func nameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,298 @@
+package library
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mealplanner/dummy"
+	"mealplanner/models"
+)
+
+// newStore returns a dummy store with two meals and a saved plan using them.
+func newStore(t *testing.T) *dummy.Store {
+	t.Helper()
+	s := dummy.NewStore()
+	tacos, err := s.CreateMeal(models.Meal{
+		MealName:       "Tacos",
+		RelativeEffort: 3,
+		URL:            "https://example.com/tacos",
+		Servings:       4,
+		Slots:          []string{"lunch", "dinner"},
+		Tags:           []string{"mexican", "quick"},
+		Ingredients:    []models.Ingredient{{Name: "tortillas", Quantity: 8, Unit: "whole"}},
+		Steps:          []models.Step{{Instruction: "Warm the tortillas"}, {Instruction: "Fill"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	soup, err := s.CreateMeal(models.Meal{MealName: "Soup", RelativeEffort: 2, Nights: 2, Ingredients: []models.Ingredient{}})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	week := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	if _, err := s.SaveMealPlan(week, map[string]*models.Meal{
+		"Monday":         tacos,
+		"Tuesday":        soup,
+		"Wednesday":      models.NewLeftovers(soup),
+		"Thursday/lunch": tacos,
+		"Friday":         {MealName: models.EatingOutMealName},
+	}, map[string]int{"Monday": 6}); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	return s
+}
+
+func TestExport(t *testing.T) {
+	lib, err := Export(newStore(t))
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if lib.Version != Version || len(lib.Meals) != 2 || len(lib.Plans) != 1 {
+		t.Fatalf("unexpected library: %+v", lib)
+	}
+	if lib.Meals[0].Name != "Soup" || lib.Meals[1].Name != "Tacos" {
+		t.Errorf("expected meals sorted by name, got %q and %q", lib.Meals[0].Name, lib.Meals[1].Name)
+	}
+	if lib.Meals[0].Slots != nil {
+		t.Errorf("expected a dinner-only meal to leave out its slots, got %v", lib.Meals[0].Slots)
+	}
+	if lib.Meals[0].Nights != 2 || lib.Meals[1].Nights != 0 {
+		t.Errorf("expected only soup to list its nights, got %d and %d", lib.Meals[0].Nights, lib.Meals[1].Nights)
+	}
+	tacos := lib.Meals[1]
+	if !reflect.DeepEqual(tacos.Steps, []string{"Warm the tortillas", "Fill"}) || len(tacos.Ingredients) != 1 || tacos.Servings != 4 ||
+		!reflect.DeepEqual(tacos.Tags, []string{"mexican", "quick"}) || !reflect.DeepEqual(tacos.Slots, []string{"lunch", "dinner"}) {
+		t.Errorf("unexpected tacos: %+v", tacos)
+	}
+	if tacos.LastPlanned == nil {
+		t.Errorf("expected the planned meal to have a last planned time")
+	}
+	want := Plan{WeekStart: "2024-03-04", Days: []PlanDay{
+		{Day: "Monday", Date: "2024-03-04", Meal: "Tacos", Servings: 6},
+		{Day: "Tuesday", Date: "2024-03-05", Meal: "Soup"},
+		{Day: "Wednesday", Date: "2024-03-06", Meal: "Soup", Leftovers: true},
+		{Day: "Thursday", Date: "2024-03-07", Slot: "lunch", Meal: "Tacos"},
+		{Day: "Friday", Date: "2024-03-08", EatingOut: true},
+	}}
+	if !reflect.DeepEqual(lib.Plans[0], want) {
+		t.Errorf("unexpected plan:\n got %+v\nwant %+v", lib.Plans[0], want)
+	}
+}
+
+func TestImport_IntoEmptyStore(t *testing.T) {
+	lib, err := Export(newStore(t))
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	target := dummy.NewStore()
+	report, err := Import(target, lib, Options{})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.MealsCreated != 2 || report.PlansSaved != 1 || len(report.Conflicts) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	again, err := Export(target)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	again.ExportedAt = lib.ExportedAt
+	if !reflect.DeepEqual(again, lib) {
+		t.Errorf("round trip changed the library:\n got %+v\nwant %+v", again, lib)
+	}
+}
+
+func TestImport_Conflicts(t *testing.T) {
+	lib := &Library{
+		Meals: []Meal{
+			{Name: " tacos ", RelativeEffort: 5, Ingredients: []Ingredient{}, Steps: []string{"Order in"}},
+			{Name: "Pasta", RelativeEffort: 2},
+		},
+		Plans: []Plan{
+			{WeekStart: "2024-03-06", Days: []PlanDay{{Day: "Monday", Meal: "Pasta"}}},
+			{WeekStart: "2024-03-11", Days: []PlanDay{{Day: "Monday", Meal: "Pasta"}, {Day: "Tuesday", Meal: "Curry"}}},
+		},
+	}
+
+	s := newStore(t)
+	report, err := Import(s, lib, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.MealsCreated != 1 || report.MealsSkipped != 1 || report.PlansSaved != 1 || report.PlansSkipped != 1 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+	if len(report.Conflicts) != 2 || report.Conflicts[0].Name != "Tacos" || report.Conflicts[1].Name != "2024-03-04" {
+		t.Errorf("unexpected conflicts: %+v", report.Conflicts)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "Curry") {
+		t.Errorf("expected a warning about the unknown meal, got %v", report.Warnings)
+	}
+	if meals, _ := s.GetAllMeals(); len(meals) != 2 {
+		t.Errorf("a dry run must not store anything, got %d meals", len(meals))
+	}
+
+	report, err = Import(s, lib, Options{Strategy: StrategyReplace})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.MealsReplaced != 1 || report.MealsCreated != 1 || report.PlansSaved != 2 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+	meals, _ := s.GetAllMeals()
+	if len(meals) != 3 {
+		t.Fatalf("expected 3 meals after replacing, got %d", len(meals))
+	}
+	for _, m := range meals {
+		if m.MealName == "tacos" && (m.RelativeEffort != 5 || len(m.Steps) != 1) {
+			t.Errorf("expected tacos replaced, got %+v", m)
+		}
+		if m.MealName == "Pasta" && !m.LastPlanned.IsZero() {
+			t.Errorf("expected the imported meal to keep its empty last planned, got %v", m.LastPlanned)
+		}
+	}
+	plans, _ := s.ListMealPlans(time.Time{}, time.Time{})
+	if len(plans) != 2 || len(plans[0].Entries) != 1 || len(plans[1].Entries) != 1 {
+		t.Errorf("expected both weeks replaced by the imported plans, got %+v", plans)
+	}
+}
+
+func TestImport_ReplaceKeepsHistory(t *testing.T) {
+	s := newStore(t)
+	week := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	if _, err := s.MarkPlanDayDone(week, "Monday", models.CookingLogEntry{Status: models.CookingStatusCooked, Rating: 5}); err != nil {
+		t.Fatalf("MarkPlanDayDone: %v", err)
+	}
+	meals, _ := s.GetAllMeals()
+	var tacosID int
+	for _, m := range meals {
+		if m.MealName == "Tacos" {
+			tacosID = m.ID
+		}
+	}
+
+	lib := &Library{Meals: []Meal{{Name: "Tacos", RelativeEffort: 4, Ingredients: []Ingredient{{Name: "shells", Quantity: 6, Unit: "whole"}}}}}
+	report, err := Import(s, lib, Options{Strategy: StrategyReplace})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.MealsReplaced != 1 || len(report.Warnings) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	got, _ := s.GetMealsByIDs([]int{tacosID})
+	if len(got) != 1 || got[0].RelativeEffort != 4 || len(got[0].Ingredients) != 1 || got[0].Ingredients[0].Name != "shells" {
+		t.Fatalf("expected tacos replaced under ID %d, got %+v", tacosID, got)
+	}
+	history, err := s.GetMealHistory(tacosID)
+	if err != nil {
+		t.Fatalf("GetMealHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].Rating != 5 {
+		t.Errorf("expected the replaced meal to keep its cooking log, got %+v", history)
+	}
+	plan, _ := s.GetLatestMealPlan()
+	if len(plan.Entries) != 5 {
+		t.Errorf("expected the stored plan to keep every day, got %+v", plan.Entries)
+	}
+}
+
+func TestImport_DatedPlan(t *testing.T) {
+	lib := &Library{
+		Meals: []Meal{{Name: "Pasta", RelativeEffort: 2}},
+		Plans: []Plan{{WeekStart: "2024-03-06", Days: []PlanDay{
+			{Day: "Wednesday", Date: "2024-03-06", Meal: "Pasta"},
+			{Date: "2024-03-15", Slot: "lunch", Meal: "Pasta"},
+		}}},
+	}
+	s := dummy.NewStore()
+	if _, err := Import(s, lib, Options{}); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	plans, err := s.ListMealPlans(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("ListMealPlans: %v", err)
+	}
+	if len(plans) != 1 || plans[0].WeekStart.Format(DateLayout) != "2024-03-06" || plans[0].Days != 10 || len(plans[0].Entries) != 2 {
+		t.Fatalf("expected a 10-day plan from 2024-03-06, got %+v", plans)
+	}
+	if e := plans[0].Entries[1]; e.DateKey() != "2024-03-15/lunch" || e.Day != "Friday" {
+		t.Errorf("unexpected last entry: %+v", e)
+	}
+
+	lib.Plans[0].Days[0].Day = "Monday"
+	var invalid *ValidationError
+	if _, err := Import(dummy.NewStore(), lib, Options{}); !errors.As(err, &invalid) || len(invalid.Problems) != 1 {
+		t.Errorf("expected a day that doesn't match its date to be rejected, got %v", err)
+	}
+}
+
+func TestImport_Invalid(t *testing.T) {
+	lib := &Library{
+		Version: 7,
+		Meals:   []Meal{{Name: "Soup"}, {Name: "soup"}, {Name: ""}, {Name: "Stew", Tags: []string{" "}, Slots: []string{"brunch"}}},
+		Plans:   []Plan{{WeekStart: "March", Days: []PlanDay{}}},
+	}
+	_, err := Import(dummy.NewStore(), lib, Options{})
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	if len(invalid.Problems) != 6 {
+		t.Errorf("expected 6 problems, got %v", invalid.Problems)
+	}
+
+	if _, err := Import(dummy.NewStore(), &Library{}, Options{Strategy: "overwrite"}); !errors.As(err, &invalid) {
+		t.Errorf("expected a ValidationError for an unknown strategy, got %v", err)
+	}
+}
+
+func TestCSV_RoundTrip(t *testing.T) {
+	lib, err := Export(newStore(t))
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, lib); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	got, err := ReadCSV(&buf)
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+	got.ExportedAt = lib.ExportedAt
+	// RFC 3339 drops sub-second precision.
+	for i := range lib.Meals {
+		if lib.Meals[i].LastPlanned != nil {
+			t := lib.Meals[i].LastPlanned.Truncate(time.Second)
+			lib.Meals[i].LastPlanned = &t
+		}
+	}
+	if !reflect.DeepEqual(got, lib) {
+		t.Errorf("CSV round trip changed the library:\n got %+v\nwant %+v", got, lib)
+	}
+}
+
+func TestReadCSV_Problems(t *testing.T) {
+	in := "kind,meal,step,relative_effort\n" +
+		"step,Stew,Simmer,\n" +
+		"meal,Soup,,lots\n" +
+		"dessert,Cake,,\n"
+	_, err := ReadCSV(strings.NewReader(in))
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	want := []string{
+		`line 3: invalid relative_effort "lots"`,
+		`line 4: unknown kind "dessert", expected meal, ingredient, step or plan`,
+		`line 2: step row for "Stew", which has no meal row`,
+	}
+	if !reflect.DeepEqual(invalid.Problems, want) {
+		t.Errorf("unexpected problems:\n got %q\nwant %q", invalid.Problems, want)
+	}
+}
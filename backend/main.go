@@ -1,18 +1,25 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"mealplanner/db"
 	"mealplanner/dummy"
 	"mealplanner/handlers"
+	"mealplanner/migrations"
 	"mealplanner/models"
+	"mealplanner/store"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -34,17 +41,24 @@ func (w *CustomErrorWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// server holds the database connection behind the API so it can be checked and reconnected.
+type server struct {
+	api   *handlers.Handler
+	db    *sql.DB
+	dummy bool
+}
+
 // DBErrorMiddleware checks for database connection errors and provides helpful messages
-func DBErrorMiddleware(next http.Handler) http.Handler {
+func (s *server) DBErrorMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cw := &CustomErrorWriter{ResponseWriter: w, status: http.StatusOK}
 		next.ServeHTTP(cw, r)
 
 		// If we got an internal server error, check if it might be a DB connection issue
-		if cw.status == http.StatusInternalServerError && !handlers.UseDummy {
+		if cw.status == http.StatusInternalServerError && !s.dummy {
 			// This is a bit of a hack, but for demo purposes it's fine.
 			// In a real app, we would need to capture the error from the handler.
-			if handlers.DB == nil || handlers.DB.Ping() != nil {
+			if s.api.Store().Ping() != nil {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusServiceUnavailable)
 				errorMessage := `{"error": "Database connection issue. Please make sure Docker is running and the database container is started."}`
@@ -61,28 +75,22 @@ func main() {
 		log.Println("No .env file found, proceeding with existing env variables")
 	}
 
-	seedFlag := flag.Bool("seed", false, "Seed the database using the CSV")
+	seedFlag := flag.Bool("seed", false, "Seed the database using the CSV, adding or updating meals by name")
+	seedDryRunFlag := flag.Bool("seed-dry-run", false, "Report what -seed would change without changing anything, and exit")
 	dummyFlag := flag.Bool("dummy", false, "Use in-memory dummy data instead of a database")
+	migrateFlag := flag.String("migrate", "", "Run a migration command (status, up, down or baseline) and exit")
+	snapshotFlag := flag.String("dummy-snapshot", "", "In dummy mode, load data from this JSON file if it exists and save it there on shutdown")
 	flag.Parse()
 
-	// Read DB config from env variables with reasonable defaults
-	config := db.DefaultConfig()
-	if os.Getenv("DB_HOST") != "" {
-		config.Host = os.Getenv("DB_HOST")
-	}
-	if os.Getenv("DB_PORT") != "" {
-		config.Port = os.Getenv("DB_PORT")
-	}
-	if os.Getenv("DB_USER") != "" {
-		config.User = os.Getenv("DB_USER")
-	}
-	if os.Getenv("DB_PASSWORD") != "" {
-		config.Password = os.Getenv("DB_PASSWORD")
-	}
-	if os.Getenv("DB_NAME") != "" {
-		config.DBName = os.Getenv("DB_NAME")
+	switch *migrateFlag {
+	case "", "status", "up", "down", "baseline":
+	default:
+		log.Fatalf("Unknown -migrate command %q: use status, up, down or baseline", *migrateFlag)
 	}
 
+	// Read DB config from env variables with reasonable defaults
+	config := dbConfigFromEnv()
+
 	var connection *sql.DB
 	var err error
 	if !*dummyFlag {
@@ -90,7 +98,7 @@ func main() {
 		connection, err = db.ConnectDB(config)
 	}
 	if err != nil {
-		if db.IsConnectionError(err) {
+		if db.IsConnectionError(err) && config.Driver != db.DriverSQLite {
 			fmt.Println("\n-------------------------------------------------------------")
 			fmt.Println("❌ DATABASE CONNECTION ERROR")
 			fmt.Println("-------------------------------------------------------------")
@@ -112,37 +120,75 @@ func main() {
 		}
 	}
 
+	if *seedDryRunFlag {
+		if connection == nil {
+			log.Fatalf("-seed-dry-run needs a database connection")
+		}
+		// A dry run changes nothing, so it reports pending migrations instead of applying them.
+		if err := checkMigrated(connection); err != nil {
+			connection.Close()
+			log.Fatalf("Seeding dry run failed: %v", err)
+		}
+		report, err := models.SeedDB(connection, "Meal_db.csv", true)
+		connection.Close()
+		if err != nil {
+			log.Fatalf("Seeding dry run failed: %v", err)
+		}
+		printSeedReport(report)
+		return
+	}
+
+	if *migrateFlag != "" {
+		if connection == nil {
+			log.Fatalf("-migrate needs a database connection")
+		}
+		if err := runMigrateCommand(connection, *migrateFlag); err != nil {
+			connection.Close()
+			log.Fatalf("Migration %s failed: %v", *migrateFlag, err)
+		}
+		connection.Close()
+		return
+	}
+
 	if connection != nil {
 		defer connection.Close()
 
 		// Run migrations (only if we have a connection)
-		if err := models.Migrate(connection); err != nil {
+		if err := migrations.Up(connection); err != nil {
 			log.Printf("Migration error: %v", err)
 		}
 
 		// Seed the DB only if the flag is provided and we have a connection
 		if *seedFlag {
-			if err := models.SeedDB(connection, "Meal_db.csv"); err != nil {
+			report, err := models.SeedDB(connection, "Meal_db.csv", false)
+			if err != nil {
 				log.Printf("Seeding error: %v", err)
 			} else {
+				printSeedReport(report)
 				log.Println("Database seeded successfully!")
 			}
 		}
 	}
 
-	// Set database connection in handlers (might be nil if connection failed)
-	handlers.DB = connection
+	// Serve from the database, or from in-memory data if there's no connection
+	srv := &server{db: connection}
+	var memory *dummy.Store
 	if connection == nil || *dummyFlag {
-		handlers.UseDummy = true
-		if err := dummy.Load("Meal_db.csv"); err != nil {
+		srv.dummy = true
+		memory = dummy.NewStore()
+		if err := loadDummyData(memory, *snapshotFlag); err != nil {
 			log.Fatalf("Failed to load dummy data: %v", err)
 		}
+		srv.api = handlers.New(memory)
 		if connection == nil {
 			log.Println("Running in dummy data mode (database unavailable)")
 		} else {
 			log.Println("Running in dummy data mode (forced)")
 		}
+	} else {
+		srv.api = handlers.New(newSQLStore(config, connection))
 	}
+	srv.api.CalendarDir = os.Getenv("CALENDAR_DIR")
 
 	// Set up HTTP routes with Chi router
 	r := chi.NewRouter()
@@ -151,7 +197,7 @@ func main() {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(30 * time.Second))
-	r.Use(DBErrorMiddleware)
+	r.Use(srv.DBErrorMiddleware)
 
 	// Enable CORS for development
 	r.Use(func(next http.Handler) http.Handler {
@@ -168,118 +214,302 @@ func main() {
 	})
 
 	// Special endpoint to check database connectivity
-	r.Get("/api/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		if handlers.UseDummy {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"status":"ok","message":"Running with dummy data"}`))
-			return
+	r.Get("/api/health", srv.health)
+
+	// Add endpoint to reconnect to the database
+	r.Post("/api/reconnect", srv.reconnect)
+
+	// Register API routes
+	r.Get("/api/mealplan", srv.api.GetMealPlan)
+	r.Post("/api/mealplan/generate", srv.api.GenerateMealPlan)
+	r.Post("/api/mealplan/generate/calendar", srv.api.GenerateFromCalendarHandler)
+	r.Post("/api/mealplan/finalize", srv.api.FinalizeMealPlanHandler)
+	r.Post("/api/mealplan/done", srv.api.MarkPlanDayDoneHandler)
+	r.Get("/api/mealplans", srv.api.ListMealPlansHandler)
+	r.Get("/api/planning-rules", srv.api.GetPlanRulesHandler)
+	r.Put("/api/planning-rules", srv.api.UpdatePlanRulesHandler)
+	r.Get("/api/mealplan/ics", srv.api.MealPlanICSHandler)
+	r.Get("/api/calendar/token", srv.api.GetCalendarTokenHandler)
+	r.Post("/api/calendar/token", srv.api.RotateCalendarTokenHandler)
+	r.Get("/api/calendar/{token}.ics", srv.api.CalendarFeedHandler)
+	r.Post("/api/mealplan/swap", srv.api.SwapMeal)
+	r.Post("/api/shoppinglist", srv.api.GetShoppingList)
+	r.Get("/api/aisles", srv.api.GetAislesHandler)
+	r.Put("/api/aisles/order", srv.api.UpdateAisleOrderHandler)
+	r.Put("/api/aisles/overrides", srv.api.SetAisleOverrideHandler)
+	r.Get("/api/pantry", srv.api.ListPantryHandler)
+	r.Post("/api/pantry", srv.api.CreatePantryItemHandler)
+	r.Put("/api/pantry/{itemId}", srv.api.UpdatePantryItemHandler)
+	r.Delete("/api/pantry/{itemId}", srv.api.DeletePantryItemHandler)
+	r.Get("/api/tags", srv.api.ListTagsHandler)
+	r.Post("/api/tags", srv.api.CreateTagHandler)
+	r.Put("/api/tags/{tagId}", srv.api.RenameTagHandler)
+	r.Delete("/api/tags/{tagId}", srv.api.DeleteTagHandler)
+	r.Get("/api/meals", srv.api.GetAllMealsHandler)
+	r.Post("/api/meals", srv.api.CreateMealHandler)
+	r.Post("/api/meals/import", handlers.ImportRecipeHandler)
+	r.Post("/api/ingredients/parse", handlers.ParseIngredientsHandler)
+	r.Get("/api/export", srv.api.ExportHandler)
+	r.Post("/api/import", srv.api.ImportHandler)
+	r.Put("/api/meals/{mealId}/ingredients/{ingredientId}", srv.api.UpdateMealIngredientHandler)
+	r.Delete("/api/meals/{mealId}/ingredients/{ingredientId}", srv.api.DeleteMealIngredientHandler)
+	r.Get("/api/meals/{mealId}", srv.api.GetMealHandler)
+	r.Put("/api/meals/{mealId}/tags", srv.api.SetMealTagsHandler)
+	r.Put("/api/meals/{mealId}/slots", srv.api.SetMealSlotsHandler)
+	r.Get("/api/meals/{mealId}/history", srv.api.GetMealHistoryHandler)
+	r.Delete("/api/meals/{mealId}", srv.api.DeleteMealHandler)
+	r.Post("/api/mealplan/replace", srv.api.ReplaceMealHandler)
+
+	// New routes for recipe steps
+	r.Get("/api/meals/{mealId}/steps", srv.api.GetStepsHandler)
+	r.Post("/api/meals/{mealId}/steps", srv.api.AddStepHandler)
+	r.Post("/api/meals/{mealId}/steps/bulk", srv.api.AddBulkStepsHandler)
+	r.Put("/api/meals/{mealId}/steps/{stepId}", srv.api.UpdateStepHandler)
+	r.Delete("/api/meals/{mealId}/steps/{stepId}", srv.api.DeleteStepHandler)
+	r.Put("/api/meals/{mealId}/steps/reorder", srv.api.ReorderStepsHandler)
+	r.Delete("/api/meals/{mealId}/steps", srv.api.DeleteAllStepsHandler)
+
+	httpServer := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Printf("Error shutting down: %v", err)
 		}
+	}()
 
-		if handlers.DB == nil {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			w.Write([]byte(`{"status":"error","message":"Database not connected. Make sure Docker is running and the database container is started."}`))
+	log.Println("Backend server starting on :8080")
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Error starting server: %v", err)
+	}
+
+	// Keep what was changed in dummy mode for the next run
+	if memory != nil && *snapshotFlag != "" {
+		if err := memory.SaveSnapshot(*snapshotFlag); err != nil {
+			log.Printf("Error saving dummy snapshot: %v", err)
+		} else {
+			log.Printf("Saved dummy data to %s", *snapshotFlag)
+		}
+	}
+}
+
+// loadDummyData fills the in-memory store from the snapshot file when there is one,
+// and from the seed CSV otherwise.
+func loadDummyData(memory *dummy.Store, snapshotPath string) error {
+	if snapshotPath != "" {
+		err := memory.LoadSnapshot(snapshotPath)
+		if err == nil {
+			log.Printf("Loaded dummy data from %s", snapshotPath)
+			return nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return memory.Load("Meal_db.csv")
+}
+
+// printSeedReport prints what seeding did with each row of the CSV, then the totals.
+func printSeedReport(report *models.SeedReport) {
+	for _, row := range report.Rows {
+		line := fmt.Sprintf("line %d: %-8s %s", row.Line, row.Action, row.Meal)
+		if row.Detail != "" {
+			line += " (" + row.Detail + ")"
+		}
+		fmt.Println(line)
+	}
+	prefix := "Seed"
+	if report.DryRun {
+		prefix = "Seed dry run, nothing was changed"
+	}
+	fmt.Printf("%s: %d inserted, %d updated, %d skipped, %d invalid\n",
+		prefix, report.Inserted, report.Updated, report.Skipped, report.Invalid)
+}
+
+// dbConfigFromEnv reads the database configuration from DB_* environment variables.
+// DB_DRIVER=sqlite stores everything in the file at DB_PATH instead of Postgres.
+func dbConfigFromEnv() db.Config {
+	config := db.DefaultConfig()
+	if os.Getenv("DB_DRIVER") != "" {
+		config.Driver = os.Getenv("DB_DRIVER")
+	}
+	if os.Getenv("DB_PATH") != "" {
+		config.Path = os.Getenv("DB_PATH")
+	}
+	if os.Getenv("DB_HOST") != "" {
+		config.Host = os.Getenv("DB_HOST")
+	}
+	if os.Getenv("DB_PORT") != "" {
+		config.Port = os.Getenv("DB_PORT")
+	}
+	if os.Getenv("DB_USER") != "" {
+		config.User = os.Getenv("DB_USER")
+	}
+	if os.Getenv("DB_PASSWORD") != "" {
+		config.Password = os.Getenv("DB_PASSWORD")
+	}
+	if os.Getenv("DB_NAME") != "" {
+		config.DBName = os.Getenv("DB_NAME")
+	}
+	return config
+}
+
+// newSQLStore returns the store for a connection opened with config.
+func newSQLStore(config db.Config, connection *sql.DB) store.Store {
+	if config.Driver == db.DriverSQLite {
+		return store.NewSQLite(connection)
+	}
+	return store.NewPostgres(connection)
+}
+
+// health reports whether the database connection is usable.
+func (s *server) health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.dummy {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"ok","message":"Running with dummy data"}`))
+		return
+	}
+
+	if s.db == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"error","message":"Database not connected. Make sure Docker is running and the database container is started."}`))
+		return
+	}
+
+	if err := s.db.Ping(); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"error","message":"Database connection lost. Make sure Docker is running and the database container is started."}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"ok","message":"Database connection is healthy"}`))
+}
+
+// reconnect connects to the database again, e.g. after starting Docker, and switches
+// the API over to it.
+func (s *server) reconnect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// If DB is already connected and not in dummy mode, just confirm it's working
+	if s.db != nil && !s.dummy {
+		if err := s.db.Ping(); err == nil {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status":"ok","message":"Database connection is already established and healthy"}`))
 			return
 		}
 
-		if err := handlers.DB.Ping(); err != nil {
+		// If we have a DB object but ping fails, close it before reconnecting
+		s.db.Close()
+	}
+
+	// Read DB config from env variables with reasonable defaults
+	config := dbConfigFromEnv()
+
+	// Attempt to reconnect to the database
+	connection, err := db.ConnectDB(config)
+	if err != nil {
+		if db.IsConnectionError(err) {
 			w.WriteHeader(http.StatusServiceUnavailable)
-			w.Write([]byte(`{"status":"error","message":"Database connection lost. Make sure Docker is running and the database container is started."}`))
+			w.Write([]byte(`{"status":"error","message":"Failed to reconnect to database. Make sure Docker is running and the database container is started."}`))
 			return
 		}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf(`{"status":"error","message":"Database reconnection failed: %s"}`, err.Error())))
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok","message":"Database connection is healthy"}`))
-	})
+	// Serve from the new connection
+	s.db = connection
+	s.dummy = false
+	s.api.SetStore(newSQLStore(config, connection))
 
-	// Add endpoint to reconnect to the database
-	r.Post("/api/reconnect", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
+	// Ensure migrations are up to date
+	if err := migrations.Up(connection); err != nil {
+		log.Printf("Migration error during reconnection: %v", err)
+		// We don't fail the reconnect if migrations have issues
+	}
 
-		// If DB is already connected and not in dummy mode, just confirm it's working
-		if handlers.DB != nil && !handlers.UseDummy {
-			if err := handlers.DB.Ping(); err == nil {
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`{"status":"ok","message":"Database connection is already established and healthy"}`))
-				return
-			}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"ok","message":"Successfully reconnected to the database"}`))
+}
 
-			// If we have a DB object but ping fails, close it before reconnecting
-			handlers.DB.Close()
+// checkMigrated returns an error listing the migrations the database is missing, if any.
+func checkMigrated(connection *sql.DB) error {
+	runner, err := migrations.New(connection)
+	if err != nil {
+		return err
+	}
+	statuses, err := runner.Status()
+	if err != nil {
+		return err
+	}
+	var behind []string
+	for _, s := range statuses {
+		if s.State != migrations.StateApplied {
+			behind = append(behind, fmt.Sprintf("%04d_%s (%s)", s.Version, s.Name, s.State))
 		}
+	}
+	if len(behind) > 0 {
+		return fmt.Errorf("the database needs migrating first, run -migrate=up: %s", strings.Join(behind, ", "))
+	}
+	return nil
+}
 
-		// Read DB config from env variables with reasonable defaults
-		config := db.DefaultConfig()
-		if os.Getenv("DB_HOST") != "" {
-			config.Host = os.Getenv("DB_HOST")
+// runMigrateCommand runs the -migrate command against the database and prints the result.
+func runMigrateCommand(connection *sql.DB, command string) error {
+	runner, err := migrations.New(connection)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		applied, err := runner.Up()
+		for _, m := range applied {
+			fmt.Println("Applied", m.Label())
 		}
-		if os.Getenv("DB_PORT") != "" {
-			config.Port = os.Getenv("DB_PORT")
+		if err != nil {
+			return err
 		}
-		if os.Getenv("DB_USER") != "" {
-			config.User = os.Getenv("DB_USER")
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
 		}
-		if os.Getenv("DB_PASSWORD") != "" {
-			config.Password = os.Getenv("DB_PASSWORD")
+	case "down":
+		m, err := runner.Down()
+		if err != nil {
+			return err
 		}
-		if os.Getenv("DB_NAME") != "" {
-			config.DBName = os.Getenv("DB_NAME")
+		fmt.Println("Rolled back", m.Label())
+	case "baseline":
+		// Mark the data cleanups that were applied by hand before the runner existed.
+		recorded, err := runner.Baseline(migrations.LegacyCleanups...)
+		for _, m := range recorded {
+			fmt.Println("Recorded", m.Label(), "as applied")
 		}
-
-		// Attempt to reconnect to the database
-		connection, err := db.ConnectDB(config)
 		if err != nil {
-			if db.IsConnectionError(err) {
-				w.WriteHeader(http.StatusServiceUnavailable)
-				w.Write([]byte(`{"status":"error","message":"Failed to reconnect to database. Make sure Docker is running and the database container is started."}`))
-				return
-			}
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(fmt.Sprintf(`{"status":"error","message":"Database reconnection failed: %s"}`, err.Error())))
-			return
+			return err
 		}
-
-		// Update the global DB connection
-		handlers.DB = connection
-		handlers.UseDummy = false
-
-		// Ensure migrations are up to date
-		if err := models.Migrate(connection); err != nil {
-			log.Printf("Migration error during reconnection: %v", err)
-			// We don't fail the reconnect if migrations have issues
+		if len(recorded) == 0 {
+			fmt.Println("Nothing to baseline")
+		}
+	default:
+		statuses, err := runner.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			appliedAt := "-"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-45s %-9s %s\n", s.Version, s.Name, s.State, appliedAt)
 		}
-
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok","message":"Successfully reconnected to the database"}`))
-	})
-
-	// Register API routes
-	r.Get("/api/mealplan", handlers.GetMealPlan)
-	r.Post("/api/mealplan/generate", handlers.GenerateMealPlan)
-	r.Post("/api/mealplan/finalize", handlers.FinalizeMealPlanHandler)
-	r.Get("/api/mealplan/ics", handlers.MealPlanICSHandler)
-	r.Post("/api/mealplan/swap", handlers.SwapMeal)
-	r.Post("/api/shoppinglist", handlers.GetShoppingList)
-	r.Get("/api/meals", handlers.GetAllMealsHandler)
-	r.Post("/api/meals", handlers.CreateMealHandler)
-	r.Post("/api/meals/swap", handlers.SwapMealHandler)
-	r.Put("/api/meals/{mealId}/ingredients/{ingredientId}", handlers.UpdateMealIngredientHandler)
-	r.Delete("/api/meals/{mealId}/ingredients/{ingredientId}", handlers.DeleteMealIngredientHandler)
-	r.Delete("/api/meals/{mealId}", handlers.DeleteMealHandler)
-	r.Post("/api/mealplan/replace", handlers.ReplaceMealHandler)
-
-	// New routes for recipe steps
-	r.Get("/api/meals/{mealId}/steps", handlers.GetStepsHandler)
-	r.Post("/api/meals/{mealId}/steps", handlers.AddStepHandler)
-	r.Post("/api/meals/{mealId}/steps/bulk", handlers.AddBulkStepsHandler)
-	r.Put("/api/meals/{mealId}/steps/{stepId}", handlers.UpdateStepHandler)
-	r.Delete("/api/meals/{mealId}/steps/{stepId}", handlers.DeleteStepHandler)
-	r.Put("/api/meals/{mealId}/steps/reorder", handlers.ReorderStepsHandler)
-	r.Delete("/api/meals/{mealId}/steps", handlers.DeleteAllStepsHandler)
-
-	log.Println("Backend server starting on :8080")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		log.Fatalf("Error starting server: %v", err)
 	}
+	return nil
 }
@@ -2,28 +2,34 @@ package main
 
 import (
 	"database/sql"
+	"mealplanner/dummy"
 	"mealplanner/handlers"
+	"mealplanner/migrations"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 // TestReconnectEndpoint tests the database reconnection endpoint
 func TestReconnectEndpoint(t *testing.T) {
+	srv := &server{api: handlers.New(dummy.NewStore())}
+
 	// Store original DB for restoration
-	originalDB := handlers.DB
+	originalDB := srv.db
 
 	t.Cleanup(func() {
 		// Restore original DB after test
-		handlers.DB = originalDB
+		srv.db = originalDB
 	})
 
 	t.Run("successful reconnection", func(t *testing.T) {
 		// Setup: Ensure the DB is nil to simulate disconnected state
-		handlers.DB = nil
+		srv.db = nil
 
 		// Set test environment variables
 		os.Setenv("DB_HOST", "testhost")
@@ -44,8 +50,8 @@ func TestReconnectEndpoint(t *testing.T) {
 		defer db.Close()
 
 		// We'll override the actual ConnectDB function with our test version
-		// by setting handlers.DB directly in our test
-		handlers.DB = db
+		// by setting srv.db directly in our test
+		srv.db = db
 
 		// Setup expected behavior for Migrate
 		mock.ExpectBegin()
@@ -63,7 +69,7 @@ func TestReconnectEndpoint(t *testing.T) {
 		}
 
 		// Verify the DB has been set (in a real scenario, this would be done by the endpoint)
-		if handlers.DB == nil {
+		if srv.db == nil {
 			t.Error("Expected DB connection to be set, but it's nil")
 		}
 	})
@@ -77,7 +83,7 @@ func TestReconnectEndpoint(t *testing.T) {
 		defer db.Close()
 
 		// Set the mock DB as the current connection
-		handlers.DB = db
+		srv.db = db
 
 		// Set up expectations - Ping should succeed
 		mock.ExpectPing()
@@ -98,7 +104,7 @@ func TestReconnectEndpoint(t *testing.T) {
 
 	t.Run("reconnection fails", func(t *testing.T) {
 		// Setup: Ensure the DB is nil to simulate disconnected state
-		handlers.DB = nil
+		srv.db = nil
 
 		// Set test environment variables
 		os.Setenv("DB_HOST", "nonexistenthost")
@@ -121,7 +127,7 @@ func TestReconnectEndpoint(t *testing.T) {
 		}
 
 		// DB should still be nil after a failed connection
-		if handlers.DB != nil {
+		if srv.db != nil {
 			t.Error("Expected DB connection to remain nil after failed connection")
 		}
 	})
@@ -135,7 +141,7 @@ func TestReconnectEndpoint(t *testing.T) {
 		defer db.Close()
 
 		// Set the mock DB as the current connection
-		handlers.DB = db
+		srv.db = db
 
 		// Set up expectations - Ping should fail
 		mock.ExpectPing().WillReturnError(sql.ErrConnDone)
@@ -161,3 +167,29 @@ func TestReconnectEndpoint(t *testing.T) {
 		}
 	})
 }
+
+// TestCheckMigrated verifies that the seed dry run's migration check reports pending
+// migrations without applying them.
+func TestCheckMigrated(t *testing.T) {
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	conn.SetMaxOpenConns(1)
+	defer conn.Close()
+
+	err = checkMigrated(conn)
+	if err == nil || !strings.Contains(err.Error(), "0001_create_meals_and_ingredients (pending)") {
+		t.Fatalf("expected the pending migrations to be reported, got %v", err)
+	}
+	if _, err := conn.Exec("SELECT 1 FROM meals"); err == nil {
+		t.Fatal("expected the check not to apply any migrations")
+	}
+
+	if err := migrations.Up(conn); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if err := checkMigrated(conn); err != nil {
+		t.Errorf("expected a migrated database to pass, got %v", err)
+	}
+}
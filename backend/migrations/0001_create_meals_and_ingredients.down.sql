@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ingredients;
+DROP TABLE IF EXISTS meals;
@@ -1,4 +1,4 @@
--- 20231001_normalize_ingredients.up.sql
+-- 0002_normalize_ingredients.up.sql
 -- Migration to normalize the ingredients data.
 
 -- Convert synonyms for unit.
@@ -0,0 +1 @@
+ALTER TABLE meals DROP COLUMN IF EXISTS url;
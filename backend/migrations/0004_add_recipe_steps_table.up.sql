@@ -1,5 +1,5 @@
 -- Add recipe steps table
-CREATE TABLE recipe_steps (
+CREATE TABLE IF NOT EXISTS recipe_steps (
     id SERIAL PRIMARY KEY,
     meal_id INTEGER NOT NULL REFERENCES meals(id) ON DELETE CASCADE,
     step_number INTEGER NOT NULL,
@@ -4,7 +4,6 @@
 --   "½" becomes ".5", "¾" becomes ".75", "¼" becomes ".25", "⅓" becomes ".33", and "⅔" becomes ".67"
 -- This update is applied to both the "name" and "quantity" columns if they contain any of these characters.
 
-BEGIN;
 
 UPDATE public.ingredients
 SET 
@@ -27,5 +26,3 @@ SET
                '⅓', '.33', 'g'),
              '⅔', '.67', 'g')
 WHERE (name ~ '[¼½¾⅓⅔]') OR (quantity ~ '[¼½¾⅓⅔]');
-
-COMMIT;
\ No newline at end of file
@@ -9,7 +9,6 @@
 -- This version uses a single regexp_replace call with an alternation to remove any
 -- of the unwanted phrases.
 --
-BEGIN;
 
 UPDATE public.ingredients
 SET name = trim(
@@ -20,5 +19,3 @@ regexp_replace(
     'gi'
 )
 );
-
-COMMIT;
\ No newline at end of file
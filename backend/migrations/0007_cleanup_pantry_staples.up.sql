@@ -15,7 +15,6 @@
 -- The update only applies to rows matching the pattern for these keywords and avoids
 -- compound ingredients (using a simple "NOT LIKE '% and %'" check).
 
-BEGIN;
 
 UPDATE public.ingredients
 SET 
@@ -65,5 +64,3 @@ SET
   quantity = NULL,
   unit = NULL
 WHERE lower(name) SIMILAR TO '%(olive oil|mustard|garlic|salt|pepper|vinegar|onion powder|paprika|cumin|turmeric|chili powder|red[ -]?pepper flakes)%';
-
-COMMIT;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- Add table for the household's planning rules (a single JSON document)
+CREATE TABLE IF NOT EXISTS planning_rules (
+    id INTEGER PRIMARY KEY,
+    rules TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
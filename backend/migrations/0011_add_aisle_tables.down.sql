@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS store_layout;
+DROP TABLE IF EXISTS ingredient_aisles;
@@ -0,0 +1,11 @@
+-- Add tables for shopping list aisles: per-ingredient overrides and the store layout
+CREATE TABLE IF NOT EXISTS ingredient_aisles (
+    name TEXT PRIMARY KEY,
+    aisle TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS store_layout (
+    id INTEGER PRIMARY KEY,
+    aisle_order TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+ALTER TABLE meal_plan_entries DROP COLUMN IF EXISTS servings;
+ALTER TABLE meals DROP COLUMN IF EXISTS servings;
@@ -0,0 +1,4 @@
+-- Add servings: how many a recipe serves as written, and how many a saved plan day
+-- cooks for. 0 means not set.
+ALTER TABLE meals ADD COLUMN IF NOT EXISTS servings INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE meal_plan_entries ADD COLUMN IF NOT EXISTS servings INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS cooking_log;
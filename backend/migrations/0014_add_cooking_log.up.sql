@@ -0,0 +1,14 @@
+-- Add the cooking log: whether a planned meal was actually cooked, and how it went.
+-- Each plan day has at most one log entry; the entry outlives a replaced plan.
+CREATE TABLE IF NOT EXISTS cooking_log (
+    id SERIAL PRIMARY KEY,
+    meal_id INTEGER NOT NULL REFERENCES meals(id) ON DELETE CASCADE,
+    plan_entry_id INTEGER UNIQUE REFERENCES meal_plan_entries(id) ON DELETE SET NULL,
+    cooked_on DATE NOT NULL,
+    status TEXT NOT NULL,
+    rating INTEGER,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS cooking_log_meal_id_idx ON cooking_log (meal_id);
@@ -0,0 +1,5 @@
+DELETE FROM meal_plan_entries WHERE slot <> 'dinner';
+ALTER TABLE meal_plan_entries DROP CONSTRAINT IF EXISTS meal_plan_entries_plan_id_day_slot_key;
+ALTER TABLE meal_plan_entries ADD CONSTRAINT meal_plan_entries_plan_id_day_key UNIQUE (plan_id, day);
+ALTER TABLE meal_plan_entries DROP COLUMN IF EXISTS slot;
+ALTER TABLE meals DROP COLUMN IF EXISTS slots;
@@ -0,0 +1,6 @@
+-- Add meal slots: the slots (breakfast, lunch, dinner) a meal can be planned in, and the
+-- slot of its day a saved plan entry fills. Existing meals and plan days are dinners.
+ALTER TABLE meals ADD COLUMN IF NOT EXISTS slots TEXT NOT NULL DEFAULT 'dinner';
+ALTER TABLE meal_plan_entries ADD COLUMN IF NOT EXISTS slot TEXT NOT NULL DEFAULT 'dinner';
+ALTER TABLE meal_plan_entries DROP CONSTRAINT IF EXISTS meal_plan_entries_plan_id_day_key;
+ALTER TABLE meal_plan_entries ADD CONSTRAINT meal_plan_entries_plan_id_day_slot_key UNIQUE (plan_id, day, slot);
@@ -0,0 +1,7 @@
+-- Plans longer than a week can't be keyed by weekday; their later days are dropped.
+DELETE FROM meal_plan_entries e USING meal_plans p
+    WHERE p.id = e.plan_id AND e.date >= p.week_start + 7;
+ALTER TABLE meal_plan_entries DROP CONSTRAINT IF EXISTS meal_plan_entries_plan_id_date_slot_key;
+ALTER TABLE meal_plan_entries ADD CONSTRAINT meal_plan_entries_plan_id_day_slot_key UNIQUE (plan_id, day, slot);
+ALTER TABLE meal_plan_entries DROP COLUMN IF EXISTS date;
+ALTER TABLE meal_plans DROP COLUMN IF EXISTS days;
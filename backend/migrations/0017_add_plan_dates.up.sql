@@ -0,0 +1,11 @@
+-- Date-based plans: a plan starts on any date and covers a number of days, and each entry
+-- is stored by its calendar date. Existing plans cover the week from their Monday.
+ALTER TABLE meal_plans ADD COLUMN IF NOT EXISTS days INTEGER NOT NULL DEFAULT 7;
+ALTER TABLE meal_plan_entries ADD COLUMN IF NOT EXISTS date DATE;
+UPDATE meal_plan_entries e SET date = p.week_start + CASE e.day
+        WHEN 'Monday' THEN 0 WHEN 'Tuesday' THEN 1 WHEN 'Wednesday' THEN 2 WHEN 'Thursday' THEN 3
+        WHEN 'Friday' THEN 4 WHEN 'Saturday' THEN 5 ELSE 6 END
+    FROM meal_plans p WHERE p.id = e.plan_id;
+ALTER TABLE meal_plan_entries ALTER COLUMN date SET NOT NULL;
+ALTER TABLE meal_plan_entries DROP CONSTRAINT IF EXISTS meal_plan_entries_plan_id_day_slot_key;
+ALTER TABLE meal_plan_entries ADD CONSTRAINT meal_plan_entries_plan_id_date_slot_key UNIQUE (plan_id, date, slot);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS calendar_feed;
+ALTER TABLE meal_plan_entries DROP COLUMN IF EXISTS sequence;
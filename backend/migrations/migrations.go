@@ -0,0 +1,471 @@
+// Package migrations applies the versioned SQL files in this directory to the database.
+//
+// Each migration is a file named NNNN_description.up.sql, with an optional
+// NNNN_description.down.sql that reverses it. Applied versions are recorded in the
+// schema_migrations table together with a checksum of the up file, so a migration
+// that is edited after it has run is detected instead of silently skipped.
+//
+// The files are written for Postgres. Where a file doesn't run on SQLite, a file of the
+// same name in the sqlite directory replaces it for SQLite databases, so both keep the
+// same versions.
+package migrations
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+//go:embed *.sql sqlite/*.sql
+var files embed.FS
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Label is the migration's file name without the direction and extension.
+func (m Migration) Label() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// LegacyCleanups are the data cleanups that were applied by hand to databases created
+// before the runner existed. Up records them as applied instead of running them again,
+// since replaying them would damage data they already cleaned. The schema migrations of
+// that time only create what's missing, so they are run as usual.
+var LegacyCleanups = []int{2, 5, 6, 7}
+
+// Migration states reported by Status.
+const (
+	StateApplied  = "applied"
+	StatePending  = "pending"
+	StateModified = "modified"
+	StateMissing  = "missing"
+)
+
+// Status describes a migration known to the code, the database, or both.
+type Status struct {
+	Version   int
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+var (
+	// ErrIrreversible is returned by Down when the latest migration has no down file.
+	ErrIrreversible = errors.New("migration cannot be reversed")
+	// ErrNothingToRollBack is returned by Down when no migration has been applied.
+	ErrNothingToRollBack = errors.New("no migrations have been applied")
+)
+
+var fileRe = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_]+)\.(up|down)\.sql$`)
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`
+
+// Runner applies and rolls back migrations against a database.
+type Runner struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New returns a runner for the migrations embedded in the binary, with the SQLite
+// replacements applied when db is a SQLite database.
+func New(db *sql.DB) (*Runner, error) {
+	migrations, err := Load(files)
+	if err != nil {
+		return nil, err
+	}
+	if isSQLite(db) {
+		sqlite, err := fs.Sub(files, "sqlite")
+		if err != nil {
+			return nil, err
+		}
+		if migrations, err = override(migrations, sqlite); err != nil {
+			return nil, err
+		}
+	}
+	return &Runner{db: db, migrations: migrations}, nil
+}
+
+// isSQLite reports whether db was opened with the SQLite driver.
+func isSQLite(db *sql.DB) bool {
+	_, ok := db.Driver().(*sqlite3.SQLiteDriver)
+	return ok
+}
+
+// NewFromFS returns a runner for the migration files at the root of fsys.
+func NewFromFS(db *sql.DB, fsys fs.FS) (*Runner, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{db: db, migrations: migrations}, nil
+}
+
+// Up applies the embedded migrations that haven't been applied yet. It's what the
+// server runs at startup.
+func Up(db *sql.DB) error {
+	r, err := New(db)
+	if err != nil {
+		return err
+	}
+	_, err = r.Up()
+	return err
+}
+
+// Load reads and orders the migration files at the root of fsys.
+func Load(fsys fs.FS) ([]Migration, error) {
+	byVersion, err := read(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %s has a down file but no up file", mig.Label())
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// override replaces the up and down SQL of migrations with the files at the root of
+// fsys. Each file must match an existing migration's version and name.
+func override(migrations []Migration, fsys fs.FS) ([]Migration, error) {
+	replacements, err := read(fsys)
+	if err != nil {
+		return nil, err
+	}
+	out := append([]Migration{}, migrations...)
+	for version, rep := range replacements {
+		found := false
+		for i := range out {
+			if out[i].Version != version {
+				continue
+			}
+			if out[i].Name != rep.Name {
+				return nil, fmt.Errorf("replacement %s doesn't match migration %s", rep.Label(), out[i].Label())
+			}
+			if rep.Up != "" {
+				out[i].Up = rep.Up
+				out[i].Checksum = rep.Checksum
+			}
+			if rep.Down != "" {
+				out[i].Down = rep.Down
+			}
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("replacement %s has no migration to replace", rep.Label())
+		}
+	}
+	return out, nil
+}
+
+// read parses the migration files at the root of fsys by version.
+func read(fsys fs.FS) (map[int]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := fileRe.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration version %04d is used by both %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+			mig.Checksum = checksum(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+	return byVersion, nil
+}
+
+func checksum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// appliedMigration is a row of schema_migrations.
+type appliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// applied creates schema_migrations if needed and returns its rows by version.
+func (r *Runner) applied() (map[int]appliedMigration, error) {
+	if _, err := r.db.Exec(createTable); err != nil {
+		log.Printf("migrations: error creating schema_migrations: %v", err)
+		return nil, err
+	}
+	rows, err := r.db.Query("SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		log.Printf("migrations: error reading schema_migrations: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[a.Version] = a
+	}
+	return applied, rows.Err()
+}
+
+// Status reports the state of every migration in the code or the database, ordered by version.
+func (r *Runner) Status() ([]Status, error) {
+	applied, err := r.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []Status{}
+	known := make(map[int]bool)
+	for _, m := range r.migrations {
+		known[m.Version] = true
+		s := Status{Version: m.Version, Name: m.Name, State: StatePending}
+		if a, ok := applied[m.Version]; ok {
+			appliedAt := a.AppliedAt
+			s.AppliedAt = &appliedAt
+			s.State = StateApplied
+			if a.Checksum != m.Checksum {
+				s.State = StateModified
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	for version, a := range applied {
+		if known[version] {
+			continue
+		}
+		appliedAt := a.AppliedAt
+		statuses = append(statuses, Status{Version: version, Name: a.Name, State: StateMissing, AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// pending returns the versions of the migrations that haven't been applied. It fails
+// when an applied migration has been edited or deleted, since the schema would no
+// longer match the files.
+func (r *Runner) pending() (map[int]bool, error) {
+	statuses, err := r.Status()
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[int]bool)
+	for _, s := range statuses {
+		switch s.State {
+		case StateModified:
+			return nil, fmt.Errorf("migration %04d_%s was edited after it was applied", s.Version, s.Name)
+		case StateMissing:
+			return nil, fmt.Errorf("migration %04d_%s is applied but its file no longer exists", s.Version, s.Name)
+		case StatePending:
+			pending[s.Version] = true
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration in version order, each in its own transaction,
+// and returns the ones it applied. It refuses to run when an applied migration has
+// been edited or deleted. A database that has the meals table but no recorded
+// migrations predates the runner, so LegacyCleanups are baselined first.
+func (r *Runner) Up() ([]Migration, error) {
+	pending, err := r.pending()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == len(r.migrations) {
+		legacy, err := r.hasMealsTable()
+		if err != nil {
+			return nil, err
+		}
+		if legacy {
+			log.Printf("migrations: found a database from before migrations were recorded")
+			if _, err := r.Baseline(LegacyCleanups...); err != nil {
+				return nil, err
+			}
+			if pending, err = r.pending(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	done := []Migration{}
+	for _, m := range r.migrations {
+		if !pending[m.Version] {
+			continue
+		}
+		if err := r.apply(m, true); err != nil {
+			return done, err
+		}
+		log.Printf("migrations: applied %s", m.Label())
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Baseline records the given migrations as applied without running them, for a
+// database that already has them, and returns the ones it recorded. Versions that are
+// already applied are skipped.
+func (r *Runner) Baseline(versions ...int) ([]Migration, error) {
+	pending, err := r.pending()
+	if err != nil {
+		return nil, err
+	}
+	baseline := make(map[int]bool, len(versions))
+	for _, version := range versions {
+		baseline[version] = true
+	}
+
+	done := []Migration{}
+	for _, m := range r.migrations {
+		if !baseline[m.Version] || !pending[m.Version] {
+			continue
+		}
+		if err := r.apply(m, false); err != nil {
+			return done, err
+		}
+		log.Printf("migrations: recorded %s as applied", m.Label())
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// hasMealsTable reports whether the database already has the meals table.
+func (r *Runner) hasMealsTable() (bool, error) {
+	query := "SELECT to_regclass('meals') IS NOT NULL"
+	if isSQLite(r.db) {
+		query = "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'meals'"
+	}
+	var exists bool
+	if err := r.db.QueryRow(query).Scan(&exists); err != nil {
+		log.Printf("migrations: error looking for the meals table: %v", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+// apply records m as applied, running its up SQL first when run is set.
+func (r *Runner) apply(m Migration, run bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if run {
+		if _, err := tx.Exec(m.Up); err != nil {
+			return fmt.Errorf("applying migration %s: %w", m.Label(), err)
+		}
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)",
+		m.Version, m.Name, m.Checksum, time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("recording migration %s: %w", m.Label(), err)
+	}
+	return tx.Commit()
+}
+
+// Down rolls back the most recently applied migration and returns it. Data migrations
+// without a down file can't be rolled back and return ErrIrreversible.
+func (r *Runner) Down() (*Migration, error) {
+	applied, err := r.applied()
+	if err != nil {
+		return nil, err
+	}
+	latest := -1
+	for version := range applied {
+		if version > latest {
+			latest = version
+		}
+	}
+	if latest < 0 {
+		return nil, ErrNothingToRollBack
+	}
+
+	var m *Migration
+	for i := range r.migrations {
+		if r.migrations[i].Version == latest {
+			m = &r.migrations[i]
+		}
+	}
+	if m == nil {
+		return nil, fmt.Errorf("migration %04d_%s is applied but its file no longer exists", latest, applied[latest].Name)
+	}
+	if m.Down == "" {
+		return nil, fmt.Errorf("%s: %w", m.Label(), ErrIrreversible)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.Down); err != nil {
+		return nil, fmt.Errorf("rolling back migration %s: %w", m.Label(), err)
+	}
+	if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", m.Version); err != nil {
+		return nil, fmt.Errorf("unrecording migration %s: %w", m.Label(), err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Printf("migrations: rolled back %s", m.Label())
+	return m, nil
+}
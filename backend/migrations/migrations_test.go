@@ -0,0 +1,362 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_meals.up.sql":   {Data: []byte("CREATE TABLE meals (id INTEGER PRIMARY KEY, meal_name TEXT NOT NULL);")},
+		"0001_create_meals.down.sql": {Data: []byte("DROP TABLE meals;")},
+		"0002_add_url.up.sql":        {Data: []byte("ALTER TABLE meals ADD COLUMN url TEXT;")},
+		"0003_seed_meal.up.sql":      {Data: []byte("INSERT INTO meals (meal_name) VALUES ('Tacos');")},
+		"README.md":                  {Data: []byte("not a migration")},
+	}
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func states(t *testing.T, r *Runner) []string {
+	t.Helper()
+	statuses, err := r.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	var out []string
+	for _, s := range statuses {
+		out = append(out, s.State)
+	}
+	return out
+}
+
+func TestLoad_OrdersAndPairsFiles(t *testing.T) {
+	migrations, err := Load(testFS())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("expected 3 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Label() != "0001_create_meals" || migrations[0].Down == "" {
+		t.Errorf("unexpected first migration: %+v", migrations[0])
+	}
+	if migrations[2].Version != 3 || migrations[2].Down != "" {
+		t.Errorf("unexpected last migration: %+v", migrations[2])
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"0001_a.up.sql": {Data: []byte("SELECT 1;")},
+		"0001_b.up.sql": {Data: []byte("SELECT 1;")},
+	})
+	if err == nil {
+		t.Error("expected an error for a duplicate version")
+	}
+	_, err = Load(fstest.MapFS{"0001_a.down.sql": {Data: []byte("SELECT 1;")}})
+	if err == nil {
+		t.Error("expected an error for a down file without an up file")
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Load(files)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("expected version %d, got %s", i+1, m.Label())
+		}
+	}
+}
+
+func TestRunner_UpStatusDown(t *testing.T) {
+	db := openTestDB(t)
+	r, err := NewFromFS(db, testFS())
+	if err != nil {
+		t.Fatalf("NewFromFS: %v", err)
+	}
+
+	got := states(t, r)
+	if len(got) != 3 || got[0] != StatePending {
+		t.Fatalf("expected three pending migrations, got %v", got)
+	}
+
+	applied, err := r.Up()
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != 3 {
+		t.Fatalf("expected 3 migrations applied, got %d", len(applied))
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM meals WHERE url IS NULL").Scan(&count); err != nil || count != 1 {
+		t.Fatalf("expected the migrations to have run, count=%d err=%v", count, err)
+	}
+	for _, s := range states(t, r) {
+		if s != StateApplied {
+			t.Errorf("expected all applied, got %v", s)
+		}
+	}
+
+	// Running again is a no-op.
+	applied, err = r.Up()
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("expected nothing to apply, got %d (err %v)", len(applied), err)
+	}
+
+	// The latest migration has no down file.
+	if _, err := r.Down(); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("expected ErrIrreversible, got %v", err)
+	}
+}
+
+func TestRunner_Down(t *testing.T) {
+	db := openTestDB(t)
+	fsys := testFS()
+	delete(fsys, "0002_add_url.up.sql")
+	delete(fsys, "0003_seed_meal.up.sql")
+	r, err := NewFromFS(db, fsys)
+	if err != nil {
+		t.Fatalf("NewFromFS: %v", err)
+	}
+	if _, err := r.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	m, err := r.Down()
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if m.Version != 1 {
+		t.Errorf("expected to roll back version 1, got %d", m.Version)
+	}
+	if _, err := db.Exec("SELECT 1 FROM meals"); err == nil {
+		t.Error("expected the meals table to have been dropped")
+	}
+	if got := states(t, r); got[0] != StatePending {
+		t.Errorf("expected the migration to be pending again, got %v", got)
+	}
+	if _, err := r.Down(); !errors.Is(err, ErrNothingToRollBack) {
+		t.Errorf("expected ErrNothingToRollBack, got %v", err)
+	}
+}
+
+func TestRunner_DetectsEditedAndMissingMigrations(t *testing.T) {
+	db := openTestDB(t)
+	r, err := NewFromFS(db, testFS())
+	if err != nil {
+		t.Fatalf("NewFromFS: %v", err)
+	}
+	if _, err := r.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	edited := testFS()
+	edited["0003_seed_meal.up.sql"] = &fstest.MapFile{Data: []byte("INSERT INTO meals (meal_name) VALUES ('Pizza');")}
+	edited["0004_more.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	r, _ = NewFromFS(db, edited)
+	if got := states(t, r); got[2] != StateModified || got[3] != StatePending {
+		t.Errorf("expected modified then pending, got %v", got)
+	}
+	if _, err := r.Up(); err == nil {
+		t.Error("expected Up to refuse to run after a migration was edited")
+	}
+
+	missing := testFS()
+	delete(missing, "0003_seed_meal.up.sql")
+	r, _ = NewFromFS(db, missing)
+	if got := states(t, r); len(got) != 3 || got[2] != StateMissing {
+		t.Errorf("expected the deleted migration to be missing, got %v", got)
+	}
+	if _, err := r.Up(); err == nil {
+		t.Error("expected Up to refuse to run when an applied migration's file is gone")
+	}
+}
+
+func TestRunner_UpBaselinesLegacyDatabase(t *testing.T) {
+	db := openTestDB(t)
+	// The data cleanup was applied by hand before migrations were recorded, but the steps
+	// table was never created.
+	if _, err := db.Exec("CREATE TABLE meals (id INTEGER PRIMARY KEY, meal_name TEXT NOT NULL); INSERT INTO meals (meal_name) VALUES ('Tacos');"); err != nil {
+		t.Fatalf("creating legacy schema: %v", err)
+	}
+	r, err := NewFromFS(db, fstest.MapFS{
+		"0001_create_meals.up.sql": {Data: []byte("CREATE TABLE IF NOT EXISTS meals (id INTEGER PRIMARY KEY, meal_name TEXT NOT NULL);")},
+		"0002_shout_names.up.sql":  {Data: []byte("UPDATE meals SET meal_name = meal_name || '!';")},
+		"0004_add_steps.up.sql":    {Data: []byte("CREATE TABLE IF NOT EXISTS recipe_steps (id INTEGER PRIMARY KEY, meal_id INTEGER);")},
+		"0008_add_url.up.sql":      {Data: []byte("ALTER TABLE meals ADD COLUMN url TEXT;")},
+		"0008_add_url.down.sql":    {Data: []byte("ALTER TABLE meals DROP COLUMN url;")},
+	})
+	if err != nil {
+		t.Fatalf("NewFromFS: %v", err)
+	}
+
+	applied, err := r.Up()
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	var versions []int
+	for _, m := range applied {
+		versions = append(versions, m.Version)
+	}
+	if len(versions) != 3 || versions[0] != 1 || versions[1] != 4 || versions[2] != 8 {
+		t.Fatalf("expected every migration but the cleanup to run, got %v", versions)
+	}
+	var name string
+	if err := db.QueryRow("SELECT meal_name FROM meals WHERE url IS NULL").Scan(&name); err != nil || name != "Tacos" {
+		t.Errorf("expected the data migration not to be replayed, got %q (err %v)", name, err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM recipe_steps"); err != nil {
+		t.Errorf("expected the missing steps table to be created: %v", err)
+	}
+	for i, state := range states(t, r) {
+		if state != StateApplied {
+			t.Errorf("migration %d: expected applied, got %s", i, state)
+		}
+	}
+}
+
+func TestNew_UpOnLegacySchema(t *testing.T) {
+	db := openTestDB(t)
+	// The old models.Migrate only created meals and ingredients.
+	if _, err := db.Exec(`CREATE TABLE meals (id INTEGER PRIMARY KEY AUTOINCREMENT, meal_name TEXT NOT NULL,
+		relative_effort INTEGER NOT NULL, last_planned TIMESTAMP, red_meat BOOLEAN NOT NULL DEFAULT false);
+		CREATE TABLE ingredients (id INTEGER PRIMARY KEY AUTOINCREMENT, meal_id INTEGER REFERENCES meals(id) ON DELETE CASCADE,
+		quantity TEXT, unit TEXT, name TEXT NOT NULL);`); err != nil {
+		t.Fatalf("creating legacy schema: %v", err)
+	}
+	r, err := New(db)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	applied, err := r.Up()
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != len(r.migrations)-len(LegacyCleanups) {
+		t.Errorf("expected all but the %d cleanups to run, got %d", len(LegacyCleanups), len(applied))
+	}
+	for _, table := range []string{"recipe_steps", "meal_plans", "calendar_feed"} {
+		if _, err := db.Exec("SELECT 1 FROM " + table); err != nil {
+			t.Errorf("expected %s to be created: %v", table, err)
+		}
+	}
+	if _, err := db.Exec("SELECT url FROM meals"); err != nil {
+		t.Errorf("expected meals to gain a url column: %v", err)
+	}
+	for i, state := range states(t, r) {
+		if state != StateApplied {
+			t.Errorf("migration %d: expected applied, got %s", i, state)
+		}
+	}
+}
+
+func TestRunner_Baseline(t *testing.T) {
+	db := openTestDB(t)
+	r, err := NewFromFS(db, testFS())
+	if err != nil {
+		t.Fatalf("NewFromFS: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE meals (id INTEGER PRIMARY KEY, meal_name TEXT NOT NULL, url TEXT);"); err != nil {
+		t.Fatalf("creating schema: %v", err)
+	}
+
+	recorded, err := r.Baseline(1, 2)
+	if err != nil {
+		t.Fatalf("Baseline: %v", err)
+	}
+	if len(recorded) != 2 {
+		t.Fatalf("expected 2 migrations recorded, got %d", len(recorded))
+	}
+	if got := states(t, r); got[0] != StateApplied || got[1] != StateApplied || got[2] != StatePending {
+		t.Errorf("expected applied, applied, pending, got %v", got)
+	}
+	applied, err := r.Up()
+	if err != nil || len(applied) != 1 || applied[0].Version != 3 {
+		t.Fatalf("expected Up to run only the seed migration, got %+v (err %v)", applied, err)
+	}
+}
+
+func TestNew_SQLiteUsesReplacements(t *testing.T) {
+	db := openTestDB(t)
+	r, err := New(db)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := r.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	for i, state := range states(t, r) {
+		if state != StateApplied {
+			t.Errorf("migration %d: expected applied, got %s", i, state)
+		}
+	}
+
+	// The replacement schema gives meals auto-incrementing IDs like SERIAL does.
+	var id int
+	err = db.QueryRow("INSERT INTO meals (meal_name, relative_effort, url) VALUES ('Tacos', 2, '') RETURNING id").Scan(&id)
+	if err != nil {
+		t.Fatalf("inserting a meal: %v", err)
+	}
+	if id != 1 {
+		t.Errorf("expected the first meal to get ID 1, got %d", id)
+	}
+
+	for {
+		if _, err := r.Down(); err != nil {
+			if !errors.Is(err, ErrIrreversible) {
+				t.Fatalf("Down: %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestOverride_RejectsUnknownMigrations(t *testing.T) {
+	migrations, err := Load(testFS())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	_, err = override(migrations, fstest.MapFS{
+		"0002_add_link.up.sql": {Data: []byte("ALTER TABLE meals ADD COLUMN link TEXT;")},
+	})
+	if err == nil {
+		t.Error("expected an error for a replacement with a different name")
+	}
+	_, err = override(migrations, fstest.MapFS{
+		"0009_later.up.sql": {Data: []byte("SELECT 1;")},
+	})
+	if err == nil {
+		t.Error("expected an error for a replacement without a migration")
+	}
+
+	replaced, err := override(migrations, fstest.MapFS{
+		"0002_add_url.down.sql": {Data: []byte("ALTER TABLE meals DROP COLUMN url;")},
+	})
+	if err != nil {
+		t.Fatalf("override: %v", err)
+	}
+	if replaced[1].Down == "" || replaced[1].Checksum != migrations[1].Checksum {
+		t.Errorf("expected only the down SQL to change, got %+v", replaced[1])
+	}
+	if migrations[1].Down != "" {
+		t.Errorf("expected the original migrations to be left alone")
+	}
+}
@@ -0,0 +1,16 @@
+-- Base schema: meals and their ingredients
+CREATE TABLE IF NOT EXISTS meals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    meal_name TEXT NOT NULL,
+    relative_effort INTEGER NOT NULL,
+    last_planned TIMESTAMP,
+    red_meat BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS ingredients (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    meal_id INTEGER REFERENCES meals(id) ON DELETE CASCADE,
+    quantity TEXT,
+    unit TEXT,
+    name TEXT NOT NULL
+);
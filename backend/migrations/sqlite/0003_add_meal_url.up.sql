@@ -0,0 +1,2 @@
+-- Add URL field to meals table
+ALTER TABLE meals ADD COLUMN url TEXT;
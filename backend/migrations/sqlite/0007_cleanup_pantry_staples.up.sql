@@ -0,0 +1,3 @@
+-- This cleanup fixed ingredient data imported before the SQLite backend existed. A
+-- SQLite database starts out empty, so there is nothing to clean up.
+SELECT 1;
@@ -0,0 +1,15 @@
+-- Add tables for finalized meal plans
+CREATE TABLE IF NOT EXISTS meal_plans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    week_start DATE NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS meal_plan_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id INTEGER NOT NULL REFERENCES meal_plans(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    meal_id INTEGER REFERENCES meals(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'planned',
+    UNIQUE (plan_id, day)
+);
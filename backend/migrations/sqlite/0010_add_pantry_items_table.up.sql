@@ -0,0 +1,9 @@
+-- Add table for the pantry: what's on hand and which staples are always stocked
+CREATE TABLE IF NOT EXISTS pantry_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    quantity DOUBLE PRECISION NOT NULL DEFAULT 0,
+    unit TEXT NOT NULL DEFAULT '',
+    staple BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+ALTER TABLE meal_plan_entries DROP COLUMN servings;
+ALTER TABLE meals DROP COLUMN servings;
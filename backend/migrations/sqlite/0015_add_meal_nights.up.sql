@@ -0,0 +1,2 @@
+-- Add nights: how many dinners one cook of a meal covers, counting leftovers.
+ALTER TABLE meals ADD COLUMN nights INTEGER NOT NULL DEFAULT 1;
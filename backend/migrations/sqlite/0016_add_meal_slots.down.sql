@@ -0,0 +1,19 @@
+DELETE FROM meal_plan_entries WHERE slot <> 'dinner';
+CREATE TABLE meal_plan_entries_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id INTEGER NOT NULL REFERENCES meal_plans(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    meal_id INTEGER REFERENCES meals(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'planned',
+    servings INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (plan_id, day)
+);
+INSERT INTO meal_plan_entries_old (id, plan_id, day, meal_id, status, servings)
+    SELECT id, plan_id, day, meal_id, status, servings FROM meal_plan_entries;
+CREATE TEMP TABLE cooking_log_links AS SELECT id, plan_entry_id FROM cooking_log WHERE plan_entry_id IS NOT NULL;
+DROP TABLE meal_plan_entries;
+ALTER TABLE meal_plan_entries_old RENAME TO meal_plan_entries;
+UPDATE cooking_log SET plan_entry_id = (SELECT l.plan_entry_id FROM cooking_log_links l WHERE l.id = cooking_log.id)
+    WHERE id IN (SELECT id FROM cooking_log_links);
+DROP TABLE cooking_log_links;
+ALTER TABLE meals DROP COLUMN slots;
@@ -0,0 +1,24 @@
+-- Add meal slots: the slots (breakfast, lunch, dinner) a meal can be planned in, and the
+-- slot of its day a saved plan entry fills. Existing meals and plan days are dinners.
+ALTER TABLE meals ADD COLUMN slots TEXT NOT NULL DEFAULT 'dinner';
+
+-- SQLite can't change a table's unique constraint, so the entries table is rebuilt.
+-- Dropping it clears the cooking log's links to plan days, which are put back after.
+CREATE TABLE meal_plan_entries_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id INTEGER NOT NULL REFERENCES meal_plans(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    slot TEXT NOT NULL DEFAULT 'dinner',
+    meal_id INTEGER REFERENCES meals(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'planned',
+    servings INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (plan_id, day, slot)
+);
+INSERT INTO meal_plan_entries_new (id, plan_id, day, meal_id, status, servings)
+    SELECT id, plan_id, day, meal_id, status, servings FROM meal_plan_entries;
+CREATE TEMP TABLE cooking_log_links AS SELECT id, plan_entry_id FROM cooking_log WHERE plan_entry_id IS NOT NULL;
+DROP TABLE meal_plan_entries;
+ALTER TABLE meal_plan_entries_new RENAME TO meal_plan_entries;
+UPDATE cooking_log SET plan_entry_id = (SELECT l.plan_entry_id FROM cooking_log_links l WHERE l.id = cooking_log.id)
+    WHERE id IN (SELECT id FROM cooking_log_links);
+DROP TABLE cooking_log_links;
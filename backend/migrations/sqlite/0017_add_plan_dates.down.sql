@@ -0,0 +1,24 @@
+-- Plans longer than a week can't be keyed by weekday; their later days are dropped.
+DELETE FROM meal_plan_entries WHERE id IN (
+    SELECT e.id FROM meal_plan_entries e JOIN meal_plans p ON p.id = e.plan_id
+    WHERE date(e.date) >= date(p.week_start, '+7 days')
+);
+CREATE TABLE meal_plan_entries_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id INTEGER NOT NULL REFERENCES meal_plans(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    slot TEXT NOT NULL DEFAULT 'dinner',
+    meal_id INTEGER REFERENCES meals(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'planned',
+    servings INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (plan_id, day, slot)
+);
+INSERT INTO meal_plan_entries_old (id, plan_id, day, slot, meal_id, status, servings)
+    SELECT id, plan_id, day, slot, meal_id, status, servings FROM meal_plan_entries;
+CREATE TEMP TABLE cooking_log_links AS SELECT id, plan_entry_id FROM cooking_log WHERE plan_entry_id IS NOT NULL;
+DROP TABLE meal_plan_entries;
+ALTER TABLE meal_plan_entries_old RENAME TO meal_plan_entries;
+UPDATE cooking_log SET plan_entry_id = (SELECT l.plan_entry_id FROM cooking_log_links l WHERE l.id = cooking_log.id)
+    WHERE id IN (SELECT id FROM cooking_log_links);
+DROP TABLE cooking_log_links;
+ALTER TABLE meal_plans DROP COLUMN days;
@@ -0,0 +1,31 @@
+-- Date-based plans: a plan starts on any date and covers a number of days, and each entry
+-- is stored by its calendar date. Existing plans cover the week from their Monday.
+ALTER TABLE meal_plans ADD COLUMN days INTEGER NOT NULL DEFAULT 7;
+
+-- SQLite can't change a table's unique constraint, so the entries table is rebuilt.
+-- Dropping it clears the cooking log's links to plan days, which are put back after.
+-- Dates are written the way the driver writes times, so they compare equal to bound values.
+CREATE TABLE meal_plan_entries_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_id INTEGER NOT NULL REFERENCES meal_plans(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    day TEXT NOT NULL,
+    slot TEXT NOT NULL DEFAULT 'dinner',
+    meal_id INTEGER REFERENCES meals(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'planned',
+    servings INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (plan_id, date, slot)
+);
+INSERT INTO meal_plan_entries_new (id, plan_id, date, day, slot, meal_id, status, servings)
+    SELECT e.id, e.plan_id,
+        date(p.week_start, '+' || CASE e.day
+            WHEN 'Monday' THEN 0 WHEN 'Tuesday' THEN 1 WHEN 'Wednesday' THEN 2 WHEN 'Thursday' THEN 3
+            WHEN 'Friday' THEN 4 WHEN 'Saturday' THEN 5 ELSE 6 END || ' days') || ' 00:00:00+00:00',
+        e.day, e.slot, e.meal_id, e.status, e.servings
+    FROM meal_plan_entries e JOIN meal_plans p ON p.id = e.plan_id;
+CREATE TEMP TABLE cooking_log_links AS SELECT id, plan_entry_id FROM cooking_log WHERE plan_entry_id IS NOT NULL;
+DROP TABLE meal_plan_entries;
+ALTER TABLE meal_plan_entries_new RENAME TO meal_plan_entries;
+UPDATE cooking_log SET plan_entry_id = (SELECT l.plan_entry_id FROM cooking_log_links l WHERE l.id = cooking_log.id)
+    WHERE id IN (SELECT id FROM cooking_log_links);
+DROP TABLE cooking_log_links;
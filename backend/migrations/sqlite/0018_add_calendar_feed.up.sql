@@ -0,0 +1,8 @@
+-- Add the calendar feed: a secret token for the subscription URL, and a sequence number per
+-- plan entry that calendars use to pick up days whose meal changed.
+ALTER TABLE meal_plan_entries ADD COLUMN sequence INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS calendar_feed (
+    id INTEGER PRIMARY KEY,
+    token TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
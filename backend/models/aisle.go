@@ -0,0 +1,316 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Grocery store aisles used to group the shopping list.
+const (
+	AisleProduce = "produce"
+	AisleMeat    = "meat"
+	AisleSeafood = "seafood"
+	AisleDairy   = "dairy"
+	AisleBakery  = "bakery"
+	AisleFrozen  = "frozen"
+	AislePantry  = "pantry"
+	AisleSpices  = "spices"
+	AisleOther   = "other"
+)
+
+// DefaultAisleOrder is the order sections are listed in when no store layout is saved:
+// the way round a typical store, from the produce at the entrance to the freezers.
+var DefaultAisleOrder = []string{
+	AisleProduce, AisleBakery, AisleMeat, AisleSeafood, AisleDairy,
+	AislePantry, AisleSpices, AisleFrozen, AisleOther,
+}
+
+// aisleKeywords are the words the default classifier looks for in an ingredient name.
+// When several match, a keyword at the end of the name (the thing itself, as in
+// "blackberry jam") beats one earlier on, and otherwise the longest keyword wins, so
+// "peanut butter" is pantry rather than dairy.
+var aisleKeywords = map[string][]string{
+	AisleProduce: {
+		"apple", "avocado", "banana", "basil", "bean sprout", "bell pepper", "berry", "blackberry",
+		"blueberry", "broccoli", "brussels sprout", "cabbage", "carrot", "cauliflower", "celery",
+		"chive", "cilantro", "corn", "cucumber", "dill", "garlic", "ginger", "green onion",
+		"jalapeño", "jalapeno", "kale", "leek", "lemon", "lettuce", "lime", "melon", "mint",
+		"mushroom", "onion", "orange", "oregano", "parsley", "pea", "peach", "pepper", "potato",
+		"radish", "green bean", "raspberry", "romaine", "rosemary", "scallion", "shallot", "spinach",
+		"squash", "strawberry", "sweet potato", "thyme", "tomato", "zucchini", "herb", "salad",
+		"arugula", "garlic clove",
+	},
+	AisleMeat: {
+		"bacon", "beef", "chicken", "chorizo", "ham", "hot dog", "lamb", "pork", "prosciutto",
+		"sausage", "steak", "turkey", "veal", "meatball", "pork chop", "rotisserie chicken",
+	},
+	AisleSeafood: {
+		"cod", "crab", "fish", "flounder", "halibut", "lobster", "salmon", "scallop", "shrimp",
+		"tilapia", "tuna",
+	},
+	AisleDairy: {
+		"butter", "buttermilk", "cheddar", "cheese", "cream", "cream cheese", "egg", "feta",
+		"half-and-half", "milk", "monterey jack", "mozzarella", "parmesan", "pepperjack", "ricotta",
+		"sour cream", "yogurt", "gruyère", "gruyere",
+	},
+	AisleBakery: {
+		"bagel", "baguette", "bread", "brioche", "bun", "ciabatta", "croissant", "english muffin",
+		"naan", "pita", "roll", "tortilla", "hot dog bun",
+	},
+	AisleFrozen: {
+		"frozen", "ice cream",
+	},
+	AislePantry: {
+		"barbecue sauce", "bean", "bread crumb", "broth", "brown sugar", "canned", "chip",
+		"chocolate", "coconut milk", "cornstarch", "cracker", "flour", "granola", "honey", "jam",
+		"ketchup", "lentil", "macaroni", "maple syrup", "marinara", "mayonnaise", "mustard",
+		"noodle", "oat", "oil", "olive oil", "panko", "pasta", "peanut butter", "pickle",
+		"preserves", "relish", "rice", "salsa", "sauce", "soy sauce", "spaghetti", "stock",
+		"sugar", "syrup", "tomato paste", "tomato sauce", "vinegar", "worcestershire", "linguine",
+		"lasagna", "tortellini", "baking powder", "baking soda", "cereal", "pancake mix",
+		"chipotles in adobo", "chipotle chiles in adobo", "capers", "chicken broth", "chicken stock",
+		"beef broth", "vegetable broth", "tortilla chip", "potato chip", "corn chip", "cooking spray",
+		"macaroni and cheese",
+	},
+	AisleSpices: {
+		"black pepper", "cayenne", "chili powder", "cinnamon", "coriander", "cumin",
+		"dried oregano", "dried dill", "dried thyme", "garlic powder", "italian seasoning",
+		"nutmeg", "onion powder", "paprika", "pepper flake", "red-pepper flake", "poppy seed",
+		"salt", "seasoning", "spice", "turmeric", "vanilla", "cocoa powder", "chipotle powder",
+		"peppercorn", "salt and pepper", "ground clove",
+	},
+}
+
+// AisleSettings are the store layout and the per-ingredient aisle overrides.
+type AisleSettings struct {
+	Order     []string          `json:"order"`
+	Overrides map[string]string `json:"overrides"`
+}
+
+// AisleSection is one aisle of a grouped shopping list.
+type AisleSection struct {
+	Aisle string         `json:"aisle"`
+	Items []ShoppingItem `json:"items"`
+}
+
+// IsKnownAisle reports whether aisle is one of the aisles the shopping list groups by.
+func IsKnownAisle(aisle string) bool {
+	for _, a := range DefaultAisleOrder {
+		if a == aisle {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeAisleOrder checks a store layout and appends any aisles it leaves out, in
+// their default order, so every item always has a section.
+func NormalizeAisleOrder(order []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(DefaultAisleOrder))
+	for _, aisle := range order {
+		if !IsKnownAisle(aisle) {
+			return nil, fmt.Errorf("unknown aisle %q", aisle)
+		}
+		if seen[aisle] {
+			return nil, fmt.Errorf("aisle %q is listed more than once", aisle)
+		}
+		seen[aisle] = true
+		normalized = append(normalized, aisle)
+	}
+	for _, aisle := range DefaultAisleOrder {
+		if !seen[aisle] {
+			normalized = append(normalized, aisle)
+		}
+	}
+	return normalized, nil
+}
+
+// ClassifyIngredient guesses the aisle for an ingredient from keywords in its name.
+// Anything frozen goes to the freezer aisle; names with no known keyword are "other".
+func ClassifyIngredient(name string) string {
+	words := strings.Fields(strings.ToLower(name))
+	for i, w := range words {
+		words[i] = singular(strings.Trim(w, ",.()"))
+	}
+	text := " " + strings.Join(words, " ") + " "
+	switch {
+	case strings.Contains(text, " frozen "):
+		return AisleFrozen
+	case strings.Contains(text, " canned "):
+		return AislePantry
+	}
+
+	// Aisles are checked in the default order so ties always resolve the same way.
+	best, bestScore := AisleOther, 0
+	for _, aisle := range DefaultAisleOrder {
+		for _, kw := range aisleKeywords[aisle] {
+			kwWords := strings.Fields(kw)
+			for i, w := range kwWords {
+				kwWords[i] = singular(w)
+			}
+			key := " " + strings.Join(kwWords, " ") + " "
+			if !strings.Contains(text, key) {
+				continue
+			}
+			score := len(key)
+			if strings.HasSuffix(text, key) {
+				score += headNounBonus
+			}
+			if score > bestScore {
+				best, bestScore = aisle, score
+			}
+		}
+	}
+	return best
+}
+
+// headNounBonus is added to the score of a keyword that ends the ingredient name.
+const headNounBonus = 100
+
+// singular strips common English plural endings so "tomatoes" matches "tomato".
+func singular(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case len(word) > 4 && strings.HasSuffix(word, "oes"):
+		return strings.TrimSuffix(word, "es")
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// Classify returns the aisle for an ingredient, preferring a stored override.
+func (s AisleSettings) Classify(name string) string {
+	if aisle, ok := s.Overrides[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return aisle
+	}
+	return ClassifyIngredient(name)
+}
+
+// AssignAisles sets the aisle of every shopping list item.
+func (s AisleSettings) AssignAisles(items []ShoppingItem) {
+	for i := range items {
+		items[i].Aisle = s.Classify(items[i].Name)
+	}
+}
+
+// GroupByAisle splits the shopping list into sections in store order. Empty aisles are
+// left out and items keep their order within a section.
+func (s AisleSettings) GroupByAisle(items []ShoppingItem) []AisleSection {
+	order, err := NormalizeAisleOrder(s.Order)
+	if err != nil {
+		order = DefaultAisleOrder
+	}
+	byAisle := make(map[string][]ShoppingItem)
+	for _, item := range items {
+		aisle := item.Aisle
+		if aisle == "" {
+			aisle = s.Classify(item.Name)
+			item.Aisle = aisle
+		}
+		byAisle[aisle] = append(byAisle[aisle], item)
+	}
+
+	sections := []AisleSection{}
+	for _, aisle := range order {
+		if len(byAisle[aisle]) > 0 {
+			sections = append(sections, AisleSection{Aisle: aisle, Items: byAisle[aisle]})
+		}
+	}
+	return sections
+}
+
+// GetAisleSettings loads the saved store layout and overrides, falling back to
+// DefaultAisleOrder when no layout is saved.
+func GetAisleSettings(db *sql.DB) (AisleSettings, error) {
+	settings := AisleSettings{Order: DefaultAisleOrder, Overrides: map[string]string{}}
+
+	var raw string
+	err := db.QueryRow("SELECT aisle_order FROM store_layout WHERE id = 1").Scan(&raw)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		log.Printf("GetAisleSettings: error loading store layout: %v", err)
+		return AisleSettings{}, err
+	default:
+		if err := json.Unmarshal([]byte(raw), &settings.Order); err != nil {
+			log.Printf("GetAisleSettings: error decoding store layout: %v", err)
+			return AisleSettings{}, err
+		}
+	}
+
+	rows, err := db.Query("SELECT name, aisle FROM ingredient_aisles")
+	if err != nil {
+		log.Printf("GetAisleSettings: error loading overrides: %v", err)
+		return AisleSettings{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, aisle string
+		if err := rows.Scan(&name, &aisle); err != nil {
+			log.Printf("GetAisleSettings: error scanning override: %v", err)
+			return AisleSettings{}, err
+		}
+		settings.Overrides[name] = aisle
+	}
+	if err := rows.Err(); err != nil {
+		return AisleSettings{}, err
+	}
+	return settings, nil
+}
+
+// SaveAisleOrder validates and stores the store layout.
+func SaveAisleOrder(db *sql.DB, order []string) ([]string, error) {
+	order, err := NormalizeAisleOrder(order)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`
+		INSERT INTO store_layout (id, aisle_order, updated_at) VALUES (1, $1, $2)
+		ON CONFLICT (id) DO UPDATE SET aisle_order = EXCLUDED.aisle_order, updated_at = EXCLUDED.updated_at
+	`, string(raw), time.Now().UTC())
+	if err != nil {
+		log.Printf("SaveAisleOrder: error saving store layout: %v", err)
+		return nil, err
+	}
+	return order, nil
+}
+
+// SetAisleOverride stores the aisle for an ingredient name. An empty aisle removes the
+// override so the default classifier applies again.
+func SetAisleOverride(db *sql.DB, name, aisle string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return errors.New("ingredient name is required")
+	}
+	if aisle == "" {
+		if _, err := db.Exec("DELETE FROM ingredient_aisles WHERE name = $1", name); err != nil {
+			log.Printf("SetAisleOverride: error removing override for %q: %v", name, err)
+			return err
+		}
+		return nil
+	}
+	if !IsKnownAisle(aisle) {
+		return fmt.Errorf("unknown aisle %q", aisle)
+	}
+	_, err := db.Exec(`
+		INSERT INTO ingredient_aisles (name, aisle) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET aisle = EXCLUDED.aisle
+	`, name, aisle)
+	if err != nil {
+		log.Printf("SetAisleOverride: error saving override for %q: %v", name, err)
+		return err
+	}
+	return nil
+}
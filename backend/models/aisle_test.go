@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestClassifyIngredient(t *testing.T) {
+	cases := map[string]string{
+		"yellow onion":                     AisleProduce,
+		"cherry tomatoes":                  AisleProduce,
+		"boneless skinless chicken thighs": AisleMeat,
+		"low-sodium chicken broth":         AislePantry,
+		"peanut butter":                    AislePantry,
+		"unsalted butter":                  AisleDairy,
+		"blackberry jam":                   AislePantry,
+		"garlic powder":                    AisleSpices,
+		"garlic cloves":                    AisleProduce,
+		"frozen peas":                      AisleFrozen,
+		"small peeled frozen shrimp":       AisleFrozen,
+		"brioche buns":                     AisleBakery,
+		"salmon filet":                     AisleSeafood,
+		"tzatziki":                         AisleOther,
+	}
+	for name, want := range cases {
+		if got := ClassifyIngredient(name); got != want {
+			t.Errorf("ClassifyIngredient(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestNormalizeAisleOrder(t *testing.T) {
+	order, err := NormalizeAisleOrder([]string{AisleFrozen, AisleDairy})
+	if err != nil {
+		t.Fatalf("NormalizeAisleOrder returned error: %v", err)
+	}
+	if len(order) != len(DefaultAisleOrder) || order[0] != AisleFrozen || order[1] != AisleDairy || order[2] != AisleProduce {
+		t.Errorf("expected the given aisles first and the rest appended, got %v", order)
+	}
+	if _, err := NormalizeAisleOrder([]string{"deli"}); err == nil {
+		t.Errorf("expected an error for an unknown aisle")
+	}
+	if _, err := NormalizeAisleOrder([]string{AisleDairy, AisleDairy}); err == nil {
+		t.Errorf("expected an error for a repeated aisle")
+	}
+}
+
+func TestGroupByAisle(t *testing.T) {
+	settings := AisleSettings{
+		Order:     []string{AisleDairy, AisleProduce},
+		Overrides: map[string]string{"tzatziki": AisleDairy},
+	}
+	items := []ShoppingItem{
+		{Ingredient: Ingredient{Name: "onion"}},
+		{Ingredient: Ingredient{Name: "milk"}},
+		{Ingredient: Ingredient{Name: "tzatziki"}},
+		{Ingredient: Ingredient{Name: "mystery"}},
+	}
+	settings.AssignAisles(items)
+	sections := settings.GroupByAisle(items)
+
+	if len(sections) != 3 {
+		t.Fatalf("expected 3 sections, got %+v", sections)
+	}
+	if sections[0].Aisle != AisleDairy || len(sections[0].Items) != 2 || sections[0].Items[1].Name != "tzatziki" {
+		t.Errorf("expected dairy first with the overridden tzatziki, got %+v", sections[0])
+	}
+	if sections[1].Aisle != AisleProduce || sections[2].Aisle != AisleOther {
+		t.Errorf("expected produce then other, got %s and %s", sections[1].Aisle, sections[2].Aisle)
+	}
+}
+
+func TestAisleSettingsStorage(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening in-memory database: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		`CREATE TABLE ingredient_aisles (name TEXT PRIMARY KEY, aisle TEXT NOT NULL)`,
+		`CREATE TABLE store_layout (id INTEGER PRIMARY KEY, aisle_order TEXT NOT NULL, updated_at TIMESTAMP)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Error setting up test database: %v", err)
+		}
+	}
+
+	settings, err := GetAisleSettings(db)
+	if err != nil {
+		t.Fatalf("GetAisleSettings returned error: %v", err)
+	}
+	if settings.Order[0] != DefaultAisleOrder[0] || len(settings.Overrides) != 0 {
+		t.Errorf("expected the default settings, got %+v", settings)
+	}
+
+	if _, err := SaveAisleOrder(db, []string{AisleFrozen}); err != nil {
+		t.Fatalf("SaveAisleOrder returned error: %v", err)
+	}
+	if err := SetAisleOverride(db, "Tzatziki", AisleDairy); err != nil {
+		t.Fatalf("SetAisleOverride returned error: %v", err)
+	}
+	if err := SetAisleOverride(db, "tzatziki", AisleDairy); err != nil {
+		t.Fatalf("SetAisleOverride returned error on update: %v", err)
+	}
+	if err := SetAisleOverride(db, "arugula", "deli"); err == nil {
+		t.Errorf("expected an error for an unknown aisle")
+	}
+
+	settings, err = GetAisleSettings(db)
+	if err != nil {
+		t.Fatalf("GetAisleSettings returned error: %v", err)
+	}
+	if settings.Order[0] != AisleFrozen || settings.Overrides["tzatziki"] != AisleDairy {
+		t.Errorf("expected the saved settings, got %+v", settings)
+	}
+
+	if err := SetAisleOverride(db, "tzatziki", ""); err != nil {
+		t.Fatalf("SetAisleOverride returned error on removal: %v", err)
+	}
+	settings, _ = GetAisleSettings(db)
+	if _, ok := settings.Overrides["tzatziki"]; ok {
+		t.Errorf("expected the override to be removed")
+	}
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// What a busy night does to its dinner.
+const (
+	// BusyLowEffort plans the dinner within the rules' lowest effort range.
+	BusyLowEffort = "low_effort"
+	// BusyEatOut plans "Eating out" instead of a dinner.
+	BusyEatOut = "eat_out"
+)
+
+// Evening is the part of a day, as times since midnight, in which an event keeps the
+// household from cooking dinner.
+type Evening struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// DefaultEvening runs from 17:00 to 21:00.
+var DefaultEvening = Evening{Start: 17 * time.Hour, End: 21 * time.Hour}
+
+// BusyNights returns the dates, from start on for days days, whose evening in loc overlaps
+// a timed event, keyed in DateLayout with the summaries of those events. All-day events
+// such as birthdays or holidays don't make a night busy.
+func BusyNights(events []ICSEvent, start time.Time, days int, loc *time.Location, evening Evening) map[string][]string {
+	start = DateOf(start, time.UTC)
+	eveningOf := func(date time.Time) (time.Time, time.Time) {
+		midnight := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+		return midnight.Add(evening.Start), midnight.Add(evening.End)
+	}
+	from, _ := eveningOf(start)
+	_, to := eveningOf(start.AddDate(0, 0, days-1))
+
+	busy := make(map[string][]string)
+	for _, e := range events {
+		if e.AllDay {
+			continue
+		}
+		for _, o := range e.Occurrences(from, to) {
+			for i := 0; i < days; i++ {
+				date := start.AddDate(0, 0, i)
+				eveningStart, eveningEnd := eveningOf(date)
+				if !o.overlaps(eveningStart, eveningEnd) {
+					continue
+				}
+				summary := o.Summary
+				if summary == "" {
+					summary = "Busy"
+				}
+				key := date.Format(DateLayout)
+				if !containsString(busy[key], summary) {
+					busy[key] = append(busy[key], summary)
+				}
+			}
+		}
+	}
+	for _, summaries := range busy {
+		sort.Strings(summaries)
+	}
+	return busy
+}
+
+// overlaps reports whether the event takes up part of [start, end). An event without a
+// length counts when it starts within it.
+func (e ICSEvent) overlaps(start, end time.Time) bool {
+	if !e.End.After(e.Start) {
+		return !e.Start.Before(start) && e.Start.Before(end)
+	}
+	return e.Start.Before(end) && e.End.After(start)
+}
+
+// containsString reports whether s is in list.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// LowEffortRange returns the effort range of the easiest dinner the rules plan, the one
+// with the lowest maximum effort, or 0 to 2 when no dinner is planned by effort.
+func (r PlanRules) LowEffortRange() (minEffort, maxEffort int) {
+	minEffort, maxEffort = 0, 2
+	found := false
+	for _, d := range r.Days {
+		if d.SlotName() != SlotDinner || d.EatOut || d.FixedMealID != 0 {
+			continue
+		}
+		if !found || d.MaxEffort < maxEffort || (d.MaxEffort == maxEffort && d.MinEffort < minEffort) {
+			minEffort, maxEffort, found = d.MinEffort, d.MaxEffort, true
+		}
+	}
+	return minEffort, maxEffort
+}
+
+// WithBusyNights returns dated rules (see ForDates) with the dinners of busy dates changed
+// by action: BusyEatOut plans eating out, and BusyLowEffort, the default, narrows the
+// dinner to LowEffortRange. Fixed dinners keep their meal unless the household eats out.
+// Breakfasts and lunches are left alone.
+func (r PlanRules) WithBusyNights(busy map[string][]string, action string) (PlanRules, error) {
+	switch action {
+	case "", BusyLowEffort, BusyEatOut:
+	default:
+		return PlanRules{}, fmt.Errorf("invalid busy night action %q, expected %s or %s", action, BusyLowEffort, BusyEatOut)
+	}
+	minEffort, maxEffort := r.LowEffortRange()
+	changed := r
+	changed.Days = make([]DayRule, len(r.Days))
+	for i, d := range r.Days {
+		if _, ok := busy[d.Day]; ok && d.SlotName() == SlotDinner && !d.EatOut {
+			switch {
+			case action == BusyEatOut:
+				d.EatOut, d.FixedMealID = true, 0
+			case d.FixedMealID == 0:
+				d.MinEffort, d.MaxEffort = minEffort, maxEffort
+			}
+		}
+		changed.Days[i] = d
+	}
+	return changed, nil
+}
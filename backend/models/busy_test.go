@@ -0,0 +1,82 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBusyNights(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	events, err := ParseICS(strings.NewReader(testCalendar), loc)
+	if err != nil {
+		t.Fatalf("ParseICS returned error: %v", err)
+	}
+	monday := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	busy := BusyNights(events, monday, 7, loc, DefaultEvening)
+	want := map[string][]string{
+		"2024-03-04": {"Soccer"},
+		"2024-03-05": {"Parent, teacher night"},
+	}
+	// The dentist is in the afternoon, the birthday is all day and Thursday's practice
+	// was called off.
+	if !reflect.DeepEqual(busy, want) {
+		t.Errorf("expected busy nights %v, got %v", want, busy)
+	}
+
+	early := BusyNights(events, monday, 7, loc, Evening{Start: 19*time.Hour + 30*time.Minute, End: 22 * time.Hour})
+	if _, ok := early["2024-03-04"]; ok || len(early) != 1 {
+		t.Errorf("expected only Tuesday to run into a later evening, got %v", early)
+	}
+}
+
+func TestPlanRulesWithBusyNights(t *testing.T) {
+	rules, err := DefaultPlanRules().ForDates(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), 7)
+	if err != nil {
+		t.Fatalf("ForDates returned error: %v", err)
+	}
+	rules.Days = append(rules.Days, DayRule{Day: "2024-03-10", Slot: SlotLunch, MinEffort: 3, MaxEffort: 5})
+	busy := map[string][]string{"2024-03-06": {"Choir"}, "2024-03-08": {"Party"}, "2024-03-10": {"Recital"}}
+
+	if min, max := rules.LowEffortRange(); min != 0 || max != 2 {
+		t.Fatalf("expected Monday's range to be the lowest, got %d-%d", min, max)
+	}
+	easy, err := rules.WithBusyNights(busy, BusyLowEffort)
+	if err != nil {
+		t.Fatalf("WithBusyNights returned error: %v", err)
+	}
+	byKey := func(r PlanRules) map[string]DayRule {
+		m := make(map[string]DayRule)
+		for _, d := range r.Days {
+			m[d.Key()] = d
+		}
+		return m
+	}
+	days := byKey(easy)
+	if d := days["2024-03-06"]; d.MinEffort != 0 || d.MaxEffort != 2 {
+		t.Errorf("expected the busy Wednesday to be low effort, got %+v", d)
+	}
+	if d := days["2024-03-10"]; d.MinEffort != 0 || d.MaxEffort != 2 {
+		t.Errorf("expected the busy Sunday dinner to be low effort, got %+v", d)
+	}
+	if d := days["2024-03-10/lunch"]; d.MinEffort != 3 {
+		t.Errorf("expected lunch to be left alone, got %+v", d)
+	}
+	if d := byKey(rules)["2024-03-06"]; d.MinEffort != 3 {
+		t.Errorf("expected the original rules to be unchanged, got %+v", d)
+	}
+
+	out, err := rules.WithBusyNights(busy, BusyEatOut)
+	if err != nil {
+		t.Fatalf("WithBusyNights returned error: %v", err)
+	}
+	days = byKey(out)
+	if !days["2024-03-06"].EatOut || !days["2024-03-10"].EatOut || days["2024-03-07"].EatOut {
+		t.Errorf("expected only the busy dinners to eat out, got %+v", out.Days)
+	}
+
+	if _, err := rules.WithBusyNights(busy, "cancel"); err == nil {
+		t.Errorf("expected an error for an unknown action")
+	}
+}
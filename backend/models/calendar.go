@@ -0,0 +1,259 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrNoCalendarToken is returned when the calendar feed hasn't been set up yet.
+var ErrNoCalendarToken = errors.New("calendar feed is not set up")
+
+// NewCalendarToken returns a random token for the calendar feed's URL.
+func NewCalendarToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GetCalendarToken returns the calendar feed's token, or ErrNoCalendarToken.
+func GetCalendarToken(db *sql.DB) (string, error) {
+	var token string
+	err := db.QueryRow("SELECT token FROM calendar_feed WHERE id = 1").Scan(&token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNoCalendarToken
+	}
+	if err != nil {
+		log.Printf("GetCalendarToken: error executing query: %v", err)
+		return "", err
+	}
+	return token, nil
+}
+
+// RotateCalendarToken replaces the calendar feed's token with a new one and returns it.
+// Calendars subscribed with the old token stop updating.
+func RotateCalendarToken(db *sql.DB) (string, error) {
+	token, err := NewCalendarToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.Exec(`
+		INSERT INTO calendar_feed (id, token, created_at) VALUES (1, $1, $2)
+		ON CONFLICT (id) DO UPDATE SET token = EXCLUDED.token, created_at = EXCLUDED.created_at
+	`, token, time.Now().UTC())
+	if err != nil {
+		log.Printf("RotateCalendarToken: error saving the token: %v", err)
+		return "", err
+	}
+	return token, nil
+}
+
+// DefaultMealTimes is when each slot is eaten, as time since midnight, for timed events.
+var DefaultMealTimes = map[string]time.Duration{
+	SlotBreakfast: 8 * time.Hour,
+	SlotLunch:     12*time.Hour + 30*time.Minute,
+	SlotDinner:    18*time.Hour + 30*time.Minute,
+}
+
+// CalendarOptions controls how planned meals become calendar events.
+type CalendarOptions struct {
+	// Timed makes each meal an event from when prep starts until it's eaten, instead of an
+	// all-day event.
+	Timed bool
+	// MealTimes overrides DefaultMealTimes for some slots.
+	MealTimes map[string]time.Duration
+	// Location is the time zone meal times are in; nil means UTC.
+	Location *time.Location
+}
+
+// mealTime returns when a slot is eaten on a date.
+func (o CalendarOptions) mealTime(date time.Time, slot string) time.Time {
+	at, ok := o.MealTimes[slot]
+	if !ok {
+		at = DefaultMealTimes[slot]
+	}
+	loc := o.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc).Add(at)
+}
+
+// PrepTime returns how long before it's eaten a meal's preparation starts: 15 minutes, plus
+// 10 for every point of relative effort, up to three hours. Eating out takes none.
+func PrepTime(m *Meal) time.Duration {
+	if m.ID == 0 && !m.IsLeftovers() {
+		return 0
+	}
+	prep := 15*time.Minute + time.Duration(m.RelativeEffort)*10*time.Minute
+	if prep > 3*time.Hour {
+		prep = 3 * time.Hour
+	}
+	return prep
+}
+
+// calendarEvent is a planned meal as an event: the meal as planned (a meal, leftovers or
+// eating out), and for a cooked meal its ingredients and steps.
+type calendarEvent struct {
+	date     time.Time
+	slot     string
+	meal     *Meal
+	recipe   *Meal
+	sequence int
+	stamp    time.Time
+}
+
+// CalendarFeed returns the saved plans as an iCalendar feed to subscribe to. Each day's
+// slot is one event whose UID is its date and slot, so it stays the same when the plan is
+// finalized again; a changed meal raises its SEQUENCE. Where plans overlap, the one that
+// starts latest wins. recipes holds the meals with their ingredients and steps by ID, for
+// the event descriptions; ingredients are scaled to the day's servings.
+func CalendarFeed(plans []*MealPlan, recipes map[int]*Meal, opts CalendarOptions) string {
+	ordered := append([]*MealPlan{}, plans...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if !ordered[i].WeekStart.Equal(ordered[j].WeekStart) {
+			return ordered[i].WeekStart.Before(ordered[j].WeekStart)
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+	events := make(map[string]calendarEvent)
+	for _, p := range ordered {
+		for _, e := range p.Entries {
+			if e.Meal == nil {
+				continue
+			}
+			event := calendarEvent{date: e.Date, slot: e.Slot, meal: e.Meal, sequence: e.Sequence, stamp: p.CreatedAt}
+			if recipe, ok := recipes[e.MealID]; ok && e.Meal.ID != 0 {
+				event.recipe = ScaleMeal(recipe, e.Servings)
+			}
+			events[e.DateKey()] = event
+		}
+	}
+
+	w := newICSWriter()
+	w.line("X-WR-CALNAME", "Meal plan")
+	w.line("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	w.line("X-PUBLISHED-TTL", "PT1H")
+	keys := make([]string, 0, len(events))
+	for key := range events {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return planKeyLess(keys[i], keys[j]) })
+	for _, key := range keys {
+		w.event(events[key], opts)
+	}
+	return w.end()
+}
+
+// icsWriter writes an iCalendar object line by line.
+type icsWriter struct {
+	b strings.Builder
+}
+
+// newICSWriter starts a calendar.
+func newICSWriter() *icsWriter {
+	w := &icsWriter{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//Meal Planner//EN")
+	return w
+}
+
+// end closes the calendar and returns it.
+func (w *icsWriter) end() string {
+	w.line("END", "VCALENDAR")
+	return w.b.String()
+}
+
+// line writes a content line, folded so no line is longer than 75 octets: continuation
+// lines start with a space, and multi-byte characters are never split.
+func (w *icsWriter) line(name, value string) {
+	s := name + ":" + value
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.b.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = 74
+	}
+	w.b.WriteString(s + "\r\n")
+}
+
+// event writes a planned meal as a VEVENT. Breakfasts and lunches are titled with their
+// slot, leftovers carry the Leftovers category, and a cooked meal's description lists its
+// servings, ingredients and steps.
+func (w *icsWriter) event(e calendarEvent, opts CalendarOptions) {
+	day := e.date.Format("20060102")
+	title := e.meal.MealName
+	if e.slot != SlotDinner {
+		title = strings.ToUpper(e.slot[:1]) + e.slot[1:] + ": " + title
+	}
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", day+"-"+e.slot+"@mealplanner")
+	w.line("DTSTAMP", e.stamp.UTC().Format("20060102T150405Z"))
+	w.line("SEQUENCE", strconv.Itoa(e.sequence))
+	if opts.Timed {
+		eat := opts.mealTime(e.date, e.slot)
+		start, end := eat.Add(-PrepTime(e.meal)), eat
+		if start.Equal(end) {
+			end = end.Add(time.Hour)
+		}
+		w.line("DTSTART", start.UTC().Format("20060102T150405Z"))
+		w.line("DTEND", end.UTC().Format("20060102T150405Z"))
+	} else {
+		w.line("DTSTART;VALUE=DATE", day)
+	}
+	w.line("SUMMARY", escapeICSString(title))
+	if e.recipe != nil {
+		if description := recipeDescription(e.recipe); description != "" {
+			w.line("DESCRIPTION", escapeICSString(description))
+		}
+	}
+	if e.meal.IsLeftovers() {
+		w.line("CATEGORIES", "Leftovers")
+	}
+	if e.meal.URL != "" {
+		w.line("URL", e.meal.URL)
+	}
+	w.line("END", "VEVENT")
+}
+
+// recipeDescription lists a meal's servings, ingredients and steps for an event description.
+func recipeDescription(m *Meal) string {
+	var parts []string
+	if m.Servings > 0 {
+		parts = append(parts, fmt.Sprintf("Serves %d", m.Servings))
+	}
+	if len(m.Ingredients) > 0 {
+		lines := []string{"Ingredients:"}
+		for _, ing := range m.Ingredients {
+			item := strings.TrimSpace(strings.Join([]string{strconv.FormatFloat(ing.Quantity, 'f', -1, 64), ing.Unit, ing.Name}, " "))
+			if ing.Quantity == 0 {
+				item = strings.TrimSpace(ing.Unit + " " + ing.Name)
+			}
+			lines = append(lines, "- "+strings.Join(strings.Fields(item), " "))
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	if len(m.Steps) > 0 {
+		lines := []string{"Steps:"}
+		for i, step := range m.Steps {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, step.Instruction))
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,216 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DateLayout is the layout of calendar dates in plan keys and API payloads.
+const DateLayout = "2006-01-02"
+
+// MaxPlanDays is the longest range a single plan can cover.
+const MaxPlanDays = 31
+
+// ParsePlanDate returns the date named by a plan day in DateLayout, as midnight UTC.
+func ParsePlanDate(day string) (time.Time, bool) {
+	t, err := time.Parse(DateLayout, day)
+	if err != nil || t.Format(DateLayout) != day {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// IsPlanDay reports whether day names a day of a plan: a weekday, or a date in DateLayout.
+func IsPlanDay(day string) bool {
+	if IsWeekday(day) {
+		return true
+	}
+	_, ok := ParsePlanDate(day)
+	return ok
+}
+
+// DateOf returns midnight UTC of the calendar date t falls on in loc. Plan dates are
+// stored this way, whatever the household's time zone.
+func DateOf(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// WeekdayOf returns the weekday name of a plan day: the day itself for a weekday, or the
+// weekday of a date.
+func WeekdayOf(day string) string {
+	if t, ok := ParsePlanDate(day); ok {
+		return t.Weekday().String()
+	}
+	return day
+}
+
+// WeekdayKey turns a dated plan key into the same slot's weekday key, as used by plans
+// that cover a single week.
+func WeekdayKey(key string) string {
+	day, slot := SplitPlanKey(key)
+	return PlanKey(WeekdayOf(day), slot)
+}
+
+// dayLess orders plan days: weekdays from Monday, dates by date, and weekdays before dates.
+func dayLess(a, b string) bool {
+	ia, ib := weekdayIndex(a), weekdayIndex(b)
+	switch {
+	case ia >= 0 && ib >= 0:
+		return ia < ib
+	case ia >= 0 || ib >= 0:
+		return ia >= 0
+	}
+	return a < b
+}
+
+// nextPlanDay returns the day after a plan day, or "" after Sunday for weekday plans,
+// which end with the week.
+func nextPlanDay(day string) string {
+	if t, ok := ParsePlanDate(day); ok {
+		return t.AddDate(0, 0, 1).Format(DateLayout)
+	}
+	if i := weekdayIndex(day); i >= 0 && i+1 < len(Weekdays) {
+		return Weekdays[i+1]
+	}
+	return ""
+}
+
+// Location returns the household's time zone, UTC when none is set.
+func (r PlanRules) Location() *time.Location {
+	if r.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// StartDay returns the weekday the household's week starts on, Monday when none is set.
+func (r PlanRules) StartDay() string {
+	if r.WeekStartDay == "" {
+		return Weekdays[0]
+	}
+	return r.WeekStartDay
+}
+
+// WeekStart returns the date the week containing t starts on, in the household's time
+// zone and from its week start day, as midnight UTC.
+func (r PlanRules) WeekStart(t time.Time) time.Time {
+	return r.StartOfWeek(DateOf(t, r.Location()))
+}
+
+// StartOfWeek returns the first date of the household's week containing a calendar date,
+// given as midnight UTC. Unlike WeekStart it doesn't apply the time zone, so dates sent by
+// the client keep their day.
+func (r PlanRules) StartOfWeek(date time.Time) time.Time {
+	date = DateOf(date, time.UTC)
+	offset := (weekdayIndex(date.Weekday().String()) - weekdayIndex(r.StartDay()) + 7) % 7
+	return date.AddDate(0, 0, -offset)
+}
+
+// WeekDates returns the date of each weekday in the week starting at weekStart, keyed by
+// weekday name.
+func WeekDates(weekStart time.Time) map[string]time.Time {
+	dates := make(map[string]time.Time, len(Weekdays))
+	for i := 0; i < len(Weekdays); i++ {
+		date := weekStart.AddDate(0, 0, i)
+		dates[date.Weekday().String()] = date
+	}
+	return dates
+}
+
+// DatedKey returns the dated plan key for a key of a plan covering days days from start.
+// A weekday key takes the first date in the range falling on that weekday; a dated key is
+// kept. ok is false when the key's day isn't in the range.
+func DatedKey(key string, start time.Time, days int) (dated string, ok bool) {
+	day, slot := SplitPlanKey(key)
+	start = DateOf(start, time.UTC)
+	if date, isDate := ParsePlanDate(day); isDate {
+		return key, !date.Before(start) && date.Before(start.AddDate(0, 0, days))
+	}
+	for i := 0; i < days && i < len(Weekdays); i++ {
+		if date := start.AddDate(0, 0, i); date.Weekday().String() == day {
+			return PlanKey(date.Format(DateLayout), slot), true
+		}
+	}
+	return key, false
+}
+
+// ForDates returns the rules for planning the days from start on: each date takes the rules
+// of its weekday, keyed by the date. The dates are in order, and a date's slots in the
+// order of the weekday's rules. Category and tag caps and tag minimums stay per week: the
+// planner applies them to each of the household's weeks the range covers (see StartDay),
+// and a week the range only partly covers needs its share of the minimums.
+func (r PlanRules) ForDates(start time.Time, days int) (PlanRules, error) {
+	if days < 1 || days > MaxPlanDays {
+		return PlanRules{}, fmt.Errorf("a plan covers 1 to %d days, got %d", MaxPlanDays, days)
+	}
+	start = DateOf(start, time.UTC)
+	dated := r
+	dated.Days = nil
+	dated.rangeStart, dated.rangeDays = start, days
+	for i := 0; i < days; i++ {
+		date := start.AddDate(0, 0, i)
+		for _, d := range r.Days {
+			if d.Day == date.Weekday().String() {
+				d.Day = date.Format(DateLayout)
+				dated.Days = append(dated.Days, d)
+			}
+		}
+	}
+	return dated, nil
+}
+
+// weekOf returns the household week a plan key counts toward: the first date of the week
+// for a dated key, or "" for a weekday key, as a plan by weekday is a single week.
+func (r PlanRules) weekOf(key string) string {
+	day, _ := SplitPlanKey(key)
+	date, ok := ParsePlanDate(day)
+	if !ok {
+		return ""
+	}
+	return r.StartOfWeek(date).Format(DateLayout)
+}
+
+// weeks returns the weeks the rules' days fall in, in order.
+func (r PlanRules) weeks() []string {
+	var weeks []string
+	seen := make(map[string]bool)
+	for _, d := range r.Days {
+		if week := r.weekOf(d.Key()); !seen[week] {
+			seen[week] = true
+			weeks = append(weeks, week)
+		}
+	}
+	sort.Strings(weeks)
+	return weeks
+}
+
+// weekMinimums returns the tag minimums of a week. A week the dates from ForDates only
+// partly cover needs its share of each minimum, rounded to the nearest meal, so a range
+// across two weeks asks for about what a single week would.
+func (r PlanRules) weekMinimums(week string) map[string]int {
+	start, ok := ParsePlanDate(week)
+	if !ok || r.rangeDays == 0 || len(r.TagMinimums) == 0 {
+		return r.TagMinimums
+	}
+	end := r.rangeStart.AddDate(0, 0, r.rangeDays)
+	covered := 0
+	for i := 0; i < len(Weekdays); i++ {
+		if date := start.AddDate(0, 0, i); !date.Before(r.rangeStart) && date.Before(end) {
+			covered++
+		}
+	}
+	if covered == len(Weekdays) {
+		return r.TagMinimums
+	}
+	share := make(map[string]int, len(r.TagMinimums))
+	for tag, n := range r.TagMinimums {
+		share[tag] = (2*n*covered + len(Weekdays)) / (2 * len(Weekdays))
+	}
+	return share
+}
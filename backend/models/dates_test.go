@@ -0,0 +1,123 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWeekStart(t *testing.T) {
+	date := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	// 2024-03-04 is a Monday. Late on Sunday evening in New York it's already Monday in UTC.
+	sundayNight := time.Date(2024, 3, 11, 2, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name  string
+		rules PlanRules
+		t     time.Time
+		want  time.Time
+	}{
+		{"monday default", PlanRules{}, date(7), date(4)},
+		{"on the start day", PlanRules{}, date(4), date(4)},
+		{"sunday start", PlanRules{WeekStartDay: "Sunday"}, date(7), date(3)},
+		{"saturday start", PlanRules{WeekStartDay: "Saturday"}, date(8), date(2)},
+		{"utc", PlanRules{}, sundayNight, date(11)},
+		{"time zone", PlanRules{TimeZone: "America/New_York"}, sundayNight, date(4)},
+	} {
+		if got := tc.rules.WeekStart(tc.t); !got.Equal(tc.want) {
+			t.Errorf("%s: WeekStart(%v) = %v, want %v", tc.name, tc.t, got, tc.want)
+		}
+	}
+	if got := (PlanRules{WeekStartDay: "Sunday", TimeZone: "Asia/Tokyo"}).StartOfWeek(date(9)); !got.Equal(date(3)) {
+		t.Errorf("expected StartOfWeek to ignore the time zone, got %v", got)
+	}
+}
+
+func TestForDates(t *testing.T) {
+	rules := PlanRules{
+		Days: []DayRule{
+			{Day: "Monday", MaxEffort: 3},
+			{Day: "Monday", Slot: SlotLunch, MaxEffort: 1},
+			{Day: "Friday", EatOut: true},
+		},
+		CategoryCaps: map[string]int{CategoryRedMeat: 2},
+		TagMinimums:  map[string]int{"fish": 1},
+	}
+	dated, err := rules.ForDates(time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC), 10)
+	if err != nil {
+		t.Fatalf("ForDates: %v", err)
+	}
+	var keys []string
+	for _, d := range dated.Days {
+		keys = append(keys, d.Key())
+	}
+	want := []string{"2024-03-08", "2024-03-11", "2024-03-11/lunch", "2024-03-15"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("unexpected dated days %v, want %v", keys, want)
+	}
+	if !dated.Days[0].EatOut || dated.Days[2].MaxEffort != 1 {
+		t.Errorf("expected each date to keep its weekday's rule, got %+v", dated.Days)
+	}
+	if dated.CategoryCaps[CategoryRedMeat] != 2 || dated.TagMinimums["fish"] != 1 {
+		t.Errorf("expected the weekly caps kept, got %v and %v", dated.CategoryCaps, dated.TagMinimums)
+	}
+	// The range covers three days of the week from 2024-03-04 and all of the next.
+	if got := dated.weekMinimums(dated.weekOf("2024-03-08")); got["fish"] != 0 {
+		t.Errorf("expected a partly covered week to need no fish, got %v", got)
+	}
+	if got := dated.weekMinimums(dated.weekOf("2024-03-11/lunch")); got["fish"] != 1 {
+		t.Errorf("expected a whole week to need its fish, got %v", got)
+	}
+	if got := dated.weeks(); !reflect.DeepEqual(got, []string{"2024-03-04", "2024-03-11"}) {
+		t.Errorf("unexpected weeks %v", got)
+	}
+	if rules.Days[0].Day != "Monday" || rules.CategoryCaps[CategoryRedMeat] != 2 {
+		t.Errorf("ForDates must not change the weekly rules")
+	}
+	for _, days := range []int{0, -1, MaxPlanDays + 1} {
+		if _, err := rules.ForDates(time.Now(), days); err == nil {
+			t.Errorf("expected %d days to be rejected", days)
+		}
+	}
+}
+
+func TestDatedKey(t *testing.T) {
+	start := time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC) // a Wednesday
+	for _, tc := range []struct {
+		key, want string
+		days      int
+		ok        bool
+	}{
+		{"Wednesday", "2024-03-06", 7, true},
+		{"Monday/lunch", "2024-03-11/lunch", 7, true},
+		{"Monday", "Monday", 3, false},
+		{"2024-03-20/breakfast", "2024-03-20/breakfast", 15, true},
+		{"2024-03-20", "2024-03-20", 14, false},
+		{"2024-03-05", "2024-03-05", 7, false},
+	} {
+		if got, ok := DatedKey(tc.key, start, tc.days); got != tc.want || ok != tc.ok {
+			t.Errorf("DatedKey(%q, %d) = %q, %v, want %q, %v", tc.key, tc.days, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestPlanSpan(t *testing.T) {
+	date := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	start, days, dates := PlanRules{}.PlanSpan(date(6), []string{"Monday", "Sunday/lunch"})
+	if !start.Equal(date(4)) || days != 7 || !dates["Sunday/lunch"].Equal(date(10)) {
+		t.Errorf("expected a weekday plan to cover its Monday-based week, got %v, %d, %v", start, days, dates)
+	}
+	sunday := PlanRules{WeekStartDay: "Sunday"}
+	start, days, dates = sunday.PlanSpan(date(6), []string{"Monday", "Sunday/lunch"})
+	if !start.Equal(date(3)) || days != 7 || !dates["Sunday/lunch"].Equal(date(3)) || !dates["Monday"].Equal(date(4)) {
+		t.Errorf("expected a weekday plan to cover the household's Sunday-based week, got %v, %d, %v", start, days, dates)
+	}
+	start, days, dates = PlanRules{}.PlanSpan(date(6), []string{"2024-03-15", "2024-03-05/lunch"})
+	if !start.Equal(date(5)) || days != 11 || !dates["2024-03-15"].Equal(date(15)) {
+		t.Errorf("expected a dated plan to run from its first to its last date, got %v, %d, %v", start, days, dates)
+	}
+	// A dated plan for a later week starts there, not in the week it was saved from.
+	start, days, _ = PlanRules{}.PlanSpan(date(4), []string{"2024-03-13", "2024-03-14"})
+	if !start.Equal(date(13)) || days != 2 {
+		t.Errorf("expected a later dated plan to start on its first date, got %v, %d", start, days)
+	}
+}
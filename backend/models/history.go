@@ -0,0 +1,308 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Cooking log statuses. A plan day marked done takes the same status.
+const (
+	CookingStatusCooked  = "cooked"
+	CookingStatusSkipped = "skipped"
+)
+
+// MaxRating is the best rating a cooked meal can get; ratings run from 1 to MaxRating.
+const MaxRating = 5
+
+// CookingLogEntry records whether a planned meal was cooked, and how it went.
+type CookingLogEntry struct {
+	ID     int `json:"id"`
+	MealID int `json:"mealId"`
+	// PlanEntryID is the plan day the entry was recorded for, or 0 once that plan is replaced.
+	PlanEntryID int       `json:"planEntryId,omitempty"`
+	CookedOn    time.Time `json:"cookedOn"`
+	Status      string    `json:"status"`
+	// Rating is 1 to MaxRating, or 0 when the meal wasn't rated.
+	Rating    int       `json:"rating,omitempty"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ErrNoMealOnDay is returned when a plan day to be marked done has no meal to cook,
+// because it's an eating out or leftovers day.
+var ErrNoMealOnDay = errors.New("no meal planned on that day")
+
+// Validate checks the parts of a log entry the caller supplies.
+func (e *CookingLogEntry) Validate() error {
+	switch e.Status {
+	case CookingStatusCooked:
+	case CookingStatusSkipped:
+		if e.Rating != 0 {
+			return errors.New("a skipped meal can't be rated")
+		}
+	default:
+		return fmt.Errorf("invalid status %q, expected %s or %s", e.Status, CookingStatusCooked, CookingStatusSkipped)
+	}
+	if e.Rating < 0 || e.Rating > MaxRating {
+		return fmt.Errorf("rating must be between 1 and %d", MaxRating)
+	}
+	return nil
+}
+
+// DayDate returns the date of a plan key's day: the date itself for a dated key, or the
+// weekday's date in the household's week containing weekStart (see StartOfWeek).
+func (r PlanRules) DayDate(weekStart time.Time, day string) time.Time {
+	day, _ = SplitPlanKey(day)
+	if date, ok := ParsePlanDate(day); ok {
+		return date
+	}
+	week := r.StartOfWeek(weekStart)
+	if date, ok := WeekDates(week)[day]; ok {
+		return date
+	}
+	return week
+}
+
+// MarkPlanDayDone records in the cooking log whether the meal planned on a day of a saved
+// plan was cooked or skipped, and sets the day's status to match. day is a plan key: a
+// date, or a weekday of the household's week containing weekStart, with a slot other than
+// dinner given as "2024-03-04/lunch". When saved plans overlap, the one that starts latest
+// is marked. Marking a day again replaces its log entry. It returns ErrNoMealPlan when no
+// saved plan covers the day, and ErrNoMealOnDay when the day has no meal.
+func MarkPlanDayDone(db *sql.DB, weekStart time.Time, day string, entry CookingLogEntry) (*CookingLogEntry, error) {
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
+	_, slot := SplitPlanKey(day)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rules, err := planRules(tx)
+	if err != nil {
+		return nil, err
+	}
+	date := rules.DayDate(weekStart, day)
+
+	covered, err := planCovers(tx, date)
+	if err != nil {
+		log.Printf("MarkPlanDayDone: error finding the plan for %s: %v", date.Format(DateLayout), err)
+		return nil, err
+	}
+	if !covered {
+		return nil, ErrNoMealPlan
+	}
+
+	var mealID sql.NullInt64
+	var status string
+	err = tx.QueryRow(`
+		SELECT e.id, e.meal_id, e.status
+		FROM meal_plan_entries e
+		JOIN meal_plans p ON p.id = e.plan_id
+		WHERE e.date = $1 AND e.slot = $2
+		ORDER BY p.week_start DESC, p.id DESC
+		LIMIT 1
+	`, date, slot).Scan(&entry.PlanEntryID, &mealID, &status)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (!mealID.Valid || status == EntryStatusEatingOut || status == EntryStatusLeftovers)) {
+		return nil, ErrNoMealOnDay
+	}
+	if err != nil {
+		log.Printf("MarkPlanDayDone: error finding %s: %v", day, err)
+		return nil, err
+	}
+	entry.MealID = int(mealID.Int64)
+	entry.CookedOn = date
+	entry.CreatedAt = time.Now().UTC()
+
+	if _, err := tx.Exec("DELETE FROM cooking_log WHERE plan_entry_id = $1", entry.PlanEntryID); err != nil {
+		log.Printf("MarkPlanDayDone: error clearing the log for entryID=%d: %v", entry.PlanEntryID, err)
+		return nil, err
+	}
+	var rating interface{}
+	if entry.Rating != 0 {
+		rating = entry.Rating
+	}
+	err = tx.QueryRow(`
+		INSERT INTO cooking_log (meal_id, plan_entry_id, cooked_on, status, rating, notes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, entry.MealID, entry.PlanEntryID, entry.CookedOn, entry.Status, rating, entry.Notes, entry.CreatedAt).Scan(&entry.ID)
+	if err != nil {
+		log.Printf("MarkPlanDayDone: error inserting the log entry for mealID=%d: %v", entry.MealID, err)
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE meal_plan_entries SET status = $1 WHERE id = $2", entry.Status, entry.PlanEntryID); err != nil {
+		log.Printf("MarkPlanDayDone: error updating entryID=%d: %v", entry.PlanEntryID, err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// planCovers reports whether any saved plan covers the date.
+func planCovers(tx *sql.Tx, date time.Time) (bool, error) {
+	rows, err := tx.Query("SELECT week_start, days FROM meal_plans WHERE week_start <= $1", date)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	covered := false
+	for rows.Next() {
+		var start time.Time
+		var days int
+		if err := rows.Scan(&start, &days); err != nil {
+			return false, err
+		}
+		if date.Before(start.AddDate(0, 0, days)) {
+			covered = true
+		}
+	}
+	return covered, rows.Err()
+}
+
+// cookingLogColumns are the columns read for a cooking log entry.
+const cookingLogColumns = "id, meal_id, plan_entry_id, cooked_on, status, rating, notes, created_at"
+
+// GetMealHistory returns a meal's cooking log, newest first. It returns ErrMealNotFound
+// for an unknown meal.
+func GetMealHistory(db *sql.DB, mealID int) ([]CookingLogEntry, error) {
+	var exists int
+	if err := db.QueryRow("SELECT COUNT(*) FROM meals WHERE id = $1", mealID).Scan(&exists); err != nil {
+		log.Printf("GetMealHistory: error checking mealID=%d: %v", mealID, err)
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, ErrMealNotFound
+	}
+
+	rows, err := db.Query("SELECT "+cookingLogColumns+" FROM cooking_log WHERE meal_id = $1 ORDER BY cooked_on DESC, id DESC", mealID)
+	if err != nil {
+		log.Printf("GetMealHistory: error executing query for mealID=%d: %v", mealID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []CookingLogEntry{}
+	for rows.Next() {
+		var (
+			entry       CookingLogEntry
+			planEntryID sql.NullInt64
+			rating      sql.NullInt64
+		)
+		if err := rows.Scan(&entry.ID, &entry.MealID, &planEntryID, &entry.CookedOn, &entry.Status, &rating, &entry.Notes, &entry.CreatedAt); err != nil {
+			log.Printf("GetMealHistory: error scanning row for mealID=%d: %v", mealID, err)
+			return nil, err
+		}
+		entry.PlanEntryID = int(planEntryID.Int64)
+		entry.Rating = int(rating.Int64)
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+// CookingStatsQuery loads every cooked entry of the cooking log for planning.
+const CookingStatsQuery = "SELECT meal_id, cooked_on, rating FROM cooking_log WHERE status = 'cooked'"
+
+// loadCookingStats sets each meal's rating, times cooked and last cooked date from the
+// cooking log.
+func loadCookingStats(db *sql.DB, meals []*Meal) error {
+	rows, err := db.Query(CookingStatsQuery)
+	if err != nil {
+		log.Printf("loadCookingStats: error executing query: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	var history []CookingLogEntry
+	for rows.Next() {
+		var entry CookingLogEntry
+		var rating sql.NullInt64
+		if err := rows.Scan(&entry.MealID, &entry.CookedOn, &rating); err != nil {
+			log.Printf("loadCookingStats: error scanning row: %v", err)
+			return err
+		}
+		entry.Status = CookingStatusCooked
+		entry.Rating = int(rating.Int64)
+		history = append(history, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	ApplyCookingStats(meals, history)
+	return nil
+}
+
+// ApplyCookingStats sets each meal's Rating, TimesCooked and LastCooked from a cooking log.
+// Only cooked entries count; the rating is the average of the rated ones.
+func ApplyCookingStats(meals []*Meal, history []CookingLogEntry) {
+	type stats struct {
+		ratings, rated int
+	}
+	byID := make(map[int]*Meal, len(meals))
+	totals := make(map[int]*stats, len(meals))
+	for _, m := range meals {
+		m.Rating, m.TimesCooked, m.LastCooked = 0, 0, time.Time{}
+		byID[m.ID] = m
+		totals[m.ID] = &stats{}
+	}
+	for _, e := range history {
+		m, ok := byID[e.MealID]
+		if !ok || e.Status != CookingStatusCooked {
+			continue
+		}
+		m.TimesCooked++
+		if e.CookedOn.After(m.LastCooked) {
+			m.LastCooked = e.CookedOn
+		}
+		if e.Rating > 0 {
+			totals[m.ID].ratings += e.Rating
+			totals[m.ID].rated++
+		}
+	}
+	for id, t := range totals {
+		if t.rated > 0 {
+			byID[id].Rating = float64(t.ratings) / float64(t.rated)
+		}
+	}
+}
+
+// Selection weighting. Among candidates that fit a day equally well, the planner favours
+// meals rated above average and meals that haven't been cooked for a while.
+const (
+	// neutralRating is the rating that neither raises nor lowers a meal's chances; unrated
+	// meals count as neutral.
+	neutralRating = 3.0
+	// restedDays is how long after being cooked a meal's recency weight reaches its peak.
+	restedDays = 90.0
+)
+
+// SelectionWeight returns how strongly the planner favours a meal, from its average rating
+// and how long ago it was last cooked. A neutral, never-cooked meal weighs 1. A 5-star meal
+// weighs about three times as much as a 1-star one; a meal cooked last week about half as
+// much as one last cooked three months ago.
+func SelectionWeight(m *Meal, now time.Time) float64 {
+	weight := 1.0
+	if m.Rating > 0 {
+		weight = (m.Rating + 1) / (neutralRating + 1)
+	}
+	if !m.LastCooked.IsZero() {
+		days := now.Sub(m.LastCooked).Hours() / 24
+		if days < 0 {
+			days = 0
+		}
+		if days > restedDays {
+			days = restedDays
+		}
+		weight *= 0.5 + 0.75*days/restedDays
+	}
+	return weight
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCookingLogEntryValidate(t *testing.T) {
+	for _, e := range []CookingLogEntry{
+		{Status: CookingStatusCooked},
+		{Status: CookingStatusCooked, Rating: MaxRating},
+		{Status: CookingStatusSkipped, Notes: "too tired"},
+	} {
+		if err := e.Validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", e, err)
+		}
+	}
+	for _, e := range []CookingLogEntry{
+		{},
+		{Status: "eaten"},
+		{Status: CookingStatusCooked, Rating: MaxRating + 1},
+		{Status: CookingStatusCooked, Rating: -1},
+		{Status: CookingStatusSkipped, Rating: 2},
+	} {
+		if err := e.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", e)
+		}
+	}
+}
+
+func TestApplyCookingStats(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	meals := []*Meal{{ID: 1}, {ID: 2}, {ID: 3, Rating: 2, TimesCooked: 9}}
+	ApplyCookingStats(meals, []CookingLogEntry{
+		{MealID: 1, Status: CookingStatusCooked, CookedOn: day(4), Rating: 4},
+		{MealID: 1, Status: CookingStatusCooked, CookedOn: day(18), Rating: 5},
+		{MealID: 1, Status: CookingStatusCooked, CookedOn: day(11)},
+		{MealID: 2, Status: CookingStatusSkipped, CookedOn: day(5)},
+		{MealID: 99, Status: CookingStatusCooked, CookedOn: day(5), Rating: 1},
+	})
+	if m := meals[0]; m.Rating != 4.5 || m.TimesCooked != 3 || !m.LastCooked.Equal(day(18)) {
+		t.Errorf("unexpected stats for meal 1: %+v", m)
+	}
+	for _, m := range meals[1:] {
+		if m.Rating != 0 || m.TimesCooked != 0 || !m.LastCooked.IsZero() {
+			t.Errorf("expected no stats for meal %d, got %+v", m.ID, m)
+		}
+	}
+}
+
+func TestSelectionWeight(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	if w := SelectionWeight(&Meal{}, now); w != 1 {
+		t.Errorf("expected a new meal to weigh 1, got %v", w)
+	}
+	loved := SelectionWeight(&Meal{Rating: 5}, now)
+	disliked := SelectionWeight(&Meal{Rating: 1}, now)
+	if loved <= 1 || disliked >= 1 || loved/disliked < 2.9 {
+		t.Errorf("expected ratings to spread the weights, got %v and %v", loved, disliked)
+	}
+	recent := SelectionWeight(&Meal{LastCooked: now.AddDate(0, 0, -7)}, now)
+	rested := SelectionWeight(&Meal{LastCooked: now.AddDate(0, 0, -120)}, now)
+	if recent >= rested || rested != SelectionWeight(&Meal{LastCooked: now.AddDate(0, 0, -90)}, now) {
+		t.Errorf("expected recency to level off after %v days, got %v and %v", restedDays, recent, rested)
+	}
+}
+
+func TestSolvePlan_FavoursRatedMeals(t *testing.T) {
+	// Two Monday meals fit equally well; the one rated 5 should win far more often.
+	pool := testPool(1, 1, 4, 4, 4, 4, 7)
+	pool[0].Rating = 5
+	pool[1].Rating = 1
+	rng := rand.New(rand.NewSource(1))
+	favoured := 0
+	for i := 0; i < 200; i++ {
+		result, err := SolvePlan(pool, DefaultPlanRules(), SolveOptions{Rand: rng})
+		if err != nil {
+			t.Fatalf("SolvePlan returned error: %v", err)
+		}
+		if result.Plan["Monday"].ID == pool[0].ID {
+			favoured++
+		}
+	}
+	// The expected share is 3/4.
+	if favoured < 130 || favoured > 170 {
+		t.Errorf("expected the 5-star meal on about 150 of 200 Mondays, got %d", favoured)
+	}
+}
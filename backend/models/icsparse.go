@@ -0,0 +1,408 @@
+package models
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ICSEvent is an event read from an iCalendar file. Timed events carry their time zone;
+// all-day events start at midnight UTC of their first date and end at midnight after
+// their last.
+type ICSEvent struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+	AllDay  bool
+
+	rule    *recurrence
+	exdates map[time.Time]bool
+}
+
+// recurrence is the part of an RRULE the planner understands: daily, weekly, monthly and
+// yearly repeats with INTERVAL, COUNT, UNTIL and, for weekly ones, BYDAY.
+type recurrence struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	byDay    []time.Weekday
+}
+
+// maxOccurrences stops expanding a recurring event that never reaches the range asked for.
+const maxOccurrences = 5000
+
+// icsWeekdays maps the two-letter weekday codes of RRULE to weekdays.
+var icsWeekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// ParseICS reads the events of an iCalendar file. Times without a time zone are read in
+// loc. Cancelled events and events marked free (TRANSP:TRANSPARENT) are left out. A
+// recurring event is returned once; Occurrences expands it. Recurrence rules other than
+// the ones recurrence describes are ignored, keeping only the first occurrence.
+func ParseICS(r io.Reader, loc *time.Location) ([]ICSEvent, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, errors.New("not an iCalendar file: expected BEGIN:VCALENDAR")
+	}
+
+	var events []ICSEvent
+	var props map[string]icsProperty
+	var exdates []icsProperty
+	depth := 0 // components nested in the current event, such as VALARM
+	for n, line := range lines {
+		name, params, value := parseICSLine(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT") && props == nil:
+			props = make(map[string]icsProperty)
+			exdates = nil
+		case props == nil:
+			continue
+		case name == "BEGIN":
+			depth++
+		case name == "END" && depth > 0:
+			depth--
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			event, keep, err := newICSEvent(props, exdates, loc)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			if keep {
+				events = append(events, event)
+			}
+			props = nil
+		case depth == 0 && name == "EXDATE":
+			exdates = append(exdates, icsProperty{params, value})
+		case depth == 0:
+			props[name] = icsProperty{params, value}
+		}
+	}
+	return events, nil
+}
+
+// icsProperty is a content line's parameters and value.
+type icsProperty struct {
+	params map[string]string
+	value  string
+}
+
+// unfoldICS reads the content lines of an iCalendar file, joining folded lines.
+func unfoldICS(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseICSLine splits a content line into its upper-cased name, its parameters and its
+// value. Colons and semicolons inside quoted parameter values don't split.
+func parseICSLine(line string) (name string, params map[string]string, value string) {
+	params = make(map[string]string)
+	quoted := false
+	var parts []string
+	start := 0
+	for i, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ';' && !quoted:
+			parts = append(parts, line[start:i])
+			start = i + 1
+		case c == ':' && !quoted:
+			parts = append(parts, line[start:i])
+			value = line[i+1:]
+			for _, p := range parts[1:] {
+				k, v, _ := strings.Cut(p, "=")
+				params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+			}
+			return strings.ToUpper(parts[0]), params, value
+		}
+	}
+	return strings.ToUpper(line), params, ""
+}
+
+// newICSEvent builds an event from its properties. keep is false for cancelled and free
+// events.
+func newICSEvent(props map[string]icsProperty, exdates []icsProperty, loc *time.Location) (event ICSEvent, keep bool, err error) {
+	if strings.EqualFold(props["STATUS"].value, "CANCELLED") || strings.EqualFold(props["TRANSP"].value, "TRANSPARENT") {
+		return ICSEvent{}, false, nil
+	}
+	event.Summary = unescapeICSString(props["SUMMARY"].value)
+
+	dtstart, ok := props["DTSTART"]
+	if !ok {
+		return ICSEvent{}, false, fmt.Errorf("event %q has no DTSTART", event.Summary)
+	}
+	event.Start, event.AllDay, err = parseICSTime(dtstart, loc)
+	if err != nil {
+		return ICSEvent{}, false, fmt.Errorf("event %q: invalid DTSTART: %w", event.Summary, err)
+	}
+	switch {
+	case props["DTEND"].value != "":
+		event.End, _, err = parseICSTime(props["DTEND"], loc)
+		if err != nil {
+			return ICSEvent{}, false, fmt.Errorf("event %q: invalid DTEND: %w", event.Summary, err)
+		}
+	case props["DURATION"].value != "":
+		d, err := parseICSDuration(props["DURATION"].value)
+		if err != nil {
+			return ICSEvent{}, false, fmt.Errorf("event %q: invalid DURATION: %w", event.Summary, err)
+		}
+		event.End = event.Start.Add(d)
+	case event.AllDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	default:
+		event.End = event.Start
+	}
+	if event.End.Before(event.Start) {
+		event.End = event.Start
+	}
+
+	if rrule := props["RRULE"].value; rrule != "" {
+		event.rule, err = parseRRule(rrule, loc)
+		if err != nil {
+			return ICSEvent{}, false, fmt.Errorf("event %q: invalid RRULE: %w", event.Summary, err)
+		}
+	}
+	for _, p := range exdates {
+		for _, v := range strings.Split(p.value, ",") {
+			t, _, err := parseICSTime(icsProperty{p.params, v}, loc)
+			if err != nil {
+				return ICSEvent{}, false, fmt.Errorf("event %q: invalid EXDATE: %w", event.Summary, err)
+			}
+			if event.exdates == nil {
+				event.exdates = make(map[time.Time]bool)
+			}
+			event.exdates[t.UTC()] = true
+		}
+	}
+	return event, true, nil
+}
+
+// parseICSTime reads a DATE or DATE-TIME value: a date is all-day, a time ending in Z is
+// UTC, and other times are in their TZID, or in loc without one.
+func parseICSTime(p icsProperty, loc *time.Location) (t time.Time, allDay bool, err error) {
+	v := strings.TrimSpace(p.value)
+	if p.params["VALUE"] == "DATE" || len(v) == len("20060102") {
+		t, err = time.Parse("20060102", v)
+		return t, true, err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err = time.Parse("20060102T150405Z", v)
+		return t, false, err
+	}
+	if tzid := p.params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			loc = tz
+		}
+	}
+	t, err = time.ParseInLocation("20060102T150405", v, loc)
+	return t, false, err
+}
+
+// parseICSDuration reads a DURATION value such as PT1H30M, P1D or P2W.
+func parseICSDuration(v string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(v, "+"), "-")
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("%q doesn't start with P", v)
+	}
+	var d time.Duration
+	inTime := false
+	num := ""
+	for _, c := range s[1:] {
+		if c >= '0' && c <= '9' {
+			num += string(c)
+			continue
+		}
+		if c == 'T' {
+			inTime = true
+			continue
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, fmt.Errorf("%q has no number before %c", v, c)
+		}
+		num = ""
+		unit := map[rune]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+		if inTime {
+			unit = map[rune]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+		}
+		u, ok := unit[c]
+		if !ok {
+			return 0, fmt.Errorf("%q has an unknown unit %c", v, c)
+		}
+		d += time.Duration(n) * u
+	}
+	if num != "" {
+		return 0, fmt.Errorf("%q ends without a unit", v)
+	}
+	if strings.HasPrefix(v, "-") {
+		d = -d
+	}
+	return d, nil
+}
+
+// parseRRule reads the parts of a recurrence rule the planner understands. It returns nil
+// for a frequency it doesn't expand.
+func parseRRule(v string, loc *time.Location) (*recurrence, error) {
+	rule := &recurrence{interval: 1}
+	for _, part := range strings.Split(v, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.freq = strings.ToUpper(value)
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(value)
+			if err == nil && rule.interval < 1 {
+				err = fmt.Errorf("INTERVAL must be at least 1, got %d", rule.interval)
+			}
+		case "COUNT":
+			rule.count, err = strconv.Atoi(value)
+		case "UNTIL":
+			rule.until, _, err = parseICSTime(icsProperty{value: value}, loc)
+			if err == nil && len(value) == len("20060102") {
+				// A date UNTIL includes that whole day.
+				rule.until = rule.until.AddDate(0, 0, 1).Add(-time.Second)
+			}
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := icsWeekdays[strings.ToUpper(code)]
+				if !ok {
+					// Positional days such as 2TU belong to monthly rules we don't expand.
+					return nil, nil
+				}
+				rule.byDay = append(rule.byDay, day)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	switch rule.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	case "":
+		return nil, errors.New("missing FREQ")
+	default:
+		return nil, nil
+	}
+	if rule.freq != "WEEKLY" && len(rule.byDay) > 0 {
+		return nil, nil
+	}
+	sort.Slice(rule.byDay, func(i, j int) bool {
+		return (rule.byDay[i]+6)%7 < (rule.byDay[j]+6)%7
+	})
+	return rule, nil
+}
+
+// Occurrences returns the occurrences of the event that overlap [from, to), in order. An
+// event that doesn't recur is its only occurrence.
+func (e ICSEvent) Occurrences(from, to time.Time) []ICSEvent {
+	if e.rule == nil {
+		if e.overlaps(from, to) {
+			return []ICSEvent{e}
+		}
+		return nil
+	}
+
+	length := e.End.Sub(e.Start)
+	var out []ICSEvent
+	seen := 0
+	first := e.rule.skip(e.Start, from)
+	for period := first; period < first+maxOccurrences; period++ {
+		for _, start := range e.rule.period(e.Start, period) {
+			if start.Before(e.Start) {
+				continue
+			}
+			if !e.rule.until.IsZero() && start.After(e.rule.until) {
+				return out
+			}
+			if (e.rule.count > 0 && seen >= e.rule.count) || !start.Before(to) {
+				return out
+			}
+			seen++
+			if e.exdates[start.UTC()] {
+				continue
+			}
+			o := e
+			o.Start, o.End, o.rule, o.exdates = start, start.Add(length), nil, nil
+			if o.overlaps(from, to) {
+				out = append(out, o)
+			}
+		}
+	}
+	return out
+}
+
+// skip returns the first period worth expanding for occurrences from a date on. A rule
+// with a COUNT is always expanded from the start, since earlier occurrences count.
+func (r *recurrence) skip(first, from time.Time) int {
+	if r.count > 0 || !from.After(first) {
+		return 0
+	}
+	days := int(from.Sub(first).Hours() / 24)
+	perPeriod := map[string]int{"DAILY": 1, "WEEKLY": 7, "MONTHLY": 31, "YEARLY": 366}[r.freq]
+	// One period back, so an occurrence that started earlier and runs into from is kept.
+	n := days/(perPeriod*r.interval) - 1
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// period returns the starts the rule gives in its nth period after first: the nth day,
+// week, month or year counting in intervals. Monthly and yearly repeats skip periods
+// without first's day, such as the 31st in a 30-day month.
+func (r *recurrence) period(first time.Time, n int) []time.Time {
+	step := n * r.interval
+	switch r.freq {
+	case "DAILY":
+		return []time.Time{first.AddDate(0, 0, step)}
+	case "WEEKLY":
+		if len(r.byDay) == 0 {
+			return []time.Time{first.AddDate(0, 0, 7*step)}
+		}
+		monday := first.AddDate(0, 0, -int((first.Weekday()+6)%7)+7*step)
+		starts := make([]time.Time, len(r.byDay))
+		for i, day := range r.byDay {
+			starts[i] = monday.AddDate(0, 0, int((day+6)%7))
+		}
+		return starts
+	case "MONTHLY", "YEARLY":
+		t := first.AddDate(0, step, 0)
+		if r.freq == "YEARLY" {
+			t = first.AddDate(step, 0, 0)
+		}
+		if t.Day() != first.Day() {
+			return nil
+		}
+		return []time.Time{t}
+	}
+	return nil
+}
+
+// unescapeICSString reverses escapeICSString.
+func unescapeICSString(s string) string {
+	replacer := strings.NewReplacer("\\\\", "\\", "\\,", ",", "\\;", ";", "\\n", "\n", "\\N", "\n")
+	return replacer.Replace(s)
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1@example.com\r\n" +
+	"SUMMARY:Parent\\, teacher\r\n" +
+	"  night\r\n" +
+	"DTSTART;TZID=America/New_York:20240305T183000\r\n" +
+	"DTEND;TZID=America/New_York:20240305T200000\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"DTSTART:19700101T000000\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Dentist\r\n" +
+	"DTSTART:20240306T150000Z\r\n" +
+	"DURATION:PT45M\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Birthday\r\n" +
+	"DTSTART;VALUE=DATE:20240307\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Called off\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART:20240307T190000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Soccer\r\n" +
+	"DTSTART:20240304T173000\r\n" +
+	"DTEND:20240304T190000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,TH;COUNT=5\r\n" +
+	"EXDATE:20240307T173000\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	events, err := ParseICS(strings.NewReader(testCalendar), loc)
+	if err != nil {
+		t.Fatalf("ParseICS returned error: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events without the cancelled one, got %+v", events)
+	}
+
+	pta := events[0]
+	if pta.Summary != "Parent, teacher night" {
+		t.Errorf("expected an unfolded, unescaped summary, got %q", pta.Summary)
+	}
+	if want := time.Date(2024, 3, 5, 23, 30, 0, 0, time.UTC); !pta.Start.Equal(want) || pta.End.Sub(pta.Start) != 90*time.Minute {
+		t.Errorf("expected 18:30-20:00 New York time, got %v-%v", pta.Start, pta.End)
+	}
+	if dentist := events[1]; !dentist.Start.Equal(time.Date(2024, 3, 6, 15, 0, 0, 0, time.UTC)) || dentist.End.Sub(dentist.Start) != 45*time.Minute {
+		t.Errorf("expected a 45 minute UTC event, got %+v", dentist)
+	}
+	if birthday := events[2]; !birthday.AllDay || birthday.End.Sub(birthday.Start) != 24*time.Hour {
+		t.Errorf("expected an all-day event, got %+v", birthday)
+	}
+
+	// Floating times are read in the given time zone, and the rule skips its EXDATE.
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var starts []string
+	for _, o := range events[3].Occurrences(from, from.AddDate(0, 1, 0)) {
+		starts = append(starts, o.Start.In(loc).Format("Mon 2006-01-02 15:04"))
+	}
+	want := []string{"Mon 2024-03-04 17:30", "Mon 2024-03-11 17:30", "Thu 2024-03-14 17:30", "Mon 2024-03-18 17:30"}
+	if strings.Join(starts, ",") != strings.Join(want, ",") {
+		t.Errorf("expected occurrences %v, got %v", want, starts)
+	}
+}
+
+func TestParseICS_Invalid(t *testing.T) {
+	for name, ics := range map[string]string{
+		"not a calendar": "hello",
+		"no start":       "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:x\nEND:VEVENT\nEND:VCALENDAR\n",
+		"bad start":      "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\nEND:VCALENDAR\n",
+		"bad duration":   "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20240304T180000Z\nDURATION:1H\nEND:VEVENT\nEND:VCALENDAR\n",
+	} {
+		if _, err := ParseICS(strings.NewReader(ics), time.UTC); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestICSEventOccurrences_Open(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Choir\nDTSTART:20000105T190000Z\nDTEND:20000105T203000Z\n" +
+		"RRULE:FREQ=WEEKLY;INTERVAL=2\nEND:VEVENT\nEND:VCALENDAR\n"
+	events, err := ParseICS(strings.NewReader(ics), time.UTC)
+	if err != nil {
+		t.Fatalf("ParseICS returned error: %v", err)
+	}
+	// Every other Wednesday since 2000 reaches March 2024 without expanding every week.
+	from := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	occurrences := events[0].Occurrences(from, from.AddDate(0, 0, 14))
+	if len(occurrences) != 1 || occurrences[0].Start.Weekday() != time.Wednesday {
+		t.Errorf("expected one Wednesday in the fortnight, got %+v", occurrences)
+	}
+}
+
+func TestParseICSDuration(t *testing.T) {
+	for v, want := range map[string]time.Duration{
+		"PT1H30M": 90 * time.Minute,
+		"P1D":     24 * time.Hour,
+		"P1W":     7 * 24 * time.Hour,
+		"P1DT2H":  26 * time.Hour,
+		"-PT15M":  -15 * time.Minute,
+	} {
+		if got, err := parseICSDuration(v); err != nil || got != want {
+			t.Errorf("parseICSDuration(%q) = %v, %v; want %v", v, got, err, want)
+		}
+	}
+}
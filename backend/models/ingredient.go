@@ -5,5 +5,5 @@ type Ingredient struct {
 	MealID   int
 	Quantity float64
 	Unit     string
-	Name     string // e.g., "unsalted butter (2 sticks), at room temperature, plus 1 tablespoon"
+	Name     string // e.g., "unsalted butter"
 }
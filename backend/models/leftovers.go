@@ -0,0 +1,31 @@
+package models
+
+// NightsCovered returns how many dinners one cook of the meal covers: Nights, or 1 when
+// it isn't set.
+func (m *Meal) NightsCovered() int {
+	if m.Nights < 1 {
+		return 1
+	}
+	return m.Nights
+}
+
+// IsLeftovers reports whether the meal is a plan day eating another day's leftovers.
+func (m *Meal) IsLeftovers() bool {
+	return m.LeftoversOf != 0
+}
+
+// LeftoversMealName returns the name shown for a day eating a meal's leftovers.
+func LeftoversMealName(mealName string) string {
+	return "Leftovers of " + mealName
+}
+
+// NewLeftovers returns the plan entry for a day eating the leftovers of a cooked meal.
+// It has no ID or ingredients of its own, so it never adds to the shopping list.
+func NewLeftovers(cooked *Meal) *Meal {
+	return &Meal{
+		MealName:    LeftoversMealName(cooked.MealName),
+		LeftoversOf: cooked.ID,
+		Tags:        []string{},
+		Ingredients: []Ingredient{},
+	}
+}
@@ -3,12 +3,17 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
-
-	"github.com/lib/pq"
 )
 
+// Meal is a recipe with its ingredients, steps and tags. Servings is how many the recipe
+// serves as written, or 0 when that isn't known. Nights is how many dinners one cook
+// covers, counting leftovers. Slots lists the meal slots (breakfast, lunch, dinner) the
+// recipe can be planned in. A plan day eating another day's leftovers has no ID of its
+// own and sets LeftoversOf to the cooked meal's ID.
 type Meal struct {
 	ID             int          `json:"id"`
 	MealName       string       `json:"mealName"`
@@ -16,12 +21,31 @@ type Meal struct {
 	LastPlanned    time.Time    `json:"lastPlanned"`
 	RedMeat        bool         `json:"redMeat"`
 	URL            string       `json:"url"`
+	Servings       int          `json:"servings"`
+	Nights         int          `json:"nights"`
+	LeftoversOf    int          `json:"leftoversOf,omitempty"`
+	Slots          []string     `json:"slots"`
+	Tags           []string     `json:"tags"`
 	Ingredients    []Ingredient `json:"ingredients"`
 	Steps          []Step       `json:"steps,omitempty"`
+	// Rating, TimesCooked and LastCooked summarise the cooking log. They are only filled
+	// in for meals loaded for planning; Rating is 0 for a meal never rated.
+	Rating      float64   `json:"rating,omitempty"`
+	TimesCooked int       `json:"timesCooked,omitempty"`
+	LastCooked  time.Time `json:"lastCooked"`
 }
 
+var (
+	// ErrMealNotFound is returned when a meal ID doesn't exist.
+	ErrMealNotFound = errors.New("meal not found")
+	// ErrIngredientNotFound is returned when an ingredient ID doesn't exist for the meal.
+	ErrIngredientNotFound = errors.New("ingredient not found")
+	// ErrNoAlternative is returned when no other meal can replace the one being swapped.
+	ErrNoAlternative = errors.New("no alternative meal found")
+)
+
 // MealColumns defines the column names for Meal queries.
-var MealColumns = []string{"id", "meal_name", "relative_effort", "last_planned", "red_meat", "url"}
+var MealColumns = []string{"id", "meal_name", "relative_effort", "last_planned", "red_meat", "url", "servings", "nights", "slots"}
 
 // MealsQueryFragment is the common fragment for querying meals along with ingredients.
 const MealsQueryFragment = `
@@ -32,30 +56,34 @@ const MealsQueryFragment = `
 		m.last_planned,
 		m.red_meat,
 		m.url,
+		m.servings,
+		m.nights,
+		m.slots,
 		mi.id AS ingredient_id,
 		mi.name,
-		CASE WHEN mi.quantity = '' THEN NULL ELSE mi.quantity::numeric END AS quantity,
+		CASE WHEN mi.quantity = '' THEN NULL ELSE CAST(mi.quantity AS NUMERIC) END AS quantity,
 		mi.unit
 	FROM meals m
 	LEFT JOIN ingredients mi ON m.id = mi.meal_id
 `
 
-// GetMealsByIDsQuery is the query used to retrieve meals (and their ingredients) for specific meal IDs.
-const GetMealsByIDsQuery = MealsQueryFragment + `
-	WHERE m.id = ANY($1)
+// GetMealsByIDsQuery returns the query used to retrieve meals (and their ingredients) for n
+// specific meal IDs. It lists a placeholder per ID rather than passing an array, which
+// SQLite doesn't support.
+func GetMealsByIDsQuery(n int) string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return MealsQueryFragment + `
+	WHERE m.id IN (` + strings.Join(placeholders, ", ") + `)
 	ORDER BY m.id, mi.id;
 `
+}
 
 // GetAllMealsQuery is the query used to retrieve all meals (and their ingredients).
 const GetAllMealsQuery = MealsQueryFragment + `;`
 
-// GetRandomMealExcludingQuery is used to retrieve a random meal excluding the provided meal id.
-const GetRandomMealExcludingQuery = MealsQueryFragment + `
-	WHERE m.id != $1
-	ORDER BY RANDOM()
-	LIMIT 1;
-`
-
 // processMealRows converts the SQL rows into a slice of Meal pointers.
 func processMealRows(rows *sql.Rows) ([]*Meal, error) {
 	var meals []*Meal
@@ -67,12 +95,15 @@ func processMealRows(rows *sql.Rows) ([]*Meal, error) {
 			nt             sql.NullTime // scan as sql.NullTime
 			redMeat        bool
 			url            sql.NullString // URL could be NULL
-			ingredientID   sql.NullInt64  // using sql.NullInt64 since a meal may have 0 ingredients
+			servings       int
+			nights         int
+			slots          string
+			ingredientID   sql.NullInt64 // using sql.NullInt64 since a meal may have 0 ingredients
 			ingredientName sql.NullString
 			quantity       sql.NullFloat64
 			unit           sql.NullString
 		)
-		err := rows.Scan(&mealID, &mealName, &relativeEffort, &nt, &redMeat, &url,
+		err := rows.Scan(&mealID, &mealName, &relativeEffort, &nt, &redMeat, &url, &servings, &nights, &slots,
 			&ingredientID, &ingredientName, &quantity, &unit)
 		if err != nil {
 			log.Printf("processMealRows: error scanning row (mealID=%d): %v", mealID, err)
@@ -107,6 +138,10 @@ func processMealRows(rows *sql.Rows) ([]*Meal, error) {
 				LastPlanned:    lp,
 				RedMeat:        redMeat,
 				URL:            urlValue,
+				Servings:       servings,
+				Nights:         nights,
+				Slots:          parseSlots(slots),
+				Tags:           []string{},
 				Ingredients:    []Ingredient{},
 				Steps:          []Step{},
 			}
@@ -134,7 +169,14 @@ func processMealRows(rows *sql.Rows) ([]*Meal, error) {
 
 // GetMealsByIDs retrieves meals (including their ingredients) from the database for the given meal IDs.
 func GetMealsByIDs(db *sql.DB, ids []int) ([]*Meal, error) {
-	rows, err := db.Query(GetMealsByIDsQuery, pq.Array(ids))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := db.Query(GetMealsByIDsQuery(len(ids)), args...)
 	if err != nil {
 		log.Printf("GetMealsByIDs: error executing query: %v", err)
 		return nil, err
@@ -155,6 +197,9 @@ func GetMealsByIDs(db *sql.DB, ids []int) ([]*Meal, error) {
 		}
 		meal.Steps = steps
 	}
+	if err := loadMealTags(db, meals, false); err != nil {
+		log.Printf("GetMealsByIDs: error getting tags: %v", err) // keep the meals without their tags
+	}
 
 	return meals, nil
 }
@@ -182,26 +227,11 @@ func GetAllMeals(db *sql.DB) ([]*Meal, error) {
 		}
 		meal.Steps = steps
 	}
-
-	return meals, nil
-}
-
-// SwapMeal returns a random meal that is not the current meal.
-func SwapMeal(currentMealID int, db *sql.DB) (*Meal, error) {
-	rows, err := db.Query(GetRandomMealExcludingQuery, currentMealID)
-	if err != nil {
-		return nil, err
+	if err := loadMealTags(db, meals, true); err != nil {
+		log.Printf("GetAllMeals: error getting tags: %v", err) // keep the meals without their tags
 	}
-	defer rows.Close()
 
-	meals, err := processMealRows(rows)
-	if err != nil {
-		return nil, err
-	}
-	if len(meals) == 0 {
-		return nil, errors.New("no alternative meal found")
-	}
-	return meals[0], nil
+	return meals, nil
 }
 
 // UpdateMealIngredient updates a single ingredient for the specified meal using its ID.
@@ -220,12 +250,16 @@ func UpdateMealIngredient(db *sql.DB, mealID int, ingredient Ingredient) error {
 	}
 	rowsAffected, _ := res.RowsAffected()
 	log.Printf("UpdateMealIngredient: updated ingredientID=%d in mealID=%d, rowsAffected=%d", ingredient.ID, mealID, rowsAffected)
+	if rowsAffected == 0 {
+		return ErrIngredientNotFound
+	}
 	return nil
 }
 
-// DeleteMealIngredient deletes an ingredient by its ID.
-func DeleteMealIngredient(db *sql.DB, ingredientID int) error {
-	result, err := db.Exec("DELETE FROM ingredients WHERE id = $1", ingredientID)
+// DeleteMealIngredient deletes an ingredient of a meal by its ID. An ingredient of another
+// meal isn't deleted and returns ErrIngredientNotFound.
+func DeleteMealIngredient(db *sql.DB, mealID, ingredientID int) error {
+	result, err := db.Exec("DELETE FROM ingredients WHERE id = $1 AND meal_id = $2", ingredientID, mealID)
 	if err != nil {
 		log.Printf("DeleteMealIngredient: error executing delete for ingredientID=%d: %v", ingredientID, err)
 		return err
@@ -236,9 +270,8 @@ func DeleteMealIngredient(db *sql.DB, ingredientID int) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		err := errors.New("ingredient not found")
-		log.Printf("DeleteMealIngredient: %v for ingredientID=%d", err, ingredientID)
-		return err
+		log.Printf("DeleteMealIngredient: %v for ingredientID=%d, mealID=%d", ErrIngredientNotFound, ingredientID, mealID)
+		return ErrIngredientNotFound
 	}
 	log.Printf("DeleteMealIngredient: deleted ingredientID=%d, rowsAffected=%d", ingredientID, rowsAffected)
 	return nil
@@ -276,40 +309,57 @@ func DeleteMeal(db *sql.DB, mealID int) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("meal not found")
+		return ErrMealNotFound
 	}
 
 	return tx.Commit()
 }
 
-// UpdateLastPlannedDates updates the last_planned date to current time for the given meal IDs
-func UpdateLastPlannedDates(db *sql.DB, mealIDs []int) error {
-	if len(mealIDs) == 0 {
-		return nil
-	}
+// SetLastPlanned records when a meal was last planned. A zero time clears it, as for a
+// meal that has never been planned.
+func SetLastPlanned(db *sql.DB, mealID int, lastPlanned time.Time) error {
+	return setLastPlanned(db, mealID, lastPlanned)
+}
 
-	// Start a transaction
-	tx, err := db.Begin()
+// setLastPlanned runs SetLastPlanned on a database or a transaction.
+func setLastPlanned(db execer, mealID int, lastPlanned time.Time) error {
+	var value interface{}
+	if !lastPlanned.IsZero() {
+		value = lastPlanned.UTC()
+	}
+	res, err := db.Exec("UPDATE meals SET last_planned = $1 WHERE id = $2", value, mealID)
 	if err != nil {
+		log.Printf("SetLastPlanned: error updating mealID=%d: %v", mealID, err)
 		return err
 	}
-	defer tx.Rollback()
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrMealNotFound
+	}
+	return nil
+}
 
-	// Update last_planned date for all meals in the plan
-	_, err = tx.Exec(`
-		UPDATE meals 
-		SET last_planned = NOW() 
-		WHERE id = ANY($1)
-	`, pq.Array(mealIDs))
+// normalizeMeal normalizes a meal's tags and slots before it's stored.
+func normalizeMeal(meal *Meal) error {
+	tags, err := NormalizeTagNames(meal.Tags)
 	if err != nil {
 		return err
 	}
-
-	return tx.Commit()
+	meal.Tags = tags
+	slots, err := NormalizeSlots(meal.Slots)
+	if err != nil {
+		return err
+	}
+	meal.Slots = slots
+	return nil
 }
 
 // CreateMeal inserts a new meal and its ingredients into the database
 func CreateMeal(db *sql.DB, meal Meal) (*Meal, error) {
+	if err := normalizeMeal(&meal); err != nil {
+		return nil, err
+	}
+
 	// Start a transaction
 	tx, err := db.Begin()
 	if err != nil {
@@ -318,31 +368,121 @@ func CreateMeal(db *sql.DB, meal Meal) (*Meal, error) {
 	}
 	defer tx.Rollback()
 
+	created, err := createMeal(tx, meal)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		log.Printf("CreateMeal: error committing transaction: %v", err)
+		return nil, err
+	}
+
+	log.Printf("CreateMeal: created meal with ID %d, %d ingredients, and %d steps",
+		created.ID, len(created.Ingredients), len(created.Steps))
+	return created, nil
+}
+
+// createMeal inserts a normalized meal with its ingredients, steps and tags within tx.
+func createMeal(tx *sql.Tx, meal Meal) (*Meal, error) {
 	// Insert the meal
 	var mealID int
-	err = tx.QueryRow(
-		"INSERT INTO meals (meal_name, relative_effort, red_meat, url) VALUES ($1, $2, $3, $4) RETURNING id",
-		meal.MealName, meal.RelativeEffort, meal.RedMeat, meal.URL,
+	err := tx.QueryRow(
+		"INSERT INTO meals (meal_name, relative_effort, red_meat, url, servings, nights, slots) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		meal.MealName, meal.RelativeEffort, meal.RedMeat, meal.URL, meal.Servings, meal.NightsCovered(), formatSlots(meal.Slots),
 	).Scan(&mealID)
 	if err != nil {
 		log.Printf("CreateMeal: error inserting meal: %v", err)
 		return nil, err
 	}
 	meal.ID = mealID
+	meal.Nights = meal.NightsCovered()
+
+	if err := insertMealParts(tx, &meal); err != nil {
+		return nil, err
+	}
+	return &meal, nil
+}
+
+// ReplaceMeal overwrites a stored meal in place: its fields, ingredients, steps, tags and
+// slots become meal's, while its ID, last_planned, plan entries and cooking log are kept.
+// It returns ErrMealNotFound for an unknown meal.
+func ReplaceMeal(db *sql.DB, mealID int, meal Meal) (*Meal, error) {
+	if err := normalizeMeal(&meal); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("ReplaceMeal: error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	replaced, err := replaceMeal(tx, mealID, meal)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		log.Printf("ReplaceMeal: error committing transaction: %v", err)
+		return nil, err
+	}
+	return replaced, nil
+}
+
+// replaceMeal overwrites a stored meal with a normalized one within tx.
+func replaceMeal(tx *sql.Tx, mealID int, meal Meal) (*Meal, error) {
+	res, err := tx.Exec(
+		"UPDATE meals SET meal_name = $1, relative_effort = $2, red_meat = $3, url = $4, servings = $5, nights = $6, slots = $7 WHERE id = $8",
+		meal.MealName, meal.RelativeEffort, meal.RedMeat, meal.URL, meal.Servings, meal.NightsCovered(), formatSlots(meal.Slots), mealID,
+	)
+	if err != nil {
+		log.Printf("ReplaceMeal: error updating mealID=%d: %v", mealID, err)
+		return nil, err
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		return nil, ErrMealNotFound
+	}
+	for _, table := range []string{"ingredients", "recipe_steps", "meal_tags"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE meal_id = $1", mealID); err != nil {
+			log.Printf("ReplaceMeal: error clearing %s for mealID=%d: %v", table, mealID, err)
+			return nil, err
+		}
+	}
+	var lastPlanned sql.NullTime
+	if err := tx.QueryRow("SELECT last_planned FROM meals WHERE id = $1", mealID).Scan(&lastPlanned); err != nil {
+		log.Printf("ReplaceMeal: error reading last_planned for mealID=%d: %v", mealID, err)
+		return nil, err
+	}
+	meal.LastPlanned = lastPlanned.Time
+	meal.ID = mealID
+	meal.Nights = meal.NightsCovered()
 
+	if err := insertMealParts(tx, &meal); err != nil {
+		return nil, err
+	}
+	log.Printf("ReplaceMeal: replaced meal with ID %d, %d ingredients, and %d steps",
+		mealID, len(meal.Ingredients), len(meal.Steps))
+	return &meal, nil
+}
+
+// insertMealParts inserts a stored meal's ingredients, steps and tags within tx, setting
+// their IDs.
+func insertMealParts(tx *sql.Tx, meal *Meal) error {
 	// Insert the ingredients
 	for i := range meal.Ingredients {
 		var ingredientID int
-		err = tx.QueryRow(
+		err := tx.QueryRow(
 			"INSERT INTO ingredients (meal_id, quantity, unit, name) VALUES ($1, $2, $3, $4) RETURNING id",
-			mealID, meal.Ingredients[i].Quantity, meal.Ingredients[i].Unit, meal.Ingredients[i].Name,
+			meal.ID, meal.Ingredients[i].Quantity, meal.Ingredients[i].Unit, meal.Ingredients[i].Name,
 		).Scan(&ingredientID)
 		if err != nil {
 			log.Printf("CreateMeal: error inserting ingredient %d: %v", i, err)
-			return nil, err
+			return err
 		}
 		meal.Ingredients[i].ID = ingredientID
-		meal.Ingredients[i].MealID = mealID
+		meal.Ingredients[i].MealID = meal.ID
 	}
 
 	// Insert the steps if any
@@ -355,7 +495,7 @@ func CreateMeal(db *sql.DB, meal Meal) (*Meal, error) {
 		`)
 		if err != nil {
 			log.Printf("CreateMeal: error preparing statement for steps: %v", err)
-			return nil, err
+			return err
 		}
 		defer stmtStep.Close()
 
@@ -363,26 +503,18 @@ func CreateMeal(db *sql.DB, meal Meal) (*Meal, error) {
 			var stepID int
 			// Make sure step number is set correctly (1-indexed)
 			meal.Steps[i].StepNumber = i + 1
-			meal.Steps[i].MealID = mealID
+			meal.Steps[i].MealID = meal.ID
 
 			err = stmtStep.QueryRow(
-				mealID, meal.Steps[i].StepNumber, meal.Steps[i].Instruction,
+				meal.ID, meal.Steps[i].StepNumber, meal.Steps[i].Instruction,
 			).Scan(&stepID)
 			if err != nil {
 				log.Printf("CreateMeal: error inserting step %d: %v", i, err)
-				return nil, err
+				return err
 			}
 			meal.Steps[i].ID = stepID
 		}
 	}
 
-	// Commit the transaction
-	if err = tx.Commit(); err != nil {
-		log.Printf("CreateMeal: error committing transaction: %v", err)
-		return nil, err
-	}
-
-	log.Printf("CreateMeal: created meal with ID %d, %d ingredients, and %d steps",
-		mealID, len(meal.Ingredients), len(meal.Steps))
-	return &meal, nil
+	return insertMealTags(tx, meal.ID, meal.Tags)
 }
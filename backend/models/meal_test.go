@@ -40,14 +40,14 @@ func setupTestDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
 // setupMealRows creates mock rows for meal queries
 func setupMealRows(meals []testMeal) *sqlmock.Rows {
 	rows := sqlmock.NewRows([]string{
-		"id", "meal_name", "relative_effort", "last_planned", "red_meat", "url",
+		"id", "meal_name", "relative_effort", "last_planned", "red_meat", "url", "servings", "nights", "slots",
 		"ingredient_id", "name", "quantity", "unit",
 	})
 
 	for _, meal := range meals {
 		// If meal has no ingredients, add a row with null ingredient values
 		if len(meal.Ingredients) == 0 {
-			rows.AddRow(meal.ID, meal.Name, meal.Effort, meal.LastPlanned, meal.RedMeat, meal.URL,
+			rows.AddRow(meal.ID, meal.Name, meal.Effort, meal.LastPlanned, meal.RedMeat, meal.URL, 0, 1, "dinner",
 				nil, nil, nil, nil)
 			continue
 		}
@@ -55,7 +55,7 @@ func setupMealRows(meals []testMeal) *sqlmock.Rows {
 		// Add a row for each ingredient
 		for _, ing := range meal.Ingredients {
 			rows.AddRow(
-				meal.ID, meal.Name, meal.Effort, meal.LastPlanned, meal.RedMeat, meal.URL,
+				meal.ID, meal.Name, meal.Effort, meal.LastPlanned, meal.RedMeat, meal.URL, 0, 1, "dinner",
 				ing.ID, ing.Name, ing.Quantity, ing.Unit)
 		}
 	}
@@ -138,7 +138,7 @@ func TestGetMealsByIDs(t *testing.T) {
 	defer db.Close()
 
 	// In tests we use the shared query
-	expectedQuery := GetMealsByIDsQuery
+	expectedQuery := GetMealsByIDsQuery(2)
 
 	// Setup test data
 	now := time.Now()
@@ -171,7 +171,7 @@ func TestGetMealsByIDs(t *testing.T) {
 	// Setup mock rows and expectations
 	rows := setupMealRows(testMeals)
 	mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).
-		WithArgs(sqlmock.AnyArg()).
+		WithArgs(1, 2).
 		WillReturnRows(rows)
 
 	// Call GetMealsByIDs with meal IDs 1 and 2
@@ -266,51 +266,6 @@ func TestGetAllMeals(t *testing.T) {
 	assertMealEquals(t, testMeals[1], mealB)
 }
 
-// TestSwapMeal tests the SwapMeal function to ensure it returns a different meal.
-func TestSwapMeal(t *testing.T) {
-	// Create a new sqlmock database connection
-	db, mock := setupTestDB(t)
-	defer db.Close()
-
-	currentMealID := 1
-	expectedQuery := GetRandomMealExcludingQuery
-
-	// Setup test data
-	now := time.Now()
-	testMeals := []testMeal{
-		{
-			ID:          2,
-			Name:        "Meal B",
-			Effort:      3,
-			LastPlanned: &now,
-			RedMeat:     true,
-			URL:         "https://example.com/mealb",
-			Ingredients: []testIngredient{
-				{ID: 2, Name: "Milk", Quantity: 2.5, Unit: "gallon"},
-				{ID: 3, Name: "Bread", Quantity: nil, Unit: "loaf"},
-			},
-		},
-	}
-
-	// Setup mock rows and expectations
-	rows := setupMealRows(testMeals)
-	mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).
-		WithArgs(currentMealID).
-		WillReturnRows(rows)
-
-	// Call SwapMeal
-	newMeal, err := SwapMeal(currentMealID, db)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if newMeal.ID == currentMealID {
-		t.Errorf("expected different meal, got same id %d", currentMealID)
-	}
-
-	// Verify properties of the returned meal
-	assertMealEquals(t, testMeals[0], newMeal)
-}
-
 // TestUpdateMealIngredient tests the UpdateMealIngredient function to ensure it properly updates an ingredient.
 func TestUpdateMealIngredient(t *testing.T) {
 	// Create a new sqlmock database connection
@@ -348,15 +303,16 @@ func TestDeleteMealIngredient(t *testing.T) {
 	db, mock := setupTestDB(t)
 	defer db.Close()
 
+	mealID := 1
 	ingredientID := 2
 
 	// Setup expectations for delete query
-	mock.ExpectExec("DELETE FROM ingredients WHERE id = \\$1").
-		WithArgs(ingredientID).
+	mock.ExpectExec("DELETE FROM ingredients WHERE id = \\$1 AND meal_id = \\$2").
+		WithArgs(ingredientID, mealID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Call DeleteMealIngredient
-	err := DeleteMealIngredient(db, ingredientID)
+	err := DeleteMealIngredient(db, mealID, ingredientID)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -428,8 +384,8 @@ func TestCreateMeal(t *testing.T) {
 	mock.ExpectBegin()
 
 	// Expect meal insertion
-	mock.ExpectQuery("INSERT INTO meals \\(meal_name, relative_effort, red_meat, url\\) VALUES").
-		WithArgs(meal.MealName, meal.RelativeEffort, meal.RedMeat, meal.URL).
+	mock.ExpectQuery("INSERT INTO meals \\(meal_name, relative_effort, red_meat, url, servings, nights, slots\\) VALUES").
+		WithArgs(meal.MealName, meal.RelativeEffort, meal.RedMeat, meal.URL, meal.Servings, 1, "dinner").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	// Expect ingredient insertions
@@ -488,7 +444,7 @@ func TestCreateMeal_Error(t *testing.T) {
 
 	// Expect meal insertion with error
 	mock.ExpectQuery("INSERT INTO meals").
-		WithArgs(meal.MealName, meal.RelativeEffort, meal.RedMeat, meal.URL).
+		WithArgs(meal.MealName, meal.RelativeEffort, meal.RedMeat, meal.URL, meal.Servings, 1, "dinner").
 		WillReturnError(sql.ErrConnDone)
 
 	// Expect transaction rollback
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 )
 
 func TestMealPlanToICS(t *testing.T) {
@@ -27,3 +28,125 @@ func TestMealPlanToICS(t *testing.T) {
 		t.Errorf("ics missing start date")
 	}
 }
+
+func TestMealPlanToICS_Leftovers(t *testing.T) {
+	chili := &Meal{ID: 4, MealName: "Chili", Nights: 2}
+	plan := map[string]*Meal{"Monday": chili, "Tuesday": NewLeftovers(chili)}
+	ics := MealPlanToICS(plan, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+
+	if !strings.Contains(ics, "SUMMARY:Leftovers of Chili\r\nCATEGORIES:Leftovers\r\n") {
+		t.Errorf("ics missing the leftovers event, got:\n%s", ics)
+	}
+	if !strings.Contains(ics, "UID:20240402-dinner@mealplanner") {
+		t.Errorf("ics missing a distinct leftovers UID, got:\n%s", ics)
+	}
+	if strings.Count(ics, "CATEGORIES:") != 1 {
+		t.Errorf("expected only the leftovers day to be categorised, got:\n%s", ics)
+	}
+}
+
+func TestMealPlanToICS_Slots(t *testing.T) {
+	plan := map[string]*Meal{
+		"Monday":           {ID: 1, MealName: "Tacos"},
+		"Monday/breakfast": {ID: 2, MealName: "Pancakes"},
+		"Tuesday/lunch":    {ID: 3, MealName: "Soup"},
+	}
+	ics := MealPlanToICS(plan, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+
+	for _, want := range []string{
+		"UID:20240401-dinner@mealplanner",
+		"UID:20240401-breakfast@mealplanner",
+		"SUMMARY:Breakfast: Pancakes",
+		"UID:20240402-lunch@mealplanner",
+		"SUMMARY:Lunch: Soup",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("ics missing %q, got:\n%s", want, ics)
+		}
+	}
+	if strings.Index(ics, "Pancakes") > strings.Index(ics, "Tacos") {
+		t.Errorf("expected breakfast before dinner, got:\n%s", ics)
+	}
+}
+
+func TestMealPlanToICS_EatingOut(t *testing.T) {
+	plan := map[string]*Meal{
+		"Friday":   {MealName: EatingOutMealName},
+		"Saturday": {MealName: EatingOutMealName},
+	}
+	ics := MealPlanToICS(plan, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+
+	if !strings.Contains(ics, "UID:20240405-dinner@mealplanner") || !strings.Contains(ics, "UID:20240406-dinner@mealplanner") {
+		t.Errorf("expected each eating out day to have its own UID, got:\n%s", ics)
+	}
+}
+
+func TestCalendarFeed(t *testing.T) {
+	monday := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2024, 3, 30, 9, 0, 0, 0, time.UTC)
+	chili := &Meal{ID: 4, MealName: "Chili", RelativeEffort: 3, Nights: 2}
+	plans := []*MealPlan{
+		{ID: 2, WeekStart: monday, Days: 7, CreatedAt: created, Entries: []MealPlanEntry{
+			{Date: monday, Slot: SlotDinner, MealID: 4, Status: EntryStatusPlanned, Servings: 8, Sequence: 1, Meal: chili},
+			{Date: monday.AddDate(0, 0, 1), Slot: SlotDinner, MealID: 4, Status: EntryStatusLeftovers, Meal: NewLeftovers(chili)},
+			{Date: monday.AddDate(0, 0, 4), Slot: SlotDinner, Status: EntryStatusEatingOut, Meal: &Meal{MealName: EatingOutMealName}},
+		}},
+		// An earlier plan overlapping the Monday loses it to the later one.
+		{ID: 1, WeekStart: monday.AddDate(0, 0, -3), Days: 4, CreatedAt: created, Entries: []MealPlanEntry{
+			{Date: monday, Slot: SlotDinner, MealID: 9, Status: EntryStatusPlanned, Meal: &Meal{ID: 9, MealName: "Toast"}},
+		}},
+	}
+	recipes := map[int]*Meal{4: {
+		ID:          4,
+		MealName:    "Chili",
+		Servings:    4,
+		Ingredients: []Ingredient{{Name: "kidney beans, drained", Quantity: 1, Unit: "can"}},
+		Steps:       []Step{{Instruction: strings.Repeat("Stir and simmer; ", 6)}},
+	}}
+
+	ics := CalendarFeed(plans, recipes, CalendarOptions{})
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	for _, want := range []string{
+		"UID:20240401-dinner@mealplanner\r\nDTSTAMP:20240330T090000Z\r\nSEQUENCE:1\r\n",
+		"SUMMARY:Chili\r\n",
+		"DESCRIPTION:Serves 8\\n\\nIngredients:\\n- 2 can kidney beans\\, drained\\n\\nSteps:",
+		"UID:20240402-dinner@mealplanner",
+		"UID:20240405-dinner@mealplanner",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("feed missing %q, got:\n%s", want, unfolded)
+		}
+	}
+	if strings.Contains(ics, "Toast") {
+		t.Errorf("expected the later plan to win the Monday, got:\n%s", ics)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+	if !strings.Contains(unfolded, "1. "+strings.Repeat("Stir and simmer\\; ", 6)) {
+		t.Errorf("expected the folded description to unfold to the steps, got:\n%s", unfolded)
+	}
+
+	loc, _ := time.LoadLocation("America/New_York")
+	timed := CalendarFeed(plans, recipes, CalendarOptions{Timed: true, Location: loc, MealTimes: map[string]time.Duration{SlotDinner: 19 * time.Hour}})
+	// Effort 3 preps for 45 minutes before 19:00 New York time (UTC-4 in April).
+	if !strings.Contains(timed, "DTSTART:20240401T221500Z\r\nDTEND:20240401T230000Z\r\n") {
+		t.Errorf("expected a timed event from prep start to dinner, got:\n%s", timed)
+	}
+}
+
+func TestICSLineFolding(t *testing.T) {
+	w := &icsWriter{}
+	w.line("SUMMARY", strings.Repeat("é", 60))
+	lines := strings.Split(strings.TrimSuffix(w.b.String(), "\r\n"), "\r\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], " ") {
+		t.Fatalf("expected one continuation line, got %q", lines)
+	}
+	for _, line := range lines {
+		if len(line) > 75 || !utf8.ValidString(line) {
+			t.Errorf("bad folded line %q", line)
+		}
+	}
+}
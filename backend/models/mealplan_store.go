@@ -0,0 +1,435 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Meal plan entry statuses.
+const (
+	EntryStatusPlanned   = "planned"
+	EntryStatusEatingOut = "eating_out"
+	// EntryStatusLeftovers is a day eating the leftovers of the meal it refers to.
+	EntryStatusLeftovers = "leftovers"
+)
+
+// EatingOutMealName is the placeholder meal name used for days where we don't cook.
+const EatingOutMealName = "Eating out"
+
+// MealPlan is a finalized plan as stored in the meal_plans table. It covers Days days from
+// WeekStart, which for a weekly plan is the first day of its week.
+type MealPlan struct {
+	ID        int             `json:"id"`
+	WeekStart time.Time       `json:"weekStart"`
+	Days      int             `json:"days"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Entries   []MealPlanEntry `json:"entries"`
+}
+
+// MealPlanEntry is a single meal slot of a day in a stored meal plan.
+type MealPlanEntry struct {
+	ID     int `json:"id"`
+	PlanID int `json:"planId"`
+	// Date is the calendar date of the entry, as midnight UTC; Day is its weekday name.
+	Date   time.Time `json:"date"`
+	Day    string    `json:"day"`
+	Slot   string    `json:"slot"`
+	MealID int       `json:"mealId"`
+	Status string    `json:"status"`
+	// Sequence counts how often the day's meal changed across saved versions of the plan,
+	// as calendars number revisions of an event.
+	Sequence int `json:"sequence"`
+	// Servings is how many the day cooks for, or 0 to cook the recipe as written.
+	Servings int   `json:"servings,omitempty"`
+	Meal     *Meal `json:"meal,omitempty"`
+}
+
+// ErrNoMealPlan is returned when no stored meal plan matches the request.
+var ErrNoMealPlan = errors.New("no saved meal plan found")
+
+// mealPlanEntriesQuery loads the entries of a plan together with the meal columns needed for display.
+const mealPlanEntriesQuery = `
+	SELECT e.id, e.plan_id, e.date, e.day, e.slot, e.meal_id, e.status, e.servings, e.sequence,
+		m.meal_name, m.relative_effort, m.last_planned, m.red_meat, m.url, m.servings, m.nights
+	FROM meal_plan_entries e
+	LEFT JOIN meals m ON m.id = e.meal_id
+	WHERE e.plan_id = $1
+	ORDER BY e.date, e.id
+`
+
+// WeekStartFor returns midnight UTC of the Monday on or before t.
+func WeekStartFor(t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(t.Weekday()) + 6) % 7 // Monday = 0
+	return t.AddDate(0, 0, -offset)
+}
+
+// PlanSpan works out the dates a plan covers: where it starts, how many days it runs and
+// the date of each key. Dated keys are used as they are, so a plan with only dated keys runs
+// from its first date to its last, wherever start is. Weekday keys fall in the household's
+// week containing start (see StartOfWeek), and a plan with any of them covers that week.
+func (r PlanRules) PlanSpan(start time.Time, keys []string) (time.Time, int, map[string]time.Time) {
+	week := r.StartOfWeek(start)
+	weekDates := WeekDates(week)
+	var first, last time.Time
+	weekly := len(keys) == 0
+	dates := make(map[string]time.Time, len(keys))
+	for _, key := range keys {
+		day, _ := SplitPlanKey(key)
+		date, ok := ParsePlanDate(day)
+		if !ok {
+			weekly = true
+			date = weekDates[day]
+		}
+		if first.IsZero() || date.Before(first) {
+			first = date
+		}
+		if date.After(last) {
+			last = date
+		}
+		dates[key] = date
+	}
+	if weekly {
+		if first.IsZero() || week.Before(first) {
+			first = week
+		}
+		if end := week.AddDate(0, 0, len(Weekdays)-1); end.After(last) {
+			last = end
+		}
+	}
+	return first, int(last.Sub(first).Hours()/24) + 1, dates
+}
+
+// SaveMealPlan stores a plan, replacing any plan previously saved with the same start, and
+// updates last_planned for every meal it contains. The plan and servings are keyed by plan
+// key (see PlanKey), by date or by weekday; PlanSpan, under the saved rules, decides the
+// dates the plan covers.
+// servings optionally overrides how many a day's slot cooks for. Keys that aren't plan keys
+// are ignored.
+func SaveMealPlan(db *sql.DB, weekStart time.Time, plan map[string]*Meal, servings map[string]int) (*MealPlan, error) {
+	rules, err := GetPlanRules(db)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("SaveMealPlan: error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	saved, err := saveMealPlan(tx, rules, weekStart, plan, servings)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("SaveMealPlan: error committing transaction: %v", err)
+		return nil, err
+	}
+	return saved, nil
+}
+
+// saveMealPlan stores a plan under rules within tx.
+func saveMealPlan(tx *sql.Tx, rules PlanRules, weekStart time.Time, plan map[string]*Meal, servings map[string]int) (*MealPlan, error) {
+	var keys []string
+	for _, key := range PlanKeys(plan) {
+		if plan[key] != nil && IsPlanKey(key) {
+			keys = append(keys, key)
+		}
+	}
+	weekStart, days, dates := rules.PlanSpan(weekStart, keys)
+
+	previous, err := savedDays(tx, weekStart, days)
+	if err != nil {
+		log.Printf("SaveMealPlan: error reading the days being replaced: %v", err)
+		return nil, err
+	}
+
+	// A start date only has one current plan; finalizing again replaces it.
+	if _, err := tx.Exec(`
+		DELETE FROM meal_plan_entries
+		WHERE plan_id IN (SELECT id FROM meal_plans WHERE week_start = $1)
+	`, weekStart); err != nil {
+		log.Printf("SaveMealPlan: error deleting previous entries for week %s: %v", weekStart.Format("2006-01-02"), err)
+		return nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM meal_plans WHERE week_start = $1", weekStart); err != nil {
+		log.Printf("SaveMealPlan: error deleting previous plan for week %s: %v", weekStart.Format("2006-01-02"), err)
+		return nil, err
+	}
+
+	saved := &MealPlan{WeekStart: weekStart, Days: days, CreatedAt: time.Now().UTC()}
+	err = tx.QueryRow(
+		"INSERT INTO meal_plans (week_start, days, created_at) VALUES ($1, $2, $3) RETURNING id",
+		weekStart, days, saved.CreatedAt,
+	).Scan(&saved.ID)
+	if err != nil {
+		log.Printf("SaveMealPlan: error inserting plan: %v", err)
+		return nil, err
+	}
+
+	for _, key := range keys {
+		meal := plan[key]
+		_, slot := SplitPlanKey(key)
+		date := dates[key]
+		entry := MealPlanEntry{PlanID: saved.ID, Date: date, Day: date.Weekday().String(), Slot: slot, MealID: meal.ID, Status: EntryStatusPlanned, Servings: servings[key], Meal: meal}
+		var mealID interface{} = meal.ID
+		switch {
+		case meal.IsLeftovers():
+			entry.Status = EntryStatusLeftovers
+			entry.MealID = meal.LeftoversOf
+			mealID = meal.LeftoversOf
+		case meal.ID == 0:
+			entry.Status = EntryStatusEatingOut
+			mealID = nil
+		}
+		if prev, ok := previous[entry.DateKey()]; ok {
+			entry.Sequence = entry.SequenceAfter(prev)
+		}
+
+		err = tx.QueryRow(
+			"INSERT INTO meal_plan_entries (plan_id, date, day, slot, meal_id, status, servings, sequence) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+			saved.ID, date, entry.Day, slot, mealID, entry.Status, entry.Servings, entry.Sequence,
+		).Scan(&entry.ID)
+		if err != nil {
+			log.Printf("SaveMealPlan: error inserting entry for %s: %v", key, err)
+			return nil, err
+		}
+		saved.Entries = append(saved.Entries, entry)
+
+		// Keep last_planned in sync so the generator's repeat window still works.
+		if meal.ID != 0 {
+			if _, err := tx.Exec("UPDATE meals SET last_planned = $1 WHERE id = $2", saved.CreatedAt, meal.ID); err != nil {
+				log.Printf("SaveMealPlan: error updating last_planned for mealID=%d: %v", meal.ID, err)
+				return nil, err
+			}
+		}
+	}
+	return saved, nil
+}
+
+// savedDays returns what the saved plans hold on the days from start, keyed by dated plan
+// key. Where plans overlap, the one that starts latest wins.
+func savedDays(tx *sql.Tx, start time.Time, days int) (map[string]MealPlanEntry, error) {
+	rows, err := tx.Query(`
+		SELECT e.date, e.slot, e.meal_id, e.status, e.sequence
+		FROM meal_plan_entries e
+		JOIN meal_plans p ON p.id = e.plan_id
+		WHERE e.date >= $1 AND e.date < $2
+		ORDER BY p.week_start, p.id
+	`, start, start.AddDate(0, 0, days))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	saved := make(map[string]MealPlanEntry)
+	for rows.Next() {
+		var e MealPlanEntry
+		var mealID sql.NullInt64
+		if err := rows.Scan(&e.Date, &e.Slot, &mealID, &e.Status, &e.Sequence); err != nil {
+			return nil, err
+		}
+		e.MealID = int(mealID.Int64)
+		saved[e.DateKey()] = e
+	}
+	return saved, rows.Err()
+}
+
+// SequenceAfter returns the entry's sequence when it replaces prev, a saved entry for the
+// same day and slot: prev's sequence while the day plans the same meal, one more once it
+// changes. Marking a day cooked or skipped doesn't count as a change.
+func (e MealPlanEntry) SequenceAfter(prev MealPlanEntry) int {
+	kind := func(status string) string {
+		if status == EntryStatusEatingOut || status == EntryStatusLeftovers {
+			return status
+		}
+		return EntryStatusPlanned
+	}
+	if e.MealID == prev.MealID && kind(e.Status) == kind(prev.Status) {
+		return prev.Sequence
+	}
+	return prev.Sequence + 1
+}
+
+// GetLatestMealPlan returns the stored plan with the most recent start.
+func GetLatestMealPlan(db *sql.DB) (*MealPlan, error) {
+	var plan MealPlan
+	err := db.QueryRow(`
+		SELECT id, week_start, days, created_at
+		FROM meal_plans
+		ORDER BY week_start DESC, id DESC
+		LIMIT 1
+	`).Scan(&plan.ID, &plan.WeekStart, &plan.Days, &plan.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoMealPlan
+	}
+	if err != nil {
+		log.Printf("GetLatestMealPlan: error executing query: %v", err)
+		return nil, err
+	}
+
+	plan.Entries, err = getMealPlanEntries(db, plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// ListMealPlans returns the stored plans covering any date within [from, to], oldest
+// first. A zero from or to leaves that side of the range open.
+func ListMealPlans(db *sql.DB, from, to time.Time) ([]*MealPlan, error) {
+	query := "SELECT id, week_start, days, created_at FROM meal_plans WHERE 1 = 1"
+	var args []interface{}
+	if !to.IsZero() {
+		args = append(args, to)
+		query += fmt.Sprintf(" AND week_start <= $%d", len(args))
+	}
+	query += " ORDER BY week_start"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		log.Printf("ListMealPlans: error executing query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []*MealPlan{}
+	for rows.Next() {
+		var plan MealPlan
+		if err := rows.Scan(&plan.ID, &plan.WeekStart, &plan.Days, &plan.CreatedAt); err != nil {
+			log.Printf("ListMealPlans: error scanning row: %v", err)
+			return nil, err
+		}
+		// A plan's length varies, so whether it reaches from is checked here.
+		if !from.IsZero() && plan.EndsBefore(from) {
+			continue
+		}
+		plans = append(plans, &plan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, plan := range plans {
+		plan.Entries, err = getMealPlanEntries(db, plan.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return plans, nil
+}
+
+// getMealPlanEntries loads the entries for a single stored plan.
+func getMealPlanEntries(db *sql.DB, planID int) ([]MealPlanEntry, error) {
+	rows, err := db.Query(mealPlanEntriesQuery, planID)
+	if err != nil {
+		log.Printf("getMealPlanEntries: error executing query for planID=%d: %v", planID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []MealPlanEntry{}
+	for rows.Next() {
+		var (
+			entry          MealPlanEntry
+			mealID         sql.NullInt64
+			mealName       sql.NullString
+			relativeEffort sql.NullInt64
+			lastPlanned    sql.NullTime
+			redMeat        sql.NullBool
+			url            sql.NullString
+			servings       sql.NullInt64
+			nights         sql.NullInt64
+		)
+		err := rows.Scan(&entry.ID, &entry.PlanID, &entry.Date, &entry.Day, &entry.Slot, &mealID, &entry.Status, &entry.Servings, &entry.Sequence,
+			&mealName, &relativeEffort, &lastPlanned, &redMeat, &url, &servings, &nights)
+		if err != nil {
+			log.Printf("getMealPlanEntries: error scanning row for planID=%d: %v", planID, err)
+			return nil, err
+		}
+
+		switch {
+		case entry.Status == EntryStatusEatingOut:
+			entry.Meal = &Meal{MealName: EatingOutMealName}
+		case entry.Status == EntryStatusLeftovers && mealName.Valid:
+			entry.MealID = int(mealID.Int64)
+			entry.Meal = NewLeftovers(&Meal{ID: entry.MealID, MealName: mealName.String})
+		case mealName.Valid:
+			entry.MealID = int(mealID.Int64)
+			entry.Meal = &Meal{
+				ID:             entry.MealID,
+				MealName:       mealName.String,
+				RelativeEffort: int(relativeEffort.Int64),
+				LastPlanned:    lastPlanned.Time,
+				RedMeat:        redMeat.Bool,
+				URL:            url.String,
+				Servings:       int(servings.Int64),
+				Nights:         int(nights.Int64),
+			}
+		default:
+			// The meal was deleted after the plan was saved.
+			entry.MealID = int(mealID.Int64)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DayMap converts the stored entries back into the plan map used by the API, keyed by
+// EntryKey.
+func (p *MealPlan) DayMap() map[string]*Meal {
+	plan := make(map[string]*Meal)
+	for _, entry := range p.Entries {
+		if entry.Meal != nil {
+			plan[p.EntryKey(entry)] = entry.Meal
+		}
+	}
+	return plan
+}
+
+// EndsBefore reports whether the plan's last day is before the calendar date of t.
+func (p *MealPlan) EndsBefore(t time.Time) bool {
+	days := p.Days
+	if days < 1 {
+		days = 1
+	}
+	return p.WeekStart.AddDate(0, 0, days-1).Before(DateOf(t, time.UTC))
+}
+
+// EntryKey returns the key of one of the plan's entries in the API's plan map. A plan that
+// covers at most a week is keyed by weekday, as weekly plans always were; a longer one is
+// keyed by date.
+func (p *MealPlan) EntryKey(e MealPlanEntry) string {
+	if p.Days > len(Weekdays) {
+		return e.DateKey()
+	}
+	return e.Key()
+}
+
+// DateMap converts the stored entries into a plan map keyed by dated plan key.
+func (p *MealPlan) DateMap() map[string]*Meal {
+	plan := make(map[string]*Meal)
+	for _, entry := range p.Entries {
+		if entry.Meal != nil {
+			plan[entry.DateKey()] = entry.Meal
+		}
+	}
+	return plan
+}
+
+// Key returns the entry's plan key by weekday.
+func (e MealPlanEntry) Key() string {
+	return PlanKey(e.Day, e.Slot)
+}
+
+// DateKey returns the entry's plan key by date.
+func (e MealPlanEntry) DateKey() string {
+	return PlanKey(e.Date.Format(DateLayout), e.Slot)
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // Import SQLite driver
+)
+
+func setupMealPlanDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening in-memory database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	stmts := []string{
+		`CREATE TABLE meals (
+			id INTEGER PRIMARY KEY,
+			meal_name TEXT NOT NULL,
+			relative_effort INTEGER DEFAULT 3,
+			last_planned TIMESTAMP,
+			red_meat BOOLEAN DEFAULT FALSE,
+			url TEXT,
+			servings INTEGER NOT NULL DEFAULT 0,
+			nights INTEGER NOT NULL DEFAULT 1,
+			slots TEXT NOT NULL DEFAULT 'dinner'
+		)`,
+		`CREATE TABLE meal_plans (
+			id INTEGER PRIMARY KEY,
+			week_start DATE NOT NULL UNIQUE,
+			days INTEGER NOT NULL DEFAULT 7,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE meal_plan_entries (
+			id INTEGER PRIMARY KEY,
+			plan_id INTEGER NOT NULL REFERENCES meal_plans(id) ON DELETE CASCADE,
+			date DATE NOT NULL,
+			day TEXT NOT NULL,
+			slot TEXT NOT NULL DEFAULT 'dinner',
+			meal_id INTEGER REFERENCES meals(id) ON DELETE SET NULL,
+			status TEXT NOT NULL DEFAULT 'planned',
+			servings INTEGER NOT NULL DEFAULT 0,
+			sequence INTEGER NOT NULL DEFAULT 0,
+			UNIQUE (plan_id, date, slot)
+		)`,
+		`CREATE TABLE planning_rules (
+			id INTEGER PRIMARY KEY,
+			rules TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO meals (id, meal_name, relative_effort, red_meat) VALUES
+			(1, 'Tacos', 2, 0),
+			(2, 'Pasta', 4, 0),
+			(3, 'Pot Roast', 7, 1)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Error setting up test database: %v", err)
+		}
+	}
+	return db
+}
+
+func TestWeekStartFor(t *testing.T) {
+	want := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	for _, day := range []int{1, 3, 7} {
+		got := WeekStartFor(time.Date(2024, 4, day, 18, 30, 0, 0, time.UTC))
+		if !got.Equal(want) {
+			t.Errorf("WeekStartFor(April %d) = %v, want %v", day, got, want)
+		}
+	}
+}
+
+func TestSaveAndGetLatestMealPlan(t *testing.T) {
+	db := setupMealPlanDB(t)
+
+	plan := map[string]*Meal{
+		"Sunday":    {ID: 3, MealName: "Pot Roast"},
+		"Monday":    {ID: 2, MealName: "Pasta"},
+		"Tuesday":   {ID: 1, MealName: "Tacos"},
+		"Wednesday": NewLeftovers(&Meal{ID: 1, MealName: "Tacos"}),
+		"Friday":    {MealName: EatingOutMealName},
+	}
+	saved, err := SaveMealPlan(db, time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC), plan, map[string]int{"Monday": 6})
+	if err != nil {
+		t.Fatalf("SaveMealPlan returned error: %v", err)
+	}
+	if saved.ID == 0 || len(saved.Entries) != 5 {
+		t.Fatalf("unexpected saved plan: %+v", saved)
+	}
+
+	latest, err := GetLatestMealPlan(db)
+	if err != nil {
+		t.Fatalf("GetLatestMealPlan returned error: %v", err)
+	}
+	if !latest.WeekStart.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected week start 2024-04-01, got %v", latest.WeekStart)
+	}
+
+	// Days must come back exactly as saved, not reassigned by recency.
+	days := latest.DayMap()
+	expected := map[string]string{"Monday": "Pasta", "Tuesday": "Tacos", "Wednesday": "Leftovers of Tacos", "Friday": EatingOutMealName, "Sunday": "Pot Roast"}
+	if len(days) != len(expected) {
+		t.Errorf("expected %d days, got %d", len(expected), len(days))
+	}
+	for day, name := range expected {
+		if days[day] == nil || days[day].MealName != name {
+			t.Errorf("expected %s on %s, got %+v", name, day, days[day])
+		}
+	}
+	if m := days["Wednesday"]; m == nil || m.ID != 0 || m.LeftoversOf != 1 {
+		t.Errorf("expected Wednesday to be leftovers of meal 1, got %+v", m)
+	}
+	for _, e := range latest.Entries {
+		if want := map[string]int{"Monday": 6}[e.Day]; e.Servings != want {
+			t.Errorf("expected %d servings on %s, got %d", want, e.Day, e.Servings)
+		}
+	}
+
+	var lastPlanned sql.NullTime
+	if err := db.QueryRow("SELECT last_planned FROM meals WHERE id = 3").Scan(&lastPlanned); err != nil {
+		t.Fatalf("failed reading last_planned: %v", err)
+	}
+	if !lastPlanned.Valid {
+		t.Errorf("expected last_planned to be set for a finalized meal")
+	}
+}
+
+func TestSaveMealPlanReplacesSameWeek(t *testing.T) {
+	db := setupMealPlanDB(t)
+	weekStart := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := SaveMealPlan(db, weekStart, map[string]*Meal{"Monday": {ID: 1}}, nil); err != nil {
+		t.Fatalf("first SaveMealPlan returned error: %v", err)
+	}
+	if _, err := SaveMealPlan(db, weekStart, map[string]*Meal{"Monday": {ID: 2}}, nil); err != nil {
+		t.Fatalf("second SaveMealPlan returned error: %v", err)
+	}
+
+	plans, err := ListMealPlans(db, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("ListMealPlans returned error: %v", err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("expected 1 plan after re-finalizing the same week, got %d", len(plans))
+	}
+	if got := plans[0].DayMap()["Monday"]; got == nil || got.ID != 2 {
+		t.Errorf("expected the second plan to win, got %+v", got)
+	}
+}
+
+func TestListMealPlans(t *testing.T) {
+	db := setupMealPlanDB(t)
+	for _, week := range []time.Time{
+		time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		if _, err := SaveMealPlan(db, week, map[string]*Meal{"Monday": {ID: 1}}, nil); err != nil {
+			t.Fatalf("SaveMealPlan returned error: %v", err)
+		}
+	}
+
+	plans, err := ListMealPlans(db, time.Date(2024, 3, 27, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ListMealPlans returned error: %v", err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("expected 2 plans in range, got %d", len(plans))
+	}
+	if plans[0].WeekStart.Day() != 25 || plans[1].WeekStart.Day() != 1 {
+		t.Errorf("unexpected plans returned: %v, %v", plans[0].WeekStart, plans[1].WeekStart)
+	}
+}
+
+func TestGetLatestMealPlan_None(t *testing.T) {
+	db := setupMealPlanDB(t)
+	if _, err := GetLatestMealPlan(db); err != ErrNoMealPlan {
+		t.Errorf("expected ErrNoMealPlan, got %v", err)
+	}
+}
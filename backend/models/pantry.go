@@ -0,0 +1,212 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"mealplanner/units"
+)
+
+// PantryItem is something the household already has on hand.
+type PantryItem struct {
+	ID       int     `json:"id"`
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+	// Staple marks items that are always stocked (salt, olive oil), whatever the quantity.
+	Staple    bool      `json:"staple"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ErrPantryItemNotFound is returned when a pantry item ID doesn't exist.
+var ErrPantryItemNotFound = errors.New("pantry item not found")
+
+// Normalize trims and lower-cases the name and canonicalizes the unit.
+func (p *PantryItem) Normalize() {
+	p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+	p.Unit = units.Canonical(p.Unit)
+}
+
+// Validate checks that the item can be stored.
+func (p PantryItem) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("pantry item name is required")
+	}
+	if p.Quantity < 0 {
+		return errors.New("pantry item quantity cannot be negative")
+	}
+	return nil
+}
+
+// ListPantryItems returns every pantry item ordered by name.
+func ListPantryItems(db *sql.DB) ([]PantryItem, error) {
+	rows, err := db.Query("SELECT id, name, quantity, unit, staple, updated_at FROM pantry_items ORDER BY name")
+	if err != nil {
+		log.Printf("ListPantryItems: error executing query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []PantryItem{}
+	for rows.Next() {
+		var item PantryItem
+		if err := rows.Scan(&item.ID, &item.Name, &item.Quantity, &item.Unit, &item.Staple, &item.UpdatedAt); err != nil {
+			log.Printf("ListPantryItems: error scanning row: %v", err)
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CreatePantryItem validates and inserts a new pantry item.
+func CreatePantryItem(db *sql.DB, item PantryItem) (*PantryItem, error) {
+	item.Normalize()
+	if err := item.Validate(); err != nil {
+		return nil, err
+	}
+	item.UpdatedAt = time.Now().UTC()
+	err := db.QueryRow(
+		"INSERT INTO pantry_items (name, quantity, unit, staple, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		item.Name, item.Quantity, item.Unit, item.Staple, item.UpdatedAt,
+	).Scan(&item.ID)
+	if err != nil {
+		log.Printf("CreatePantryItem: error inserting %q: %v", item.Name, err)
+		return nil, err
+	}
+	return &item, nil
+}
+
+// UpdatePantryItem validates and replaces the pantry item with the given ID.
+func UpdatePantryItem(db *sql.DB, item PantryItem) (*PantryItem, error) {
+	item.Normalize()
+	if err := item.Validate(); err != nil {
+		return nil, err
+	}
+	item.UpdatedAt = time.Now().UTC()
+	res, err := db.Exec(
+		"UPDATE pantry_items SET name = $1, quantity = $2, unit = $3, staple = $4, updated_at = $5 WHERE id = $6",
+		item.Name, item.Quantity, item.Unit, item.Staple, item.UpdatedAt, item.ID,
+	)
+	if err != nil {
+		log.Printf("UpdatePantryItem: error updating itemID=%d: %v", item.ID, err)
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrPantryItemNotFound
+	}
+	return &item, nil
+}
+
+// DeletePantryItem removes the pantry item with the given ID.
+func DeletePantryItem(db *sql.DB, id int) error {
+	res, err := db.Exec("DELETE FROM pantry_items WHERE id = $1", id)
+	if err != nil {
+		log.Printf("DeletePantryItem: error deleting itemID=%d: %v", id, err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrPantryItemNotFound
+	}
+	return nil
+}
+
+// ShoppingItem is a line of the shopping list after the pantry has been taken into account.
+type ShoppingItem struct {
+	Ingredient
+	// OnHand is how much of the ingredient the pantry already covers, in the line's unit.
+	OnHand float64
+	// Staple marks ingredients the household always keeps stocked. They're listed
+	// after everything else so they can be checked rather than bought.
+	Staple bool
+	// Aisle is the grocery store section the item is found in.
+	Aisle string
+	// Slots are the meal slots the item is bought for, when the list was built from
+	// planned slots.
+	Slots []string `json:",omitempty"`
+}
+
+// ApplyPantry subtracts pantry stock from an aggregated shopping list. An ingredient
+// matches a pantry item when its name is the item's name or ends with it ("kosher salt"
+// matches "salt"); the longest match wins. Lines the pantry fully covers are dropped,
+// partly covered lines are reduced, and staples stay on the list marked as such.
+func ApplyPantry(list []Ingredient, pantry []PantryItem) []ShoppingItem {
+	remaining := make([]float64, len(pantry))
+	for i, item := range pantry {
+		remaining[i] = item.Quantity
+	}
+
+	items := []ShoppingItem{}
+	for _, ing := range list {
+		line := ShoppingItem{Ingredient: ing}
+		i := matchPantryItem(ing.Name, pantry)
+		if i < 0 {
+			items = append(items, line)
+			continue
+		}
+		item := pantry[i]
+		if item.Staple {
+			line.Staple = true
+			items = append(items, line)
+			continue
+		}
+		if remaining[i] <= 0 {
+			items = append(items, line)
+			continue
+		}
+		if ing.Quantity == 0 {
+			// An unmeasured ingredient ("Kosher salt") is covered by any stock.
+			continue
+		}
+
+		have, ok := units.Convert(remaining[i], item.Unit, ing.Unit)
+		if !ok {
+			items = append(items, line)
+			continue
+		}
+		if have >= ing.Quantity {
+			used, _ := units.Convert(ing.Quantity, ing.Unit, item.Unit)
+			remaining[i] -= used
+			continue
+		}
+		remaining[i] = 0
+		line.OnHand = units.Round(have)
+		line.Quantity = units.Round(ing.Quantity - have)
+		items = append(items, line)
+	}
+
+	// Keep the list's order but move staples to the end.
+	sort.SliceStable(items, func(a, b int) bool {
+		return !items[a].Staple && items[b].Staple
+	})
+	return items
+}
+
+// matchPantryItem returns the index of the pantry item matching the ingredient name, or -1.
+func matchPantryItem(name string, pantry []PantryItem) int {
+	name = strings.ToLower(strings.TrimSpace(name))
+	best := -1
+	for i, item := range pantry {
+		if item.Name == "" {
+			continue
+		}
+		if name != item.Name && !strings.HasSuffix(name, " "+item.Name) {
+			continue
+		}
+		if best < 0 || len(item.Name) > len(pantry[best].Name) {
+			best = i
+		}
+	}
+	return best
+}
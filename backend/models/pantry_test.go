@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func setupPantryDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening in-memory database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE pantry_items (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		quantity REAL NOT NULL DEFAULT 0,
+		unit TEXT NOT NULL DEFAULT '',
+		staple BOOLEAN NOT NULL DEFAULT false,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		t.Fatalf("Error setting up test database: %v", err)
+	}
+	return db
+}
+
+func TestPantryItemCRUD(t *testing.T) {
+	db := setupPantryDB(t)
+
+	created, err := CreatePantryItem(db, PantryItem{Name: "  Olive Oil ", Quantity: 2, Unit: "Cups"})
+	if err != nil {
+		t.Fatalf("CreatePantryItem returned error: %v", err)
+	}
+	if created.ID == 0 || created.Name != "olive oil" || created.Unit != "cup" {
+		t.Errorf("expected a normalized item with an ID, got %+v", created)
+	}
+	if _, err := CreatePantryItem(db, PantryItem{Name: ""}); err == nil {
+		t.Errorf("expected an error for an item without a name")
+	}
+
+	created.Quantity = 1
+	created.Staple = true
+	if _, err := UpdatePantryItem(db, *created); err != nil {
+		t.Fatalf("UpdatePantryItem returned error: %v", err)
+	}
+	if _, err := UpdatePantryItem(db, PantryItem{ID: 99, Name: "salt"}); err != ErrPantryItemNotFound {
+		t.Errorf("expected ErrPantryItemNotFound, got %v", err)
+	}
+
+	items, err := ListPantryItems(db)
+	if err != nil {
+		t.Fatalf("ListPantryItems returned error: %v", err)
+	}
+	if len(items) != 1 || items[0].Quantity != 1 || !items[0].Staple {
+		t.Errorf("unexpected pantry after update: %+v", items)
+	}
+
+	if err := DeletePantryItem(db, created.ID); err != nil {
+		t.Fatalf("DeletePantryItem returned error: %v", err)
+	}
+	if err := DeletePantryItem(db, created.ID); err != ErrPantryItemNotFound {
+		t.Errorf("expected ErrPantryItemNotFound on second delete, got %v", err)
+	}
+}
+
+func TestApplyPantry(t *testing.T) {
+	list := []Ingredient{
+		{Name: "butter", Quantity: 1, Unit: "cup"},
+		{Name: "eggs", Quantity: 6},
+		{Name: "extra-virgin olive oil", Quantity: 3, Unit: "tbsp"},
+		{Name: "flour", Quantity: 2, Unit: "cup"},
+		{Name: "kosher salt"},
+		{Name: "milk", Quantity: 1, Unit: "cup"},
+	}
+	pantry := []PantryItem{
+		{Name: "butter", Quantity: 8, Unit: "tbsp"},
+		{Name: "eggs", Quantity: 12},
+		{Name: "olive oil", Staple: true},
+		{Name: "flour", Quantity: 500, Unit: "g"},
+		{Name: "salt", Quantity: 1, Unit: "lb"},
+	}
+
+	expected := []ShoppingItem{
+		{Ingredient: Ingredient{Name: "butter", Quantity: 0.5, Unit: "cup"}, OnHand: 0.5},
+		{Ingredient: Ingredient{Name: "flour", Quantity: 2, Unit: "cup"}},
+		{Ingredient: Ingredient{Name: "milk", Quantity: 1, Unit: "cup"}},
+		{Ingredient: Ingredient{Name: "extra-virgin olive oil", Quantity: 3, Unit: "tbsp"}, Staple: true},
+	}
+
+	actual := ApplyPantry(list, pantry)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected shopping list %+v, got %+v", expected, actual)
+	}
+}
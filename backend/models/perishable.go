@@ -0,0 +1,100 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// perishableKeywords maps ingredient name fragments to a canonical perishable item.
+// Longer phrases come first so "buttermilk" is not reported as "milk".
+var perishableKeywords = []string{
+	"green onion", "scallion", "cilantro", "parsley", "basil", "mint", "dill", "chives",
+	"rosemary", "thyme", "spinach", "arugula", "lettuce", "kale", "greens", "cabbage",
+	"celery", "cucumber", "zucchini", "mushroom", "avocado", "broccoli", "bell pepper",
+	"tomato", "lime", "lemon", "ginger", "sour cream", "heavy cream", "buttermilk",
+	"ricotta", "mozzarella", "yogurt", "milk",
+}
+
+// shelfStableMarkers mark an ingredient as a long-lived form of an otherwise perishable item.
+var shelfStableMarkers = []string{"dried", "powder", "ground", "frozen", "canned", "paste", "sauce"}
+
+// PerishableKey returns the canonical perishable item an ingredient name refers to, if any.
+func PerishableKey(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	for _, marker := range shelfStableMarkers {
+		if strings.Contains(lower, marker) {
+			return "", false
+		}
+	}
+	for _, kw := range perishableKeywords {
+		if strings.Contains(lower, kw) {
+			return kw, true
+		}
+	}
+	return "", false
+}
+
+// PerishableKeys returns the distinct perishable items a meal uses, sorted by name.
+func PerishableKeys(m *Meal) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, ing := range m.Ingredients {
+		if key, ok := PerishableKey(ing.Name); ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WasteReport summarizes how a plan uses perishable ingredients.
+type WasteReport struct {
+	// Shared lists perishables used by two or more meals in the plan.
+	Shared []string `json:"shared"`
+	// Unshared lists perishables bought for a single meal, the likely leftovers.
+	Unshared []string `json:"unshared"`
+	// Score rewards each extra use of a perishable and penalizes each unshared one.
+	Score int `json:"score"`
+}
+
+// ScorePerishableOverlap scores a set of planned meals by how well they reuse perishables.
+func ScorePerishableOverlap(meals []*Meal) WasteReport {
+	uses := make(map[string]int)
+	for _, m := range meals {
+		if m == nil {
+			continue
+		}
+		for _, key := range PerishableKeys(m) {
+			uses[key]++
+		}
+	}
+
+	report := WasteReport{Shared: []string{}, Unshared: []string{}}
+	for key, n := range uses {
+		if n > 1 {
+			report.Shared = append(report.Shared, key)
+			report.Score += n - 1
+		} else {
+			report.Unshared = append(report.Unshared, key)
+			report.Score--
+		}
+	}
+	sort.Strings(report.Shared)
+	sort.Strings(report.Unshared)
+	return report
+}
+
+// SharedBy returns the shared perishables that the given meal uses.
+func (r WasteReport) SharedBy(m *Meal) []string {
+	var shared []string
+	for _, key := range PerishableKeys(m) {
+		for _, s := range r.Shared {
+			if s == key {
+				shared = append(shared, key)
+				break
+			}
+		}
+	}
+	return shared
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPerishableKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		ok   bool
+	}{
+		{"1 bunch fresh cilantro, chopped", "cilantro", true},
+		{"3 scallions, thinly sliced", "scallion", true},
+		{"1 cup buttermilk", "buttermilk", true},
+		{"1 tsp dried parsley", "", false},
+		{"2 tbsp tomato paste", "", false},
+		{"1 lb ground beef", "", false},
+		{"2 cups rice", "", false},
+	}
+	for _, tt := range tests {
+		key, ok := PerishableKey(tt.name)
+		if key != tt.key || ok != tt.ok {
+			t.Errorf("PerishableKey(%q) = %q, %t; want %q, %t", tt.name, key, ok, tt.key, tt.ok)
+		}
+	}
+}
+
+func TestScorePerishableOverlap(t *testing.T) {
+	tacos := &Meal{ID: 1, Ingredients: []Ingredient{{Name: "cilantro"}, {Name: "lime"}, {Name: "tortillas"}}}
+	rice := &Meal{ID: 2, Ingredients: []Ingredient{{Name: "fresh cilantro leaves"}, {Name: "rice"}}}
+	salad := &Meal{ID: 3, Ingredients: []Ingredient{{Name: "romaine lettuce"}}}
+
+	report := ScorePerishableOverlap([]*Meal{tacos, rice, salad, nil})
+	if !reflect.DeepEqual(report.Shared, []string{"cilantro"}) {
+		t.Errorf("expected cilantro to be shared, got %v", report.Shared)
+	}
+	if !reflect.DeepEqual(report.Unshared, []string{"lettuce", "lime"}) {
+		t.Errorf("expected lettuce and lime unshared, got %v", report.Unshared)
+	}
+	if report.Score != -1 {
+		t.Errorf("expected score -1, got %d", report.Score)
+	}
+	if got := report.SharedBy(rice); !reflect.DeepEqual(got, []string{"cilantro"}) {
+		t.Errorf("expected rice to share cilantro, got %v", got)
+	}
+}
@@ -0,0 +1,768 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Relaxation names a soft planning rule that the planner may loosen when no exact plan exists.
+type Relaxation string
+
+// Soft rules, listed in the order the planner gives them up.
+const (
+	RelaxCooldown    Relaxation = "cooldown"
+	RelaxEffortRange Relaxation = "effort_range"
+)
+
+// RelaxTagMinimum marks a swap that leaves the week short of a tag the rules require.
+// The generator never relaxes it; swaps only rank such meals after the others.
+const RelaxTagMinimum Relaxation = "tag_minimum"
+
+// relaxationTiers lists which soft rules are relaxed at each tier, from exact to most relaxed.
+// A tier admits the candidates whose relaxations are all among its own, so relaxing the effort
+// range doesn't also let in meals that break the cooldown.
+var relaxationTiers = [][]Relaxation{
+	nil,
+	{RelaxCooldown},
+	{RelaxEffortRange},
+	{RelaxCooldown, RelaxEffortRange},
+}
+
+// wasteSamples is how many plans are compared when planning to reduce waste.
+const wasteSamples = 24
+
+// maxSearchSteps bounds the backtracking search so a pathological rule set fails fast.
+const maxSearchSteps = 200000
+
+// DayExplanation describes which rules were relaxed to plan a day's slot.
+type DayExplanation struct {
+	Day     string       `json:"day"`
+	Slot    string       `json:"slot,omitempty"`
+	Relaxed []Relaxation `json:"relaxed"`
+	Notes   []string     `json:"notes"`
+}
+
+// PlanResult is a generated plan together with explanations for days that needed relaxed
+// rules. Both are keyed by plan key (see PlanKey).
+type PlanResult struct {
+	Plan         map[string]*Meal          `json:"plan"`
+	Explanations map[string]DayExplanation `json:"explanations"`
+	// Waste is set when the plan was chosen for perishable ingredient overlap.
+	Waste *WasteReport `json:"waste,omitempty"`
+}
+
+// SolveOptions controls a single run of the planner.
+type SolveOptions struct {
+	// Now is the reference time for the repeat cooldown; zero means time.Now().
+	Now time.Time
+	// Rand orders equally good candidates; nil means a time-seeded source.
+	Rand *rand.Rand
+	// ReduceWaste prefers plans whose meals share perishable ingredients.
+	// The pool's meals must have their ingredients loaded.
+	ReduceWaste bool
+	// Locked keeps the given meal IDs on their days, keyed by plan key; only the other days
+	// are planned. Locked meals count toward the category caps and are not repeated elsewhere
+	// in the week.
+	Locked map[string]int
+}
+
+// candidate is a meal that may be placed on a day and the rules it would relax.
+type candidate struct {
+	meal *Meal
+	tier int
+}
+
+// solver holds the state of one backtracking search.
+type solver struct {
+	rules      PlanRules
+	order      []DayRule
+	candidates map[string][]candidate
+	assigned   map[string]candidate
+	used       map[int]bool
+	weeks      []string
+	counts     map[string]map[string]int // category counts by week, see PlanRules.weekOf
+	tagCounts  map[string]map[string]int // tag counts by week
+	tier       int
+	steps      int
+
+	// Leftovers: open days filled by the meal cooked on an earlier day.
+	open    map[string]bool
+	covered map[string]*Meal
+
+	// Perishable tracking, only populated when reducing waste.
+	reduceWaste bool
+	perishables map[int][]string
+	keyUses     map[string]int
+}
+
+// CandidatePoolQuery loads every meal the planner can choose from.
+var CandidatePoolQuery = "SELECT " + strings.Join(MealColumns, ", ") + " FROM meals ORDER BY id"
+
+// LoadCandidatePool reads all meals (without ingredients) for the planner in a single query.
+func LoadCandidatePool(db *sql.DB) ([]*Meal, error) {
+	rows, err := db.Query(CandidatePoolQuery)
+	if err != nil {
+		log.Printf("LoadCandidatePool: error executing query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pool []*Meal
+	for rows.Next() {
+		var m Meal
+		var lastPlanned sql.NullTime
+		var url sql.NullString
+		var slots string
+		if err := rows.Scan(&m.ID, &m.MealName, &m.RelativeEffort, &lastPlanned, &m.RedMeat, &url, &m.Servings, &m.Nights, &slots); err != nil {
+			log.Printf("LoadCandidatePool: error scanning row: %v", err)
+			return nil, err
+		}
+		m.LastPlanned = lastPlanned.Time
+		m.URL = url.String
+		m.Slots = parseSlots(slots)
+		pool = append(pool, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := loadMealTags(db, pool, true); err != nil {
+		return nil, err
+	}
+	if err := loadCookingStats(db, pool); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// PoolIngredientsQuery loads the ingredient names for every meal in one pass.
+const PoolIngredientsQuery = "SELECT id, meal_id, name, unit FROM ingredients ORDER BY meal_id, id"
+
+// LoadPoolIngredients attaches each meal's ingredients (names and units only) to the pool.
+func LoadPoolIngredients(db *sql.DB, pool []*Meal) error {
+	byID := make(map[int]*Meal, len(pool))
+	for _, m := range pool {
+		byID[m.ID] = m
+	}
+
+	rows, err := db.Query(PoolIngredientsQuery)
+	if err != nil {
+		log.Printf("LoadPoolIngredients: error executing query: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ing Ingredient
+		var unit sql.NullString
+		if err := rows.Scan(&ing.ID, &ing.MealID, &ing.Name, &unit); err != nil {
+			log.Printf("LoadPoolIngredients: error scanning row: %v", err)
+			return err
+		}
+		ing.Unit = unit.String
+		if m, ok := byID[ing.MealID]; ok {
+			m.Ingredients = append(m.Ingredients, ing)
+		}
+	}
+	return rows.Err()
+}
+
+// SolvePlan builds a plan for every day in the rules from the candidate pool.
+//
+// Category caps, tag caps and minimums, and "no meal twice in a week" are hard constraints. The effort range and
+// repeat cooldown are soft: the planner first searches for a plan that satisfies everything,
+// and only if none exists does it allow relaxed candidates, tier by tier in the order of
+// relaxationTiers. Within a tier, each day still prefers exact candidates, so relaxations are
+// confined to the days that need them and reported in the result's explanations.
+//
+// A meal that covers several nights fills the open days after it with its leftovers. Those
+// days use up the week's slots without counting toward any cap or minimum.
+//
+// With ReduceWaste set, the search prefers meals that reuse perishables already in the plan,
+// several plans are sampled, and the one that relaxes the least and scores best is returned.
+func SolvePlan(pool []*Meal, rules PlanRules, opts SolveOptions) (*PlanResult, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	rng := opts.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	runs := 1
+	if opts.ReduceWaste {
+		runs = wasteSamples
+	}
+
+	var best *PlanResult
+	bestCost := 0
+	for run := 0; run < runs; run++ {
+		result, cost, err := solveOnce(pool, rules, now, rng, opts)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.ReduceWaste {
+			return result, nil
+		}
+		planned := make([]*Meal, 0, len(result.Plan))
+		for _, m := range result.Plan {
+			planned = append(planned, m)
+		}
+		report := ScorePerishableOverlap(planned)
+		result.Waste = &report
+		if best == nil || cost < bestCost || (cost == bestCost && report.Score > best.Waste.Score) {
+			best, bestCost = result, cost
+		}
+	}
+	return best, nil
+}
+
+// solveOnce runs one full search and returns the plan with its total relaxation tier.
+func solveOnce(pool []*Meal, rules PlanRules, now time.Time, rng *rand.Rand, opts SolveOptions) (*PlanResult, int, error) {
+	cutoff := rules.RepeatCutoff(now)
+	s := &solver{
+		rules:       rules,
+		candidates:  make(map[string][]candidate),
+		assigned:    make(map[string]candidate),
+		used:        make(map[int]bool),
+		weeks:       rules.weeks(),
+		counts:      make(map[string]map[string]int),
+		tagCounts:   make(map[string]map[string]int),
+		open:        make(map[string]bool),
+		covered:     make(map[string]*Meal),
+		reduceWaste: opts.ReduceWaste,
+		perishables: make(map[int][]string),
+		keyUses:     make(map[string]int),
+	}
+	if opts.ReduceWaste {
+		for _, m := range pool {
+			s.perishables[m.ID] = PerishableKeys(m)
+		}
+	}
+	result := &PlanResult{Plan: make(map[string]*Meal), Explanations: make(map[string]DayExplanation)}
+
+	locked := make([]string, 0, len(opts.Locked))
+	for key := range opts.Locked {
+		locked = append(locked, key)
+	}
+	sort.Slice(locked, func(i, j int) bool { return planKeyLess(locked[i], locked[j]) })
+	for _, key := range locked {
+		id := opts.Locked[key]
+		meal := findMeal(pool, id)
+		if meal == nil {
+			return nil, 0, fmt.Errorf("locked %s meal %d: %w", key, id, ErrMealNotFound)
+		}
+		result.Plan[key] = meal
+		s.take(key, meal)
+	}
+
+	var open []DayRule
+	for _, day := range rules.Days {
+		if _, locked := opts.Locked[day.Key()]; locked {
+			continue
+		}
+		switch {
+		case day.EatOut:
+			result.Plan[day.Key()] = &Meal{MealName: EatingOutMealName}
+		case day.FixedMealID != 0:
+			meal := findMeal(pool, day.FixedMealID)
+			if meal == nil {
+				return nil, 0, fmt.Errorf("fixed %s meal %d: %w", day.label(), day.FixedMealID, ErrMealNotFound)
+			}
+			result.Plan[day.Key()] = meal
+			s.take(day.Key(), meal)
+		default:
+			open = append(open, day)
+			s.open[day.Key()] = true
+			s.candidates[day.Key()] = rankCandidates(pool, day, cutoff, now, rng)
+		}
+	}
+	// Locked and fixed meals that cover several nights leave their leftovers on the open
+	// days after them.
+	for _, key := range PlanKeys(result.Plan) {
+		if meal := result.Plan[key]; meal.ID != 0 {
+			s.cover(key, meal)
+		}
+	}
+
+	solved := len(open) == 0 && s.minimumsReachable(0)
+	for tier := 0; tier < len(relaxationTiers) && !solved; tier++ {
+		s.tier = tier
+		s.order = s.orderDays(open)
+		solved = s.search(0)
+		if s.steps > maxSearchSteps {
+			return nil, 0, errors.New("meal plan search gave up: the planning rules are too tightly constrained")
+		}
+	}
+	if !solved {
+		return nil, 0, s.failure(open)
+	}
+
+	cost := 0
+	for _, day := range open {
+		if meal := s.covered[day.Key()]; meal != nil {
+			result.Plan[day.Key()] = NewLeftovers(meal)
+			continue
+		}
+		c := s.assigned[day.Key()]
+		result.Plan[day.Key()] = c.meal
+		cost += c.tier
+		if c.tier > 0 {
+			result.Explanations[day.Key()] = explain(day, c, rules)
+		}
+	}
+	return result, cost, nil
+}
+
+// rankCandidates orders the meals suitable for a day's slot: exact matches first, then by
+// relaxation tier, with meals closer to the day's effort range ahead of those further away.
+// Equally good meals are in random order, weighted by their rating and how long ago they
+// were cooked.
+func rankCandidates(pool []*Meal, day DayRule, cutoff, now time.Time, rng *rand.Rand) []candidate {
+	shuffled := weightedShuffle(pool, now, rng)
+
+	candidates := make([]candidate, 0, len(shuffled))
+	for _, m := range shuffled {
+		if !m.SuitableFor(day.SlotName()) {
+			continue
+		}
+		inRange := m.RelativeEffort >= day.MinEffort && m.RelativeEffort <= day.MaxEffort
+		rested := m.LastPlanned.IsZero() || m.LastPlanned.Before(cutoff)
+		tier := 0
+		switch {
+		case !inRange && !rested:
+			tier = 3
+		case !inRange:
+			tier = 2
+		case !rested:
+			tier = 1
+		}
+		candidates = append(candidates, candidate{meal: m, tier: tier})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].tier != candidates[j].tier {
+			return candidates[i].tier < candidates[j].tier
+		}
+		return effortDistance(candidates[i].meal, day) < effortDistance(candidates[j].meal, day)
+	})
+	return candidates
+}
+
+// weightedShuffle returns the pool in random order, where each next meal is drawn with
+// probability proportional to its SelectionWeight.
+func weightedShuffle(pool []*Meal, now time.Time, rng *rand.Rand) []*Meal {
+	keys := make(map[*Meal]float64, len(pool))
+	shuffled := make([]*Meal, len(pool))
+	copy(shuffled, pool)
+	for _, m := range shuffled {
+		// The smallest of independent exponential draws with rate w_i is meal i with
+		// probability w_i / sum(w).
+		keys[m] = rng.ExpFloat64() / SelectionWeight(m, now)
+	}
+	sort.SliceStable(shuffled, func(i, j int) bool { return keys[shuffled[i]] < keys[shuffled[j]] })
+	return shuffled
+}
+
+// orderDays sorts the open days so the most constrained day is searched first.
+func (s *solver) orderDays(open []DayRule) []DayRule {
+	order := make([]DayRule, len(open))
+	copy(order, open)
+	sort.SliceStable(order, func(i, j int) bool {
+		return s.viableCount(order[i].Key()) < s.viableCount(order[j].Key())
+	})
+	return order
+}
+
+// search assigns order[i:] depth first, backtracking on dead ends.
+func (s *solver) search(i int) bool {
+	if !s.minimumsReachable(i) {
+		return false
+	}
+	if i == len(s.order) {
+		return true
+	}
+	day := s.order[i].Key()
+	if s.covered[day] != nil {
+		return s.search(i + 1)
+	}
+	candidates := s.candidates[day]
+	if s.reduceWaste {
+		candidates = s.byOverlap(candidates)
+	}
+	if len(s.rules.TagMinimums) > 0 {
+		candidates = s.byShortfall(day, candidates)
+	}
+	for _, c := range candidates {
+		if !tierAdmits(s.tier, c.tier) {
+			continue
+		}
+		if !s.fits(day, c.meal) {
+			continue
+		}
+		s.steps++
+		if s.steps > maxSearchSteps {
+			return false
+		}
+		s.assigned[day] = c
+		s.take(day, c.meal)
+		leftovers := s.cover(day, c.meal)
+		if s.remainingViable(i+1) && s.search(i+1) {
+			return true
+		}
+		s.uncover(leftovers)
+		s.release(day, c.meal)
+		delete(s.assigned, day)
+	}
+	return false
+}
+
+// remainingViable is the forward check: every unassigned day must still have a candidate.
+func (s *solver) remainingViable(from int) bool {
+	for _, day := range s.order[from:] {
+		if s.covered[day.Key()] == nil && s.viableCount(day.Key()) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// viableCount counts the candidates a day could still take at the current tier.
+func (s *solver) viableCount(day string) int {
+	n := 0
+	for _, c := range s.candidates[day] {
+		if !tierAdmits(s.tier, c.tier) {
+			continue
+		}
+		if s.fits(day, c.meal) {
+			n++
+		}
+	}
+	return n
+}
+
+// minimumsReachable reports whether every week's tag minimums can still be met by the
+// days from order[from:] left to plan in it. Each day adds at most one meal of any tag.
+func (s *solver) minimumsReachable(from int) bool {
+	left := make(map[string]int)
+	for _, day := range s.order[from:] {
+		left[s.rules.weekOf(day.Key())]++
+	}
+	for _, week := range s.weeks {
+		for _, n := range s.shortfall(week) {
+			if n > left[week] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// shortfall returns how many more meals with each required tag a week still needs.
+func (s *solver) shortfall(week string) map[string]int {
+	_, tags := s.weekCounts(week)
+	return tagShortfall(s.rules.weekMinimums(week), tags)
+}
+
+// weekCounts returns the category and tag counts of a week.
+func (s *solver) weekCounts(week string) (map[string]int, map[string]int) {
+	if s.counts[week] == nil {
+		s.counts[week] = make(map[string]int)
+		s.tagCounts[week] = make(map[string]int)
+	}
+	return s.counts[week], s.tagCounts[week]
+}
+
+// fits checks the hard constraints for adding a meal on a plan key to the current partial
+// plan. The caps apply to the week the key falls in.
+func (s *solver) fits(key string, m *Meal) bool {
+	if s.used[m.ID] {
+		return false
+	}
+	counts, tagCounts := s.weekCounts(s.rules.weekOf(key))
+	for _, category := range m.Categories() {
+		if !s.rules.CategoryAllowed(category, counts) {
+			return false
+		}
+	}
+	for _, tag := range m.Tags {
+		if !s.rules.TagAllowed(tag, tagCounts) {
+			return false
+		}
+	}
+	return true
+}
+
+// cover fills the same slot on the open days after key with the leftovers of a meal that
+// covers several nights, and returns the keys it filled. Leftovers stop at the end of the
+// plan and at the first day that isn't open or is already planned.
+func (s *solver) cover(key string, m *Meal) []string {
+	day, slot := SplitPlanKey(key)
+	var covered []string
+	for day = nextPlanDay(day); day != "" && len(covered) < m.NightsCovered()-1; day = nextPlanDay(day) {
+		next := PlanKey(day, slot)
+		if _, assigned := s.assigned[next]; !s.open[next] || assigned || s.covered[next] != nil {
+			break
+		}
+		s.covered[next] = m
+		covered = append(covered, next)
+	}
+	return covered
+}
+
+// uncover undoes cover when the search backtracks.
+func (s *solver) uncover(days []string) {
+	for _, day := range days {
+		delete(s.covered, day)
+	}
+}
+
+func (s *solver) take(key string, m *Meal) {
+	s.used[m.ID] = true
+	counts, tagCounts := s.weekCounts(s.rules.weekOf(key))
+	for _, category := range m.Categories() {
+		counts[category]++
+	}
+	for _, tag := range m.Tags {
+		tagCounts[tag]++
+	}
+	for _, key := range s.perishables[m.ID] {
+		s.keyUses[key]++
+	}
+}
+
+func (s *solver) release(key string, m *Meal) {
+	delete(s.used, m.ID)
+	counts, tagCounts := s.weekCounts(s.rules.weekOf(key))
+	for _, category := range m.Categories() {
+		counts[category]--
+	}
+	for _, tag := range m.Tags {
+		tagCounts[tag]--
+	}
+	for _, key := range s.perishables[m.ID] {
+		s.keyUses[key]--
+	}
+}
+
+// byOverlap reorders a day's candidates so that, within each tier, meals reusing more of the
+// perishables already in the plan come first.
+func (s *solver) byOverlap(candidates []candidate) []candidate {
+	overlap := func(m *Meal) int {
+		n := 0
+		for _, key := range s.perishables[m.ID] {
+			if s.keyUses[key] > 0 {
+				n++
+			}
+		}
+		return n
+	}
+	ordered := make([]candidate, len(candidates))
+	copy(ordered, candidates)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].tier != ordered[j].tier {
+			return ordered[i].tier < ordered[j].tier
+		}
+		return overlap(ordered[i].meal) > overlap(ordered[j].meal)
+	})
+	return ordered
+}
+
+// byShortfall reorders a day's candidates so that, within each tier, meals with more of
+// the tags the day's week is still short of come first.
+func (s *solver) byShortfall(day string, candidates []candidate) []candidate {
+	short := s.shortfall(s.rules.weekOf(day))
+	if len(short) == 0 {
+		return candidates
+	}
+	covers := func(m *Meal) int {
+		n := 0
+		for _, tag := range m.Tags {
+			if short[tag] > 0 {
+				n++
+			}
+		}
+		return n
+	}
+	ordered := make([]candidate, len(candidates))
+	copy(ordered, candidates)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].tier != ordered[j].tier {
+			return ordered[i].tier < ordered[j].tier
+		}
+		return covers(ordered[i].meal) > covers(ordered[j].meal)
+	})
+	return ordered
+}
+
+// failure explains why no plan could be built even with every soft rule relaxed.
+func (s *solver) failure(open []DayRule) error {
+	for _, day := range open {
+		if len(s.candidates[day.Key()]) == 0 {
+			return fmt.Errorf("no meals available for %s", day.label())
+		}
+	}
+	if len(s.rules.TagMinimums) > 0 {
+		return errors.New("no meal plan satisfies the category caps and tag rules without repeating a meal")
+	}
+	return errors.New("no meal plan satisfies the category caps without repeating a meal")
+}
+
+// tierAdmits reports whether every rule a candidate of tier c relaxes is relaxed at tier t.
+func tierAdmits(t, c int) bool {
+	for _, r := range relaxationTiers[c] {
+		relaxed := false
+		for _, allowed := range relaxationTiers[t] {
+			relaxed = relaxed || r == allowed
+		}
+		if !relaxed {
+			return false
+		}
+	}
+	return true
+}
+
+// explain describes the rules relaxed for a day's pick.
+func explain(day DayRule, c candidate, rules PlanRules) DayExplanation {
+	exp := DayExplanation{Day: day.Day, Relaxed: []Relaxation{}, Notes: []string{}}
+	if slot := day.SlotName(); slot != SlotDinner {
+		exp.Slot = slot
+	}
+	for _, r := range relaxationTiers[c.tier] {
+		exp.Relaxed = append(exp.Relaxed, r)
+		switch r {
+		case RelaxCooldown:
+			exp.Notes = append(exp.Notes, fmt.Sprintf("%s was last planned %s, inside the %d-day repeat window",
+				c.meal.MealName, c.meal.LastPlanned.Format("2006-01-02"), rules.RepeatCooldownDays))
+		case RelaxEffortRange:
+			exp.Notes = append(exp.Notes, fmt.Sprintf("%s has effort %d, outside the %d-%d range for %s",
+				c.meal.MealName, c.meal.RelativeEffort, day.MinEffort, day.MaxEffort, day.label()))
+		}
+	}
+	return exp
+}
+
+func effortDistance(m *Meal, day DayRule) int {
+	switch {
+	case m.RelativeEffort < day.MinEffort:
+		return day.MinEffort - m.RelativeEffort
+	case m.RelativeEffort > day.MaxEffort:
+		return m.RelativeEffort - day.MaxEffort
+	}
+	return 0
+}
+
+func findMeal(pool []*Meal, id int) *Meal {
+	for _, m := range pool {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// SwapCandidate is a possible replacement for one day of a plan.
+type SwapCandidate struct {
+	Meal        *Meal          `json:"meal"`
+	Explanation DayExplanation `json:"explanation"`
+}
+
+// RankSwapCandidates ranks replacements for one day's slot of an existing plan using the same
+// rules as the generator. The plan maps plan keys to meal IDs; meals planned in the other
+// slots count toward the category caps and are never offered, and neither is the current
+// meal. Only meals suitable for the slot are offered.
+// Exact matches come first, followed by candidates that relax the cooldown or effort range.
+// At most limit candidates are returned (all of them when limit <= 0).
+func RankSwapCandidates(pool []*Meal, rules PlanRules, plan map[string]int, day string, opts SolveOptions, limit int) ([]SwapCandidate, error) {
+	if !IsPlanKey(day) {
+		return nil, fmt.Errorf("unknown day %q", day)
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	rng := opts.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	// Days without a cooking rule (e.g. an eat-out day) accept any effort.
+	weekday, slot := SplitPlanKey(day)
+	dayRule := DayRule{Day: weekday, Slot: slot, MinEffort: 0, MaxEffort: 100}
+	for _, d := range rules.Days {
+		if d.Key() == day && !d.EatOut && d.FixedMealID == 0 {
+			dayRule = d
+		}
+	}
+
+	s := &solver{rules: rules, used: make(map[int]bool), counts: make(map[string]map[string]int), tagCounts: make(map[string]map[string]int)}
+	for d, id := range plan {
+		if d == day {
+			s.used[id] = true // never offer the meal being swapped out
+			continue
+		}
+		if meal := findMeal(pool, id); meal != nil {
+			s.take(d, meal)
+		}
+	}
+
+	// Meals that leave the week short of a required tag are still offered, after the rest.
+	week := rules.weekOf(day)
+	minimums := rules.weekMinimums(week)
+	short := s.shortfall(week)
+	var ranked, shortOf []SwapCandidate
+	for _, c := range rankCandidates(pool, dayRule, rules.RepeatCutoff(now), now, rng) {
+		if !s.fits(day, c.meal) {
+			continue
+		}
+		swap := SwapCandidate{Meal: c.meal, Explanation: explain(dayRule, c, rules)}
+		if missing := missingTags(c.meal, short); len(missing) > 0 {
+			swap.Explanation.Relaxed = append(swap.Explanation.Relaxed, RelaxTagMinimum)
+			for _, tag := range missing {
+				have := minimums[tag] - short[tag]
+				if c.meal.HasTag(tag) {
+					have++
+				}
+				swap.Explanation.Notes = append(swap.Explanation.Notes, fmt.Sprintf("the week would have %d of the %d %s meals the rules require",
+					have, minimums[tag], tag))
+			}
+			shortOf = append(shortOf, swap)
+			continue
+		}
+		ranked = append(ranked, swap)
+	}
+	ranked = append(ranked, shortOf...)
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	if len(ranked) == 0 {
+		return nil, ErrNoAlternative
+	}
+	return ranked, nil
+}
+
+// missingTags returns the tags the week would still be short of with the meal added, sorted.
+func missingTags(m *Meal, short map[string]int) []string {
+	var missing []string
+	for tag, n := range short {
+		if n > 1 || !m.HasTag(tag) {
+			missing = append(missing, tag)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// SwapMealInPlan loads the candidate pool and ranks replacements for one day of the plan.
+func SwapMealInPlan(db *sql.DB, rules PlanRules, plan map[string]int, day string, limit int) ([]SwapCandidate, error) {
+	pool, err := LoadCandidatePool(db)
+	if err != nil {
+		return nil, err
+	}
+	return RankSwapCandidates(pool, rules, plan, day, SolveOptions{}, limit)
+}
@@ -0,0 +1,411 @@
+package models
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPool builds a candidate pool of meals with the given efforts, all unplanned and not red meat.
+func testPool(efforts ...int) []*Meal {
+	pool := make([]*Meal, len(efforts))
+	for i, effort := range efforts {
+		pool[i] = &Meal{ID: i + 1, MealName: "Meal", RelativeEffort: effort}
+	}
+	return pool
+}
+
+func solveOpts(now time.Time) SolveOptions {
+	return SolveOptions{Now: now, Rand: rand.New(rand.NewSource(1))}
+}
+
+func TestSolvePlan_ExactSolution(t *testing.T) {
+	pool := testPool(1, 4, 4, 4, 4, 7)
+	result, err := SolvePlan(pool, DefaultPlanRules(), solveOpts(time.Now()))
+	if err != nil {
+		t.Fatalf("SolvePlan returned error: %v", err)
+	}
+	if len(result.Plan) != 7 {
+		t.Fatalf("expected 7 planned days, got %d", len(result.Plan))
+	}
+	if len(result.Explanations) != 0 {
+		t.Errorf("expected no relaxations, got %+v", result.Explanations)
+	}
+	seen := map[int]bool{}
+	for day, meal := range result.Plan {
+		if meal.ID != 0 && seen[meal.ID] {
+			t.Errorf("meal %d planned twice (again on %s)", meal.ID, day)
+		}
+		seen[meal.ID] = true
+	}
+}
+
+func TestSolvePlan_BacktracksOverRedMeatCap(t *testing.T) {
+	// The only Sunday-effort meal is red meat, so an early red meat pick midweek must be undone.
+	pool := []*Meal{
+		{ID: 1, RelativeEffort: 1},
+		{ID: 2, RelativeEffort: 4, RedMeat: true},
+		{ID: 3, RelativeEffort: 4},
+		{ID: 4, RelativeEffort: 4},
+		{ID: 5, RelativeEffort: 4},
+		{ID: 6, RelativeEffort: 4},
+		{ID: 7, RelativeEffort: 8, RedMeat: true},
+	}
+	for seed := int64(0); seed < 20; seed++ {
+		result, err := SolvePlan(pool, DefaultPlanRules(), SolveOptions{Rand: rand.New(rand.NewSource(seed))})
+		if err != nil {
+			t.Fatalf("seed %d: SolvePlan returned error: %v", seed, err)
+		}
+		if result.Plan["Sunday"].ID != 7 {
+			t.Fatalf("seed %d: expected the red meat roast on Sunday, got %+v", seed, result.Plan["Sunday"])
+		}
+		if len(result.Explanations) != 0 {
+			t.Fatalf("seed %d: expected an exact plan, got %+v", seed, result.Explanations)
+		}
+	}
+}
+
+func TestSolvePlan_RelaxesCooldownBeforeEffort(t *testing.T) {
+	now := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	// The only Sunday-effort meal was planned last week; using it beats stretching an easier meal.
+	pool := testPool(1, 4, 4, 4, 4, 7, 5)
+	pool[5].LastPlanned = now.AddDate(0, 0, -7)
+
+	result, err := SolvePlan(pool, DefaultPlanRules(), solveOpts(now))
+	if err != nil {
+		t.Fatalf("SolvePlan returned error: %v", err)
+	}
+	exp, ok := result.Explanations["Sunday"]
+	if !ok {
+		t.Fatalf("expected an explanation for Sunday, got %+v", result.Explanations)
+	}
+	if len(exp.Relaxed) != 1 || exp.Relaxed[0] != RelaxCooldown {
+		t.Errorf("expected only the cooldown to be relaxed, got %v", exp.Relaxed)
+	}
+	if len(result.Explanations) != 1 {
+		t.Errorf("expected relaxations only on Sunday, got %+v", result.Explanations)
+	}
+}
+
+func TestSolvePlan_EffortTierKeepsCooldown(t *testing.T) {
+	now := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	// Nothing is easy enough for Monday, so the effort range has to be relaxed. Sunday's
+	// recent roast still breaks the cooldown, so Sunday stretches a fresh meal instead.
+	pool := testPool(4, 4, 4, 4, 7, 5, 5)
+	pool[4].LastPlanned = now.AddDate(0, 0, -7)
+
+	result, err := SolvePlan(pool, DefaultPlanRules(), solveOpts(now))
+	if err != nil {
+		t.Fatalf("SolvePlan returned error: %v", err)
+	}
+	if result.Plan["Sunday"].ID == pool[4].ID {
+		t.Errorf("expected the effort tier not to break the cooldown on Sunday")
+	}
+	for day, exp := range result.Explanations {
+		for _, r := range exp.Relaxed {
+			if r != RelaxEffortRange {
+				t.Errorf("expected only effort ranges to be relaxed, got %v on %s", exp.Relaxed, day)
+			}
+		}
+	}
+}
+
+func TestSolvePlan_RelaxesEffortRange(t *testing.T) {
+	// Nothing is hard enough for Sunday, so the closest effort is used.
+	pool := testPool(1, 4, 4, 4, 4, 5)
+	result, err := SolvePlan(pool, DefaultPlanRules(), solveOpts(time.Now()))
+	if err != nil {
+		t.Fatalf("SolvePlan returned error: %v", err)
+	}
+	exp, ok := result.Explanations["Sunday"]
+	if !ok || len(exp.Relaxed) != 1 || exp.Relaxed[0] != RelaxEffortRange {
+		t.Fatalf("expected Sunday's effort range to be relaxed, got %+v", result.Explanations)
+	}
+	if len(exp.Notes) != 1 {
+		t.Errorf("expected a note describing the relaxation, got %v", exp.Notes)
+	}
+}
+
+func TestSolvePlan_Infeasible(t *testing.T) {
+	// Six cooking days but only three meals: no amount of relaxing helps.
+	if _, err := SolvePlan(testPool(1, 4, 7), DefaultPlanRules(), solveOpts(time.Now())); err == nil {
+		t.Errorf("expected an error when there are fewer meals than days")
+	}
+
+	rules := DefaultPlanRules()
+	rules.Days[0].FixedMealID = 99
+	if _, err := SolvePlan(testPool(1, 4, 4, 4, 4, 7), rules, solveOpts(time.Now())); err == nil {
+		t.Errorf("expected an error for a missing fixed meal")
+	}
+}
+
+func TestSolvePlan_ReduceWaste(t *testing.T) {
+	rules := PlanRules{
+		Days: []DayRule{
+			{Day: "Monday", MinEffort: 0, MaxEffort: 5},
+			{Day: "Tuesday", MinEffort: 0, MaxEffort: 5},
+		},
+	}
+	// Two cilantro meals and several meals that each need their own perishable.
+	pool := []*Meal{
+		{ID: 1, RelativeEffort: 2, Ingredients: []Ingredient{{Name: "cilantro"}}},
+		{ID: 2, RelativeEffort: 2, Ingredients: []Ingredient{{Name: "chopped cilantro"}}},
+		{ID: 3, RelativeEffort: 2, Ingredients: []Ingredient{{Name: "basil"}}},
+		{ID: 4, RelativeEffort: 2, Ingredients: []Ingredient{{Name: "dill"}}},
+		{ID: 5, RelativeEffort: 2, Ingredients: []Ingredient{{Name: "kale"}}},
+		{ID: 6, RelativeEffort: 2, Ingredients: []Ingredient{{Name: "spinach"}}},
+	}
+
+	result, err := SolvePlan(pool, rules, SolveOptions{Rand: rand.New(rand.NewSource(3)), ReduceWaste: true})
+	if err != nil {
+		t.Fatalf("SolvePlan returned error: %v", err)
+	}
+	if result.Waste == nil {
+		t.Fatalf("expected a waste report")
+	}
+	ids := map[int]bool{result.Plan["Monday"].ID: true, result.Plan["Tuesday"].ID: true}
+	if !ids[1] || !ids[2] {
+		t.Errorf("expected the two cilantro meals to be paired, got %v", ids)
+	}
+	if result.Waste.Score != 1 {
+		t.Errorf("expected waste score 1, got %d", result.Waste.Score)
+	}
+}
+
+func TestSolvePlan_LockedDays(t *testing.T) {
+	pool := []*Meal{
+		{ID: 1, RelativeEffort: 1},
+		{ID: 2, RelativeEffort: 4, RedMeat: true},
+		{ID: 3, RelativeEffort: 4},
+		{ID: 4, RelativeEffort: 4},
+		{ID: 5, RelativeEffort: 4},
+		{ID: 6, RelativeEffort: 4, RedMeat: true},
+		{ID: 7, RelativeEffort: 4},
+		{ID: 8, RelativeEffort: 8},
+		{ID: 9, RelativeEffort: 9, LastPlanned: time.Now()},
+	}
+	for seed := int64(0); seed < 10; seed++ {
+		opts := SolveOptions{Rand: rand.New(rand.NewSource(seed)), Locked: map[string]int{"Tuesday": 6, "Friday": 9}}
+		result, err := SolvePlan(pool, DefaultPlanRules(), opts)
+		if err != nil {
+			t.Fatalf("seed %d: SolvePlan returned error: %v", seed, err)
+		}
+		if result.Plan["Tuesday"].ID != 6 {
+			t.Errorf("seed %d: expected Tuesday to stay locked to 6, got %d", seed, result.Plan["Tuesday"].ID)
+		}
+		// A locked day overrides the rules, even an eat-out day or a recently planned meal.
+		if result.Plan["Friday"].ID != 9 {
+			t.Errorf("seed %d: expected Friday to stay locked to 9, got %+v", seed, result.Plan["Friday"])
+		}
+		for day, meal := range result.Plan {
+			if day != "Tuesday" && meal.RedMeat {
+				t.Errorf("seed %d: the locked red meat meal should use up the cap, but %s has %d", seed, day, meal.ID)
+			}
+			if day != "Tuesday" && meal.ID == 6 {
+				t.Errorf("seed %d: locked meal repeated on %s", seed, day)
+			}
+		}
+	}
+
+	if _, err := SolvePlan(pool, DefaultPlanRules(), SolveOptions{Locked: map[string]int{"Monday": 42}}); err == nil {
+		t.Errorf("expected an error for a locked meal that does not exist")
+	}
+}
+
+func TestSolvePlan_Leftovers(t *testing.T) {
+	// The only Monday meal feeds three nights, so Tuesday and Wednesday are leftovers and
+	// the three midweek meals are enough for Thursday and Saturday.
+	pool := []*Meal{
+		{ID: 1, MealName: "Lasagne", RelativeEffort: 1, Nights: 3},
+		{ID: 2, RelativeEffort: 4},
+		{ID: 3, RelativeEffort: 4},
+		{ID: 4, RelativeEffort: 4},
+		{ID: 5, RelativeEffort: 8},
+		{ID: 6, MealName: "Stew", RelativeEffort: 4, Nights: 2},
+	}
+	for seed := int64(0); seed < 10; seed++ {
+		opts := SolveOptions{Rand: rand.New(rand.NewSource(seed)), Locked: map[string]int{"Saturday": 6}}
+		result, err := SolvePlan(pool, DefaultPlanRules(), opts)
+		if err != nil {
+			t.Fatalf("seed %d: SolvePlan returned error: %v", seed, err)
+		}
+		for _, day := range []string{"Tuesday", "Wednesday"} {
+			if m := result.Plan[day]; !m.IsLeftovers() || m.LeftoversOf != 1 || m.MealName != "Leftovers of Lasagne" {
+				t.Errorf("seed %d: expected leftovers of meal 1 on %s, got %+v", seed, day, m)
+			}
+		}
+		// A locked meal's leftovers fill the open day after it.
+		if m := result.Plan["Sunday"]; !m.IsLeftovers() || m.LeftoversOf != 6 {
+			t.Errorf("seed %d: expected leftovers of meal 6 on Sunday, got %+v", seed, m)
+		}
+		if m := result.Plan["Thursday"]; m.IsLeftovers() || m.ID == 0 {
+			t.Errorf("seed %d: expected a meal cooked on Thursday, got %+v", seed, m)
+		}
+		if result.Plan["Friday"].MealName != EatingOutMealName {
+			t.Errorf("seed %d: expected Friday to stay an eat-out day, got %+v", seed, result.Plan["Friday"])
+		}
+	}
+}
+
+func TestRankSwapCandidates(t *testing.T) {
+	pool := []*Meal{
+		{ID: 1, RelativeEffort: 4},
+		{ID: 2, RelativeEffort: 4, RedMeat: true},
+		{ID: 3, RelativeEffort: 4},
+		{ID: 4, RelativeEffort: 4},
+		{ID: 5, RelativeEffort: 1},
+		{ID: 6, RelativeEffort: 8, RedMeat: true},
+	}
+	// Sunday already has the week's red meat meal and Wednesday already has meal 3.
+	plan := map[string]int{"Tuesday": 1, "Wednesday": 3, "Sunday": 6}
+
+	ranked, err := RankSwapCandidates(pool, DefaultPlanRules(), plan, "Tuesday", solveOpts(time.Now()), 0)
+	if err != nil {
+		t.Fatalf("RankSwapCandidates returned error: %v", err)
+	}
+	if len(ranked) != 2 {
+		t.Fatalf("expected 2 candidates, got %d", len(ranked))
+	}
+	if ranked[0].Meal.ID != 4 || len(ranked[0].Explanation.Relaxed) != 0 {
+		t.Errorf("expected the exact match first, got %+v", ranked[0])
+	}
+	if ranked[1].Meal.ID != 5 || len(ranked[1].Explanation.Relaxed) == 0 {
+		t.Errorf("expected the easy meal second with a relaxed effort range, got %+v", ranked[1])
+	}
+
+	ranked, err = RankSwapCandidates(pool, DefaultPlanRules(), plan, "Tuesday", solveOpts(time.Now()), 1)
+	if err != nil || len(ranked) != 1 {
+		t.Errorf("expected a single candidate with limit 1, got %+v (%v)", ranked, err)
+	}
+
+	if _, err := RankSwapCandidates(pool[:1], DefaultPlanRules(), plan, "Tuesday", solveOpts(time.Now()), 0); err == nil {
+		t.Errorf("expected an error when no alternative fits")
+	}
+}
+
+func TestSolvePlan_TagRules(t *testing.T) {
+	pool := testPool(1, 4, 4, 4, 4, 4, 4, 7)
+	for _, m := range pool[1:4] {
+		m.Tags = []string{"chicken"}
+	}
+	pool[4].Tags = []string{"vegetarian"}
+	rules := DefaultPlanRules()
+	rules.TagCaps = map[string]int{"chicken": 1}
+	rules.TagMinimums = map[string]int{"vegetarian": 1}
+
+	for seed := int64(0); seed < 20; seed++ {
+		result, err := SolvePlan(pool, rules, SolveOptions{Rand: rand.New(rand.NewSource(seed))})
+		if err != nil {
+			t.Fatalf("seed %d: SolvePlan returned error: %v", seed, err)
+		}
+		counts := map[string]int{}
+		for _, meal := range result.Plan {
+			for _, tag := range meal.Tags {
+				counts[tag]++
+			}
+		}
+		if counts["chicken"] > 1 || counts["vegetarian"] != 1 {
+			t.Fatalf("seed %d: expected at most 1 chicken and 1 vegetarian meal, got %v", seed, counts)
+		}
+	}
+
+	rules.TagMinimums["vegetarian"] = 2
+	_, err := SolvePlan(pool, rules, solveOpts(time.Now()))
+	if err == nil || !strings.Contains(err.Error(), "tag rules") {
+		t.Errorf("expected an error naming the tag rules, got %v", err)
+	}
+}
+
+func TestSolvePlan_WeeklyRulesPerWeek(t *testing.T) {
+	pool := []*Meal{
+		{ID: 1, RelativeEffort: 4, RedMeat: true},
+		{ID: 2, RelativeEffort: 4, RedMeat: true},
+		{ID: 3, RelativeEffort: 4, Tags: []string{"fish"}},
+		{ID: 4, RelativeEffort: 4, Tags: []string{"fish"}},
+	}
+	weekly := PlanRules{
+		Days:         []DayRule{{Day: "Saturday", MaxEffort: 5}, {Day: "Sunday", MaxEffort: 5}},
+		CategoryCaps: map[string]int{CategoryRedMeat: 1},
+		TagMinimums:  map[string]int{"fish": 1},
+		WeekStartDay: "Sunday",
+	}
+	// Saturday 2024-03-02 ends one week; 2024-03-03 and 2024-03-09 make up the next, and
+	// the range covers six days of the week from 2024-03-10, so it needs its fish too.
+	rules, err := weekly.ForDates(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), 14)
+	if err != nil {
+		t.Fatalf("ForDates: %v", err)
+	}
+	for seed := int64(0); seed < 20; seed++ {
+		result, err := SolvePlan(pool, rules, SolveOptions{Rand: rand.New(rand.NewSource(seed))})
+		if err != nil {
+			t.Fatalf("seed %d: SolvePlan returned error: %v", seed, err)
+		}
+		if !result.Plan["2024-03-02"].RedMeat || !result.Plan["2024-03-10"].HasTag("fish") {
+			t.Fatalf("seed %d: expected red meat on 2024-03-02 and fish on 2024-03-10, got %+v", seed, result.Plan)
+		}
+		sun, sat := result.Plan["2024-03-03"], result.Plan["2024-03-09"]
+		if sun.RedMeat == sat.RedMeat || sun.HasTag("fish") == sat.HasTag("fish") {
+			t.Fatalf("seed %d: expected one red meat and one fish meal in the week from 2024-03-03, got %+v and %+v", seed, sun, sat)
+		}
+	}
+}
+
+func TestRankSwapCandidates_TagMinimum(t *testing.T) {
+	pool := []*Meal{
+		{ID: 1, RelativeEffort: 4, Tags: []string{"vegetarian"}},
+		{ID: 2, RelativeEffort: 4},
+		{ID: 3, RelativeEffort: 4, Tags: []string{"vegetarian"}},
+	}
+	rules := DefaultPlanRules()
+	rules.TagMinimums = map[string]int{"vegetarian": 1}
+	plan := map[string]int{"Tuesday": 1}
+
+	ranked, err := RankSwapCandidates(pool, rules, plan, "Tuesday", solveOpts(time.Now()), 0)
+	if err != nil {
+		t.Fatalf("RankSwapCandidates returned error: %v", err)
+	}
+	if len(ranked) != 2 || ranked[0].Meal.ID != 3 {
+		t.Fatalf("expected the vegetarian meal first, got %+v", ranked)
+	}
+	relaxed := ranked[1].Explanation.Relaxed
+	if ranked[1].Meal.ID != 2 || len(relaxed) != 1 || relaxed[0] != RelaxTagMinimum {
+		t.Errorf("expected the other meal last with the tag minimum relaxed, got %+v", ranked[1])
+	}
+}
+
+func TestSolvePlan_Slots(t *testing.T) {
+	// Breakfasts only come from breakfast meals, and dinner meals never land on a breakfast.
+	pool := append(testPool(1, 4, 4, 4, 4, 7),
+		&Meal{ID: 20, MealName: "Porridge", RelativeEffort: 1, Slots: []string{SlotBreakfast}},
+		&Meal{ID: 21, MealName: "Pancakes", RelativeEffort: 2, Slots: []string{SlotBreakfast, SlotLunch}},
+	)
+	rules := DefaultPlanRules()
+	rules.Days = append(rules.Days,
+		DayRule{Day: "Saturday", Slot: SlotBreakfast, MinEffort: 0, MaxEffort: 5},
+		DayRule{Day: "Sunday", Slot: SlotBreakfast, MinEffort: 0, MaxEffort: 5},
+	)
+	result, err := SolvePlan(pool, rules, solveOpts(time.Now()))
+	if err != nil {
+		t.Fatalf("SolvePlan returned error: %v", err)
+	}
+	if len(result.Plan) != 9 {
+		t.Fatalf("expected 9 planned slots, got %d: %v", len(result.Plan), PlanKeys(result.Plan))
+	}
+	breakfasts := map[int]bool{}
+	for _, key := range []string{"Saturday/breakfast", "Sunday/breakfast"} {
+		m := result.Plan[key]
+		if m == nil || !m.SuitableFor(SlotBreakfast) {
+			t.Fatalf("expected a breakfast meal on %s, got %+v", key, m)
+		}
+		breakfasts[m.ID] = true
+	}
+	if len(breakfasts) != 2 {
+		t.Errorf("expected two different breakfasts, got %v", breakfasts)
+	}
+	for _, day := range Weekdays {
+		if m := result.Plan[day]; m.ID >= 20 {
+			t.Errorf("expected a dinner on %s, got %+v", day, m)
+		}
+	}
+}
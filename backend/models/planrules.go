@@ -0,0 +1,265 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// CategoryRedMeat is the category counted for meals flagged as red meat.
+const CategoryRedMeat = "red_meat"
+
+// DayRule describes how a single day of the week is planned, for one meal slot. Rules
+// without a slot are for dinner.
+type DayRule struct {
+	Day       string `json:"day"`
+	Slot      string `json:"slot,omitempty"`
+	MinEffort int    `json:"minEffort"`
+	MaxEffort int    `json:"maxEffort"`
+	// EatOut marks the day as "Eating out" instead of picking a meal.
+	EatOut bool `json:"eatOut,omitempty"`
+	// FixedMealID always plans the given meal on this day when set.
+	FixedMealID int `json:"fixedMealId,omitempty"`
+}
+
+// PlanRules are the household's rules for generating a weekly meal plan.
+type PlanRules struct {
+	Days []DayRule `json:"days"`
+	// RepeatCooldownDays is how long a meal must rest before it can be planned again.
+	RepeatCooldownDays int `json:"repeatCooldownDays"`
+	// CategoryCaps limits how many meals of a category may appear in one week.
+	CategoryCaps map[string]int `json:"categoryCaps"`
+	// TagCaps limits how many meals with a tag may appear in one week.
+	TagCaps map[string]int `json:"tagCaps,omitempty"`
+	// TagMinimums requires at least this many meals with a tag in every week.
+	TagMinimums map[string]int `json:"tagMinimums,omitempty"`
+	// WeekStartDay is the weekday the household's week starts on; empty means Monday.
+	WeekStartDay string `json:"weekStartDay,omitempty"`
+	// TimeZone is the IANA time zone that decides what "today" is; empty means UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// rangeStart and rangeDays are the dates ForDates planned the rules for.
+	rangeStart time.Time
+	rangeDays  int
+}
+
+// DefaultPlanRules returns the rules the planner has always used: an easy Monday,
+// medium effort midweek and Saturday, eating out on Friday, a big Sunday cook,
+// a three week repeat window and at most one red meat meal per week.
+func DefaultPlanRules() PlanRules {
+	return PlanRules{
+		Days: []DayRule{
+			{Day: "Monday", MinEffort: 0, MaxEffort: 2},
+			{Day: "Tuesday", MinEffort: 3, MaxEffort: 5},
+			{Day: "Wednesday", MinEffort: 3, MaxEffort: 5},
+			{Day: "Thursday", MinEffort: 3, MaxEffort: 5},
+			{Day: "Friday", EatOut: true},
+			{Day: "Saturday", MinEffort: 3, MaxEffort: 5},
+			{Day: "Sunday", MinEffort: 6, MaxEffort: 100},
+		},
+		RepeatCooldownDays: 21,
+		CategoryCaps:       map[string]int{CategoryRedMeat: 1},
+	}
+}
+
+// Key returns the plan key of the day and slot the rule plans.
+func (d DayRule) Key() string {
+	return PlanKey(d.Day, d.Slot)
+}
+
+// SlotName returns the rule's slot, dinner when it has none.
+func (d DayRule) SlotName() string {
+	return NormalizeSlot(d.Slot)
+}
+
+// label names the rule's day in messages: the day alone for dinner, "Monday breakfast"
+// for other slots.
+func (d DayRule) label() string {
+	if d.SlotName() == SlotDinner {
+		return d.Day
+	}
+	return d.Day + " " + d.SlotName()
+}
+
+// KnownCategories lists the meal categories that can be capped.
+var KnownCategories = []string{CategoryRedMeat}
+
+// Validate checks that the rules are internally consistent.
+func (r PlanRules) Validate() error {
+	if len(r.Days) == 0 {
+		return errors.New("at least one day rule is required")
+	}
+	seen := make(map[string]bool)
+	for _, d := range r.Days {
+		if !IsWeekday(d.Day) {
+			return fmt.Errorf("unknown day %q", d.Day)
+		}
+		if !IsSlot(d.SlotName()) {
+			return fmt.Errorf("unknown slot %q for %s, expected one of %s", d.Slot, d.Day, strings.Join(Slots, ", "))
+		}
+		if seen[d.Key()] {
+			return fmt.Errorf("day %q is listed more than once", d.label())
+		}
+		seen[d.Key()] = true
+		if d.EatOut && d.FixedMealID != 0 {
+			return fmt.Errorf("%s cannot be both an eat-out day and a fixed meal day", d.label())
+		}
+		if d.MinEffort < 0 || d.MaxEffort < d.MinEffort {
+			return fmt.Errorf("invalid effort range %d-%d for %s", d.MinEffort, d.MaxEffort, d.label())
+		}
+	}
+	if r.RepeatCooldownDays < 0 {
+		return errors.New("repeat cooldown cannot be negative")
+	}
+	for category, max := range r.CategoryCaps {
+		if !isKnownCategory(category) {
+			return fmt.Errorf("unknown category %q", category)
+		}
+		if max < 0 {
+			return fmt.Errorf("cap for %q cannot be negative", category)
+		}
+	}
+	for _, tags := range []map[string]int{r.TagCaps, r.TagMinimums} {
+		for tag, n := range tags {
+			if tag == "" {
+				return errors.New("tag rules need a tag name")
+			}
+			if normalized := NormalizeTagName(tag); normalized != tag {
+				return fmt.Errorf("invalid tag %q, expected %q", tag, normalized)
+			}
+			if n < 0 {
+				return fmt.Errorf("rule for tag %q cannot be negative", tag)
+			}
+		}
+	}
+	for tag, min := range r.TagMinimums {
+		if max, ok := r.TagCaps[tag]; ok && min > max {
+			return fmt.Errorf("tag %q requires %d meals a week but is capped at %d", tag, min, max)
+		}
+	}
+	if r.WeekStartDay != "" && !IsWeekday(r.WeekStartDay) {
+		return fmt.Errorf("unknown week start day %q", r.WeekStartDay)
+	}
+	if r.TimeZone != "" {
+		if _, err := time.LoadLocation(r.TimeZone); err != nil {
+			return fmt.Errorf("unknown time zone %q", r.TimeZone)
+		}
+	}
+	return nil
+}
+
+// RepeatCutoff returns the time before which a meal must have last been planned to be eligible.
+func (r PlanRules) RepeatCutoff(now time.Time) time.Time {
+	return now.AddDate(0, 0, -r.RepeatCooldownDays)
+}
+
+// CategoryAllowed reports whether another meal of the category fits under its weekly cap.
+func (r PlanRules) CategoryAllowed(category string, counts map[string]int) bool {
+	max, ok := r.CategoryCaps[category]
+	return !ok || counts[category] < max
+}
+
+// TagAllowed reports whether another meal with the tag fits under its weekly cap.
+func (r PlanRules) TagAllowed(tag string, counts map[string]int) bool {
+	max, ok := r.TagCaps[tag]
+	return !ok || counts[tag] < max
+}
+
+// TagShortfall returns how many more meals with each required tag the week needs, given
+// how many it already has. Tags whose minimum is met are left out.
+func (r PlanRules) TagShortfall(counts map[string]int) map[string]int {
+	return tagShortfall(r.TagMinimums, counts)
+}
+
+// tagShortfall returns how many more meals with each tag are needed to reach minimums.
+func tagShortfall(minimums, counts map[string]int) map[string]int {
+	short := make(map[string]int)
+	for tag, min := range minimums {
+		if n := min - counts[tag]; n > 0 {
+			short[tag] = n
+		}
+	}
+	return short
+}
+
+// Categories returns the cappable categories the meal belongs to.
+func (m *Meal) Categories() []string {
+	var categories []string
+	if m.RedMeat {
+		categories = append(categories, CategoryRedMeat)
+	}
+	return categories
+}
+
+// GetPlanRules loads the stored planning rules, falling back to DefaultPlanRules when none are saved.
+func GetPlanRules(db *sql.DB) (PlanRules, error) {
+	return planRules(db)
+}
+
+// planRules runs GetPlanRules on a database or a transaction.
+func planRules(db rowQuerier) (PlanRules, error) {
+	var raw string
+	err := db.QueryRow("SELECT rules FROM planning_rules WHERE id = 1").Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultPlanRules(), nil
+	}
+	if err != nil {
+		log.Printf("GetPlanRules: error executing query: %v", err)
+		return PlanRules{}, err
+	}
+
+	var rules PlanRules
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		log.Printf("GetPlanRules: error decoding stored rules: %v", err)
+		return PlanRules{}, err
+	}
+	return rules, nil
+}
+
+// SavePlanRules validates and stores the planning rules.
+func SavePlanRules(db *sql.DB, rules PlanRules) error {
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO planning_rules (id, rules, updated_at) VALUES (1, $1, $2)
+		ON CONFLICT (id) DO UPDATE SET rules = EXCLUDED.rules, updated_at = EXCLUDED.updated_at
+	`, string(raw), time.Now().UTC())
+	if err != nil {
+		log.Printf("SavePlanRules: error saving rules: %v", err)
+		return err
+	}
+	return nil
+}
+
+// weekdayIndex returns the position of day in Weekdays, or -1.
+func weekdayIndex(day string) int {
+	for i, d := range Weekdays {
+		if d == day {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsWeekday reports whether day is one of the plan's weekday names.
+func IsWeekday(day string) bool {
+	return weekdayIndex(day) >= 0
+}
+
+func isKnownCategory(category string) bool {
+	for _, c := range KnownCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3" // Import SQLite driver
+)
+
+func TestPlanRulesValidate(t *testing.T) {
+	if err := DefaultPlanRules().Validate(); err != nil {
+		t.Fatalf("default rules should be valid, got %v", err)
+	}
+	withBreakfast := DefaultPlanRules()
+	withBreakfast.Days = append(withBreakfast.Days, DayRule{Day: "Monday", Slot: SlotBreakfast, MaxEffort: 2})
+	if err := withBreakfast.Validate(); err != nil {
+		t.Fatalf("a breakfast rule next to the day's dinner should be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(r *PlanRules)
+	}{
+		{"no days", func(r *PlanRules) { r.Days = nil }},
+		{"unknown day", func(r *PlanRules) { r.Days[0].Day = "Funday" }},
+		{"duplicate day", func(r *PlanRules) { r.Days[1].Day = "Monday" }},
+		{"unknown slot", func(r *PlanRules) { r.Days[0].Slot = "brunch" }},
+		{"duplicate slot", func(r *PlanRules) { r.Days = append(r.Days, DayRule{Day: "Monday", Slot: SlotDinner}) }},
+		{"inverted effort range", func(r *PlanRules) { r.Days[0].MinEffort, r.Days[0].MaxEffort = 5, 2 }},
+		{"eat out and fixed", func(r *PlanRules) { r.Days[4].FixedMealID = 3 }},
+		{"negative cooldown", func(r *PlanRules) { r.RepeatCooldownDays = -1 }},
+		{"unknown category", func(r *PlanRules) { r.CategoryCaps["dessert"] = 1 }},
+		{"negative cap", func(r *PlanRules) { r.CategoryCaps[CategoryRedMeat] = -1 }},
+		{"unnormalized tag", func(r *PlanRules) { r.TagCaps = map[string]int{"Chicken": 2} }},
+		{"empty tag", func(r *PlanRules) { r.TagMinimums = map[string]int{"": 1} }},
+		{"negative tag minimum", func(r *PlanRules) { r.TagMinimums = map[string]int{"vegetarian": -1} }},
+		{"unknown week start day", func(r *PlanRules) { r.WeekStartDay = "Caturday" }},
+		{"unknown time zone", func(r *PlanRules) { r.TimeZone = "Mars/Olympus_Mons" }},
+		{"minimum above cap", func(r *PlanRules) {
+			r.TagCaps = map[string]int{"fish": 1}
+			r.TagMinimums = map[string]int{"fish": 2}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultPlanRules()
+			tt.mutate(&rules)
+			if err := rules.Validate(); err == nil {
+				t.Errorf("expected validation error")
+			}
+		})
+	}
+}
+
+func TestGetAndSavePlanRules(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening in-memory database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE planning_rules (
+		id INTEGER PRIMARY KEY,
+		rules TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		t.Fatalf("Error creating planning_rules table: %v", err)
+	}
+
+	rules, err := GetPlanRules(db)
+	if err != nil {
+		t.Fatalf("GetPlanRules returned error: %v", err)
+	}
+	if rules.RepeatCooldownDays != 21 || len(rules.Days) != 7 {
+		t.Errorf("expected default rules when none are stored, got %+v", rules)
+	}
+
+	rules.RepeatCooldownDays = 14
+	rules.Days[4] = DayRule{Day: "Friday", MinEffort: 0, MaxEffort: 2}
+	for i := 0; i < 2; i++ { // saving twice must update, not duplicate
+		if err := SavePlanRules(db, rules); err != nil {
+			t.Fatalf("SavePlanRules returned error: %v", err)
+		}
+	}
+
+	stored, err := GetPlanRules(db)
+	if err != nil {
+		t.Fatalf("GetPlanRules returned error: %v", err)
+	}
+	if stored.RepeatCooldownDays != 14 || stored.Days[4].EatOut {
+		t.Errorf("stored rules not returned, got %+v", stored)
+	}
+
+	rules.RepeatCooldownDays = -5
+	if err := SavePlanRules(db, rules); err == nil {
+		t.Errorf("expected invalid rules to be rejected")
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "mealplanner/units"
+
+// ScaleFactor returns how much a recipe written for recipeServings must be multiplied by
+// to serve servings. It is 1 when either is unknown.
+func ScaleFactor(recipeServings, servings int) float64 {
+	if recipeServings <= 0 || servings <= 0 {
+		return 1
+	}
+	return float64(servings) / float64(recipeServings)
+}
+
+// ScaleMeal returns a copy of the meal with its ingredient quantities scaled to serve
+// servings and rounded with units.RoundScaled. The meal itself is returned when it needs
+// no scaling, either because servings matches or because its servings aren't known.
+func ScaleMeal(meal *Meal, servings int) *Meal {
+	factor := ScaleFactor(meal.Servings, servings)
+	if factor == 1 {
+		return meal
+	}
+	scaled := *meal
+	scaled.Servings = servings
+	scaled.Ingredients = make([]Ingredient, len(meal.Ingredients))
+	for i, ing := range meal.Ingredients {
+		ing.Quantity = units.RoundScaled(ing.Quantity*factor, ing.Unit)
+		scaled.Ingredients[i] = ing
+	}
+	return &scaled
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScaleFactor(t *testing.T) {
+	tests := []struct {
+		recipe, servings int
+		want             float64
+	}{
+		{4, 6, 1.5},
+		{4, 2, 0.5},
+		{0, 6, 1},
+		{4, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := ScaleFactor(tt.recipe, tt.servings); got != tt.want {
+			t.Errorf("ScaleFactor(%d, %d) = %v, want %v", tt.recipe, tt.servings, got, tt.want)
+		}
+	}
+}
+
+func TestScaleMeal(t *testing.T) {
+	meal := &Meal{ID: 1, MealName: "Chili", Servings: 4, Ingredients: []Ingredient{
+		{Name: "ground beef", Quantity: 1, Unit: "lb"},
+		{Name: "onion", Quantity: 1},
+		{Name: "chili powder", Quantity: 2, Unit: "tbsp"},
+		{Name: "tomatoes", Quantity: 400, Unit: "g"},
+		{Name: "salt"},
+	}}
+
+	scaled := ScaleMeal(meal, 6)
+	want := []Ingredient{
+		{Name: "ground beef", Quantity: 1.5, Unit: "lb"},
+		{Name: "onion", Quantity: 1.5},
+		{Name: "chili powder", Quantity: 3, Unit: "tbsp"},
+		{Name: "tomatoes", Quantity: 600, Unit: "g"},
+		{Name: "salt"},
+	}
+	if scaled.Servings != 6 || !reflect.DeepEqual(scaled.Ingredients, want) {
+		t.Errorf("unexpected scaled meal: %+v", scaled)
+	}
+	if meal.Servings != 4 || meal.Ingredients[0].Quantity != 1 {
+		t.Errorf("ScaleMeal must not change the original meal, got %+v", meal)
+	}
+
+	scaled = ScaleMeal(meal, 3)
+	if scaled.Ingredients[0].Quantity != 0.75 || scaled.Ingredients[1].Quantity != 1 {
+		t.Errorf("unexpected scaled ingredients: %+v", scaled.Ingredients)
+	}
+
+	unknown := &Meal{MealName: "Toast", Ingredients: []Ingredient{{Name: "bread", Quantity: 2}}}
+	if ScaleMeal(unknown, 8) != unknown {
+		t.Errorf("expected a meal without servings to be left as it is")
+	}
+}
@@ -5,102 +5,329 @@ import (
 	"database/sql"
 	"encoding/csv"
 	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+
+	"mealplanner/ingredient"
+)
+
+// CSVTimeLayout is the format of the last_planned column in the seed CSV.
+const CSVTimeLayout = "2006-01-02 15:04:05.000000"
+
+// seedDateLayout is the date at the start of a last_planned value.
+const seedDateLayout = "2006-01-02"
+
+// SeedRecord is a valid row of the seed CSV: a meal and one of its ingredients.
+type SeedRecord struct {
+	MealName       string
+	RelativeEffort int
+	// LastPlanned is zero when the row leaves it empty.
+	LastPlanned time.Time
+	Ingredient  Ingredient
+}
+
+// ParseSeedRecord checks one row of the seed CSV (meal name, ingredient line, relative
+// effort, last planned) and parses its ingredient line.
+func ParseSeedRecord(record []string) (SeedRecord, error) {
+	if len(record) < 4 {
+		return SeedRecord{}, fmt.Errorf("expected 4 columns, got %d", len(record))
+	}
+	rec := SeedRecord{MealName: strings.TrimSpace(record[0])}
+	if rec.MealName == "" {
+		return SeedRecord{}, errors.New("no meal name")
+	}
+	line := strings.TrimSpace(record[1])
+	if line == "" {
+		return SeedRecord{}, errors.New("no ingredient")
+	}
+	effort, err := strconv.Atoi(strings.TrimSpace(record[2]))
+	if err != nil || effort < 0 {
+		return SeedRecord{}, fmt.Errorf("invalid relative effort %q", record[2])
+	}
+	rec.RelativeEffort = effort
+	if v := strings.TrimSpace(record[3]); v != "" {
+		rec.LastPlanned, err = time.Parse(CSVTimeLayout, v)
+		if err != nil && len(v) >= len(seedDateLayout) {
+			// Some rows have a mangled time of day; the date is what matters.
+			rec.LastPlanned, err = time.Parse(seedDateLayout, v[:len(seedDateLayout)])
+		}
+		if err != nil {
+			return SeedRecord{}, fmt.Errorf("invalid last_planned %q, expected %s", v, CSVTimeLayout)
+		}
+	}
+	parsed := ingredient.Parse(line)
+	rec.Ingredient = Ingredient{Name: parsed.Name, Quantity: parsed.ShoppingQuantity(), Unit: parsed.Unit}
+	return rec, nil
+}
+
+// What seeding did with a row of the CSV.
+const (
+	SeedInserted = "inserted"
+	SeedUpdated  = "updated"
+	SeedSkipped  = "skipped"
+	SeedInvalid  = "invalid"
 )
 
-// SeedDB reads the CSV file and seeds the database. It only inserts each meal once.
-func SeedDB(db *sql.DB, csvPath string) error {
+// SeedRow is what seeding did with one row of the CSV.
+type SeedRow struct {
+	// Line is the row's line number in the file, counting the header as line 1.
+	Line   int    `json:"line"`
+	Meal   string `json:"meal"`
+	Action string `json:"action"`
+	// Detail says what changed, or why the row was skipped or invalid.
+	Detail string `json:"detail,omitempty"`
+}
+
+// SeedReport summarizes a seeding run.
+type SeedReport struct {
+	DryRun   bool      `json:"dryRun"`
+	Inserted int       `json:"inserted"`
+	Updated  int       `json:"updated"`
+	Skipped  int       `json:"skipped"`
+	Invalid  int       `json:"invalid"`
+	Rows     []SeedRow `json:"rows"`
+}
+
+func (r *SeedReport) add(row SeedRow) {
+	switch row.Action {
+	case SeedInserted:
+		r.Inserted++
+	case SeedUpdated:
+		r.Updated++
+	case SeedSkipped:
+		r.Skipped++
+	case SeedInvalid:
+		r.Invalid++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// seedMeal tracks a meal while seeding: the stored ingredients not yet matched to a row,
+// by name.
+type seedMeal struct {
+	id     int
+	effort int
+	// effortSet is true once the meal's relative effort has been upserted.
+	effortSet   bool
+	ingredients map[string][]Ingredient
+}
+
+// SeedDB reads the seed CSV and upserts it into the database, so it can be run again
+// safely. Meals are matched by name, ignoring case. A new meal is inserted with its
+// ingredients. For a stored meal, the relative effort is updated from the CSV, and its
+// ingredients are matched to the rows by name, in order: matching ones are skipped, ones
+// with a different quantity or unit are updated and missing ones are inserted. Nothing
+// is ever deleted, and last_planned is only set for new meals, so edits and planning
+// history survive. Invalid rows are reported and left out.
+//
+// With dryRun the changes are made in a transaction that is rolled back, so the report
+// is exactly what a real run would do.
+func SeedDB(db *sql.DB, csvPath string, dryRun bool) (*SeedReport, error) {
 	file, err := os.Open(csvPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer file.Close()
 
 	reader := csv.NewReader(bufio.NewReader(file))
-	records, err := reader.ReadAll()
-	if err != nil {
-		return err
+	reader.FieldsPerRecord = -1
+	// Skip the header row
+	if _, err := reader.Read(); err == io.EOF {
+		return nil, errors.New("CSV file is empty")
+	} else if err != nil {
+		return nil, err
 	}
-	if len(records) < 1 {
-		return errors.New("CSV file is empty")
-	}
-
-	// Skip header row
-	records = records[1:]
-	mealMap := make(map[string]int)
 
 	tx, err := db.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	for _, record := range records {
-		if len(record) < 4 {
-			continue // skip invalid row
+	rows, err := tx.Query(MealsQueryFragment + " ORDER BY m.id, mi.id")
+	if err != nil {
+		log.Printf("SeedDB: error loading meals: %v", err)
+		return nil, err
+	}
+	stored, err := processMealRows(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	meals := map[string]*seedMeal{}
+	for _, m := range stored {
+		key := strings.ToLower(strings.TrimSpace(m.MealName))
+		if _, ok := meals[key]; ok {
+			continue // an earlier seed may have left duplicates; use the first
+		}
+		sm := &seedMeal{id: m.ID, effort: m.RelativeEffort, ingredients: map[string][]Ingredient{}}
+		for _, ing := range m.Ingredients {
+			name := strings.ToLower(ing.Name)
+			sm.ingredients[name] = append(sm.ingredients[name], ing)
+		}
+		meals[key] = sm
+	}
+
+	report := &SeedReport{DryRun: dryRun, Rows: []SeedRow{}}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
 		}
-		mealName := record[0]
-		ingredientField := record[1]
-		relativeEffort, err := strconv.Atoi(record[2])
 		if err != nil {
-			continue
+			return nil, err
 		}
-		lastPlannedStr := record[3]
-		var lastPlanned time.Time
-		if lastPlannedStr != "" {
-			lastPlanned, err = time.Parse("2006-01-02 15:04:05.000000", lastPlannedStr)
-			if err != nil {
-				lastPlanned = time.Time{}
-			}
+		line, _ := reader.FieldPos(0)
+		row := SeedRow{Line: line}
+		if len(record) > 0 {
+			row.Meal = strings.TrimSpace(record[0])
+		}
+
+		rec, err := ParseSeedRecord(record)
+		if err != nil {
+			row.Action, row.Detail = SeedInvalid, err.Error()
+			report.add(row)
+			continue
 		}
+		key := strings.ToLower(rec.MealName)
+		ing := rec.Ingredient
 
-		mealID, exists := mealMap[mealName]
-		if !exists {
-			redMeat := isRedMeat(mealName)
+		sm, ok := meals[key]
+		if !ok {
+			var lastPlanned interface{}
+			if !rec.LastPlanned.IsZero() {
+				lastPlanned = rec.LastPlanned
+			}
+			var mealID int
 			err := tx.QueryRow(
 				"INSERT INTO meals (meal_name, relative_effort, last_planned, red_meat) VALUES ($1, $2, $3, $4) RETURNING id",
-				mealName, relativeEffort, lastPlanned, redMeat,
+				rec.MealName, rec.RelativeEffort, lastPlanned, IsRedMeat(rec.MealName),
 			).Scan(&mealID)
 			if err != nil {
-				return err
+				log.Printf("SeedDB: error inserting meal %q: %v", rec.MealName, err)
+				return nil, err
+			}
+			meals[key] = &seedMeal{id: mealID, effort: rec.RelativeEffort, effortSet: true, ingredients: map[string][]Ingredient{}}
+			if err := insertSeedIngredient(tx, mealID, ing); err != nil {
+				return nil, err
 			}
-			mealMap[mealName] = mealID
+			row.Action, row.Detail = SeedInserted, "new meal"
+			report.add(row)
+			continue
 		}
 
-		qty, unit, ingName := parseIngredient(ingredientField)
-		_, err = tx.Exec(
-			"INSERT INTO ingredients (meal_id, quantity, unit, name) VALUES ($1, $2, $3, $4)",
-			mealID, qty, unit, ingName,
-		)
-		if err != nil {
-			return err
+		var changes []string
+		if !sm.effortSet {
+			sm.effortSet = true
+			if sm.effort != rec.RelativeEffort {
+				if _, err := tx.Exec("UPDATE meals SET relative_effort = $1 WHERE id = $2", rec.RelativeEffort, sm.id); err != nil {
+					log.Printf("SeedDB: error updating mealID=%d: %v", sm.id, err)
+					return nil, err
+				}
+				changes = append(changes, fmt.Sprintf("relative effort %d -> %d", sm.effort, rec.RelativeEffort))
+				sm.effort = rec.RelativeEffort
+			}
+		}
+
+		name := strings.ToLower(ing.Name)
+		row.Action = SeedSkipped
+		if candidates := sm.ingredients[name]; len(candidates) > 0 {
+			match := candidates[0]
+			sm.ingredients[name] = candidates[1:]
+			if !sameAmount(match, ing) {
+				if _, err := tx.Exec("UPDATE ingredients SET quantity = $1, unit = $2 WHERE id = $3",
+					seedQuantity(ing.Quantity), ing.Unit, match.ID); err != nil {
+					log.Printf("SeedDB: error updating ingredientID=%d: %v", match.ID, err)
+					return nil, err
+				}
+				changes = append(changes, fmt.Sprintf("%s %s -> %s", ing.Name, formatAmount(match), formatAmount(ing)))
+			}
+			if len(changes) > 0 {
+				row.Action = SeedUpdated
+			} else {
+				row.Detail = "unchanged"
+			}
+		} else {
+			if err := insertSeedIngredient(tx, sm.id, ing); err != nil {
+				return nil, err
+			}
+			row.Action = SeedInserted
+			changes = append(changes, "new ingredient "+ing.Name)
+		}
+		if len(changes) > 0 {
+			row.Detail = strings.Join(changes, ", ")
 		}
+		report.add(row)
+	}
+
+	if dryRun {
+		return report, nil
 	}
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		log.Printf("SeedDB: error committing transaction: %v", err)
+		return nil, err
+	}
+	return report, nil
 }
 
-// parseIngredient attempts to split an ingredient string into quantity, unit, and the remainder of the description.
-// For example: "1 cup unsalted butter (2 sticks), at room temperature, plus 1 tablespoon"
-// might be parsed as: quantity="1", unit="cup", name="unsalted butter (2 sticks), at room temperature, plus 1 tablespoon".
-func parseIngredient(s string) (string, string, string) {
-	tokens := strings.Fields(s)
-	if len(tokens) < 3 {
-		return "", "", s
+// insertSeedIngredient stores an ingredient of a seeded meal.
+func insertSeedIngredient(tx *sql.Tx, mealID int, ing Ingredient) error {
+	_, err := tx.Exec(
+		"INSERT INTO ingredients (meal_id, quantity, unit, name) VALUES ($1, $2, $3, $4)",
+		mealID, seedQuantity(ing.Quantity), ing.Unit, ing.Name,
+	)
+	if err != nil {
+		log.Printf("SeedDB: error inserting ingredient %q for mealID=%d: %v", ing.Name, mealID, err)
 	}
-	return tokens[0], tokens[1], strings.Join(tokens[2:], " ")
+	return err
 }
 
-// isRedMeat determines if a meal is red meat based on keywords in the meal name.
-func isRedMeat(mealName string) bool {
-	lower := strings.ToLower(mealName)
+// seedQuantity formats a quantity for the text quantity column, leaving it empty for none.
+func seedQuantity(q float64) string {
+	if q <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+// sameAmount reports whether two ingredients have the same quantity and unit.
+func sameAmount(a, b Ingredient) bool {
+	return math.Abs(a.Quantity-b.Quantity) < 1e-9 && strings.EqualFold(a.Unit, b.Unit)
+}
+
+// formatAmount formats an ingredient's quantity and unit for the seed report.
+func formatAmount(ing Ingredient) string {
+	amount := strings.TrimSpace(seedQuantity(ing.Quantity) + " " + ing.Unit)
+	if amount == "" {
+		return "(none)"
+	}
+	return amount
+}
+
+// IsRedMeat determines if a meal is red meat based on keywords in the meal name. Keywords
+// must start a word, so "Hamburgers" and "Steaks" count but "Graham Cracker Crust" doesn't;
+// "burger" also counts at the end of a word, as in "Cheeseburger".
+func IsRedMeat(mealName string) bool {
+	words := strings.FieldsFunc(strings.ToLower(mealName), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
 	keywords := []string{"beef", "steak", "burger", "pork", "ham"}
-	for _, kw := range keywords {
-		if strings.Contains(lower, kw) {
+	for _, word := range words {
+		if strings.HasSuffix(strings.TrimSuffix(word, "s"), "burger") {
 			return true
 		}
+		for _, kw := range keywords {
+			if strings.HasPrefix(word, kw) {
+				return true
+			}
+		}
 	}
 	return false
 }
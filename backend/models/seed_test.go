@@ -0,0 +1,179 @@
+package models
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"mealplanner/db"
+	"mealplanner/migrations"
+)
+
+// newSeedDB returns a migrated SQLite database in a temporary directory.
+func newSeedDB(t *testing.T) *sql.DB {
+	t.Helper()
+	cfg := db.DefaultConfig()
+	cfg.Driver = db.DriverSQLite
+	cfg.Path = filepath.Join(t.TempDir(), "seed.db")
+	conn, err := db.ConnectDB(cfg)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := migrations.Up(conn); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return conn
+}
+
+// writeSeedCSV writes a seed CSV with the standard header and returns its path.
+func writeSeedCSV(t *testing.T, rows string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "meals.csv")
+	content := "Meal Name,Ingredient,Relative effort,last_planned\n" + rows
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+	return path
+}
+
+// actions returns the action of each row of a seed report.
+func actions(report *SeedReport) []string {
+	var out []string
+	for _, row := range report.Rows {
+		out = append(out, row.Action)
+	}
+	return out
+}
+
+func TestSeedDB_Upserts(t *testing.T) {
+	conn := newSeedDB(t)
+	path := writeSeedCSV(t,
+		"Beef Stew,2 pounds beef chuck,4,2024-11-03 00:00:00.000000\n"+
+			"Beef Stew,3 carrots,4,2024-11-03 00:00:00.000000\n"+
+			"Toast,2 slices bread,1,\n"+
+			"Broken,1 egg,lots,\n"+
+			",1 egg,1,\n"+
+			"Short row\n")
+
+	report, err := SeedDB(conn, path, false)
+	if err != nil {
+		t.Fatalf("SeedDB: %v", err)
+	}
+	want := []string{SeedInserted, SeedInserted, SeedInserted, SeedInvalid, SeedInvalid, SeedInvalid}
+	if got := actions(report); !reflect.DeepEqual(got, want) {
+		t.Fatalf("first run actions = %v, want %v", got, want)
+	}
+	if report.Inserted != 3 || report.Invalid != 3 || report.Rows[3].Line != 5 || report.Rows[3].Detail != `invalid relative effort "lots"` {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	meals, err := GetAllMeals(conn)
+	if err != nil {
+		t.Fatalf("GetAllMeals: %v", err)
+	}
+	if len(meals) != 2 {
+		t.Fatalf("expected 2 meals, got %d", len(meals))
+	}
+
+	// Running the same file again changes nothing.
+	report, err = SeedDB(conn, path, false)
+	if err != nil {
+		t.Fatalf("SeedDB: %v", err)
+	}
+	if report.Inserted != 0 || report.Updated != 0 || report.Skipped != 3 {
+		t.Errorf("expected a second run to skip every valid row, got %+v", report)
+	}
+	meals, _ = GetAllMeals(conn)
+	if len(meals) != 2 || len(meals[0].Ingredients)+len(meals[1].Ingredients) != 3 {
+		t.Errorf("expected no duplicates after seeding twice, got %+v", meals)
+	}
+
+	// A changed file updates amounts and effort and adds new ingredients.
+	changed := writeSeedCSV(t,
+		"beef stew,3 pounds beef chuck,5,\n"+
+			"Beef Stew,3 carrots,5,\n"+
+			"Beef Stew,1 onion,5,\n")
+	report, err = SeedDB(conn, changed, true)
+	if err != nil {
+		t.Fatalf("SeedDB dry run: %v", err)
+	}
+	want = []string{SeedUpdated, SeedSkipped, SeedInserted}
+	if got := actions(report); !reflect.DeepEqual(got, want) || !report.DryRun {
+		t.Fatalf("dry run actions = %v, want %v", got, want)
+	}
+	if report.Rows[0].Detail != "relative effort 4 -> 5, beef chuck 2 lb -> 3 lb" {
+		t.Errorf("unexpected detail %q", report.Rows[0].Detail)
+	}
+	meals, _ = GetAllMeals(conn)
+	for _, m := range meals {
+		if m.MealName == "Beef Stew" && (m.RelativeEffort != 4 || len(m.Ingredients) != 2) {
+			t.Errorf("a dry run must not change anything, got %+v", m)
+		}
+	}
+
+	if _, err := SeedDB(conn, changed, false); err != nil {
+		t.Fatalf("SeedDB: %v", err)
+	}
+	meals, _ = GetAllMeals(conn)
+	for _, m := range meals {
+		if m.MealName != "Beef Stew" {
+			continue
+		}
+		if m.RelativeEffort != 5 || len(m.Ingredients) != 3 {
+			t.Errorf("expected the stew updated, got %+v", m)
+		}
+		for _, ing := range m.Ingredients {
+			if ing.Name == "beef chuck" && ing.Quantity != 3 {
+				t.Errorf("expected 3 lb beef chuck, got %+v", ing)
+			}
+		}
+		if m.LastPlanned.IsZero() {
+			t.Errorf("expected last planned kept from the first seed")
+		}
+	}
+}
+
+func TestParseSeedRecord(t *testing.T) {
+	rec, err := ParseSeedRecord([]string{" Soup ", "2 cups stock", "2", ""})
+	if err != nil {
+		t.Fatalf("ParseSeedRecord: %v", err)
+	}
+	if rec.MealName != "Soup" || rec.RelativeEffort != 2 || !rec.LastPlanned.IsZero() || rec.Ingredient.Name != "stock" {
+		t.Errorf("unexpected record: %+v", rec)
+	}
+	rec, err = ParseSeedRecord([]string{"Soup", "2 cups stock", "2", "2025-02-02 00:00:000000001"})
+	if err != nil || !rec.LastPlanned.Equal(time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected a mangled time to fall back to its date, got %v, %v", rec.LastPlanned, err)
+	}
+	for _, bad := range [][]string{
+		{"Soup", "2 cups stock", "2"},
+		{"Soup", "", "2", ""},
+		{"Soup", "2 cups stock", "-1", ""},
+		{"Soup", "2 cups stock", "2", "yesterday"},
+	} {
+		if _, err := ParseSeedRecord(bad); err == nil {
+			t.Errorf("ParseSeedRecord(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestIsRedMeat(t *testing.T) {
+	for name, want := range map[string]bool{
+		"Beef Stew":             true,
+		"Hamburgers":            true,
+		"Bacon Cheeseburger":    true,
+		"Grilled Steaks":        true,
+		"Ham and Pea Soup":      true,
+		"Graham Cracker Crust":  false,
+		"Chicken Shawarma":      false,
+		"Shampoo-Free Porridge": false,
+	} {
+		if got := IsRedMeat(name); got != want {
+			t.Errorf("IsRedMeat(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -2,32 +2,121 @@ package models
 
 import (
 	"sort"
+
+	"mealplanner/units"
 )
 
+// shoppingLine accumulates the quantities of one ingredient that can be added together.
+type shoppingLine struct {
+	ingredient Ingredient
+	total      float64  // in the unit of ingredient
+	used       []string // every unit that contributed to the total
+}
+
 // GenerateShoppingListFromMeals aggregates the ingredients needed for the given meals.
-// It returns a sorted slice of unique ingredients with aggregated quantities.
-func GenerateShoppingListFromMeals(meals []*Meal) []Ingredient {
-	aggregated := make(map[string]Ingredient)
+// Quantities of the same ingredient are added together when their units are compatible
+// (e.g. "1 cup" and "2 tbsp" of butter become "1.125 cup"); incompatible units such as
+// a volume and a weight stay on separate lines. Meals listed in servings, by meal ID, are
+// scaled to that many servings first. It returns the lines sorted by name and unit.
+func GenerateShoppingListFromMeals(meals []*Meal, servings map[int]int) []Ingredient {
+	scaled := make([]*Meal, 0, len(meals))
+	for _, meal := range meals {
+		if n, ok := servings[meal.ID]; ok {
+			meal = ScaleMeal(meal, n)
+		}
+		scaled = append(scaled, meal)
+	}
+	return aggregateIngredients(scaled)
+}
+
+// PlannedMeal is a meal as planned in one slot of a plan, for the shopping list.
+type PlannedMeal struct {
+	Meal *Meal
+	Slot string
+	// Servings scales the meal for this slot; 0 keeps the meal's own servings.
+	Servings int
+}
+
+// GenerateShoppingListFromPlan aggregates the ingredients needed for every planned meal,
+// the same way as GenerateShoppingListFromMeals. A meal planned in two slots is bought for
+// twice, each time scaled to its slot's servings.
+func GenerateShoppingListFromPlan(planned []PlannedMeal) []Ingredient {
+	meals := make([]*Meal, 0, len(planned))
+	for _, p := range planned {
+		meal := p.Meal
+		if p.Servings > 0 {
+			meal = ScaleMeal(meal, p.Servings)
+		}
+		meals = append(meals, meal)
+	}
+	return aggregateIngredients(meals)
+}
+
+// AssignSlots sets the slots each shopping list item is needed for, in slot order, from
+// the planned meals whose ingredients it came from.
+func AssignSlots(items []ShoppingItem, planned []PlannedMeal) {
+	byName := make(map[string]map[string]bool)
+	for _, p := range planned {
+		for _, ing := range p.Meal.Ingredients {
+			if byName[ing.Name] == nil {
+				byName[ing.Name] = make(map[string]bool)
+			}
+			byName[ing.Name][NormalizeSlot(p.Slot)] = true
+		}
+	}
+	for i := range items {
+		items[i].Slots = []string{}
+		for _, slot := range Slots {
+			if byName[items[i].Name][slot] {
+				items[i].Slots = append(items[i].Slots, slot)
+			}
+		}
+	}
+}
+
+// aggregateIngredients adds up the ingredients of already scaled meals.
+func aggregateIngredients(meals []*Meal) []Ingredient {
+	aggregated := make(map[string]*shoppingLine)
+	var keys []string
 	for _, meal := range meals {
 		for _, ing := range meal.Ingredients {
-			// Aggregate ingredients by summing their quantity.
-			if existing, ok := aggregated[ing.Name]; ok {
-				existing.Quantity += ing.Quantity
-				aggregated[ing.Name] = existing
-			} else {
-				aggregated[ing.Name] = ing
+			ing.Unit = units.Canonical(ing.Unit)
+			key := ing.Name + "|" + unitGroup(ing.Unit)
+			line, ok := aggregated[key]
+			if !ok {
+				aggregated[key] = &shoppingLine{ingredient: ing, total: ing.Quantity, used: []string{ing.Unit}}
+				keys = append(keys, key)
+				continue
 			}
+			qty, _ := units.Convert(ing.Quantity, ing.Unit, line.ingredient.Unit)
+			line.total += qty
+			line.used = append(line.used, ing.Unit)
 		}
 	}
 
-	ingredients := make([]Ingredient, 0, len(aggregated))
-	for _, ing := range aggregated {
+	ingredients := make([]Ingredient, 0, len(keys))
+	for _, key := range keys {
+		line := aggregated[key]
+		ing := line.ingredient
+		ing.Quantity, ing.Unit = units.Display(line.total, line.used)
 		ingredients = append(ingredients, ing)
 	}
 
-	// Sort the slice by ingredient name.
+	// Sort the slice by ingredient name, then unit.
 	sort.Slice(ingredients, func(i, j int) bool {
-		return ingredients[i].Name < ingredients[j].Name
+		if ingredients[i].Name != ingredients[j].Name {
+			return ingredients[i].Name < ingredients[j].Name
+		}
+		return ingredients[i].Unit < ingredients[j].Unit
 	})
 	return ingredients
 }
+
+// unitGroup returns the key under which quantities in the unit can be added together:
+// the dimension for known units, or the unit itself for anything else.
+func unitGroup(unit string) string {
+	if u, ok := units.Lookup(unit); ok {
+		return string(u.Dimension)
+	}
+	return "unit:" + unit
+}
@@ -67,7 +67,57 @@ func TestGenerateShoppingListFromMeals(t *testing.T) {
 		{Name: "Milk", Quantity: 2, Unit: "gallon"},
 	}
 
-	actual := GenerateShoppingListFromMeals(meals)
+	actual := GenerateShoppingListFromMeals(meals, nil)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected shopping list %v, got %v", expected, actual)
+	}
+}
+
+func TestGenerateShoppingListFromMeals_ConvertsUnits(t *testing.T) {
+	meals := []*Meal{
+		{ID: 1, Ingredients: []Ingredient{
+			{Name: "butter", Quantity: 1, Unit: "cup"},
+			{Name: "ground beef", Quantity: 8, Unit: "ounces"},
+			{Name: "flour", Quantity: 2, Unit: "cups"},
+		}},
+		{ID: 2, Ingredients: []Ingredient{
+			{Name: "butter", Quantity: 2, Unit: "tbsp"},
+			{Name: "ground beef", Quantity: 1, Unit: "lbs"},
+			{Name: "flour", Quantity: 100, Unit: "g"},
+		}},
+	}
+
+	expected := []Ingredient{
+		{Name: "butter", Quantity: 1.125, Unit: "cup"},
+		{Name: "flour", Quantity: 2, Unit: "cup"},
+		{Name: "flour", Quantity: 100, Unit: "g"},
+		{Name: "ground beef", Quantity: 1.5, Unit: "lb"},
+	}
+
+	actual := GenerateShoppingListFromMeals(meals, nil)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected shopping list %v, got %v", expected, actual)
+	}
+}
+
+func TestGenerateShoppingListFromMeals_ScalesServings(t *testing.T) {
+	meals := []*Meal{
+		{ID: 1, Servings: 4, Ingredients: []Ingredient{
+			{Name: "ground beef", Quantity: 1, Unit: "lb"},
+			{Name: "onion", Quantity: 1},
+		}},
+		{ID: 2, Ingredients: []Ingredient{
+			{Name: "onion", Quantity: 2},
+		}},
+	}
+
+	expected := []Ingredient{
+		{Name: "ground beef", Quantity: 2, Unit: "lb"},
+		{Name: "onion", Quantity: 4},
+	}
+
+	// Meal 2 doesn't know its servings, so it is left as it is.
+	actual := GenerateShoppingListFromMeals(meals, map[int]int{1: 8, 2: 8})
 	if !reflect.DeepEqual(actual, expected) {
 		t.Errorf("expected shopping list %v, got %v", expected, actual)
 	}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Meal slots of a plan day, in the order they're eaten.
+const (
+	SlotBreakfast = "breakfast"
+	SlotLunch     = "lunch"
+	SlotDinner    = "dinner"
+)
+
+// Slots lists the meal slots of a day in order.
+var Slots = []string{SlotBreakfast, SlotLunch, SlotDinner}
+
+// slotIndex returns the position of slot in Slots, or -1.
+func slotIndex(slot string) int {
+	for i, s := range Slots {
+		if s == slot {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsSlot reports whether slot is one of the meal slots.
+func IsSlot(slot string) bool {
+	return slotIndex(slot) >= 0
+}
+
+// NormalizeSlot lowercases a slot name and defaults an empty one to dinner.
+func NormalizeSlot(slot string) string {
+	slot = strings.ToLower(strings.TrimSpace(slot))
+	if slot == "" {
+		return SlotDinner
+	}
+	return slot
+}
+
+// NormalizeSlots normalizes a meal's slots, dropping duplicates and sorting them in the
+// order of Slots. An empty list means dinner only. It returns an error for an unknown slot.
+func NormalizeSlots(slots []string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, slot := range slots {
+		slot = NormalizeSlot(slot)
+		if !IsSlot(slot) {
+			return nil, fmt.Errorf("unknown slot %q, expected one of %s", slot, strings.Join(Slots, ", "))
+		}
+		seen[slot] = true
+	}
+	if len(seen) == 0 {
+		return []string{SlotDinner}, nil
+	}
+	normalized := make([]string, 0, len(seen))
+	for _, slot := range Slots {
+		if seen[slot] {
+			normalized = append(normalized, slot)
+		}
+	}
+	return normalized, nil
+}
+
+// SuitableFor reports whether the meal can be planned in a slot. A meal without slots
+// is a dinner.
+func (m *Meal) SuitableFor(slot string) bool {
+	if len(m.Slots) == 0 {
+		return slot == SlotDinner
+	}
+	for _, s := range m.Slots {
+		if s == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanKey returns the key of a day's slot in a plan map. The day is a weekday or a date in
+// DateLayout. Dinner is keyed by the day alone, so plans with only dinners keep their day
+// keys; other slots are "Monday/breakfast" or "2024-03-04/breakfast".
+func PlanKey(day, slot string) string {
+	slot = NormalizeSlot(slot)
+	if slot == SlotDinner {
+		return day
+	}
+	return day + "/" + slot
+}
+
+// SplitPlanKey returns the day and slot of a plan key.
+func SplitPlanKey(key string) (day, slot string) {
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return key, SlotDinner
+}
+
+// IsPlanKey reports whether key names a plan day (a weekday or a date) and meal slot.
+func IsPlanKey(key string) bool {
+	day, slot := SplitPlanKey(key)
+	return IsPlanDay(day) && IsSlot(slot) && PlanKey(day, slot) == key
+}
+
+// planKeyLess orders plan keys by day, then by slot.
+func planKeyLess(a, b string) bool {
+	dayA, slotA := SplitPlanKey(a)
+	dayB, slotB := SplitPlanKey(b)
+	if dayA != dayB {
+		return dayLess(dayA, dayB)
+	}
+	return slotIndex(slotA) < slotIndex(slotB)
+}
+
+// PlanKeys returns every key of a plan map in calendar order: by day, then slot.
+func PlanKeys(plan map[string]*Meal) []string {
+	keys := make([]string, 0, len(plan))
+	for key := range plan {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return planKeyLess(keys[i], keys[j]) })
+	return keys
+}
+
+// formatSlots and parseSlots store a meal's slots in the meals.slots column.
+func formatSlots(slots []string) string {
+	normalized, err := NormalizeSlots(slots)
+	if err != nil {
+		return SlotDinner
+	}
+	return strings.Join(normalized, ",")
+}
+
+func parseSlots(raw string) []string {
+	slots, err := NormalizeSlots(strings.Split(raw, ","))
+	if err != nil {
+		return []string{SlotDinner}
+	}
+	return slots
+}
+
+// SetMealSlots replaces the slots a meal can be planned in and returns them normalized.
+// It returns ErrMealNotFound for an unknown meal.
+func SetMealSlots(db *sql.DB, mealID int, slots []string) ([]string, error) {
+	normalized, err := NormalizeSlots(slots)
+	if err != nil {
+		return nil, err
+	}
+	res, err := db.Exec("UPDATE meals SET slots = $1 WHERE id = $2", strings.Join(normalized, ","), mealID)
+	if err != nil {
+		log.Printf("SetMealSlots: error updating mealID=%d: %v", mealID, err)
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrMealNotFound
+	}
+	return normalized, nil
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanKey(t *testing.T) {
+	for _, tc := range []struct {
+		day, slot, key string
+	}{
+		{"Monday", "", "Monday"},
+		{"Monday", SlotDinner, "Monday"},
+		{"Monday", "Breakfast", "Monday/breakfast"},
+		{"Sunday", SlotLunch, "Sunday/lunch"},
+	} {
+		key := PlanKey(tc.day, tc.slot)
+		if key != tc.key {
+			t.Errorf("PlanKey(%q, %q) = %q, want %q", tc.day, tc.slot, key, tc.key)
+		}
+		if day, slot := SplitPlanKey(key); day != tc.day || slot != NormalizeSlot(tc.slot) {
+			t.Errorf("SplitPlanKey(%q) = %q, %q", key, day, slot)
+		}
+		if !IsPlanKey(key) {
+			t.Errorf("expected %q to be a plan key", key)
+		}
+	}
+	for _, key := range []string{"", "Someday", "Monday/brunch", "Monday/dinner", "Monday/Lunch"} {
+		if IsPlanKey(key) {
+			t.Errorf("expected %q not to be a plan key", key)
+		}
+	}
+
+	plan := map[string]*Meal{"Tuesday": {}, "Monday": {}, "Tuesday/breakfast": {}, "Monday/lunch": {}}
+	if keys := PlanKeys(plan); !reflect.DeepEqual(keys, []string{"Monday/lunch", "Monday", "Tuesday/breakfast", "Tuesday"}) {
+		t.Errorf("unexpected key order %v", keys)
+	}
+}
+
+func TestNormalizeSlots(t *testing.T) {
+	slots, err := NormalizeSlots([]string{" Dinner", "breakfast", "dinner"})
+	if err != nil || !reflect.DeepEqual(slots, []string{SlotBreakfast, SlotDinner}) {
+		t.Errorf("unexpected slots %v, %v", slots, err)
+	}
+	if slots, _ := NormalizeSlots(nil); !reflect.DeepEqual(slots, []string{SlotDinner}) {
+		t.Errorf("expected no slots to mean dinner, got %v", slots)
+	}
+	if _, err := NormalizeSlots([]string{"brunch"}); err == nil {
+		t.Errorf("expected an unknown slot to be rejected")
+	}
+	if (&Meal{}).SuitableFor(SlotLunch) || !(&Meal{}).SuitableFor(SlotDinner) {
+		t.Errorf("expected a meal without slots to be a dinner only")
+	}
+}
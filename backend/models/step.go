@@ -14,6 +14,9 @@ type Step struct {
 	Instruction string `json:"instruction"`
 }
 
+// ErrStepNotFound is returned when a step ID doesn't exist for the meal.
+var ErrStepNotFound = errors.New("step not found")
+
 // GetStepsForMeal retrieves all steps for a given meal ID, ordered by step number
 func GetStepsForMeal(db *sql.DB, mealID int) ([]Step, error) {
 	rows, err := db.Query(`
@@ -56,7 +59,7 @@ func AddStepToMeal(db *sql.DB, step Step) (*Step, error) {
 		return nil, err
 	}
 	if !mealExists {
-		return nil, errors.New("meal does not exist")
+		return nil, ErrMealNotFound
 	}
 
 	// If no step number is provided, find the next available one
@@ -100,7 +103,7 @@ func AddMultipleStepsToMeal(db *sql.DB, mealID int, instructions []string) ([]St
 		return nil, err
 	}
 	if !mealExists {
-		return nil, errors.New("meal does not exist")
+		return nil, ErrMealNotFound
 	}
 
 	// Start a transaction
@@ -185,7 +188,7 @@ func UpdateStep(db *sql.DB, step Step) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("step not found")
+		return ErrStepNotFound
 	}
 
 	return nil
@@ -205,7 +208,7 @@ func DeleteStep(db *sql.DB, stepID int, mealID int) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("step not found")
+		return ErrStepNotFound
 	}
 
 	return nil
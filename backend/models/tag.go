@@ -0,0 +1,279 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Tag labels meals with a protein, cuisine, diet or anything else the household plans
+// by ("chicken", "mexican", "vegetarian", "kid-friendly"). MealCount is how many meals
+// carry the tag.
+type Tag struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	MealCount int    `json:"mealCount"`
+}
+
+var (
+	// ErrTagNotFound is returned when a tag ID doesn't exist.
+	ErrTagNotFound = errors.New("tag not found")
+	// ErrTagExists is returned when a tag is created or renamed to a name already in use.
+	ErrTagExists = errors.New("tag already exists")
+)
+
+// NormalizeTagName lower-cases and trims a tag name and joins its words with hyphens,
+// so "Gluten Free" and "gluten-free" are the same tag.
+func NormalizeTagName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), "-")
+}
+
+// NormalizeTagNames normalizes a list of tag names, dropping duplicates, and returns
+// them sorted. It fails when a name is empty.
+func NormalizeTagNames(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	out := []string{}
+	for _, name := range names {
+		tag := NormalizeTagName(name)
+		if tag == "" {
+			return nil, errors.New("tag name is required")
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// HasTag reports whether the meal carries the tag.
+func (m *Meal) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ListTags returns every tag ordered by name, with the number of meals carrying it.
+func ListTags(db *sql.DB) ([]Tag, error) {
+	rows, err := db.Query(`
+		SELECT t.id, t.name, COUNT(mt.meal_id)
+		FROM tags t
+		LEFT JOIN meal_tags mt ON mt.tag_id = t.id
+		GROUP BY t.id, t.name
+		ORDER BY t.name
+	`)
+	if err != nil {
+		log.Printf("ListTags: error executing query: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.MealCount); err != nil {
+			log.Printf("ListTags: error scanning row: %v", err)
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// CreateTag adds a tag, or returns ErrTagExists when its normalized name is taken.
+func CreateTag(db *sql.DB, name string) (*Tag, error) {
+	tag := Tag{Name: NormalizeTagName(name)}
+	if tag.Name == "" {
+		return nil, errors.New("tag name is required")
+	}
+	if _, err := findTagID(db, tag.Name); err == nil {
+		return nil, ErrTagExists
+	} else if !errors.Is(err, ErrTagNotFound) {
+		return nil, err
+	}
+	if err := db.QueryRow("INSERT INTO tags (name) VALUES ($1) RETURNING id", tag.Name).Scan(&tag.ID); err != nil {
+		log.Printf("CreateTag: error inserting %q: %v", tag.Name, err)
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// RenameTag renames a tag on every meal that carries it. It returns ErrTagNotFound for
+// an unknown tag and ErrTagExists when another tag already has the name.
+func RenameTag(db *sql.DB, id int, name string) (*Tag, error) {
+	tag := Tag{ID: id, Name: NormalizeTagName(name)}
+	if tag.Name == "" {
+		return nil, errors.New("tag name is required")
+	}
+	if other, err := findTagID(db, tag.Name); err == nil && other != id {
+		return nil, ErrTagExists
+	} else if err != nil && !errors.Is(err, ErrTagNotFound) {
+		return nil, err
+	}
+	res, err := db.Exec("UPDATE tags SET name = $1 WHERE id = $2", tag.Name, id)
+	if err != nil {
+		log.Printf("RenameTag: error updating tagID=%d: %v", id, err)
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrTagNotFound
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM meal_tags WHERE tag_id = $1", id).Scan(&tag.MealCount); err != nil {
+		log.Printf("RenameTag: error counting meals for tagID=%d: %v", id, err)
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// DeleteTag removes a tag from every meal and deletes it.
+func DeleteTag(db *sql.DB, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM meal_tags WHERE tag_id = $1", id); err != nil {
+		log.Printf("DeleteTag: error untagging meals for tagID=%d: %v", id, err)
+		return err
+	}
+	res, err := tx.Exec("DELETE FROM tags WHERE id = $1", id)
+	if err != nil {
+		log.Printf("DeleteTag: error deleting tagID=%d: %v", id, err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTagNotFound
+	}
+	return tx.Commit()
+}
+
+// SetMealTags replaces a meal's tags, creating tags that don't exist yet, and returns
+// the meal's tags normalized and sorted. It returns ErrMealNotFound for an unknown meal.
+func SetMealTags(db *sql.DB, mealID int, names []string) ([]string, error) {
+	tags, err := NormalizeTagNames(names)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM meals WHERE id = $1", mealID).Scan(&exists); err != nil {
+		log.Printf("SetMealTags: error checking mealID=%d: %v", mealID, err)
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, ErrMealNotFound
+	}
+	if _, err := tx.Exec("DELETE FROM meal_tags WHERE meal_id = $1", mealID); err != nil {
+		log.Printf("SetMealTags: error clearing tags for mealID=%d: %v", mealID, err)
+		return nil, err
+	}
+	if err := insertMealTags(tx, mealID, tags); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// insertMealTags tags a meal with already normalized names, creating missing tags.
+func insertMealTags(tx *sql.Tx, mealID int, tags []string) error {
+	for _, name := range tags {
+		var tagID int
+		err := tx.QueryRow("SELECT id FROM tags WHERE name = $1", name).Scan(&tagID)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = tx.QueryRow("INSERT INTO tags (name) VALUES ($1) RETURNING id", name).Scan(&tagID)
+		}
+		if err != nil {
+			log.Printf("insertMealTags: error finding or creating tag %q: %v", name, err)
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO meal_tags (meal_id, tag_id) VALUES ($1, $2)", mealID, tagID); err != nil {
+			log.Printf("insertMealTags: error tagging mealID=%d with %q: %v", mealID, name, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// findTagID returns the ID of the tag with the given normalized name, or ErrTagNotFound.
+func findTagID(db *sql.DB, name string) (int, error) {
+	var id int
+	err := db.QueryRow("SELECT id FROM tags WHERE name = $1", name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrTagNotFound
+	}
+	if err != nil {
+		log.Printf("findTagID: error looking up %q: %v", name, err)
+	}
+	return id, err
+}
+
+// MealTagsQuery loads the tag names of every meal.
+const MealTagsQuery = `
+	SELECT mt.meal_id, t.name
+	FROM meal_tags mt
+	JOIN tags t ON t.id = mt.tag_id
+`
+
+// loadMealTags attaches the tags of the given meals, sorted by name. Callers that loaded
+// every meal pass all, which reads the tags in one pass instead of listing the IDs.
+func loadMealTags(db *sql.DB, meals []*Meal, all bool) error {
+	if len(meals) == 0 {
+		return nil
+	}
+	byID := make(map[int]*Meal, len(meals))
+	var args []interface{}
+	var placeholders []string
+	for _, m := range meals {
+		m.Tags = []string{}
+		byID[m.ID] = m
+		args = append(args, m.ID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	query := MealTagsQuery + " ORDER BY t.name"
+	if all {
+		args = nil
+	} else {
+		query = MealTagsQuery + " WHERE mt.meal_id IN (" + strings.Join(placeholders, ", ") + ") ORDER BY t.name"
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		log.Printf("loadMealTags: error executing query: %v", err)
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var mealID int
+		var name string
+		if err := rows.Scan(&mealID, &name); err != nil {
+			log.Printf("loadMealTags: error scanning row: %v", err)
+			return err
+		}
+		if m, ok := byID[mealID]; ok {
+			m.Tags = append(m.Tags, name)
+		}
+	}
+	return rows.Err()
+}
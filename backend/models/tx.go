@@ -0,0 +1,74 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// execer is a database or transaction to run statements on.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// rowQuerier is a database or transaction to read a single row from.
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Tx writes meals and plans in one transaction, so a change made of several writes takes
+// effect all at once or not at all. Its methods behave like the functions of the same
+// name. Commit or Rollback ends it.
+type Tx struct {
+	tx *sql.Tx
+}
+
+// BeginTx starts a transaction on db.
+func BeginTx(db *sql.DB) (*Tx, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("BeginTx: error starting transaction: %v", err)
+		return nil, err
+	}
+	return &Tx{tx: tx}, nil
+}
+
+// Commit makes the transaction's writes permanent.
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback discards the transaction's writes.
+func (t *Tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// CreateMeal inserts a new meal with its ingredients, steps and tags.
+func (t *Tx) CreateMeal(meal Meal) (*Meal, error) {
+	if err := normalizeMeal(&meal); err != nil {
+		return nil, err
+	}
+	return createMeal(t.tx, meal)
+}
+
+// ReplaceMeal overwrites a stored meal in place; see ReplaceMeal.
+func (t *Tx) ReplaceMeal(mealID int, meal Meal) (*Meal, error) {
+	if err := normalizeMeal(&meal); err != nil {
+		return nil, err
+	}
+	return replaceMeal(t.tx, mealID, meal)
+}
+
+// SaveMealPlan stores a plan under the saved rules; see SaveMealPlan.
+func (t *Tx) SaveMealPlan(weekStart time.Time, plan map[string]*Meal, servings map[string]int) (*MealPlan, error) {
+	rules, err := planRules(t.tx)
+	if err != nil {
+		return nil, err
+	}
+	return saveMealPlan(t.tx, rules, weekStart, plan, servings)
+}
+
+// SetLastPlanned records when a meal was last planned; see SetLastPlanned.
+func (t *Tx) SetLastPlanned(mealID int, lastPlanned time.Time) error {
+	return setLastPlanned(t.tx, mealID, lastPlanned)
+}
@@ -0,0 +1,222 @@
+// Package recipe extracts the schema.org Recipe that most recipe sites embed in their
+// pages as JSON-LD.
+package recipe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recipe is the part of a schema.org Recipe the planner uses, with the instructions
+// flattened into one string per step.
+type Recipe struct {
+	Name         string   `json:"name"`
+	URL          string   `json:"url,omitempty"`
+	Ingredients  []string `json:"ingredients"`
+	Instructions []string `json:"instructions"`
+	// TotalTime is the recipe's totalTime, or prepTime plus cookTime when it has none.
+	// Zero when the page gives neither.
+	TotalTime time.Duration `json:"-"`
+	Yield     string        `json:"yield,omitempty"`
+}
+
+// ErrNoRecipe is returned when a page has no JSON-LD Recipe.
+var ErrNoRecipe = errors.New("no schema.org Recipe found in the page")
+
+var (
+	scriptRe = regexp.MustCompile(`(?is)<script[^>]*type\s*=\s*["']?application/ld\+json["']?[^>]*>(.*?)</script>`)
+	tagRe    = regexp.MustCompile(`<[^>]*>`)
+	spaceRe  = regexp.MustCompile(`\s+`)
+)
+
+// Extract finds the first schema.org Recipe in the JSON-LD blocks of an HTML page.
+// Blocks that aren't valid JSON are skipped, since a broken block elsewhere on the page
+// shouldn't stop the import.
+func Extract(page []byte) (*Recipe, error) {
+	for _, match := range scriptRe.FindAllSubmatch(page, -1) {
+		var doc interface{}
+		if err := json.Unmarshal(match[1], &doc); err != nil {
+			continue
+		}
+		if node := findRecipe(doc); node != nil {
+			return fromNode(node), nil
+		}
+	}
+	return nil, ErrNoRecipe
+}
+
+// findRecipe searches a JSON-LD document for a node typed Recipe, looking through
+// top-level arrays, @graph and mainEntity.
+func findRecipe(doc interface{}) map[string]interface{} {
+	switch v := doc.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if node := findRecipe(item); node != nil {
+				return node
+			}
+		}
+	case map[string]interface{}:
+		if hasType(v["@type"], "Recipe") {
+			return v
+		}
+		for _, key := range []string{"@graph", "mainEntity"} {
+			if node := findRecipe(v[key]); node != nil {
+				return node
+			}
+		}
+	}
+	return nil
+}
+
+// hasType reports whether an @type value, a string or a list of strings, includes want.
+func hasType(t interface{}, want string) bool {
+	switch v := t.(type) {
+	case string:
+		return v == want
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fromNode reads the fields the planner uses from a Recipe node.
+func fromNode(node map[string]interface{}) *Recipe {
+	r := &Recipe{
+		Name:         clean(str(node["name"])),
+		URL:          str(node["url"]),
+		Ingredients:  []string{},
+		Instructions: []string{},
+		Yield:        yield(node["recipeYield"]),
+	}
+
+	ingredients := node["recipeIngredient"]
+	if ingredients == nil {
+		ingredients = node["ingredients"] // the older schema.org name
+	}
+	for _, line := range strs(ingredients) {
+		if line = clean(line); line != "" {
+			r.Ingredients = append(r.Ingredients, line)
+		}
+	}
+
+	r.Instructions = instructions(node["recipeInstructions"], r.Instructions)
+
+	if d, err := ParseDuration(str(node["totalTime"])); err == nil && d > 0 {
+		r.TotalTime = d
+	} else {
+		prep, _ := ParseDuration(str(node["prepTime"]))
+		cook, _ := ParseDuration(str(node["cookTime"]))
+		r.TotalTime = prep + cook
+	}
+	return r
+}
+
+// instructions appends the steps in a recipeInstructions value. It may be a block of
+// text, a list of strings, HowToStep objects, or HowToSection objects holding steps.
+func instructions(v interface{}, steps []string) []string {
+	switch val := v.(type) {
+	case string:
+		for _, line := range strings.Split(tagRe.ReplaceAllString(val, "\n"), "\n") {
+			if line = clean(line); line != "" {
+				steps = append(steps, line)
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			steps = instructions(item, steps)
+		}
+	case map[string]interface{}:
+		if list, ok := val["itemListElement"]; ok {
+			return instructions(list, steps)
+		}
+		text := str(val["text"])
+		if text == "" {
+			text = str(val["name"])
+		}
+		if text = clean(text); text != "" {
+			steps = append(steps, text)
+		}
+	}
+	return steps
+}
+
+// yield returns a recipeYield as text. Sites give a number, a string, or a list such as
+// ["4", "4 servings"], in which case the most descriptive entry is used.
+func yield(v interface{}) string {
+	best := ""
+	for _, s := range strs(v) {
+		if s = clean(s); len(s) > len(best) {
+			best = s
+		}
+	}
+	return best
+}
+
+// str returns a JSON string or number as text, and "" for anything else.
+func str(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return ""
+}
+
+// strs returns a JSON value that may be one string or a list of them as a list.
+func strs(v interface{}) []string {
+	if list, ok := v.([]interface{}); ok {
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s := str(item); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	if s := str(v); s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+// clean strips markup and entities that sites leave in JSON-LD text and collapses whitespace.
+func clean(s string) string {
+	s = html.UnescapeString(tagRe.ReplaceAllString(s, ""))
+	return strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
+}
+
+var durationRe = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseDuration parses the ISO 8601 durations schema.org uses for times, such as
+// "PT1H30M" or "P0DT45M". An empty string is a zero duration.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	m := durationRe.FindStringSubmatch(s)
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid ISO 8601 duration %q", s)
+	}
+	var d time.Duration
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		n, _ := strconv.ParseFloat(m[i+1], 64)
+		d += time.Duration(n * float64(unit))
+	}
+	return d, nil
+}
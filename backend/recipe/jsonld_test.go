@@ -0,0 +1,110 @@
+package recipe
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const graphPage = `<!doctype html>
+<html><head>
+<script type="application/ld+json">{not json</script>
+<script type="application/ld+json">
+{
+  "@context": "https://schema.org",
+  "@graph": [
+    {"@type": "WebSite", "name": "Cooking"},
+    {
+      "@type": ["Recipe", "NewsArticle"],
+      "name": "Skillet Chicken &amp; Rice",
+      "url": "https://example.com/skillet-chicken",
+      "recipeYield": ["4", "4 servings"],
+      "prepTime": "PT10M",
+      "cookTime": "PT35M",
+      "recipeIngredient": ["1 1/2 cups long-grain rice", "  4 boneless chicken thighs ", ""],
+      "recipeInstructions": [
+        {"@type": "HowToSection", "name": "Chicken", "itemListElement": [
+          {"@type": "HowToStep", "text": "Season the <b>chicken</b>."},
+          {"@type": "HowToStep", "text": "Brown it in the skillet."}
+        ]},
+        {"@type": "HowToStep", "text": "Add the rice and 3 cups water, cover and cook 20 minutes."}
+      ]
+    }
+  ]
+}
+</script>
+</head><body></body></html>`
+
+func TestExtract_Graph(t *testing.T) {
+	r, err := Extract([]byte(graphPage))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	want := &Recipe{
+		Name:        "Skillet Chicken & Rice",
+		URL:         "https://example.com/skillet-chicken",
+		Ingredients: []string{"1 1/2 cups long-grain rice", "4 boneless chicken thighs"},
+		Instructions: []string{
+			"Season the chicken.",
+			"Brown it in the skillet.",
+			"Add the rice and 3 cups water, cover and cook 20 minutes.",
+		},
+		TotalTime: 45 * time.Minute,
+		Yield:     "4 servings",
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("Extract:\n got %+v\nwant %+v", r, want)
+	}
+}
+
+func TestExtract_TextInstructions(t *testing.T) {
+	page := `<script type='application/ld+json'>[{"@type":"Recipe","name":"Toast","totalTime":"PT5M",
+		"recipeYield":2,"ingredients":"2 slices bread",
+		"recipeInstructions":"Toast the bread.\nButter it.<br>Eat."}]</script>`
+	r, err := Extract([]byte(page))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if r.Yield != "2" || r.TotalTime != 5*time.Minute {
+		t.Errorf("unexpected yield %q or time %v", r.Yield, r.TotalTime)
+	}
+	if !reflect.DeepEqual(r.Ingredients, []string{"2 slices bread"}) {
+		t.Errorf("unexpected ingredients %v", r.Ingredients)
+	}
+	if !reflect.DeepEqual(r.Instructions, []string{"Toast the bread.", "Butter it.", "Eat."}) {
+		t.Errorf("unexpected instructions %v", r.Instructions)
+	}
+}
+
+func TestExtract_NoRecipe(t *testing.T) {
+	page := `<script type="application/ld+json">{"@type":"Article","name":"News"}</script>`
+	if _, err := Extract([]byte(page)); !errors.Is(err, ErrNoRecipe) {
+		t.Errorf("expected ErrNoRecipe, got %v", err)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"PT45M", 45 * time.Minute},
+		{"PT1H30M", 90 * time.Minute},
+		{"P0DT1H", time.Hour},
+		{"P1D", 24 * time.Hour},
+		{"pt20m30s", 20*time.Minute + 30*time.Second},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"45 minutes", "P", "PT", "1H"} {
+		if _, err := ParseDuration(bad); err == nil {
+			t.Errorf("ParseDuration(%q): expected an error", bad)
+		}
+	}
+}
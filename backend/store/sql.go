@@ -0,0 +1,208 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"mealplanner/models"
+)
+
+// SQLStore is a Store backed by a Postgres or SQLite database. The models' queries
+// are written to run on both.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewPostgres returns a Store that reads and writes the given Postgres connection.
+func NewPostgres(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// NewSQLite returns a Store that reads and writes the given SQLite connection. It
+// should be opened with foreign keys on, as db.ConnectDB does, so deletes cascade.
+func NewSQLite(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// DB returns the underlying connection.
+func (s *SQLStore) DB() *sql.DB {
+	return s.db
+}
+
+// Atomically runs fn in a database transaction, committed only when fn succeeds.
+func (s *SQLStore) Atomically(fn func(w Writer) error) error {
+	tx, err := models.BeginTx(s.db)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Ping checks the database connection.
+func (s *SQLStore) Ping() error {
+	if s.db == nil {
+		return errors.New("database not connected")
+	}
+	return s.db.Ping()
+}
+
+func (s *SQLStore) GetAllMeals() ([]*models.Meal, error) {
+	return models.GetAllMeals(s.db)
+}
+
+func (s *SQLStore) GetMealsByIDs(ids []int) ([]*models.Meal, error) {
+	return models.GetMealsByIDs(s.db, ids)
+}
+
+func (s *SQLStore) CreateMeal(meal models.Meal) (*models.Meal, error) {
+	return models.CreateMeal(s.db, meal)
+}
+
+func (s *SQLStore) ReplaceMeal(mealID int, meal models.Meal) (*models.Meal, error) {
+	return models.ReplaceMeal(s.db, mealID, meal)
+}
+
+func (s *SQLStore) DeleteMeal(mealID int) error {
+	return models.DeleteMeal(s.db, mealID)
+}
+
+func (s *SQLStore) UpdateMealIngredient(mealID int, ingredient models.Ingredient) error {
+	return models.UpdateMealIngredient(s.db, mealID, ingredient)
+}
+
+func (s *SQLStore) DeleteMealIngredient(mealID, ingredientID int) error {
+	return models.DeleteMealIngredient(s.db, mealID, ingredientID)
+}
+
+func (s *SQLStore) SetLastPlanned(mealID int, lastPlanned time.Time) error {
+	return models.SetLastPlanned(s.db, mealID, lastPlanned)
+}
+
+func (s *SQLStore) SetMealSlots(mealID int, slots []string) ([]string, error) {
+	return models.SetMealSlots(s.db, mealID, slots)
+}
+
+func (s *SQLStore) GetStepsForMeal(mealID int) ([]models.Step, error) {
+	return models.GetStepsForMeal(s.db, mealID)
+}
+
+func (s *SQLStore) AddStepToMeal(step models.Step) (*models.Step, error) {
+	return models.AddStepToMeal(s.db, step)
+}
+
+func (s *SQLStore) AddMultipleStepsToMeal(mealID int, instructions []string) ([]models.Step, error) {
+	return models.AddMultipleStepsToMeal(s.db, mealID, instructions)
+}
+
+func (s *SQLStore) UpdateStep(step models.Step) error {
+	return models.UpdateStep(s.db, step)
+}
+
+func (s *SQLStore) DeleteStep(stepID, mealID int) error {
+	return models.DeleteStep(s.db, stepID, mealID)
+}
+
+func (s *SQLStore) ReorderSteps(mealID int, stepIDs []int) error {
+	return models.ReorderSteps(s.db, mealID, stepIDs)
+}
+
+func (s *SQLStore) DeleteAllStepsForMeal(mealID int) error {
+	return models.DeleteAllStepsForMeal(s.db, mealID)
+}
+
+func (s *SQLStore) ListTags() ([]models.Tag, error) {
+	return models.ListTags(s.db)
+}
+
+func (s *SQLStore) CreateTag(name string) (*models.Tag, error) {
+	return models.CreateTag(s.db, name)
+}
+
+func (s *SQLStore) RenameTag(id int, name string) (*models.Tag, error) {
+	return models.RenameTag(s.db, id, name)
+}
+
+func (s *SQLStore) DeleteTag(id int) error {
+	return models.DeleteTag(s.db, id)
+}
+
+func (s *SQLStore) SetMealTags(mealID int, tags []string) ([]string, error) {
+	return models.SetMealTags(s.db, mealID, tags)
+}
+
+func (s *SQLStore) GenerateMealPlan(rules models.PlanRules, opts models.SolveOptions) (*models.PlanResult, error) {
+	return models.GenerateWeeklyMealPlan(s.db, rules, opts)
+}
+
+func (s *SQLStore) SwapMealInPlan(rules models.PlanRules, plan map[string]int, day string, limit int) ([]models.SwapCandidate, error) {
+	return models.SwapMealInPlan(s.db, rules, plan, day, limit)
+}
+
+func (s *SQLStore) SaveMealPlan(weekStart time.Time, plan map[string]*models.Meal, servings map[string]int) (*models.MealPlan, error) {
+	return models.SaveMealPlan(s.db, weekStart, plan, servings)
+}
+
+func (s *SQLStore) MarkPlanDayDone(weekStart time.Time, day string, entry models.CookingLogEntry) (*models.CookingLogEntry, error) {
+	return models.MarkPlanDayDone(s.db, weekStart, day, entry)
+}
+
+func (s *SQLStore) GetMealHistory(mealID int) ([]models.CookingLogEntry, error) {
+	return models.GetMealHistory(s.db, mealID)
+}
+
+func (s *SQLStore) GetLatestMealPlan() (*models.MealPlan, error) {
+	return models.GetLatestMealPlan(s.db)
+}
+
+func (s *SQLStore) ListMealPlans(from, to time.Time) ([]*models.MealPlan, error) {
+	return models.ListMealPlans(s.db, from, to)
+}
+
+func (s *SQLStore) GetPlanRules() (models.PlanRules, error) {
+	return models.GetPlanRules(s.db)
+}
+
+func (s *SQLStore) SavePlanRules(rules models.PlanRules) error {
+	return models.SavePlanRules(s.db, rules)
+}
+
+func (s *SQLStore) ListPantryItems() ([]models.PantryItem, error) {
+	return models.ListPantryItems(s.db)
+}
+
+func (s *SQLStore) CreatePantryItem(item models.PantryItem) (*models.PantryItem, error) {
+	return models.CreatePantryItem(s.db, item)
+}
+
+func (s *SQLStore) UpdatePantryItem(item models.PantryItem) (*models.PantryItem, error) {
+	return models.UpdatePantryItem(s.db, item)
+}
+
+func (s *SQLStore) DeletePantryItem(id int) error {
+	return models.DeletePantryItem(s.db, id)
+}
+
+func (s *SQLStore) GetAisleSettings() (models.AisleSettings, error) {
+	return models.GetAisleSettings(s.db)
+}
+
+func (s *SQLStore) SaveAisleOrder(order []string) ([]string, error) {
+	return models.SaveAisleOrder(s.db, order)
+}
+
+func (s *SQLStore) SetAisleOverride(name, aisle string) error {
+	return models.SetAisleOverride(s.db, name, aisle)
+}
+
+func (s *SQLStore) GetCalendarToken() (string, error) {
+	return models.GetCalendarToken(s.db)
+}
+
+func (s *SQLStore) RotateCalendarToken() (string, error) {
+	return models.RotateCalendarToken(s.db)
+}
@@ -0,0 +1,59 @@
+package store_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/lib/pq"
+
+	"mealplanner/db"
+	"mealplanner/migrations"
+	"mealplanner/store"
+	"mealplanner/store/storetest"
+)
+
+// TestPostgresConformance runs the suite against a real database. It needs
+// TEST_DATABASE_URL pointing at a database the test may wipe.
+func TestPostgresConformance(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if err := migrations.Up(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	storetest.Run(t, func(t *testing.T) store.Store {
+		_, err := db.Exec(`TRUNCATE meals, ingredients, recipe_steps, meal_plans, meal_plan_entries,
+			planning_rules, pantry_items, ingredient_aisles, store_layout, tags, meal_tags,
+			calendar_feed RESTART IDENTITY CASCADE`)
+		if err != nil {
+			t.Fatalf("failed to reset database: %v", err)
+		}
+		return store.NewPostgres(db)
+	})
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		cfg := db.DefaultConfig()
+		cfg.Driver = db.DriverSQLite
+		cfg.Path = filepath.Join(t.TempDir(), "mealplanner.db")
+		conn, err := db.ConnectDB(cfg)
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		if err := migrations.Up(conn); err != nil {
+			t.Fatalf("failed to migrate: %v", err)
+		}
+		return store.NewSQLite(conn)
+	})
+}
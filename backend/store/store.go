@@ -0,0 +1,151 @@
+// Package store defines the storage the HTTP handlers work against. The SQL
+// implementation in this package wraps the models functions for Postgres and SQLite;
+// the in-memory one lives in the dummy package. All of them pass the conformance
+// suite in storetest.
+package store
+
+import (
+	"time"
+
+	"mealplanner/models"
+)
+
+// MealStore stores meals and their ingredients.
+type MealStore interface {
+	GetAllMeals() ([]*models.Meal, error)
+	// GetMealsByIDs returns the meals that exist among ids, with ingredients and steps.
+	GetMealsByIDs(ids []int) ([]*models.Meal, error)
+	CreateMeal(meal models.Meal) (*models.Meal, error)
+	// DeleteMeal removes a meal with its ingredients and steps, or returns models.ErrMealNotFound.
+	DeleteMeal(mealID int) error
+	// UpdateMealIngredient replaces an ingredient of a meal, or returns models.ErrIngredientNotFound.
+	UpdateMealIngredient(mealID int, ingredient models.Ingredient) error
+	// DeleteMealIngredient removes an ingredient of a meal, or returns models.ErrIngredientNotFound.
+	DeleteMealIngredient(mealID, ingredientID int) error
+	// SetLastPlanned sets when a meal was last planned; a zero time clears it. It returns
+	// models.ErrMealNotFound for an unknown meal.
+	SetLastPlanned(mealID int, lastPlanned time.Time) error
+	// SetMealSlots replaces the slots a meal can be planned in and returns them in slot
+	// order. It returns models.ErrMealNotFound for an unknown meal.
+	SetMealSlots(mealID int, slots []string) ([]string, error)
+	// ReplaceMeal overwrites a meal in place with meal's fields, ingredients, steps, tags
+	// and slots. The meal keeps its ID, last planned time, plan entries and cooking log.
+	// It returns models.ErrMealNotFound for an unknown meal.
+	ReplaceMeal(mealID int, meal models.Meal) (*models.Meal, error)
+}
+
+// StepStore stores the recipe steps of meals.
+type StepStore interface {
+	GetStepsForMeal(mealID int) ([]models.Step, error)
+	// AddStepToMeal appends a step, numbering it after the last one when it has no number.
+	AddStepToMeal(step models.Step) (*models.Step, error)
+	AddMultipleStepsToMeal(mealID int, instructions []string) ([]models.Step, error)
+	// UpdateStep replaces a step's number and instruction, or returns models.ErrStepNotFound.
+	UpdateStep(step models.Step) error
+	// DeleteStep removes a step of a meal, or returns models.ErrStepNotFound.
+	DeleteStep(stepID, mealID int) error
+	// ReorderSteps renumbers a meal's steps in the order of stepIDs, starting at 1.
+	ReorderSteps(mealID int, stepIDs []int) error
+	DeleteAllStepsForMeal(mealID int) error
+}
+
+// TagStore stores the tags meals can be labelled with. Tag names are normalized with
+// models.NormalizeTagName.
+type TagStore interface {
+	// ListTags returns every tag ordered by name, with how many meals carry it.
+	ListTags() ([]models.Tag, error)
+	// CreateTag adds a tag, or returns models.ErrTagExists.
+	CreateTag(name string) (*models.Tag, error)
+	// RenameTag renames a tag on every meal, or returns models.ErrTagNotFound or
+	// models.ErrTagExists.
+	RenameTag(id int, name string) (*models.Tag, error)
+	// DeleteTag removes a tag from every meal, or returns models.ErrTagNotFound.
+	DeleteTag(id int) error
+	// SetMealTags replaces a meal's tags, creating new tags as needed, and returns them
+	// sorted. It returns models.ErrMealNotFound for an unknown meal.
+	SetMealTags(mealID int, tags []string) ([]string, error)
+}
+
+// PlanStore generates, swaps and keeps meal plans and the rules they follow.
+type PlanStore interface {
+	GenerateMealPlan(rules models.PlanRules, opts models.SolveOptions) (*models.PlanResult, error)
+	SwapMealInPlan(rules models.PlanRules, plan map[string]int, day string, limit int) ([]models.SwapCandidate, error)
+	// SaveMealPlan stores the plan over the dates the saved rules' PlanSpan gives it,
+	// replacing any earlier plan with the same start, and updates last_planned for its
+	// meals. Weekday keys fall in the household's week containing weekStart. servings
+	// optionally sets how many a day cooks for.
+	SaveMealPlan(weekStart time.Time, plan map[string]*models.Meal, servings map[string]int) (*models.MealPlan, error)
+	// GetLatestMealPlan returns the most recent saved plan, or models.ErrNoMealPlan.
+	GetLatestMealPlan() (*models.MealPlan, error)
+	// ListMealPlans returns the saved plans covering any date within [from, to], oldest
+	// first. A zero from or to leaves that side open.
+	ListMealPlans(from, to time.Time) ([]*models.MealPlan, error)
+	// GetPlanRules returns the saved rules, or models.DefaultPlanRules when none are saved.
+	GetPlanRules() (models.PlanRules, error)
+	SavePlanRules(rules models.PlanRules) error
+}
+
+// HistoryStore keeps the cooking log of which planned meals were cooked and how they
+// were rated.
+type HistoryStore interface {
+	// MarkPlanDayDone records whether the meal planned on a day of the saved plans was
+	// cooked or skipped, replacing any earlier record for that day, and sets the day's
+	// status to match. day is a dated plan key, or a weekday one in the household's week
+	// containing weekStart (see models.PlanRules.StartOfWeek). It returns models.ErrNoMealPlan when no saved plan covers the day and
+	// models.ErrNoMealOnDay when the day has no meal.
+	MarkPlanDayDone(weekStart time.Time, day string, entry models.CookingLogEntry) (*models.CookingLogEntry, error)
+	// GetMealHistory returns a meal's cooking log, newest first, or models.ErrMealNotFound.
+	GetMealHistory(mealID int) ([]models.CookingLogEntry, error)
+}
+
+// PantryStore stores what the household has on hand.
+type PantryStore interface {
+	ListPantryItems() ([]models.PantryItem, error)
+	CreatePantryItem(item models.PantryItem) (*models.PantryItem, error)
+	// UpdatePantryItem replaces a pantry item, or returns models.ErrPantryItemNotFound.
+	UpdatePantryItem(item models.PantryItem) (*models.PantryItem, error)
+	// DeletePantryItem removes a pantry item, or returns models.ErrPantryItemNotFound.
+	DeletePantryItem(id int) error
+}
+
+// AisleStore stores the store layout and per-ingredient aisle overrides.
+type AisleStore interface {
+	GetAisleSettings() (models.AisleSettings, error)
+	SaveAisleOrder(order []string) ([]string, error)
+	// SetAisleOverride sets the aisle for an ingredient name; an empty aisle removes it.
+	SetAisleOverride(name, aisle string) error
+}
+
+// CalendarStore keeps the secret token of the calendar feed's URL.
+type CalendarStore interface {
+	// GetCalendarToken returns the feed's token, or models.ErrNoCalendarToken.
+	GetCalendarToken() (string, error)
+	// RotateCalendarToken sets a new random token and returns it; the old URL stops working.
+	RotateCalendarToken() (string, error)
+}
+
+// Writer is what a batch of writes run by Store.Atomically can do. Its methods behave
+// like the Store methods of the same name.
+type Writer interface {
+	CreateMeal(meal models.Meal) (*models.Meal, error)
+	ReplaceMeal(mealID int, meal models.Meal) (*models.Meal, error)
+	SaveMealPlan(weekStart time.Time, plan map[string]*models.Meal, servings map[string]int) (*models.MealPlan, error)
+	SetLastPlanned(mealID int, lastPlanned time.Time) error
+}
+
+// Store is everything the handlers read and write.
+type Store interface {
+	MealStore
+	StepStore
+	TagStore
+	PlanStore
+	HistoryStore
+	PantryStore
+	AisleStore
+	CalendarStore
+	// Atomically runs fn's writes as one: if fn returns an error, none of them are kept
+	// and the error is returned.
+	Atomically(fn func(w Writer) error) error
+	// Ping reports whether the store can currently be reached.
+	Ping() error
+}
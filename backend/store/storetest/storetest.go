@@ -0,0 +1,1100 @@
+// Package storetest is a conformance suite for store.Store implementations. Every
+// implementation runs it from its own tests so they behave the same behind the handlers.
+package storetest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"mealplanner/models"
+	"mealplanner/store"
+)
+
+// Run runs the suite. newStore must return an empty store each time it is called.
+func Run(t *testing.T, newStore func(t *testing.T) store.Store) {
+	t.Run("Meals", func(t *testing.T) { testMeals(t, newStore(t)) })
+	t.Run("Ingredients", func(t *testing.T) { testIngredients(t, newStore(t)) })
+	t.Run("Steps", func(t *testing.T) { testSteps(t, newStore(t)) })
+	t.Run("Tags", func(t *testing.T) { testTags(t, newStore(t)) })
+	t.Run("PlanRules", func(t *testing.T) { testPlanRules(t, newStore(t)) })
+	t.Run("MealPlans", func(t *testing.T) { testMealPlans(t, newStore(t)) })
+	t.Run("Generate", func(t *testing.T) { testGenerate(t, newStore(t)) })
+	t.Run("History", func(t *testing.T) { testHistory(t, newStore(t)) })
+	t.Run("Replace", func(t *testing.T) { testReplace(t, newStore(t)) })
+	t.Run("Atomically", func(t *testing.T) { testAtomically(t, newStore(t)) })
+	t.Run("Leftovers", func(t *testing.T) { testLeftovers(t, newStore(t)) })
+	t.Run("Slots", func(t *testing.T) { testSlots(t, newStore(t)) })
+	t.Run("Dates", func(t *testing.T) { testDates(t, newStore(t)) })
+	t.Run("Pantry", func(t *testing.T) { testPantry(t, newStore(t)) })
+	t.Run("Aisles", func(t *testing.T) { testAisles(t, newStore(t)) })
+	t.Run("Calendar", func(t *testing.T) { testCalendar(t, newStore(t)) })
+}
+
+// mustCreateMeal creates a meal with one ingredient and fails the test on error.
+func mustCreateMeal(t *testing.T, s store.Store, name string, effort int) *models.Meal {
+	t.Helper()
+	meal, err := s.CreateMeal(models.Meal{
+		MealName:       name,
+		RelativeEffort: effort,
+		Servings:       4,
+		Ingredients:    []models.Ingredient{{Name: "onion", Quantity: 1, Unit: "whole"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateMeal(%q): %v", name, err)
+	}
+	return meal
+}
+
+func testMeals(t *testing.T, s store.Store) {
+	if err := s.Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	meal, err := s.CreateMeal(models.Meal{
+		MealName:       "Tacos",
+		RelativeEffort: 3,
+		URL:            "https://example.com/tacos",
+		Servings:       6,
+		Ingredients: []models.Ingredient{
+			{Name: "tortillas", Quantity: 8, Unit: "whole"},
+			{Name: "beans", Quantity: 1, Unit: "can"},
+		},
+		Steps: []models.Step{{Instruction: "Warm the tortillas"}, {Instruction: "Fill"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	if meal.ID == 0 {
+		t.Fatalf("expected the created meal to have an ID")
+	}
+	for _, ing := range meal.Ingredients {
+		if ing.ID == 0 || ing.MealID != meal.ID {
+			t.Errorf("ingredient %q not assigned to the meal: %+v", ing.Name, ing)
+		}
+	}
+	other := mustCreateMeal(t, s, "Soup", 2)
+	if other.ID == meal.ID {
+		t.Fatalf("expected distinct meal IDs, both got %d", meal.ID)
+	}
+
+	all, err := s.GetAllMeals()
+	if err != nil {
+		t.Fatalf("GetAllMeals: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 meals, got %d", len(all))
+	}
+
+	got, err := s.GetMealsByIDs([]int{meal.ID, 9999})
+	if err != nil {
+		t.Fatalf("GetMealsByIDs: %v", err)
+	}
+	if len(got) != 1 || got[0].MealName != "Tacos" || got[0].URL != "https://example.com/tacos" || got[0].Servings != 6 {
+		t.Fatalf("unexpected meals by ID: %+v", got)
+	}
+	if len(got[0].Ingredients) != 2 {
+		t.Errorf("expected 2 ingredients, got %d", len(got[0].Ingredients))
+	}
+	if len(got[0].Steps) != 2 || got[0].Steps[0].Instruction != "Warm the tortillas" {
+		t.Errorf("unexpected steps: %+v", got[0].Steps)
+	}
+
+	planned := time.Date(2024, 3, 4, 18, 30, 0, 0, time.UTC)
+	if err := s.SetLastPlanned(other.ID, planned); err != nil {
+		t.Fatalf("SetLastPlanned: %v", err)
+	}
+	got, _ = s.GetMealsByIDs([]int{other.ID})
+	if len(got) != 1 || !got[0].LastPlanned.Equal(planned) {
+		t.Errorf("expected last planned %v, got %+v", planned, got)
+	}
+	if err := s.SetLastPlanned(other.ID, time.Time{}); err != nil {
+		t.Fatalf("SetLastPlanned clearing: %v", err)
+	}
+	got, _ = s.GetMealsByIDs([]int{other.ID})
+	if len(got) != 1 || !got[0].LastPlanned.IsZero() {
+		t.Errorf("expected last planned cleared, got %+v", got)
+	}
+	if err := s.SetLastPlanned(9999, planned); !errors.Is(err, models.ErrMealNotFound) {
+		t.Errorf("expected ErrMealNotFound for an unknown meal, got %v", err)
+	}
+
+	if err := s.DeleteMeal(meal.ID); err != nil {
+		t.Fatalf("DeleteMeal: %v", err)
+	}
+	if err := s.DeleteMeal(meal.ID); !errors.Is(err, models.ErrMealNotFound) {
+		t.Errorf("expected ErrMealNotFound deleting twice, got %v", err)
+	}
+	steps, err := s.GetStepsForMeal(meal.ID)
+	if err != nil {
+		t.Fatalf("GetStepsForMeal: %v", err)
+	}
+	if len(steps) != 0 {
+		t.Errorf("expected the deleted meal's steps to be gone, got %d", len(steps))
+	}
+	all, _ = s.GetAllMeals()
+	if len(all) != 1 {
+		t.Errorf("expected 1 meal after deleting, got %d", len(all))
+	}
+}
+
+func testIngredients(t *testing.T, s store.Store) {
+	meal := mustCreateMeal(t, s, "Curry", 4)
+	ing := meal.Ingredients[0]
+
+	ing.Name = "red onion"
+	ing.Quantity = 2
+	if err := s.UpdateMealIngredient(meal.ID, ing); err != nil {
+		t.Fatalf("UpdateMealIngredient: %v", err)
+	}
+	got, _ := s.GetMealsByIDs([]int{meal.ID})
+	if len(got) != 1 || len(got[0].Ingredients) != 1 {
+		t.Fatalf("expected the meal with one ingredient, got %+v", got)
+	}
+	if updated := got[0].Ingredients[0]; updated.Name != "red onion" || updated.Quantity != 2 {
+		t.Errorf("ingredient not updated: %+v", updated)
+	}
+
+	missing := models.Ingredient{ID: 9999, Name: "garlic", Quantity: 1}
+	if err := s.UpdateMealIngredient(meal.ID, missing); !errors.Is(err, models.ErrIngredientNotFound) {
+		t.Errorf("expected ErrIngredientNotFound updating an unknown ingredient, got %v", err)
+	}
+
+	other := mustCreateMeal(t, s, "Dal", 2)
+	if err := s.DeleteMealIngredient(other.ID, ing.ID); !errors.Is(err, models.ErrIngredientNotFound) {
+		t.Errorf("expected ErrIngredientNotFound deleting through another meal, got %v", err)
+	}
+	if got, _ = s.GetMealsByIDs([]int{meal.ID}); len(got) != 1 || len(got[0].Ingredients) != 1 {
+		t.Fatalf("expected the ingredient to survive a delete through another meal, got %+v", got)
+	}
+	if err := s.DeleteMealIngredient(meal.ID, ing.ID); err != nil {
+		t.Fatalf("DeleteMealIngredient: %v", err)
+	}
+	if err := s.DeleteMealIngredient(meal.ID, ing.ID); !errors.Is(err, models.ErrIngredientNotFound) {
+		t.Errorf("expected ErrIngredientNotFound deleting twice, got %v", err)
+	}
+	got, _ = s.GetMealsByIDs([]int{meal.ID})
+	if len(got) != 1 || len(got[0].Ingredients) != 0 {
+		t.Errorf("expected no ingredients left, got %+v", got)
+	}
+}
+
+func testSteps(t *testing.T, s store.Store) {
+	meal := mustCreateMeal(t, s, "Risotto", 4)
+
+	if _, err := s.AddStepToMeal(models.Step{MealID: 9999, Instruction: "Stir"}); !errors.Is(err, models.ErrMealNotFound) {
+		t.Errorf("expected ErrMealNotFound adding a step to an unknown meal, got %v", err)
+	}
+
+	first, err := s.AddStepToMeal(models.Step{MealID: meal.ID, Instruction: "Toast the rice"})
+	if err != nil {
+		t.Fatalf("AddStepToMeal: %v", err)
+	}
+	if first.ID == 0 || first.StepNumber != 1 {
+		t.Errorf("expected step 1 with an ID, got %+v", first)
+	}
+	more, err := s.AddMultipleStepsToMeal(meal.ID, []string{"Add stock", "Stir in parmesan"})
+	if err != nil {
+		t.Fatalf("AddMultipleStepsToMeal: %v", err)
+	}
+	if len(more) != 2 || more[0].StepNumber != 2 || more[1].StepNumber != 3 {
+		t.Fatalf("expected steps 2 and 3, got %+v", more)
+	}
+
+	if err := s.UpdateStep(models.Step{ID: first.ID, MealID: meal.ID, StepNumber: 1, Instruction: "Toast the rice in butter"}); err != nil {
+		t.Fatalf("UpdateStep: %v", err)
+	}
+	if err := s.UpdateStep(models.Step{ID: 9999, MealID: meal.ID, StepNumber: 9, Instruction: "Nothing"}); !errors.Is(err, models.ErrStepNotFound) {
+		t.Errorf("expected ErrStepNotFound updating an unknown step, got %v", err)
+	}
+
+	if err := s.ReorderSteps(meal.ID, []int{more[1].ID, first.ID, more[0].ID}); err != nil {
+		t.Fatalf("ReorderSteps: %v", err)
+	}
+	steps, err := s.GetStepsForMeal(meal.ID)
+	if err != nil {
+		t.Fatalf("GetStepsForMeal: %v", err)
+	}
+	want := []string{"Stir in parmesan", "Toast the rice in butter", "Add stock"}
+	if len(steps) != len(want) {
+		t.Fatalf("expected %d steps, got %d", len(want), len(steps))
+	}
+	for i, step := range steps {
+		if step.StepNumber != i+1 || step.Instruction != want[i] {
+			t.Errorf("step %d: got %d %q, want %d %q", i, step.StepNumber, step.Instruction, i+1, want[i])
+		}
+	}
+
+	if err := s.DeleteStep(first.ID, meal.ID); err != nil {
+		t.Fatalf("DeleteStep: %v", err)
+	}
+	if err := s.DeleteStep(first.ID, meal.ID); !errors.Is(err, models.ErrStepNotFound) {
+		t.Errorf("expected ErrStepNotFound deleting twice, got %v", err)
+	}
+	if err := s.DeleteAllStepsForMeal(meal.ID); err != nil {
+		t.Fatalf("DeleteAllStepsForMeal: %v", err)
+	}
+	steps, _ = s.GetStepsForMeal(meal.ID)
+	if len(steps) != 0 {
+		t.Errorf("expected no steps left, got %d", len(steps))
+	}
+}
+
+func testTags(t *testing.T, s store.Store) {
+	curry, err := s.CreateMeal(models.Meal{MealName: "Curry", RelativeEffort: 3, Tags: []string{"Vegetarian", " Indian "}, Ingredients: []models.Ingredient{}})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	if len(curry.Tags) != 2 || curry.Tags[0] != "indian" || curry.Tags[1] != "vegetarian" {
+		t.Errorf("expected normalized sorted tags, got %v", curry.Tags)
+	}
+	stew := mustCreateMeal(t, s, "Stew", 4)
+
+	quick, err := s.CreateTag("Quick Weeknight")
+	if err != nil {
+		t.Fatalf("CreateTag: %v", err)
+	}
+	if quick.ID == 0 || quick.Name != "quick-weeknight" {
+		t.Errorf("expected a normalized tag with an ID, got %+v", quick)
+	}
+	if _, err := s.CreateTag("quick weeknight"); !errors.Is(err, models.ErrTagExists) {
+		t.Errorf("expected ErrTagExists creating a duplicate, got %v", err)
+	}
+
+	tags, err := s.SetMealTags(stew.ID, []string{"gluten free", "Vegetarian", "vegetarian"})
+	if err != nil {
+		t.Fatalf("SetMealTags: %v", err)
+	}
+	if len(tags) != 2 || tags[0] != "gluten-free" || tags[1] != "vegetarian" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	if _, err := s.SetMealTags(9999, []string{"vegetarian"}); !errors.Is(err, models.ErrMealNotFound) {
+		t.Errorf("expected ErrMealNotFound tagging an unknown meal, got %v", err)
+	}
+
+	all, err := s.ListTags()
+	if err != nil {
+		t.Fatalf("ListTags: %v", err)
+	}
+	counts := map[string]int{}
+	for _, tag := range all {
+		counts[tag.Name] = tag.MealCount
+	}
+	want := map[string]int{"gluten-free": 1, "indian": 1, "quick-weeknight": 0, "vegetarian": 2}
+	if len(all) != len(want) || all[0].Name != "gluten-free" {
+		t.Fatalf("expected %d tags ordered by name, got %+v", len(want), all)
+	}
+	for name, n := range want {
+		if counts[name] != n {
+			t.Errorf("expected %d meals tagged %s, got %d", n, name, counts[name])
+		}
+	}
+
+	var vegetarian models.Tag
+	for _, tag := range all {
+		if tag.Name == "vegetarian" {
+			vegetarian = tag
+		}
+	}
+	renamed, err := s.RenameTag(vegetarian.ID, "Veggie")
+	if err != nil {
+		t.Fatalf("RenameTag: %v", err)
+	}
+	if renamed.Name != "veggie" || renamed.MealCount != 2 {
+		t.Errorf("unexpected renamed tag %+v", renamed)
+	}
+	if _, err := s.RenameTag(vegetarian.ID, "indian"); !errors.Is(err, models.ErrTagExists) {
+		t.Errorf("expected ErrTagExists renaming onto another tag, got %v", err)
+	}
+	if _, err := s.RenameTag(9999, "anything"); !errors.Is(err, models.ErrTagNotFound) {
+		t.Errorf("expected ErrTagNotFound renaming an unknown tag, got %v", err)
+	}
+	got, _ := s.GetMealsByIDs([]int{stew.ID})
+	if len(got) != 1 || len(got[0].Tags) != 2 || got[0].Tags[1] != "veggie" {
+		t.Errorf("expected the rename on the meal, got %+v", got)
+	}
+
+	if err := s.DeleteTag(vegetarian.ID); err != nil {
+		t.Fatalf("DeleteTag: %v", err)
+	}
+	if err := s.DeleteTag(vegetarian.ID); !errors.Is(err, models.ErrTagNotFound) {
+		t.Errorf("expected ErrTagNotFound deleting twice, got %v", err)
+	}
+	meals, _ := s.GetAllMeals()
+	for _, m := range meals {
+		if m.HasTag("veggie") {
+			t.Errorf("expected the deleted tag gone from %s, got %v", m.MealName, m.Tags)
+		}
+	}
+
+	if err := s.DeleteMeal(curry.ID); err != nil {
+		t.Fatalf("DeleteMeal: %v", err)
+	}
+	all, _ = s.ListTags()
+	for _, tag := range all {
+		if tag.Name == "indian" && tag.MealCount != 0 {
+			t.Errorf("expected a deleted meal to drop out of the tag counts, got %+v", tag)
+		}
+	}
+}
+
+func testPlanRules(t *testing.T, s store.Store) {
+	rules, err := s.GetPlanRules()
+	if err != nil {
+		t.Fatalf("GetPlanRules: %v", err)
+	}
+	if rules.RepeatCooldownDays != models.DefaultPlanRules().RepeatCooldownDays {
+		t.Errorf("expected the default rules before any are saved, got %+v", rules)
+	}
+
+	rules.RepeatCooldownDays = 10
+	rules.CategoryCaps = map[string]int{models.CategoryRedMeat: 2}
+	if err := s.SavePlanRules(rules); err != nil {
+		t.Fatalf("SavePlanRules: %v", err)
+	}
+	got, err := s.GetPlanRules()
+	if err != nil {
+		t.Fatalf("GetPlanRules: %v", err)
+	}
+	if got.RepeatCooldownDays != 10 || got.CategoryCaps[models.CategoryRedMeat] != 2 {
+		t.Errorf("saved rules not returned: %+v", got)
+	}
+	if len(got.Days) != 7 {
+		t.Errorf("expected 7 day rules, got %d", len(got.Days))
+	}
+}
+
+func testMealPlans(t *testing.T, s store.Store) {
+	if _, err := s.GetLatestMealPlan(); !errors.Is(err, models.ErrNoMealPlan) {
+		t.Errorf("expected ErrNoMealPlan before any plan is saved, got %v", err)
+	}
+
+	a := mustCreateMeal(t, s, "Lasagne", 6)
+	b := mustCreateMeal(t, s, "Omelette", 1)
+	week := time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC) // a Wednesday
+	saved, err := s.SaveMealPlan(week, map[string]*models.Meal{
+		"Monday": b,
+		"Friday": {MealName: models.EatingOutMealName},
+		"Sunday": a,
+	}, map[string]int{"Sunday": 8})
+	if err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	if !saved.WeekStart.Equal(models.WeekStartFor(week)) {
+		t.Errorf("expected the week to start %v, got %v", models.WeekStartFor(week), saved.WeekStart)
+	}
+
+	latest, err := s.GetLatestMealPlan()
+	if err != nil {
+		t.Fatalf("GetLatestMealPlan: %v", err)
+	}
+	if latest.ID != saved.ID || len(latest.Entries) != 3 {
+		t.Fatalf("unexpected latest plan: %+v", latest)
+	}
+	for _, entry := range latest.Entries {
+		switch entry.Day {
+		case "Monday":
+			if entry.MealID != b.ID || entry.Status != models.EntryStatusPlanned {
+				t.Errorf("unexpected Monday entry: %+v", entry)
+			}
+		case "Friday":
+			if entry.Status != models.EntryStatusEatingOut {
+				t.Errorf("expected Friday to be eating out, got %+v", entry)
+			}
+		case "Sunday":
+			if entry.Meal == nil || entry.Meal.MealName != "Lasagne" || entry.Meal.Servings != 4 {
+				t.Errorf("expected Sunday to carry the meal, got %+v", entry)
+			}
+			if entry.Servings != 8 {
+				t.Errorf("expected Sunday to cook for 8, got %d", entry.Servings)
+			}
+		default:
+			t.Errorf("unexpected entry for %s", entry.Day)
+		}
+	}
+
+	got, _ := s.GetMealsByIDs([]int{a.ID})
+	if len(got) != 1 || got[0].LastPlanned.IsZero() {
+		t.Errorf("expected last_planned to be set for a planned meal")
+	}
+
+	// Saving the same week again replaces the plan.
+	if _, err := s.SaveMealPlan(week, map[string]*models.Meal{"Tuesday": a}, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	next := week.AddDate(0, 0, 7)
+	if _, err := s.SaveMealPlan(next, map[string]*models.Meal{"Monday": b}, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	plans, err := s.ListMealPlans(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("ListMealPlans: %v", err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("expected 2 plans, got %d", len(plans))
+	}
+	if !plans[0].WeekStart.Before(plans[1].WeekStart) {
+		t.Errorf("expected plans oldest first")
+	}
+	if len(plans[0].Entries) != 1 || plans[0].Entries[0].Day != "Tuesday" {
+		t.Errorf("expected the replaced plan to hold only Tuesday, got %+v", plans[0].Entries)
+	}
+
+	plans, err = s.ListMealPlans(next, time.Time{})
+	if err != nil {
+		t.Fatalf("ListMealPlans: %v", err)
+	}
+	if len(plans) != 1 {
+		t.Errorf("expected 1 plan from the second week on, got %d", len(plans))
+	}
+	latest, _ = s.GetLatestMealPlan()
+	if latest == nil || !latest.WeekStart.Equal(models.WeekStartFor(next)) {
+		t.Errorf("expected the latest plan to be the second week")
+	}
+}
+
+func testGenerate(t *testing.T, s store.Store) {
+	for i, effort := range []int{1, 2, 3, 4, 5, 3, 4, 6, 7} {
+		mustCreateMeal(t, s, "Meal "+string(rune('A'+i)), effort)
+	}
+	rules := models.DefaultPlanRules()
+
+	result, err := s.GenerateMealPlan(rules, models.SolveOptions{})
+	if err != nil {
+		t.Fatalf("GenerateMealPlan: %v", err)
+	}
+	if len(result.Plan) != 7 {
+		t.Fatalf("expected 7 planned days, got %d", len(result.Plan))
+	}
+	plan := map[string]int{}
+	for day, meal := range result.Plan {
+		if meal != nil {
+			plan[day] = meal.ID
+		}
+	}
+
+	alternatives, err := s.SwapMealInPlan(rules, plan, "Tuesday", 2)
+	if err != nil {
+		t.Fatalf("SwapMealInPlan: %v", err)
+	}
+	if len(alternatives) > 2 {
+		t.Errorf("expected at most 2 alternatives, got %d", len(alternatives))
+	}
+	for _, alt := range alternatives {
+		for day, id := range plan {
+			if alt.Meal.ID == id {
+				t.Errorf("alternative %d is already planned on %s", id, day)
+			}
+		}
+	}
+	if _, err := s.SwapMealInPlan(rules, plan, "Someday", 2); err == nil {
+		t.Errorf("expected an error for an unknown day")
+	}
+
+	// The generator sees stored tags.
+	veggie := mustCreateMeal(t, s, "Veggie Chili", 4)
+	if _, err := s.SetMealTags(veggie.ID, []string{"vegetarian"}); err != nil {
+		t.Fatalf("SetMealTags: %v", err)
+	}
+	rules.TagMinimums = map[string]int{"vegetarian": 1}
+	result, err = s.GenerateMealPlan(rules, models.SolveOptions{})
+	if err != nil {
+		t.Fatalf("GenerateMealPlan with a tag minimum: %v", err)
+	}
+	found := false
+	for _, meal := range result.Plan {
+		found = found || meal.ID == veggie.ID
+	}
+	if !found {
+		t.Errorf("expected the only vegetarian meal in the plan, got %+v", result.Plan)
+	}
+}
+
+func testHistory(t *testing.T, s store.Store) {
+	soup := mustCreateMeal(t, s, "Soup", 2)
+	stew := mustCreateMeal(t, s, "Stew", 4)
+	week := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	plan := map[string]*models.Meal{"Monday": soup, "Tuesday": stew, "Friday": {MealName: models.EatingOutMealName}}
+	if _, err := s.SaveMealPlan(week, plan, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+
+	cooked := models.CookingLogEntry{Status: models.CookingStatusCooked, Rating: 3, Notes: "needed salt"}
+	if _, err := s.MarkPlanDayDone(week, "Monday", cooked); err != nil {
+		t.Fatalf("MarkPlanDayDone: %v", err)
+	}
+	// Marking the day again replaces its entry.
+	cooked.Rating = 5
+	entry, err := s.MarkPlanDayDone(week.AddDate(0, 0, 2), "Monday", cooked)
+	if err != nil {
+		t.Fatalf("MarkPlanDayDone: %v", err)
+	}
+	if entry.ID == 0 || entry.MealID != soup.ID || entry.PlanEntryID == 0 || !entry.CookedOn.Equal(week) {
+		t.Errorf("unexpected log entry %+v", entry)
+	}
+	if _, err := s.MarkPlanDayDone(week, "Tuesday", models.CookingLogEntry{Status: models.CookingStatusSkipped}); err != nil {
+		t.Fatalf("MarkPlanDayDone: %v", err)
+	}
+
+	if _, err := s.MarkPlanDayDone(week, "Friday", cooked); !errors.Is(err, models.ErrNoMealOnDay) {
+		t.Errorf("expected ErrNoMealOnDay for an eating out day, got %v", err)
+	}
+	if _, err := s.MarkPlanDayDone(week, "Wednesday", cooked); !errors.Is(err, models.ErrNoMealOnDay) {
+		t.Errorf("expected ErrNoMealOnDay for an unplanned day, got %v", err)
+	}
+	if _, err := s.MarkPlanDayDone(week.AddDate(0, 0, 7), "Monday", cooked); !errors.Is(err, models.ErrNoMealPlan) {
+		t.Errorf("expected ErrNoMealPlan for a week without a plan, got %v", err)
+	}
+	if _, err := s.MarkPlanDayDone(week, "Monday", models.CookingLogEntry{Status: "eaten"}); err == nil {
+		t.Errorf("expected an error for an unknown status")
+	}
+
+	history, err := s.GetMealHistory(soup.ID)
+	if err != nil {
+		t.Fatalf("GetMealHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].Rating != 5 || history[0].Notes != "needed salt" || history[0].Status != models.CookingStatusCooked {
+		t.Errorf("expected one replaced entry, got %+v", history)
+	}
+	if _, err := s.GetMealHistory(9999); !errors.Is(err, models.ErrMealNotFound) {
+		t.Errorf("expected ErrMealNotFound for an unknown meal, got %v", err)
+	}
+
+	saved, _ := s.GetLatestMealPlan()
+	statuses := map[string]string{}
+	for _, e := range saved.Entries {
+		statuses[e.Day] = e.Status
+	}
+	if statuses["Monday"] != models.CookingStatusCooked || statuses["Tuesday"] != models.CookingStatusSkipped {
+		t.Errorf("expected the plan days marked done, got %v", statuses)
+	}
+
+	// Replacing the week's plan keeps the log.
+	if _, err := s.SaveMealPlan(week, map[string]*models.Meal{"Monday": stew}, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	history, _ = s.GetMealHistory(soup.ID)
+	if len(history) != 1 || history[0].PlanEntryID != 0 {
+		t.Errorf("expected the entry kept without its plan day, got %+v", history)
+	}
+	history, _ = s.GetMealHistory(stew.ID)
+	if len(history) != 1 || history[0].Status != models.CookingStatusSkipped {
+		t.Errorf("expected the skipped entry, got %+v", history)
+	}
+
+	// The generator sees the stats of cooked meals only.
+	for _, name := range []string{"Chili", "Curry", "Tacos"} {
+		mustCreateMeal(t, s, name, 4)
+	}
+	mustCreateMeal(t, s, "Roast", 7)
+	rules, _ := s.GetPlanRules()
+	rules.RepeatCooldownDays = 0
+	locked := map[string]int{"Monday": soup.ID, "Tuesday": stew.ID}
+	result, err := s.GenerateMealPlan(rules, models.SolveOptions{Locked: locked})
+	if err != nil {
+		t.Fatalf("GenerateMealPlan: %v", err)
+	}
+	if m := result.Plan["Monday"]; m.Rating != 5 || m.TimesCooked != 1 || !m.LastCooked.Equal(week) {
+		t.Errorf("expected soup's cooking stats, got %+v", m)
+	}
+	if m := result.Plan["Tuesday"]; m.TimesCooked != 0 || !m.LastCooked.IsZero() {
+		t.Errorf("expected a skipped meal to have no stats, got %+v", m)
+	}
+
+	if err := s.DeleteMeal(soup.ID); err != nil {
+		t.Fatalf("DeleteMeal: %v", err)
+	}
+	if _, err := s.GetMealHistory(soup.ID); !errors.Is(err, models.ErrMealNotFound) {
+		t.Errorf("expected ErrMealNotFound after deleting the meal, got %v", err)
+	}
+}
+
+func testReplace(t *testing.T, s store.Store) {
+	soup := mustCreateMeal(t, s, "Soup", 2)
+	week := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	if _, err := s.SaveMealPlan(week, map[string]*models.Meal{"Monday": soup}, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	if _, err := s.MarkPlanDayDone(week, "Monday", models.CookingLogEntry{Status: models.CookingStatusCooked, Rating: 4}); err != nil {
+		t.Fatalf("MarkPlanDayDone: %v", err)
+	}
+	before, _ := s.GetMealsByIDs([]int{soup.ID})
+
+	replaced, err := s.ReplaceMeal(soup.ID, models.Meal{
+		MealName:       "Lentil soup",
+		RelativeEffort: 3,
+		Servings:       2,
+		Tags:           []string{"vegetarian"},
+		Ingredients:    []models.Ingredient{{Name: "lentils", Quantity: 200, Unit: "g"}},
+		Steps:          []models.Step{{Instruction: "Simmer"}},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceMeal: %v", err)
+	}
+	if replaced.ID != soup.ID {
+		t.Errorf("expected the replaced meal to keep ID %d, got %d", soup.ID, replaced.ID)
+	}
+	for _, ing := range replaced.Ingredients {
+		if ing.ID == 0 || ing.MealID != soup.ID {
+			t.Errorf("ingredient %q not assigned to the meal: %+v", ing.Name, ing)
+		}
+	}
+	got, _ := s.GetMealsByIDs([]int{soup.ID})
+	if len(got) != 1 || got[0].MealName != "Lentil soup" || got[0].RelativeEffort != 3 || got[0].Servings != 2 {
+		t.Fatalf("unexpected replaced meal: %+v", got)
+	}
+	if len(got[0].Ingredients) != 1 || got[0].Ingredients[0].Name != "lentils" {
+		t.Errorf("expected the ingredients replaced, got %+v", got[0].Ingredients)
+	}
+	if len(got[0].Steps) != 1 || got[0].Steps[0].Instruction != "Simmer" {
+		t.Errorf("expected the steps replaced, got %+v", got[0].Steps)
+	}
+	if !reflect.DeepEqual(got[0].Tags, []string{"vegetarian"}) {
+		t.Errorf("expected the tags replaced, got %v", got[0].Tags)
+	}
+	if len(before) != 1 || !got[0].LastPlanned.Equal(before[0].LastPlanned) {
+		t.Errorf("expected the last planned time kept, got %v", got[0].LastPlanned)
+	}
+
+	history, err := s.GetMealHistory(soup.ID)
+	if err != nil {
+		t.Fatalf("GetMealHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].Rating != 4 || history[0].PlanEntryID == 0 {
+		t.Errorf("expected the cooking log kept, got %+v", history)
+	}
+	plan, _ := s.GetLatestMealPlan()
+	if plan == nil || len(plan.Entries) != 1 || plan.Entries[0].Meal == nil || plan.Entries[0].Meal.MealName != "Lentil soup" {
+		t.Errorf("expected the plan to show the replaced meal, got %+v", plan)
+	}
+
+	if _, err := s.ReplaceMeal(9999, models.Meal{MealName: "Ghost", RelativeEffort: 1}); !errors.Is(err, models.ErrMealNotFound) {
+		t.Errorf("expected ErrMealNotFound for an unknown meal, got %v", err)
+	}
+}
+
+func testAtomically(t *testing.T, s store.Store) {
+	soup := mustCreateMeal(t, s, "Soup", 2)
+	week := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	failed := errors.New("failed")
+	err := s.Atomically(func(w store.Writer) error {
+		stew, err := w.CreateMeal(models.Meal{MealName: "Stew", RelativeEffort: 4, Ingredients: []models.Ingredient{}})
+		if err != nil {
+			return err
+		}
+		if _, err := w.ReplaceMeal(soup.ID, models.Meal{MealName: "Broth", RelativeEffort: 1, Ingredients: []models.Ingredient{}}); err != nil {
+			return err
+		}
+		if _, err := w.SaveMealPlan(week, map[string]*models.Meal{"Monday": stew}, nil); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("expected the function's error, got %v", err)
+	}
+	meals, _ := s.GetAllMeals()
+	if len(meals) != 1 || meals[0].MealName != "Soup" || len(meals[0].Ingredients) != 1 {
+		t.Errorf("expected a failed batch to change nothing, got %+v", meals)
+	}
+	if _, err := s.GetLatestMealPlan(); !errors.Is(err, models.ErrNoMealPlan) {
+		t.Errorf("expected no plan after a failed batch, got %v", err)
+	}
+
+	err = s.Atomically(func(w store.Writer) error {
+		_, err := w.CreateMeal(models.Meal{MealName: "Stew", RelativeEffort: 4, Ingredients: []models.Ingredient{}})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Atomically: %v", err)
+	}
+	if meals, _ := s.GetAllMeals(); len(meals) != 2 {
+		t.Errorf("expected the batch kept, got %d meals", len(meals))
+	}
+}
+
+func testLeftovers(t *testing.T, s store.Store) {
+	stew, err := s.CreateMeal(models.Meal{MealName: "Stew", RelativeEffort: 1, Nights: 3, Ingredients: []models.Ingredient{}})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	soup := mustCreateMeal(t, s, "Soup", 4)
+	if soup.Nights != 1 {
+		t.Errorf("expected a meal without nights to cover 1, got %d", soup.Nights)
+	}
+	got, _ := s.GetMealsByIDs([]int{stew.ID})
+	if len(got) != 1 || got[0].Nights != 3 {
+		t.Fatalf("expected the stew to cover 3 nights, got %+v", got)
+	}
+
+	rules := models.PlanRules{Days: []models.DayRule{
+		{Day: "Monday", MinEffort: 0, MaxEffort: 2},
+		{Day: "Tuesday", MinEffort: 3, MaxEffort: 5},
+		{Day: "Wednesday", MinEffort: 3, MaxEffort: 5},
+		{Day: "Thursday", MinEffort: 3, MaxEffort: 5},
+	}}
+	result, err := s.GenerateMealPlan(rules, models.SolveOptions{})
+	if err != nil {
+		t.Fatalf("GenerateMealPlan: %v", err)
+	}
+	for _, day := range []string{"Tuesday", "Wednesday"} {
+		if m := result.Plan[day]; m == nil || m.LeftoversOf != stew.ID {
+			t.Errorf("expected leftovers of the stew on %s, got %+v", day, m)
+		}
+	}
+	if m := result.Plan["Thursday"]; m == nil || m.ID != soup.ID {
+		t.Errorf("expected soup on Thursday, got %+v", m)
+	}
+
+	week := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	if _, err := s.SaveMealPlan(week, result.Plan, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	saved, err := s.GetLatestMealPlan()
+	if err != nil {
+		t.Fatalf("GetLatestMealPlan: %v", err)
+	}
+	for _, e := range saved.Entries {
+		if e.Day != "Tuesday" && e.Day != "Wednesday" {
+			continue
+		}
+		if e.Status != models.EntryStatusLeftovers || e.MealID != stew.ID || e.Meal == nil || e.Meal.MealName != "Leftovers of Stew" || e.Meal.ID != 0 {
+			t.Errorf("unexpected leftovers entry: %+v", e)
+		}
+	}
+	if _, err := s.MarkPlanDayDone(week, "Tuesday", models.CookingLogEntry{Status: models.CookingStatusCooked}); !errors.Is(err, models.ErrNoMealOnDay) {
+		t.Errorf("expected ErrNoMealOnDay for a leftovers day, got %v", err)
+	}
+}
+
+func testSlots(t *testing.T, s store.Store) {
+	porridge, err := s.CreateMeal(models.Meal{MealName: "Porridge", RelativeEffort: 1, Slots: []string{"Breakfast"}, Ingredients: []models.Ingredient{}})
+	if err != nil {
+		t.Fatalf("CreateMeal: %v", err)
+	}
+	if !reflect.DeepEqual(porridge.Slots, []string{models.SlotBreakfast}) {
+		t.Errorf("expected normalized slots, got %v", porridge.Slots)
+	}
+	soup := mustCreateMeal(t, s, "Soup", 4)
+	if !reflect.DeepEqual(soup.Slots, []string{models.SlotDinner}) {
+		t.Errorf("expected a meal without slots to be a dinner, got %v", soup.Slots)
+	}
+	stew := mustCreateMeal(t, s, "Stew", 4)
+
+	if _, err := s.SetMealSlots(9999, []string{models.SlotLunch}); !errors.Is(err, models.ErrMealNotFound) {
+		t.Errorf("expected ErrMealNotFound, got %v", err)
+	}
+	if _, err := s.SetMealSlots(soup.ID, []string{"brunch"}); err == nil {
+		t.Errorf("expected an unknown slot to be rejected")
+	}
+	slots, err := s.SetMealSlots(soup.ID, []string{"dinner", "lunch"})
+	if err != nil {
+		t.Fatalf("SetMealSlots: %v", err)
+	}
+	if !reflect.DeepEqual(slots, []string{models.SlotLunch, models.SlotDinner}) {
+		t.Errorf("expected slots in day order, got %v", slots)
+	}
+	got, _ := s.GetMealsByIDs([]int{soup.ID})
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Slots, slots) {
+		t.Fatalf("expected the soup's slots to be stored, got %+v", got)
+	}
+
+	rules := models.PlanRules{Days: []models.DayRule{
+		{Day: "Monday", Slot: models.SlotBreakfast, MinEffort: 0, MaxEffort: 5},
+		{Day: "Monday", Slot: models.SlotLunch, MinEffort: 0, MaxEffort: 5},
+		{Day: "Monday", MinEffort: 0, MaxEffort: 5},
+	}}
+	result, err := s.GenerateMealPlan(rules, models.SolveOptions{})
+	if err != nil {
+		t.Fatalf("GenerateMealPlan: %v", err)
+	}
+	want := map[string]int{"Monday/breakfast": porridge.ID, "Monday/lunch": soup.ID, "Monday": stew.ID}
+	for key, id := range want {
+		if m := result.Plan[key]; m == nil || m.ID != id {
+			t.Errorf("expected meal %d on %s, got %+v", id, key, m)
+		}
+	}
+
+	week := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	if _, err := s.SaveMealPlan(week, result.Plan, map[string]int{"Monday/lunch": 2}); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	saved, err := s.GetLatestMealPlan()
+	if err != nil {
+		t.Fatalf("GetLatestMealPlan: %v", err)
+	}
+	if len(saved.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", saved.Entries)
+	}
+	for _, e := range saved.Entries {
+		if e.Day != "Monday" || e.MealID != want[e.Key()] {
+			t.Errorf("unexpected entry: %+v", e)
+		}
+		if e.Slot == models.SlotLunch && e.Servings != 2 {
+			t.Errorf("expected lunch servings of 2, got %d", e.Servings)
+		}
+	}
+	days := saved.DayMap()
+	if days["Monday/breakfast"] == nil || days["Monday"] == nil {
+		t.Errorf("expected the day map keyed by slot, got %v", models.PlanKeys(days))
+	}
+
+	logged, err := s.MarkPlanDayDone(week, "Monday/breakfast", models.CookingLogEntry{Status: models.CookingStatusCooked})
+	if err != nil {
+		t.Fatalf("MarkPlanDayDone: %v", err)
+	}
+	if logged.MealID != porridge.ID || !logged.CookedOn.Equal(week) {
+		t.Errorf("expected the breakfast logged on Monday, got %+v", logged)
+	}
+}
+
+func testDates(t *testing.T, s store.Store) {
+	var ids []int
+	for _, name := range []string{"Chili", "Curry", "Pasta", "Tacos"} {
+		ids = append(ids, mustCreateMeal(t, s, name, 3).ID)
+	}
+	weekly := models.PlanRules{Days: []models.DayRule{
+		{Day: "Wednesday", MinEffort: 0, MaxEffort: 5},
+		{Day: "Friday", MinEffort: 0, MaxEffort: 5},
+	}}
+	start := time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)
+	rules, err := weekly.ForDates(start, 10)
+	if err != nil {
+		t.Fatalf("ForDates: %v", err)
+	}
+	result, err := s.GenerateMealPlan(rules, models.SolveOptions{})
+	if err != nil {
+		t.Fatalf("GenerateMealPlan: %v", err)
+	}
+	keys := models.PlanKeys(result.Plan)
+	if !reflect.DeepEqual(keys, []string{"2024-03-06", "2024-03-08", "2024-03-13", "2024-03-15"}) {
+		t.Fatalf("expected the Wednesdays and Fridays of the range, got %v", keys)
+	}
+
+	if _, err := s.SaveMealPlan(start, result.Plan, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	saved, err := s.GetLatestMealPlan()
+	if err != nil {
+		t.Fatalf("GetLatestMealPlan: %v", err)
+	}
+	if !saved.WeekStart.Equal(start) || saved.Days != 10 || len(saved.Entries) != 4 {
+		t.Fatalf("expected a 10-day plan from %v, got %+v", start, saved)
+	}
+	if e := saved.Entries[3]; e.DateKey() != "2024-03-15" || e.Day != "Friday" {
+		t.Errorf("expected the entries in date order, got %+v", saved.Entries)
+	}
+	if days := saved.DayMap(); !reflect.DeepEqual(models.PlanKeys(days), keys) {
+		t.Errorf("expected a plan longer than a week keyed by date, got %v", models.PlanKeys(days))
+	}
+
+	// A later plan overlapping the first takes over the days they share.
+	later := map[string]*models.Meal{"2024-03-13": {ID: ids[0]}, "2024-03-14": {MealName: models.EatingOutMealName}}
+	if _, err := s.SaveMealPlan(time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC), later, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	plans, err := s.ListMealPlans(time.Time{}, time.Time{})
+	if err != nil || len(plans) != 2 || plans[1].Days != 2 {
+		t.Fatalf("expected both plans, the later one covering 2 days, got %+v, %v", plans, err)
+	}
+	// A dated plan saved from an earlier week starts on its own first date.
+	if _, err := s.SaveMealPlan(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), map[string]*models.Meal{"2024-03-20": {ID: ids[1]}}, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	plans, err = s.ListMealPlans(time.Time{}, time.Time{})
+	if err != nil || len(plans) != 3 || !plans[2].WeekStart.Equal(time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)) || plans[2].Days != 1 {
+		t.Fatalf("expected a third plan on 2024-03-20 alone, got %+v, %v", plans, err)
+	}
+	// Listing from a date returns the plans still running on it, wherever they start.
+	plans, err = s.ListMealPlans(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), time.Time{})
+	if err != nil || len(plans) != 2 || !plans[0].WeekStart.Equal(start) || plans[1].Days != 1 {
+		t.Fatalf("expected the 10-day plan and the one on 2024-03-20, got %+v, %v", plans, err)
+	}
+	logged, err := s.MarkPlanDayDone(time.Time{}, "2024-03-13", models.CookingLogEntry{Status: models.CookingStatusCooked})
+	if err != nil {
+		t.Fatalf("MarkPlanDayDone: %v", err)
+	}
+	if logged.MealID != ids[0] || logged.CookedOn.Format(models.DateLayout) != "2024-03-13" {
+		t.Errorf("expected the later plan's meal logged on 2024-03-13, got %+v", logged)
+	}
+	if _, err := s.MarkPlanDayDone(time.Time{}, "2024-03-15", models.CookingLogEntry{Status: models.CookingStatusSkipped}); err != nil {
+		t.Errorf("expected the first plan's last day to be marked, got %v", err)
+	}
+	if _, err := s.MarkPlanDayDone(time.Time{}, "2024-03-07", models.CookingLogEntry{Status: models.CookingStatusCooked}); !errors.Is(err, models.ErrNoMealOnDay) {
+		t.Errorf("expected ErrNoMealOnDay for a covered day without a meal, got %v", err)
+	}
+	if _, err := s.MarkPlanDayDone(time.Time{}, "2024-03-16", models.CookingLogEntry{Status: models.CookingStatusCooked}); !errors.Is(err, models.ErrNoMealPlan) {
+		t.Errorf("expected ErrNoMealPlan past the end of the plans, got %v", err)
+	}
+
+	// With weeks starting on Sunday, a weekly plan is listed from any of its days.
+	sunday := models.DefaultPlanRules()
+	sunday.WeekStartDay = "Sunday"
+	if err := s.SavePlanRules(sunday); err != nil {
+		t.Fatalf("SavePlanRules: %v", err)
+	}
+	week := time.Date(2024, 3, 24, 0, 0, 0, 0, time.UTC)
+	if _, err := s.SaveMealPlan(week, map[string]*models.Meal{"Saturday": {ID: ids[2]}}, nil); err != nil {
+		t.Fatalf("SaveMealPlan: %v", err)
+	}
+	plans, err = s.ListMealPlans(time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC), time.Time{})
+	if err != nil || len(plans) != 1 || !plans[0].WeekStart.Equal(week) {
+		t.Errorf("expected the week from Sunday 2024-03-24, got %+v, %v", plans, err)
+	}
+	// A weekday is marked in the household's week containing weekStart.
+	logged, err = s.MarkPlanDayDone(week, "Saturday", models.CookingLogEntry{Status: models.CookingStatusCooked})
+	if err != nil {
+		t.Fatalf("MarkPlanDayDone: %v", err)
+	}
+	if logged.MealID != ids[2] || logged.CookedOn.Format(models.DateLayout) != "2024-03-30" {
+		t.Errorf("expected Saturday 2024-03-30 logged, got %+v", logged)
+	}
+}
+
+func testPantry(t *testing.T, s store.Store) {
+	items, err := s.ListPantryItems()
+	if err != nil {
+		t.Fatalf("ListPantryItems: %v", err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("expected an empty pantry, got %d items", len(items))
+	}
+
+	salt, err := s.CreatePantryItem(models.PantryItem{Name: " Salt ", Staple: true})
+	if err != nil {
+		t.Fatalf("CreatePantryItem: %v", err)
+	}
+	if salt.ID == 0 || salt.Name != "salt" {
+		t.Errorf("expected a normalized item with an ID, got %+v", salt)
+	}
+	if _, err := s.CreatePantryItem(models.PantryItem{Name: "rice", Quantity: 500, Unit: "g"}); err != nil {
+		t.Fatalf("CreatePantryItem: %v", err)
+	}
+	if _, err := s.CreatePantryItem(models.PantryItem{Name: ""}); err == nil {
+		t.Errorf("expected an error creating an item without a name")
+	}
+
+	salt.Quantity = 1
+	salt.Unit = "kg"
+	updated, err := s.UpdatePantryItem(*salt)
+	if err != nil {
+		t.Fatalf("UpdatePantryItem: %v", err)
+	}
+	if updated.Quantity != 1 || updated.Unit != "kg" {
+		t.Errorf("item not updated: %+v", updated)
+	}
+	if _, err := s.UpdatePantryItem(models.PantryItem{ID: 9999, Name: "pepper"}); !errors.Is(err, models.ErrPantryItemNotFound) {
+		t.Errorf("expected ErrPantryItemNotFound updating an unknown item, got %v", err)
+	}
+
+	items, _ = s.ListPantryItems()
+	if len(items) != 2 || items[0].Name != "rice" || items[1].Name != "salt" {
+		t.Errorf("expected rice and salt ordered by name, got %+v", items)
+	}
+
+	if err := s.DeletePantryItem(salt.ID); err != nil {
+		t.Fatalf("DeletePantryItem: %v", err)
+	}
+	if err := s.DeletePantryItem(salt.ID); !errors.Is(err, models.ErrPantryItemNotFound) {
+		t.Errorf("expected ErrPantryItemNotFound deleting twice, got %v", err)
+	}
+}
+
+func testAisles(t *testing.T, s store.Store) {
+	settings, err := s.GetAisleSettings()
+	if err != nil {
+		t.Fatalf("GetAisleSettings: %v", err)
+	}
+	if len(settings.Order) != len(models.DefaultAisleOrder) {
+		t.Errorf("expected the default store layout, got %v", settings.Order)
+	}
+
+	order, err := s.SaveAisleOrder([]string{models.AisleDairy})
+	if err != nil {
+		t.Fatalf("SaveAisleOrder: %v", err)
+	}
+	if len(order) == 0 || order[0] != models.AisleDairy {
+		t.Errorf("expected dairy first, got %v", order)
+	}
+	if _, err := s.SaveAisleOrder([]string{"space"}); err == nil {
+		t.Errorf("expected an error for an unknown aisle")
+	}
+
+	if err := s.SetAisleOverride("Tofu", models.AisleDairy); err != nil {
+		t.Fatalf("SetAisleOverride: %v", err)
+	}
+	if err := s.SetAisleOverride("tofu", "space"); err == nil {
+		t.Errorf("expected an error overriding to an unknown aisle")
+	}
+	settings, err = s.GetAisleSettings()
+	if err != nil {
+		t.Fatalf("GetAisleSettings: %v", err)
+	}
+	if settings.Order[0] != models.AisleDairy {
+		t.Errorf("expected the saved layout, got %v", settings.Order)
+	}
+	if settings.Overrides["tofu"] != models.AisleDairy {
+		t.Errorf("expected tofu overridden to dairy, got %v", settings.Overrides)
+	}
+
+	if err := s.SetAisleOverride("tofu", ""); err != nil {
+		t.Fatalf("SetAisleOverride: %v", err)
+	}
+	settings, _ = s.GetAisleSettings()
+	if _, ok := settings.Overrides["tofu"]; ok {
+		t.Errorf("expected the override to be cleared")
+	}
+}
+
+func testCalendar(t *testing.T, s store.Store) {
+	if _, err := s.GetCalendarToken(); !errors.Is(err, models.ErrNoCalendarToken) {
+		t.Errorf("expected ErrNoCalendarToken before the feed is set up, got %v", err)
+	}
+	token, err := s.RotateCalendarToken()
+	if err != nil {
+		t.Fatalf("RotateCalendarToken: %v", err)
+	}
+	if got, err := s.GetCalendarToken(); err != nil || got != token || token == "" {
+		t.Errorf("expected token %q, got %q, %v", token, got, err)
+	}
+	rotated, err := s.RotateCalendarToken()
+	if err != nil {
+		t.Fatalf("RotateCalendarToken: %v", err)
+	}
+	if got, _ := s.GetCalendarToken(); rotated == token || got != rotated {
+		t.Errorf("expected a new token replacing %q, got %q then %q", token, rotated, got)
+	}
+
+	// Saving a day again keeps its sequence unless its meal changes.
+	chili := mustCreateMeal(t, s, "Chili", 3)
+	curry := mustCreateMeal(t, s, "Curry", 3)
+	monday := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	sequences := func(plan map[string]*models.Meal) map[string]int {
+		t.Helper()
+		saved, err := s.SaveMealPlan(monday, plan, nil)
+		if err != nil {
+			t.Fatalf("SaveMealPlan: %v", err)
+		}
+		seqs := make(map[string]int)
+		for _, e := range saved.Entries {
+			seqs[e.Day] = e.Sequence
+		}
+		return seqs
+	}
+	if seqs := sequences(map[string]*models.Meal{"Monday": chili, "Tuesday": curry}); seqs["Monday"] != 0 || seqs["Tuesday"] != 0 {
+		t.Errorf("expected a new plan to start at sequence 0, got %v", seqs)
+	}
+	if _, err := s.MarkPlanDayDone(monday, "Monday", models.CookingLogEntry{Status: models.CookingStatusCooked}); err != nil {
+		t.Fatalf("MarkPlanDayDone: %v", err)
+	}
+	seqs := sequences(map[string]*models.Meal{"Monday": chili, "Tuesday": chili})
+	if seqs["Monday"] != 0 || seqs["Tuesday"] != 1 {
+		t.Errorf("expected only the swapped Tuesday to move on, got %v", seqs)
+	}
+	seqs = sequences(map[string]*models.Meal{"Monday": chili, "Tuesday": {MealName: models.EatingOutMealName}})
+	if seqs["Monday"] != 0 || seqs["Tuesday"] != 2 {
+		t.Errorf("expected eating out on Tuesday to move on again, got %v", seqs)
+	}
+}
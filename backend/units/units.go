@@ -0,0 +1,231 @@
+package units
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Dimension is the kind of measurement a unit belongs to. Only units of the same
+// dimension can be converted into one another.
+type Dimension string
+
+const (
+	Volume Dimension = "volume"
+	Mass   Dimension = "mass"
+	Count  Dimension = "count"
+)
+
+// Unit is a known measurement unit. Factor converts one of the unit into the
+// dimension's base unit (millilitres, grams or single items).
+type Unit struct {
+	Name      string
+	Dimension Dimension
+	Factor    float64
+}
+
+// known lists every unit by its canonical name.
+var known = map[string]Unit{
+	// Volume, in millilitres.
+	"ml":     {"ml", Volume, 1},
+	"l":      {"l", Volume, 1000},
+	"tsp":    {"tsp", Volume, 4.92892},
+	"tbsp":   {"tbsp", Volume, 14.7868},
+	"fl oz":  {"fl oz", Volume, 29.5735},
+	"cup":    {"cup", Volume, 236.588},
+	"pint":   {"pint", Volume, 473.176},
+	"quart":  {"quart", Volume, 946.353},
+	"gallon": {"gallon", Volume, 3785.41},
+
+	// Mass, in grams.
+	"g":  {"g", Mass, 1},
+	"kg": {"kg", Mass, 1000},
+	"oz": {"oz", Mass, 28.3495},
+	"lb": {"lb", Mass, 453.592},
+
+	// Count, in single items. The empty unit is a plain count ("2 onions").
+	"":      {"", Count, 1},
+	"dozen": {"dozen", Count, 12},
+}
+
+// aliases maps spellings found in recipes to canonical unit names.
+var aliases = map[string]string{
+	"milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml", "mls": "ml",
+	"liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"teaspoon": "tsp", "teaspoons": "tsp", "tsps": "tsp",
+	"tablespoon": "tbsp", "tablespoons": "tbsp", "tbsps": "tbsp", "tbs": "tbsp", "tbl": "tbsp",
+	"fluid ounce": "fl oz", "fluid ounces": "fl oz", "fl. oz": "fl oz", "floz": "fl oz",
+	"cups":  "cup",
+	"pints": "pint", "pt": "pint", "pts": "pint",
+	"quarts": "quart", "qt": "quart", "qts": "quart",
+	"gallons": "gallon", "gal": "gallon", "gals": "gallon",
+	"gram": "g", "grams": "g", "gr": "g", "grs": "g",
+	"kilogram": "kg", "kilograms": "kg", "kgs": "kg", "kilo": "kg", "kilos": "kg",
+	"ounce": "oz", "ounces": "oz",
+	"pound": "lb", "pounds": "lb", "lbs": "lb",
+	"dozens": "dozen", "doz": "dozen",
+	"each": "", "ea": "", "whole": "",
+}
+
+// packaging maps plural packaging and piece words to their singular form. They are
+// recognised as units ("2 cans beans") but can't be converted into anything else.
+var packaging = map[string]string{
+	"can": "can", "cans": "can",
+	"jar": "jar", "jars": "jar",
+	"bottle": "bottle", "bottles": "bottle",
+	"box": "box", "boxes": "box",
+	"bag": "bag", "bags": "bag",
+	"package": "package", "packages": "package", "pkg": "package",
+	"packet": "packet", "packets": "packet",
+	"bunch": "bunch", "bunches": "bunch",
+	"head": "head", "heads": "head",
+	"clove": "clove", "cloves": "clove",
+	"stick": "stick", "sticks": "stick",
+	"slice": "slice", "slices": "slice",
+	"piece": "piece", "pieces": "piece",
+	"sprig": "sprig", "sprigs": "sprig",
+	"stalk": "stalk", "stalks": "stalk",
+	"pinch": "pinch", "pinches": "pinch",
+	"dash": "dash", "dashes": "dash",
+	"handful": "handful", "handfuls": "handful",
+	"loaf": "loaf", "loaves": "loaf",
+	"pack": "pack", "packs": "pack",
+}
+
+// Canonical returns the canonical spelling of a unit ("Pounds" -> "lb"). Units that
+// aren't known are returned lower-cased and trimmed so they still compare equal.
+func Canonical(unit string) string {
+	u := strings.ToLower(strings.TrimSpace(unit))
+	u = strings.TrimSuffix(u, ".")
+	if _, ok := known[u]; ok {
+		return u
+	}
+	if c, ok := aliases[u]; ok {
+		return c
+	}
+	if c, ok := packaging[u]; ok {
+		return c
+	}
+	return u
+}
+
+// IsUnit reports whether word is a unit or packaging word that can follow a quantity.
+// The empty count unit and its aliases ("each", "whole") don't count as units here.
+func IsUnit(word string) bool {
+	u := strings.ToLower(strings.TrimSpace(word))
+	u = strings.TrimSuffix(u, ".")
+	if _, ok := packaging[u]; ok {
+		return true
+	}
+	c := Canonical(u)
+	_, ok := known[c]
+	return ok && c != ""
+}
+
+// Lookup returns the known unit for the given spelling.
+func Lookup(unit string) (Unit, bool) {
+	u, ok := known[Canonical(unit)]
+	return u, ok
+}
+
+// Compatible reports whether quantities in the two units can be added together.
+// Unknown units are only compatible with themselves.
+func Compatible(a, b string) bool {
+	ua, okA := Lookup(a)
+	ub, okB := Lookup(b)
+	if okA && okB {
+		return ua.Dimension == ub.Dimension
+	}
+	return Canonical(a) == Canonical(b)
+}
+
+// Convert converts qty from one unit to another. It reports false when the units aren't compatible.
+func Convert(qty float64, from, to string) (float64, bool) {
+	if Canonical(from) == Canonical(to) {
+		return qty, true
+	}
+	uf, okF := Lookup(from)
+	ut, okT := Lookup(to)
+	if !okF || !okT || uf.Dimension != ut.Dimension {
+		return 0, false
+	}
+	return qty * uf.Factor / ut.Factor, true
+}
+
+// Display picks a sensible unit for showing a total that was added up from quantities in
+// the given units. The largest unit used is preferred as long as the total is at least one
+// of it ("1 cup + 2 tbsp" -> "1.125 cup"); otherwise the next smaller unit used is tried
+// ("1/4 cup + 1 tbsp" -> "5 tbsp"). qty is in the first unit of used.
+func Display(qty float64, used []string) (float64, string) {
+	if len(used) == 0 {
+		return qty, ""
+	}
+	from := Canonical(used[0])
+	base, ok := Lookup(from)
+	if !ok {
+		return Round(qty), from
+	}
+
+	// Candidates are the known units used, largest first.
+	seen := make(map[string]bool)
+	var candidates []Unit
+	for _, name := range used {
+		u, ok := Lookup(name)
+		if !ok || u.Dimension != base.Dimension || seen[u.Name] {
+			continue
+		}
+		seen[u.Name] = true
+		candidates = append(candidates, u)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Factor > candidates[j].Factor
+	})
+
+	for _, u := range candidates {
+		converted := qty * base.Factor / u.Factor
+		if Round(converted) >= 1 {
+			return Round(converted), u.Name
+		}
+	}
+	smallest := candidates[len(candidates)-1]
+	return Round(qty * base.Factor / smallest.Factor), smallest.Name
+}
+
+// Round rounds a converted quantity to three decimal places to hide floating point noise.
+func Round(qty float64) float64 {
+	return math.Round(qty*1000) / 1000
+}
+
+// RoundScaled rounds a quantity that was scaled for a different number of servings to
+// something that can be measured in a kitchen: halves for counts and packaging, whole
+// numbers or multiples of 5 or 10 for grams and millilitres, and eighths, quarters or
+// whole numbers for other units as the quantity grows. A positive quantity never rounds
+// down to zero.
+func RoundScaled(qty float64, unit string) float64 {
+	if qty <= 0 {
+		return qty
+	}
+	step := 1.0
+	u, known := Lookup(unit)
+	switch {
+	case !known && IsUnit(unit), known && u.Dimension == Count:
+		step = 0.5
+	case known && u.Factor == 1:
+		// Grams and millilitres.
+		switch {
+		case qty >= 100:
+			step = 10
+		case qty >= 10:
+			step = 5
+		}
+	case qty < 1:
+		step = 0.125
+	case qty < 10:
+		step = 0.25
+	}
+	rounded := math.Round(qty/step) * step
+	if rounded == 0 {
+		rounded = step
+	}
+	return Round(rounded)
+}
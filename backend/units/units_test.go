@@ -0,0 +1,94 @@
+package units
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+	cases := map[string]string{
+		"lb":          "lb",
+		"lbs":         "lb",
+		"Pounds":      "lb",
+		"Tbsp.":       "tbsp",
+		"tablespoons": "tbsp",
+		"cups":        "cup",
+		"Jar":         "jar",
+		"":            "",
+	}
+	for in, want := range cases {
+		if got := Canonical(in); got != want {
+			t.Errorf("Canonical(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	got, ok := Convert(3, "tsp", "tbsp")
+	if !ok || Round(got) != 1 {
+		t.Errorf("expected 3 tsp = 1 tbsp, got %v (%v)", got, ok)
+	}
+	got, ok = Convert(16, "ounces", "pound")
+	if !ok || Round(got) != 1 {
+		t.Errorf("expected 16 oz = 1 lb, got %v (%v)", got, ok)
+	}
+	if _, ok := Convert(1, "cup", "lb"); ok {
+		t.Errorf("expected volume and mass to be incompatible")
+	}
+	if _, ok := Convert(1, "jar", "can"); ok {
+		t.Errorf("expected unknown units to be incompatible with each other")
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	if !Compatible("cup", "tbsp") || !Compatible("dozen", "") || !Compatible("jar", "Jar.") {
+		t.Errorf("expected units of the same dimension to be compatible")
+	}
+	if Compatible("cup", "oz") || Compatible("jar", "") {
+		t.Errorf("expected units of different dimensions to be incompatible")
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	tests := []struct {
+		qty      float64
+		used     []string
+		wantQty  float64
+		wantUnit string
+	}{
+		{1.125, []string{"cup", "tbsp"}, 1.125, "cup"},
+		{0.3125, []string{"cup", "tbsp"}, 5, "tbsp"},
+		{2, []string{"tbsp", "tsp", "tsp"}, 2, "tbsp"},
+		{20, []string{"oz", "lb"}, 1.25, "lb"},
+		{2, []string{"jar"}, 2, "jar"},
+	}
+	for _, tt := range tests {
+		qty, unit := Display(tt.qty, tt.used)
+		if qty != tt.wantQty || unit != tt.wantUnit {
+			t.Errorf("Display(%v, %v) = %v %q, want %v %q", tt.qty, tt.used, qty, unit, tt.wantQty, tt.wantUnit)
+		}
+	}
+}
+
+func TestRoundScaled(t *testing.T) {
+	tests := []struct {
+		qty  float64
+		unit string
+		want float64
+	}{
+		{1.3333, "", 1.5},
+		{0.1, "cans", 0.5},
+		{2.6667, "whole", 2.5},
+		{6.6667, "g", 7},
+		{42, "ml", 40},
+		{333.33, "g", 330},
+		{0.0416, "tsp", 0.125},
+		{0.6667, "cup", 0.625},
+		{1.3333, "lb", 1.25},
+		{13.3333, "oz", 13},
+		{0.3, "sprinkles", 0.25},
+		{0, "cup", 0},
+	}
+	for _, tt := range tests {
+		if got := RoundScaled(tt.qty, tt.unit); got != tt.want {
+			t.Errorf("RoundScaled(%v, %q) = %v, want %v", tt.qty, tt.unit, got, tt.want)
+		}
+	}
+}
@@ -84,7 +84,7 @@ describe("App", () => {
         };
 
         (global.fetch as jest.Mock).mockImplementation((url, options) => {
-            if (url.toString().includes("/api/meals/swap")) {
+            if (url.toString().includes("/api/mealplan/swap")) {
                 return Promise.resolve({
                     ok: true,
                     json: () => Promise.resolve(swappedMeal)
@@ -107,7 +107,7 @@ describe("App", () => {
 
         // Verify the API call was made
         expect(global.fetch).toHaveBeenCalledWith(
-            expect.stringContaining("/api/meals/swap"),
+            expect.stringContaining("/api/mealplan/swap"),
             expect.any(Object)
         );
     });
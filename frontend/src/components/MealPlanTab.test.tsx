@@ -140,7 +140,7 @@ describe("MealPlanTab", () => {
         // Mock the swap endpoint with a proper implementation
         const originalFetch = global.fetch;
         global.fetch = jest.fn().mockImplementation((url, options) => {
-            if (url.toString().includes("/api/meals/swap")) {
+            if (url.toString().includes("/api/mealplan/swap")) {
                 return Promise.resolve({
                     ok: true,
                     json: () => Promise.resolve(newMeal),
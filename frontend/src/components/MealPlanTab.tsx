@@ -114,12 +114,15 @@ export const MealPlanTab: React.FC<MealPlanTabProps> = ({ showToast }) => {
     const swapMeal = (day: string) => {
         const currentMeal = mealPlan ? mealPlan[day] : null;
         if (!currentMeal) return;
-        fetch("/api/meals/swap", {
+        fetch("/api/mealplan/swap", {
             method: "POST",
             headers: { "Content-Type": "application/json" },
-            body: JSON.stringify({ meal_id: currentMeal.id }),
+            body: JSON.stringify({ day, plan: mealPlan }),
         })
-            .then((res) => res.json())
+            .then((res) => {
+                if (!res.ok) throw new Error("Failed to swap meal");
+                return res.json();
+            })
             .then((newMeal: Meal) => {
                 setMealPlan({ ...mealPlan, [day]: newMeal });
                 setShoppingList([]);
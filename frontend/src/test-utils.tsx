@@ -75,7 +75,7 @@ export const setupFetchMocks = (options?: {
     global.fetch = jest.fn((url: RequestInfo) => {
         const urlStr = url.toString();
 
-        if (urlStr.includes("/api/mealplan") && !urlStr.includes("replace") && !urlStr.includes("generate") && !urlStr.includes("finalize")) {
+        if (urlStr.includes("/api/mealplan") && !urlStr.includes("replace") && !urlStr.includes("swap") && !urlStr.includes("generate") && !urlStr.includes("finalize")) {
             return Promise.resolve({
                 ok: true,
                 json: () => Promise.resolve(mocks.mealPlan),